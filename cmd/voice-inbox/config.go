@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"voice-inbox-daemon/internal/config"
+)
+
+func runConfig(opts config.Options, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "config requires a subcommand: show, validate")
+		return 1
+	}
+	switch args[0] {
+	case "show":
+		return runConfigShow(opts, args[1:])
+	case "validate":
+		return runConfigValidate(opts, args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown config subcommand: %s\n", args[0])
+		return 1
+	}
+}
+
+func runConfigShow(opts config.Options, args []string) int {
+	fs := flag.NewFlagSet("config show", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "output as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	opts.Command = "config"
+	cfg, report, err := config.Resolve(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config error: %v\n", err)
+		return 1
+	}
+	for i, s := range report.Settings {
+		if s.Secret {
+			report.Settings[i].Value = config.RedactValue(s.Value)
+		}
+	}
+
+	if *asJSON {
+		printJSON(map[string]any{
+			"file":     report.File,
+			"settings": report.Settings,
+			"channels": cfg.Channels,
+			"sinks":    cfg.Sinks,
+			"routes":   cfg.Routes,
+			"problems": report.Problems,
+		})
+		return 0
+	}
+
+	fmt.Printf("file=%s\n", orDash(report.File))
+	tw := tabwriter.NewWriter(os.Stdout, 0, 2, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, s := range report.Settings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Key, orDash(s.Value), s.Source)
+	}
+	_ = tw.Flush()
+	fmt.Println("channels:")
+	for _, ch := range cfg.Channels {
+		fmt.Printf("  - id=%s allowed_author_ids=%s\n", ch.ID, strings.Join(ch.AllowedAuthorIDs, ","))
+	}
+	if len(cfg.Sinks) > 0 {
+		fmt.Println("sinks:")
+		for _, sink := range cfg.Sinks {
+			fmt.Printf("  - name=%s type=%s base_url=%s journal_dir=%s\n", sink.Name, sink.Type, sink.BaseURL, sink.JournalDir)
+		}
+	}
+	if len(cfg.Routes) > 0 {
+		fmt.Println("routes:")
+		for _, route := range cfg.Routes {
+			fmt.Printf("  - name=%s prefix=%s match=%s path=%s sink=%s tags=%s\n", route.Name, orDash(strings.Join(route.Prefixes, ",")), orDash(route.Match), orDash(route.Path), orDash(route.Sink), orDash(strings.Join(route.Tags, ",")))
+		}
+	}
+	printProblems(report.Problems)
+	return 0
+}
+
+func runConfigValidate(opts config.Options, args []string) int {
+	fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	command := fs.String("command", "daemon", "validate the requirements of this command")
+	asJSON := fs.Bool("json", false, "output as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	opts.Command = *command
+	_, report, err := config.Resolve(opts)
+	if err != nil {
+		report.Problems = append(report.Problems, err.Error())
+	}
+	var syntaxErr *config.SyntaxError
+	badSyntax := errors.As(err, &syntaxErr)
+	if *asJSON {
+		out := map[string]any{
+			"file":     report.File,
+			"command":  *command,
+			"valid":    len(report.Problems) == 0,
+			"problems": report.Problems,
+		}
+		if badSyntax {
+			out["supported_syntax"] = config.TOMLSubset
+		}
+		printJSON(out)
+	} else if len(report.Problems) == 0 {
+		fmt.Printf("ok file=%s command=%s\n", orDash(report.File), *command)
+	} else {
+		printProblems(report.Problems)
+		if badSyntax {
+			fmt.Fprintln(os.Stderr, config.TOMLSubset)
+		}
+	}
+	if len(report.Problems) > 0 {
+		return 1
+	}
+	return 0
+}
+
+func printProblems(problems []string) {
+	if len(problems) == 0 {
+		return
+	}
+	fmt.Fprintln(os.Stderr, "problems:")
+	for _, p := range problems {
+		fmt.Fprintf(os.Stderr, "- %s\n", p)
+	}
+}
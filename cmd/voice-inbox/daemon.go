@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"voice-inbox-daemon/internal/config"
+	"voice-inbox-daemon/internal/daemon"
+	"voice-inbox-daemon/internal/ingest"
+	"voice-inbox-daemon/internal/metrics"
+	"voice-inbox-daemon/internal/pipeline"
+)
+
+const captureInterval = 5 * time.Second
+
+func runDaemon(live *liveRunner, args []string) int {
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	cfg := live.config()
+	hour, minute, err := config.ParseClock(cfg.CleanupAt)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config error: %v\n", err)
+		return 1
+	}
+	cleanupSchedule := daemon.DailyAt(hour, minute)
+
+	loops := []daemon.Loop{
+		captureLoop(live),
+		sinkProbeLoop(live),
+		{
+			Name:     "poll",
+			Schedule: daemon.Every(time.Duration(cfg.PollIntervalSeconds) * time.Second),
+			Timeout:  30 * time.Minute,
+			Run:      live.loop((*pipeline.Runner).PollOnce),
+		},
+		{
+			Name:     "retry",
+			Schedule: daemon.Every(time.Duration(cfg.RetryIntervalSeconds) * time.Second),
+			Timeout:  30 * time.Minute,
+			First: func(now time.Time) time.Time {
+				return now.Add(time.Duration(cfg.RetryIntervalSeconds) * time.Second)
+			},
+			Run: live.loop((*pipeline.Runner).Retry),
+		},
+		{
+			Name:     "cleanup",
+			Schedule: cleanupSchedule,
+			Timeout:  10 * time.Minute,
+			First: func(now time.Time) time.Time {
+				last, ok, err := live.store.LastSuccessfulRun("cleanup")
+				if err == nil && (!ok || now.Sub(last) > 24*time.Hour) {
+					return now
+				}
+				return cleanupSchedule.Next(now)
+			},
+			Run: live.loop((*pipeline.Runner).Cleanup),
+		},
+	}
+	if cfg.DigestAt != "" {
+		hour, minute, err := config.ParseClock(cfg.DigestAt)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "config error: %v\n", err)
+			return 1
+		}
+		loops = append(loops, daemon.Loop{
+			Name:     "digest",
+			Schedule: daemon.DailyAt(hour, minute),
+			Timeout:  10 * time.Minute,
+			Run:      live.loop((*pipeline.Runner).ScheduledDigest),
+		})
+	}
+	return serveWithLoops(live, loops)
+}
+
+func captureLoop(live *liveRunner) daemon.Loop {
+	return daemon.Loop{
+		Name:     "captures",
+		Schedule: daemon.Every(captureInterval),
+		Timeout:  30 * time.Minute,
+		Run:      live.loop((*pipeline.Runner).ProcessCapturesOnce),
+	}
+}
+
+func sinkProbeLoop(live *liveRunner) daemon.Loop {
+	return daemon.Loop{
+		Name:     "sink-probe",
+		Schedule: daemon.Every(time.Duration(live.config().ProbeIntervalSeconds) * time.Second),
+		Timeout:  30 * time.Minute,
+		Run:      live.loop((*pipeline.Runner).ProbeSinks),
+	}
+}
+
+func loopRun(fn func(context.Context) (pipeline.Result, error)) func(context.Context) error {
+	return func(ctx context.Context) error {
+		res, err := fn(ctx)
+		level := slog.LevelDebug
+		if res.Processed > 0 || res.Failed > 0 {
+			level = slog.LevelInfo
+		}
+		slog.Log(ctx, level, "run finished",
+			"command", res.Command,
+			"run_id", res.RunID,
+			"processed", res.Processed,
+			"succeeded", res.Succeeded,
+			"failed", res.Failed,
+			"requeued", res.Requeued,
+			"duration_ms", res.DurationMS,
+		)
+		if err != nil && res.Aborted() {
+			return err
+		}
+		return nil
+	}
+}
+
+func serveWithLoops(live *liveRunner, loops []daemon.Loop) int {
+	cfg := live.config()
+	supervisor := daemon.New(loops, daemon.Options{
+		BackoffMax:    time.Duration(cfg.LoopBackoffMaxSeconds) * time.Second,
+		ShutdownGrace: 30 * time.Second,
+	})
+	pipeline.RegisterMetrics(metrics.Default, live.current)
+	supervisor.RegisterMetrics(metrics.Default)
+
+	server := ingest.NewServer(cfg, live.store)
+	server.SetHealthReporter(func() (any, bool) {
+		return supervisor.Health()
+	})
+	httpServer := &http.Server{
+		Addr:              cfg.IngestListenAddr,
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: 15 * time.Second,
+	}
+
+	live.attach(server)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				slog.Info("SIGHUP received; reloading config")
+				live.reload()
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		supervisor.Run(ctx)
+	}()
+
+	go func() {
+		<-ctx.Done()
+		slog.Info("shutting down")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		_ = httpServer.Shutdown(shutdownCtx)
+	}()
+
+	slog.Info("ingest server listening", "addr", cfg.IngestListenAddr)
+	code := 0
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("ingest server failed", "addr", cfg.IngestListenAddr, "error", err)
+		code = 1
+		stop()
+	}
+	wg.Wait()
+	return code
+}
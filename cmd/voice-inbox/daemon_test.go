@@ -0,0 +1,27 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"voice-inbox-daemon/internal/pipeline"
+)
+
+func TestLoopRunCountsOnlyAbortedRunsAsFailures(t *testing.T) {
+	runErr := errors.New("run failed")
+	for _, tc := range []struct {
+		name    string
+		res     pipeline.Result
+		wantErr bool
+	}{
+		{"lock or fetch error", pipeline.Result{Failed: 1}, true},
+		{"only item failed", pipeline.Result{Processed: 1, Failed: 1}, false},
+		{"some items failed", pipeline.Result{Processed: 3, Succeeded: 2, Failed: 1}, false},
+	} {
+		run := loopRun(func(context.Context) (pipeline.Result, error) { return tc.res, runErr })
+		if err := run(context.Background()); (err != nil) != tc.wantErr {
+			t.Fatalf("%s: got err=%v, want error=%v", tc.name, err, tc.wantErr)
+		}
+	}
+}
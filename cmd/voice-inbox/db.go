@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"voice-inbox-daemon/internal/config"
+	"voice-inbox-daemon/internal/state"
+)
+
+func runDB(cfg config.Config, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "db requires a subcommand: migrate, backup, restore")
+		return 1
+	}
+	switch args[0] {
+	case "migrate":
+		return runDBMigrate(cfg, args[1:])
+	case "backup":
+		return runDBBackup(cfg, args[1:])
+	case "restore":
+		return runDBRestore(cfg, args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown db subcommand: %s\n", args[0])
+		return 1
+	}
+}
+
+func runDBMigrate(cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("db migrate", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "show pending migrations without applying them")
+	status := fs.Bool("status", false, "show applied and pending migrations")
+	asJSON := fs.Bool("json", false, "output as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	store, err := state.OpenUnmigrated(cfg.StateDBPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open state db: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	version, err := store.SchemaVersion()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read schema version: %v\n", err)
+		return 1
+	}
+
+	var migrations []state.MigrationStatus
+	switch {
+	case *status:
+		migrations, err = store.MigrationStatus()
+	case *dryRun:
+		migrations, err = store.PendingMigrations()
+	default:
+		migrations, err = store.Migrate()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "db migrate: %v\n", err)
+		return 1
+	}
+
+	if *asJSON {
+		printJSON(map[string]any{
+			"schema_version": version,
+			"latest_version": state.LatestSchemaVersion(),
+			"dry_run":        *dryRun,
+			"migrations":     migrations,
+		})
+		return 0
+	}
+
+	fmt.Printf("schema_version=%d latest_version=%d\n", version, state.LatestSchemaVersion())
+	if len(migrations) == 0 {
+		fmt.Println("no pending migrations")
+		return 0
+	}
+	for _, m := range migrations {
+		switch {
+		case m.AppliedAt != nil && !*status:
+			fmt.Printf("applied %3d %s\n", m.Version, m.Name)
+		case m.AppliedAt != nil:
+			fmt.Printf("applied %3d %s (%s)\n", m.Version, m.Name, m.AppliedAt.Format(time.RFC3339))
+		default:
+			fmt.Printf("pending %3d %s\n", m.Version, m.Name)
+			if *dryRun {
+				for _, stmt := range m.Stmts {
+					fmt.Printf("    %s;\n", strings.Join(strings.Fields(stmt), " "))
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func runDBBackup(cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("db backup", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "output as JSON")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 1
+	}
+	var path string
+	switch {
+	case len(positional) > 0:
+		path = positional[0]
+	case cfg.BackupDir != "":
+		path = filepath.Join(cfg.BackupDir, state.BackupFileName(time.Now()))
+	default:
+		fmt.Fprintln(os.Stderr, "usage: voice-inbox db backup <path> (or set BACKUP_DIR)")
+		return 1
+	}
+
+	store, err := state.OpenUnmigrated(cfg.StateDBPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "open state db: %v\n", err)
+		return 1
+	}
+	defer store.Close()
+
+	info, err := store.BackupTo(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "db backup: %v\n", err)
+		return 1
+	}
+	if *asJSON {
+		printJSON(info)
+		return 0
+	}
+	fmt.Printf("backed up %s -> %s (%d bytes, schema_version=%d)\n", cfg.StateDBPath, info.Path, info.SizeBytes, info.SchemaVersion)
+	return 0
+}
+
+func runDBRestore(cfg config.Config, args []string) int {
+	fs := flag.NewFlagSet("db restore", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "only verify the backup, do not replace the state db")
+	asJSON := fs.Bool("json", false, "output as JSON")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 1
+	}
+	if len(positional) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: voice-inbox db restore <backup-path> [--dry-run]")
+		return 1
+	}
+	src := positional[0]
+
+	if *dryRun {
+		info, err := state.CheckIntegrity(src)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "db restore: %v\n", err)
+			return 1
+		}
+		if *asJSON {
+			printJSON(map[string]any{"dry_run": true, "backup": info})
+			return 0
+		}
+		fmt.Printf("%s: integrity ok (%d bytes, schema_version=%d)\n", src, info.SizeBytes, info.SchemaVersion)
+		return 0
+	}
+
+	lock, err := state.AcquireFileLock(cfg.LockFilePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "db restore: another run is in progress: %v\n", err)
+		return 1
+	}
+	defer lock.Release()
+
+	info, previous, err := state.Restore(src, cfg.StateDBPath, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "db restore: %v\n", err)
+		return 1
+	}
+	if *asJSON {
+		printJSON(map[string]any{"dry_run": false, "restored": info, "previous": previous})
+		return 0
+	}
+	fmt.Printf("restored %s -> %s (schema_version=%d)\n", src, info.Path, info.SchemaVersion)
+	if previous != "" {
+		fmt.Printf("previous state db kept at %s\n", previous)
+	}
+	return 0
+}
+
+func printJSON(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"voice-inbox-daemon/internal/logging"
+	"voice-inbox-daemon/internal/pipeline"
+	"voice-inbox-daemon/internal/state"
+)
+
+func runFailed(runner *pipeline.Runner, args []string) int {
+	if len(args) == 0 || args[0] != "list" {
+		fmt.Fprintln(os.Stderr, "usage: voice-inbox failed list [--source discord|capture] [--json]")
+		return 1
+	}
+	fs := flag.NewFlagSet("failed list", flag.ContinueOnError)
+	source := fs.String("source", "", "discord or capture")
+	asJSON := fs.Bool("json", false, "output as JSON")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	res, err := runner.FailedList(ctx, *source)
+	if err != nil || *asJSON {
+		printResult(res, *asJSON)
+		return res.ExitCode()
+	}
+
+	items, _ := res.Data["items"].([]state.FailedItem)
+	if len(items) == 0 {
+		fmt.Println("no failed items")
+		return 0
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SOURCE\tID\tSTAGE\tATTEMPTS\tNEXT RETRY\tLAST ERROR")
+	for _, item := range items {
+		next := "never"
+		if item.NextRetryAt != nil {
+			next = item.NextRetryAt.Local().Format(time.DateTime)
+		}
+		stage := item.Stage
+		if stage == "" {
+			stage = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\n", item.Source, item.ID, stage, item.Attempts, next, logging.Redact(oneLine(item.LastError, 80)))
+	}
+	_ = tw.Flush()
+	return 0
+}
+
+func runRequeue(runner *pipeline.Runner, args []string) int {
+	fs := flag.NewFlagSet("requeue", flag.ContinueOnError)
+	allFailed := fs.Bool("all-failed", false, "requeue every item that exhausted its retries")
+	sinceRaw := fs.String("since", "", "with --all-failed, only items failed since this time (RFC3339, YYYY-MM-DD or a duration like 24h)")
+	asJSON := fs.Bool("json", false, "output as JSON")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 1
+	}
+	if *allFailed == (len(positional) == 1) || len(positional) > 1 {
+		fmt.Fprintln(os.Stderr, "usage: voice-inbox requeue <id> | --all-failed [--since <time>] [--json]")
+		return 1
+	}
+	if *sinceRaw != "" && !*allFailed {
+		fmt.Fprintln(os.Stderr, "--since requires --all-failed")
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	var res pipeline.Result
+	if *allFailed {
+		var since *time.Time
+		if *sinceRaw != "" {
+			t, err := parseSince(*sinceRaw, time.Now())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "invalid --since: %v\n", err)
+				return 1
+			}
+			since = &t
+		}
+		res, err = runner.RequeueAllFailed(ctx, since)
+	} else {
+		res, err = runner.Requeue(ctx, positional[0])
+	}
+	printResult(res, *asJSON)
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+func runAbandon(runner *pipeline.Runner, args []string) int {
+	fs := flag.NewFlagSet("abandon", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "output as JSON")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 1
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: voice-inbox abandon <id> [--json]")
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	res, err := runner.Abandon(ctx, positional[0])
+	printResult(res, *asJSON)
+	if err != nil {
+		return 1
+	}
+	return 0
+}
+
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if consumed := len(args) - fs.NArg(); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, fs.Args()...), nil
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func parseSince(raw string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, raw, time.Local); err == nil {
+		return t, nil
+	}
+	if d, err := time.ParseDuration(raw); err == nil && d > 0 {
+		return now.Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not RFC3339, YYYY-MM-DD or a positive duration", raw)
+}
+
+func oneLine(s string, max int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > max {
+		return string(r[:max-1]) + "…"
+	}
+	return s
+}
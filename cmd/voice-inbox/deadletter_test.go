@@ -0,0 +1,30 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"testing"
+)
+
+func TestParseInterspersedStopsAtDoubleDash(t *testing.T) {
+	for _, tc := range []struct {
+		args       []string
+		json       bool
+		positional []string
+	}{
+		{args: []string{"cap-1", "--json"}, json: true, positional: []string{"cap-1"}},
+		{args: []string{"--", "cap-1"}, positional: []string{"cap-1"}},
+		{args: []string{"--json", "--", "-cap", "--json"}, json: true, positional: []string{"-cap", "--json"}},
+		{args: []string{"cap-1", "--", "--all-failed"}, positional: []string{"cap-1", "--all-failed"}},
+	} {
+		fs := flag.NewFlagSet("requeue", flag.ContinueOnError)
+		asJSON := fs.Bool("json", false, "")
+		positional, err := parseInterspersed(fs, tc.args)
+		if err != nil {
+			t.Fatalf("%q: %v", tc.args, err)
+		}
+		if *asJSON != tc.json || !reflect.DeepEqual(positional, tc.positional) {
+			t.Fatalf("%q: got json=%v positional=%q", tc.args, *asJSON, positional)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"voice-inbox-daemon/internal/pipeline"
+)
+
+func runDigest(runner *pipeline.Runner, args []string) int {
+	fs := flag.NewFlagSet("digest", flag.ContinueOnError)
+	day := fs.String("day", "", "digest of this day (YYYY-MM-DD, default today)")
+	week := fs.String("week", "", "weekly digest note for this ISO week (YYYY-Www)")
+	dryRun := fs.Bool("dry-run", false, "print the digest instead of writing it to the vault")
+	asJSON := fs.Bool("json", false, "output as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	period, err := pipeline.ParseDigestPeriod(*day, *week, time.Now())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, "usage: voice-inbox digest [--day YYYY-MM-DD | --week YYYY-Www] [--dry-run] [--json]")
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	res, err := runner.Digest(ctx, period, *dryRun)
+	if err != nil || *asJSON {
+		printResult(res, *asJSON)
+		return res.ExitCode()
+	}
+	if *dryRun {
+		fmt.Print(res.Data["markdown"])
+		return 0
+	}
+	if skipped, ok := res.Data["skipped"]; ok {
+		fmt.Printf("%s: %s\n", period.Label, skipped)
+		return 0
+	}
+	fmt.Printf("%s: %d entries -> %s\n", period.Label, res.Data["entries"], res.Data["journal_path"])
+	return 0
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"voice-inbox-daemon/internal/pipeline"
+	"voice-inbox-daemon/internal/state"
+)
+
+func runExport(runner *pipeline.Runner, args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", pipeline.ExportJSONL, "output format: jsonl or csv")
+	tablesRaw := fs.String("table", strings.Join(state.ExportTables, ","), "comma-separated tables to export (messages, captures, runs)")
+	outDir := fs.String("out", "", "write one <table>.<format> file per table into this directory instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+	if *format != pipeline.ExportJSONL && *format != pipeline.ExportCSV {
+		fmt.Fprintf(os.Stderr, "unknown --format %q (want jsonl or csv)\n", *format)
+		return 1
+	}
+	var tables []string
+	for _, t := range strings.Split(*tablesRaw, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if !state.ValidExportTable(t) {
+			fmt.Fprintf(os.Stderr, "unknown --table %q (want one of %s)\n", t, strings.Join(state.ExportTables, ", "))
+			return 1
+		}
+		tables = append(tables, t)
+	}
+	if len(tables) == 0 {
+		fmt.Fprintln(os.Stderr, "export requires at least one table")
+		return 1
+	}
+	if *outDir == "" && *format == pipeline.ExportCSV && len(tables) > 1 {
+		fmt.Fprintln(os.Stderr, "csv export to stdout needs a single --table; use --out <dir> to export several tables")
+		return 1
+	}
+
+	if *outDir == "" {
+		w := bufio.NewWriter(os.Stdout)
+		for _, table := range tables {
+			if _, err := runner.ExportTable(w, *format, table); err != nil {
+				_ = w.Flush()
+				fmt.Fprintf(os.Stderr, "export %s: %v\n", table, err)
+				return 1
+			}
+		}
+		if err := w.Flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "export: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	if err := os.MkdirAll(*outDir, 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "create export dir: %v\n", err)
+		return 1
+	}
+	for _, table := range tables {
+		path := filepath.Join(*outDir, table+"."+*format)
+		n, err := exportToFile(runner, path, *format, table)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "export %s: %v\n", table, err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "exported %d %s rows to %s\n", n, table, path)
+	}
+	return 0
+}
+
+func exportToFile(runner *pipeline.Runner, path, format, table string) (int, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return 0, err
+	}
+	w := bufio.NewWriter(f)
+	n, err := runner.ExportTable(w, format, table)
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return n, err
+}
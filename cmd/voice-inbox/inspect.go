@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"voice-inbox-daemon/internal/logging"
+	"voice-inbox-daemon/internal/pipeline"
+)
+
+func runInspect(runner *pipeline.Runner, args []string) int {
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "output as JSON")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 1
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: voice-inbox inspect <message_id|capture_id> [--json]")
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	res, err := runner.Inspect(ctx, positional[0])
+	if err != nil || *asJSON {
+		printResult(res, *asJSON)
+		if err != nil {
+			return 1
+		}
+		return 0
+	}
+
+	report, _ := res.Data["item"].(pipeline.InspectReport)
+	fmt.Printf("%s %s\n", report.Source, report.ID)
+	switch {
+	case report.Message != nil:
+		m := report.Message
+		fmt.Printf("  status=%s stage=%s attempts=%d\n", m.Status, orDash(m.Stage), m.Attempts)
+		fmt.Printf("  content_type=%s attachment=%s\n", orDash(m.ContentType), orDash(m.AttachmentFilename))
+		printNextRetry(m.NextRetryAt)
+		if m.LastError != "" {
+			fmt.Printf("  last_error=%s\n", logging.Redact(m.LastError))
+		}
+		if m.DiscordJumpURL != "" {
+			fmt.Printf("  discord=%s\n", m.DiscordJumpURL)
+		}
+	case report.Capture != nil:
+		c := report.Capture
+		fmt.Printf("  status=%s stage=%s attempts=%d\n", c.Status, orDash(c.Stage), c.Attempts)
+		fmt.Printf("  source=%s device=%s content_type=%s\n", c.Source, orDash(c.DeviceID), orDash(c.ContentType))
+		if c.CapturedAt != nil {
+			fmt.Printf("  captured_at=%s\n", c.CapturedAt.Local().Format(time.DateTime))
+		}
+		printNextRetry(c.NextRetryAt)
+		if c.LastError != "" {
+			fmt.Printf("  last_error=%s\n", logging.Redact(c.LastError))
+		}
+	}
+
+	for _, a := range report.Artifacts {
+		presence := "present"
+		if !a.Exists {
+			presence = "missing"
+		}
+		fmt.Printf("  %s=%s (%s)\n", a.Kind, a.Path, presence)
+	}
+	if j := report.Journal; j != nil {
+		marker := "marker found"
+		switch {
+		case j.Error != "":
+			marker = "marker unknown: " + logging.Redact(j.Error)
+		case !j.MarkerFound:
+			marker = "marker NOT found"
+		}
+		fmt.Printf("  journal=%s (sink=%s, %s)\n", j.Path, j.Sink, marker)
+	}
+
+	fmt.Println("history:")
+	for _, ev := range report.Events {
+		line := fmt.Sprintf("  %s %-16s", ev.CreatedAt.Local().Format(time.DateTime), ev.Event)
+		if ev.Status != "" {
+			line += " status=" + ev.Status
+		}
+		if ev.Stage != "" {
+			line += " stage=" + ev.Stage
+		}
+		if ev.Attempt > 0 {
+			line += fmt.Sprintf(" attempt=%d", ev.Attempt)
+		}
+		if ev.Error != "" {
+			line += " error=" + logging.Redact(oneLine(ev.Error, 120))
+		}
+		fmt.Println(line)
+	}
+	return 0
+}
+
+func printNextRetry(next *time.Time) {
+	if next != nil {
+		fmt.Printf("  next_retry_at=%s\n", next.Local().Format(time.DateTime))
+	}
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"voice-inbox-daemon/internal/config"
+	"voice-inbox-daemon/internal/systemd"
+)
+
+func runInstall(cfg config.Config, args []string) int {
+	if len(args) == 0 || args[0] != "systemd" {
+		fmt.Fprintln(os.Stderr, "install requires a target: systemd")
+		return 1
+	}
+
+	fs := flag.NewFlagSet("install systemd", flag.ContinueOnError)
+	userUnits := fs.Bool("user", false, "install user units instead of system units")
+	mode := fs.String("mode", systemd.ModeDaemon, "daemon (single long-running service) or timers (serve + poll/cleanup timers)")
+	outDir := fs.String("out", "", "directory to write units to (default: systemd unit directory)")
+	dryRun := fs.Bool("dry-run", false, "print units instead of writing them")
+	force := fs.Bool("force", false, "overwrite existing unit files")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 1
+	}
+
+	binary, err := os.Executable()
+	if err == nil {
+		binary, err = filepath.EvalSymlinks(binary)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "resolve binary path: %v\n", err)
+		return 1
+	}
+	workDir, err := os.Getwd()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "resolve working directory: %v\n", err)
+		return 1
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "resolve home: %v\n", err)
+		return 1
+	}
+
+	hour, minute, err := config.ParseClock(cfg.CleanupAt)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config error: %v\n", err)
+		return 1
+	}
+	opts := systemd.Options{
+		Mode:       *mode,
+		User:       *userUnits,
+		Binary:     binary,
+		WorkingDir: workDir,
+		ReadWritePaths: []string{
+			filepath.Dir(cfg.StateDBPath),
+			cfg.AudioStoreDir,
+			cfg.LogDir,
+			cfg.BackupDir,
+			filepath.Join(home, ".cache"),
+		},
+		PollIntervalSeconds: cfg.PollIntervalSeconds,
+		CleanupHour:         hour,
+		CleanupMinute:       minute,
+	}
+	if !*userUnits {
+		if u, err := user.Current(); err == nil {
+			opts.RunAs = u.Username
+		}
+	}
+	units, err := systemd.Render(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "install systemd: %v\n", err)
+		return 1
+	}
+
+	if *dryRun {
+		for _, u := range units {
+			fmt.Printf("# %s\n%s\n", u.Name, u.Content)
+		}
+		return 0
+	}
+
+	dir := *outDir
+	if dir == "" {
+		dir = "/etc/systemd/system"
+		if *userUnits {
+			dir = filepath.Join(config.ConfigHome(home), "systemd", "user")
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "create unit dir: %v\n", err)
+		return 1
+	}
+	for _, u := range units {
+		path := filepath.Join(dir, u.Name)
+		if _, err := os.Stat(path); err == nil && !*force {
+			fmt.Fprintf(os.Stderr, "%s already exists (use --force to overwrite)\n", path)
+			return 1
+		}
+	}
+	var enable []string
+	for _, u := range units {
+		path := filepath.Join(dir, u.Name)
+		if err := os.WriteFile(path, []byte(u.Content), 0o644); err != nil {
+			fmt.Fprintf(os.Stderr, "write %s: %v\n", path, err)
+			return 1
+		}
+		fmt.Printf("wrote %s\n", path)
+		if u.Enable() {
+			enable = append(enable, u.Name)
+		}
+	}
+
+	systemctl := "sudo systemctl"
+	if *userUnits {
+		systemctl = "systemctl --user"
+	}
+	fmt.Println("next:")
+	fmt.Printf("  %s daemon-reload\n", systemctl)
+	fmt.Printf("  %s enable --now %s\n", systemctl, strings.Join(enable, " "))
+	if *userUnits {
+		fmt.Println("  loginctl enable-linger \"$USER\"  # keep running after logout")
+	}
+	return 0
+}
@@ -3,20 +3,15 @@ package main
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"flag"
 	"fmt"
-	"net/http"
 	"os"
-	"os/signal"
 	"strings"
-	"syscall"
 	"time"
 
 	"voice-inbox-daemon/internal/config"
-	"voice-inbox-daemon/internal/discord"
-	"voice-inbox-daemon/internal/ingest"
-	"voice-inbox-daemon/internal/obsidian"
+	"voice-inbox-daemon/internal/daemon"
+	"voice-inbox-daemon/internal/logging"
 	"voice-inbox-daemon/internal/pipeline"
 	"voice-inbox-daemon/internal/state"
 )
@@ -26,23 +21,44 @@ func main() {
 }
 
 func run() int {
-	if len(os.Args) < 2 {
+	opts, args, err := parseGlobalFlags(os.Args[1:])
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(args) == 0 {
 		printUsage()
 		return 1
 	}
 
-	cmd := os.Args[1]
+	cmd := args[0]
 	switch cmd {
 	case "help", "-h", "--help":
 		printUsage()
 		return 0
+	case "config":
+		return runConfig(opts, args[1:])
+	case "secret":
+		return runSecret(args[1:])
 	}
 
-	cfg, err := config.LoadForCommand(cmd)
+	opts.Command = cmd
+	cfg, report, err := config.Resolve(opts)
+	if err == nil {
+		err = report.Err()
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "config error: %v\n", err)
 		return 1
 	}
+	if _, err := logging.Setup(os.Stderr, logging.Options{
+		Format:  cfg.LogFormat,
+		Level:   cfg.LogLevel,
+		Secrets: cfg.SecretValues(),
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "logging setup: %v\n", err)
+		return 1
+	}
 
 	if err := os.MkdirAll(cfg.AudioStoreDir, 0o755); err != nil {
 		fmt.Fprintf(os.Stderr, "create audio store dir: %v\n", err)
@@ -53,6 +69,13 @@ func run() int {
 		return 1
 	}
 
+	switch cmd {
+	case "db":
+		return runDB(cfg, args[1:])
+	case "install":
+		return runInstall(cfg, args[1:])
+	}
+
 	store, err := state.Open(cfg.StateDBPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "open state db: %v\n", err)
@@ -60,26 +83,39 @@ func run() int {
 	}
 	defer store.Close()
 
-	runner := pipeline.New(
-		cfg,
-		store,
-		discord.NewWithBaseURL(cfg.DiscordBotToken, cfg.DiscordAPIBaseURL),
-		obsidian.New(cfg.ObsidianBaseURL, cfg.ObsidianAuthHeader, cfg.ObsidianAPIKey, cfg.ObsidianVerifyTLS),
-	)
+	runner := newRunner(cfg, store)
 
 	switch cmd {
 	case "doctor":
-		return runDoctor(runner, os.Args[2:])
+		return runDoctor(runner, args[1:])
 	case "poll":
-		return runPoll(runner, os.Args[2:])
+		return runPoll(runner, args[1:])
 	case "retry":
-		return runRetry(runner, os.Args[2:])
+		return runRetry(runner, args[1:])
 	case "cleanup":
-		return runCleanup(runner, os.Args[2:])
+		return runCleanup(runner, args[1:])
 	case "status":
-		return runStatus(runner, os.Args[2:])
+		return runStatus(runner, args[1:])
 	case "serve":
-		return runServe(runner, store, cfg, os.Args[2:])
+		return runServe(newLiveRunner(opts, cfg, report.Settings, store, runner), args[1:])
+	case "daemon":
+		return runDaemon(newLiveRunner(opts, cfg, report.Settings, store, runner), args[1:])
+	case "failed":
+		return runFailed(runner, args[1:])
+	case "requeue":
+		return runRequeue(runner, args[1:])
+	case "abandon":
+		return runAbandon(runner, args[1:])
+	case "inspect":
+		return runInspect(runner, args[1:])
+	case "search":
+		return runSearch(runner, args[1:])
+	case "export":
+		return runExport(runner, args[1:])
+	case "runs":
+		return runRuns(runner, args[1:])
+	case "digest":
+		return runDigest(runner, args[1:])
 	default:
 		fmt.Fprintf(os.Stderr, "unknown command: %s\n", cmd)
 		printUsage()
@@ -182,55 +218,40 @@ func runStatus(runner *pipeline.Runner, args []string) int {
 	return 0
 }
 
-func runServe(runner *pipeline.Runner, store *state.Store, cfg config.Config, args []string) int {
+func runServe(live *liveRunner, args []string) int {
 	fs := flag.NewFlagSet("serve", flag.ContinueOnError)
 	if err := fs.Parse(args); err != nil {
 		return 1
 	}
+	return serveWithLoops(live, []daemon.Loop{captureLoop(live), sinkProbeLoop(live)})
+}
 
-	server := ingest.NewServer(cfg, store)
-	httpServer := &http.Server{
-		Addr:              cfg.IngestListenAddr,
-		Handler:           server.Handler(),
-		ReadHeaderTimeout: 15 * time.Second,
-	}
-
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
-	defer stop()
-
-	go func() {
-		ticker := time.NewTicker(5 * time.Second)
-		defer ticker.Stop()
-
-		for {
-			runCtx, cancel := context.WithTimeout(ctx, 30*time.Minute)
-			res, err := runner.ProcessCapturesOnce(runCtx)
-			cancel()
-			if err != nil && (res.Processed > 0 || len(res.Errors) > 0) {
-				fmt.Fprintf(os.Stderr, "capture processor error: %v\n", err)
-			}
-
-			select {
-			case <-ctx.Done():
-				return
-			case <-ticker.C:
+func parseGlobalFlags(args []string) (config.Options, []string, error) {
+	opts := config.Options{Overrides: map[string]string{}}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "--") || (name != "config" && name != "set") {
+			return opts, args[i:], nil
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return opts, nil, fmt.Errorf("--%s requires a value", name)
 			}
+			i++
+			value = args[i]
 		}
-	}()
-
-	go func() {
-		<-ctx.Done()
-		shutdownCtx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
-		defer cancel()
-		_ = httpServer.Shutdown(shutdownCtx)
-	}()
-
-	fmt.Fprintf(os.Stdout, "listening on %s\n", cfg.IngestListenAddr)
-	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-		fmt.Fprintf(os.Stderr, "serve error: %v\n", err)
-		return 1
+		if name == "config" {
+			opts.ConfigPath = value
+			continue
+		}
+		key, v, ok := strings.Cut(value, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return opts, nil, fmt.Errorf("--set expects KEY=VALUE, got %q", value)
+		}
+		opts.Overrides[strings.TrimSpace(key)] = v
 	}
-	return 0
+	return opts, nil, nil
 }
 
 func printResult(res pipeline.Result, asJSON bool) {
@@ -254,7 +275,7 @@ func printResult(res pipeline.Result, asJSON bool) {
 	if len(res.Errors) > 0 {
 		fmt.Fprintln(os.Stderr, "errors:")
 		for _, e := range res.Errors {
-			fmt.Fprintf(os.Stderr, "- %s\n", sanitize(e))
+			fmt.Fprintf(os.Stderr, "- %s\n", logging.Redact(e))
 		}
 	}
 	if len(res.Data) > 0 {
@@ -265,30 +286,36 @@ func printResult(res pipeline.Result, asJSON bool) {
 	}
 }
 
-func sanitize(s string) string {
-	if s == "" {
-		return s
-	}
-	patterns := []string{"Bot ", "Bearer "}
-	for _, p := range patterns {
-		i := strings.Index(s, p)
-		if i >= 0 {
-			return s[:i+len(p)] + "[REDACTED]"
-		}
-	}
-	return s
-}
-
 func printUsage() {
 	msg := `voice-inbox: Discord voice inbox -> Obsidian journal CLI
 
 Usage:
+  voice-inbox [--config <path>] [--set KEY=VALUE]... <command> ...
+
+Commands:
   voice-inbox doctor [--json]
   voice-inbox poll --once [--json]
   voice-inbox retry [--json]
   voice-inbox cleanup [--json]
   voice-inbox status [--json]
   voice-inbox serve
+  voice-inbox daemon
+  voice-inbox config show [--json]
+  voice-inbox config validate [--command <name>] [--json]
+  voice-inbox secret set <KEY> [--store keyring|dotenv] [--env-file .env]
+  voice-inbox install systemd [--user] [--mode daemon|timers] [--out <dir>] [--dry-run] [--force]
+  voice-inbox failed list [--source discord|capture] [--json]
+  voice-inbox requeue <id> | --all-failed [--since <time>] [--json]
+  voice-inbox abandon <id> [--json]
+  voice-inbox inspect <id> [--json]
+  voice-inbox runs [--limit N] [--command poll] [--failed-only] [--json]
+  voice-inbox runs show <run_id> [--json]
+  voice-inbox search "<query>" [--since <time>] [--until <time>] [--source <source>] [--json]
+  voice-inbox digest [--day YYYY-MM-DD | --week YYYY-Www] [--dry-run] [--json]
+  voice-inbox db migrate [--dry-run|--status] [--json]
+  voice-inbox db backup [<path>] [--json]
+  voice-inbox db restore <backup-path> [--dry-run] [--json]
+  voice-inbox export [--format jsonl|csv] [--table messages,captures,runs] [--out <dir>]
 `
 	_, _ = fmt.Fprint(os.Stderr, msg)
 }
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseGlobalFlags(t *testing.T) {
+	for _, tc := range []struct {
+		args      []string
+		config    string
+		overrides map[string]string
+		rest      []string
+		wantErr   bool
+	}{
+		{args: []string{"--config", "x", "poll", "--json"}, config: "x", overrides: map[string]string{}, rest: []string{"poll", "--json"}},
+		{args: []string{"--set=LOG_LEVEL=debug", "poll"}, overrides: map[string]string{"LOG_LEVEL": "debug"}, rest: []string{"poll"}},
+		{args: []string{"--set", "log.level=debug", "--config=y", "poll"}, config: "y", overrides: map[string]string{"log.level": "debug"}, rest: []string{"poll"}},
+		{args: []string{"--config"}, wantErr: true},
+		{args: []string{"--set", "LOG_LEVEL"}, wantErr: true},
+		{args: []string{"search", "--config", "y", "--set", "K=V"}, overrides: map[string]string{}, rest: []string{"search", "--config", "y", "--set", "K=V"}},
+	} {
+		opts, rest, err := parseGlobalFlags(tc.args)
+		if tc.wantErr {
+			if err == nil {
+				t.Fatalf("%q: expected an error", tc.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%q: %v", tc.args, err)
+		}
+		if opts.ConfigPath != tc.config || !reflect.DeepEqual(opts.Overrides, tc.overrides) || !reflect.DeepEqual(rest, tc.rest) {
+			t.Fatalf("%q: got config=%q overrides=%v rest=%q", tc.args, opts.ConfigPath, opts.Overrides, rest)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+
+	"voice-inbox-daemon/internal/config"
+	"voice-inbox-daemon/internal/discord"
+	"voice-inbox-daemon/internal/ingest"
+	"voice-inbox-daemon/internal/logging"
+	"voice-inbox-daemon/internal/obsidian"
+	"voice-inbox-daemon/internal/pipeline"
+	"voice-inbox-daemon/internal/state"
+)
+
+type liveRunner struct {
+	opts     config.Options
+	store    *state.Store
+	runner   atomic.Pointer[pipeline.Runner]
+	mu       sync.Mutex
+	cfg      config.Config
+	settings []config.SettingValue
+	server   *ingest.Server
+}
+
+func newRunner(cfg config.Config, store *state.Store) *pipeline.Runner {
+	return pipeline.New(
+		cfg,
+		store,
+		discord.NewWithBaseURL(cfg.DiscordBotToken, cfg.DiscordAPIBaseURL),
+		obsidian.New(cfg.ObsidianBaseURL, cfg.ObsidianAuthHeader, cfg.ObsidianAPIKey, cfg.ObsidianVerifyTLS),
+	)
+}
+
+func newLiveRunner(opts config.Options, cfg config.Config, settings []config.SettingValue, store *state.Store, runner *pipeline.Runner) *liveRunner {
+	l := &liveRunner{opts: opts, store: store, cfg: cfg, settings: settings}
+	l.runner.Store(runner)
+	return l
+}
+
+func (l *liveRunner) current() *pipeline.Runner {
+	return l.runner.Load()
+}
+
+func (l *liveRunner) config() config.Config {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.cfg
+}
+
+func (l *liveRunner) loop(fn func(*pipeline.Runner, context.Context) (pipeline.Result, error)) func(context.Context) error {
+	return loopRun(func(ctx context.Context) (pipeline.Result, error) {
+		return fn(l.current(), ctx)
+	})
+}
+
+func (l *liveRunner) attach(server *ingest.Server) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.server = server
+}
+
+func (l *liveRunner) reload() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	next, report, err := config.Resolve(l.opts)
+	if err == nil {
+		err = report.Err()
+	}
+	if err != nil {
+		slog.Error("config reload rejected; keeping current config", "error", err)
+		return false
+	}
+	next = config.PreserveRestartSettings(next, l.cfg)
+
+	changes := config.Diff(l.cfg, l.settings, next, report.Settings)
+	if len(changes) == 0 {
+		slog.Info("config reloaded; no changes")
+		return true
+	}
+	if _, err := logging.Setup(os.Stderr, logging.Options{
+		Format:  next.LogFormat,
+		Level:   next.LogLevel,
+		Secrets: next.SecretValues(),
+	}); err != nil {
+		slog.Error("config reload rejected; keeping current config", "error", err)
+		return false
+	}
+
+	l.runner.Store(newRunner(next, l.store))
+	if l.server != nil {
+		l.server.SetConfig(next)
+	}
+	l.cfg = next
+	l.settings = report.Settings
+
+	for _, c := range changes {
+		if c.Restart {
+			slog.Warn("config changed; restart required to apply", "key", c.Key, "old", c.Old, "new", c.New)
+			continue
+		}
+		slog.Info("config changed", "key", c.Key, "old", c.Old, "new", c.New)
+	}
+	slog.Info("config reloaded", "changes", len(changes))
+	return true
+}
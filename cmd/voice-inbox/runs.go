@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"voice-inbox-daemon/internal/logging"
+	"voice-inbox-daemon/internal/pipeline"
+	"voice-inbox-daemon/internal/state"
+)
+
+func runRuns(runner *pipeline.Runner, args []string) int {
+	if len(args) > 0 && args[0] == "show" {
+		return runRunsShow(runner, args[1:])
+	}
+	fs := flag.NewFlagSet("runs", flag.ContinueOnError)
+	limit := fs.Int("limit", 20, "maximum number of runs")
+	command := fs.String("command", "", "only runs of this command (poll, retry, cleanup, serve-process-captures)")
+	failedOnly := fs.Bool("failed-only", false, "only runs with failures or errors")
+	asJSON := fs.Bool("json", false, "output as JSON")
+	if err := fs.Parse(args); err != nil {
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	res, err := runner.RunList(ctx, state.RunFilter{Command: *command, FailedOnly: *failedOnly, Limit: *limit})
+	if err != nil || *asJSON {
+		printResult(res, *asJSON)
+		return res.ExitCode()
+	}
+
+	runs, _ := res.Data["runs"].([]state.RunRecord)
+	if len(runs) == 0 {
+		fmt.Println("no runs")
+		return 0
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "STARTED\tCOMMAND\tRUN ID\tDURATION\tOK\tFAILED\tFIRST ERROR")
+	for _, run := range runs {
+		duration := "running"
+		if run.FinishedAt != nil {
+			duration = run.FinishedAt.Sub(run.StartedAt).String()
+		}
+		firstErr := "-"
+		if len(run.Errors) > 0 {
+			firstErr = logging.Redact(oneLine(run.Errors[0], 80))
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%s\n", run.StartedAt.Local().Format(time.DateTime), run.Command, run.RunID, duration, run.Succeeded, run.Failed, firstErr)
+	}
+	_ = tw.Flush()
+	return 0
+}
+
+func runRunsShow(runner *pipeline.Runner, args []string) int {
+	fs := flag.NewFlagSet("runs show", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "output as JSON")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 1
+	}
+	if len(positional) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: voice-inbox runs show <run_id> [--json]")
+		return 1
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	res, err := runner.RunShow(ctx, positional[0])
+	if err != nil || *asJSON {
+		printResult(res, *asJSON)
+		return res.ExitCode()
+	}
+
+	run, _ := res.Data["run"].(state.RunRecord)
+	items, _ := res.Data["items"].([]pipeline.RunItem)
+	finished := "still running"
+	if run.FinishedAt != nil {
+		finished = run.FinishedAt.Local().Format(time.DateTime)
+	}
+	fmt.Printf("run:      %s (%s)\n", run.RunID, run.Command)
+	fmt.Printf("started:  %s\n", run.StartedAt.Local().Format(time.DateTime))
+	fmt.Printf("finished: %s\n", finished)
+	fmt.Printf("counts:   processed=%d succeeded=%d failed=%d\n", run.Processed, run.Succeeded, run.Failed)
+	if len(run.Errors) > 0 {
+		fmt.Println("\nerrors:")
+		for _, e := range run.Errors {
+			fmt.Printf("  - %s\n", logging.Redact(oneLine(e, 200)))
+		}
+	}
+	if len(items) == 0 {
+		fmt.Println("\nno items touched")
+		return 0
+	}
+	fmt.Println("\nitems:")
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "  SOURCE\tID\tLAST EVENT\tSTATUS\tSTAGE\tERROR")
+	for _, item := range items {
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\t%s\t%s\n", item.Source, item.ID, item.LastEvent, orDash(item.Status), orDash(item.Stage), logging.Redact(oneLine(orDash(item.Error), 80)))
+	}
+	_ = tw.Flush()
+	return 0
+}
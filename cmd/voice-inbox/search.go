@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"voice-inbox-daemon/internal/pipeline"
+	"voice-inbox-daemon/internal/state"
+)
+
+func runSearch(runner *pipeline.Runner, args []string) int {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	sinceRaw := fs.String("since", "", "only transcripts captured at or after this time (RFC3339, YYYY-MM-DD or a duration like 720h)")
+	untilRaw := fs.String("until", "", "only transcripts captured before this time (RFC3339, YYYY-MM-DD or a duration like 24h)")
+	source := fs.String("source", "", "discord or capture")
+	limit := fs.Int("limit", 20, "maximum number of results")
+	asJSON := fs.Bool("json", false, "output as JSON")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return 1
+	}
+	if len(positional) == 0 {
+		fmt.Fprintln(os.Stderr, `usage: voice-inbox search "<query>" [--since <time>] [--until <time>] [--source discord|capture] [--json]`)
+		return 1
+	}
+
+	q := state.SearchQuery{Text: strings.Join(positional, " "), Source: *source, Limit: *limit}
+	now := time.Now()
+	for _, p := range []struct {
+		raw  string
+		dest **time.Time
+		name string
+	}{{*sinceRaw, &q.Since, "--since"}, {*untilRaw, &q.Until, "--until"}} {
+		if p.raw == "" {
+			continue
+		}
+		t, err := parseSince(p.raw, now)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid %s: %v\n", p.name, err)
+			return 1
+		}
+		*p.dest = &t
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	res, err := runner.Search(ctx, q)
+	if err != nil || *asJSON {
+		printResult(res, *asJSON)
+		if err != nil {
+			return 1
+		}
+		return 0
+	}
+
+	hits, _ := res.Data["results"].([]state.SearchHit)
+	if len(hits) == 0 {
+		fmt.Println("no matches")
+		return 0
+	}
+	for _, hit := range hits {
+		fmt.Printf("%s  %s %s\n", hit.CapturedAt.Local().Format(time.DateTime), hit.Source, hit.ItemID)
+		fmt.Printf("    %s\n", oneLine(hit.Snippet, 200))
+		if hit.JournalPath != "" {
+			fmt.Printf("    -> %s\n", hit.JournalPath)
+		}
+	}
+	return 0
+}
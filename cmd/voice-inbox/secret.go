@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"voice-inbox-daemon/internal/config"
+)
+
+func runSecret(args []string) int {
+	if len(args) == 0 || args[0] != "set" {
+		fmt.Fprintln(os.Stderr, "usage: voice-inbox secret set <KEY> [--store keyring|dotenv] [--env-file .env]")
+		return 1
+	}
+	fs := flag.NewFlagSet("secret set", flag.ContinueOnError)
+	store := fs.String("store", "keyring", "where to store the secret: keyring or dotenv")
+	envFile := fs.String("env-file", ".env", "dotenv file used with --store dotenv")
+	rest, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		return 1
+	}
+	if len(rest) != 1 {
+		fmt.Fprintf(os.Stderr, "secret set requires exactly one key: %s\n", strings.Join(config.SecretKeys(), ", "))
+		return 1
+	}
+	key := strings.ToUpper(rest[0])
+	if !config.IsSecretKey(key) {
+		fmt.Fprintf(os.Stderr, "unknown secret %q (want one of %s)\n", rest[0], strings.Join(config.SecretKeys(), ", "))
+		return 1
+	}
+
+	value, err := readSecretValue(key)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read secret: %v\n", err)
+		return 1
+	}
+	if value == "" {
+		fmt.Fprintln(os.Stderr, "secret is empty; nothing stored")
+		return 1
+	}
+
+	switch *store {
+	case "keyring":
+		backend, err := config.StoreSecret(key, value)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "store %s in keyring: %v (use --store dotenv to write %s instead)\n", key, err, *envFile)
+			return 1
+		}
+		fmt.Printf("stored: %s=[SET] backend=%s\n", key, backend)
+		fmt.Printf("remove any %s line from .env so the keyring value is used\n", key)
+	case "dotenv":
+		if err := config.WriteDotEnv(*envFile, key, value); err != nil {
+			fmt.Fprintf(os.Stderr, "update %s: %v\n", *envFile, err)
+			return 1
+		}
+		fmt.Printf("updated: %s=[SET] file=%s\n", key, *envFile)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown --store %q (want keyring or dotenv)\n", *store)
+		return 1
+	}
+	return 0
+}
+
+func readSecretValue(key string) (string, error) {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&os.ModeCharDevice == 0 {
+		raw, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(raw), "\r\n"), nil
+	}
+
+	fmt.Fprintf(os.Stderr, "%s: ", key)
+	if err := stty("-echo"); err == nil {
+		defer func() {
+			_ = stty("echo")
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func stty(arg string) error {
+	cmd := exec.Command("stty", arg)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
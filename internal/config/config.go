@@ -2,12 +2,19 @@ package config
 
 import (
 	"bufio"
-	"errors"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"runtime"
+	"sort"
 	"strconv"
 	"strings"
+
+	"voice-inbox-daemon/internal/logging"
+	"voice-inbox-daemon/internal/routing"
+	"voice-inbox-daemon/internal/tagging"
+	"voice-inbox-daemon/internal/tasks"
 )
 
 type Config struct {
@@ -18,10 +25,18 @@ type Config struct {
 	AllowedAuthorIDsList    []string
 	DiscordFetchLimit       int
 	PollIntervalSeconds     int
+	RetryIntervalSeconds    int
+	ProbeIntervalSeconds    int
+	CleanupAt               string
+	LoopBackoffMaxSeconds   int
 	WhisperBin              string
 	WhisperModel            string
 	WhisperLanguage         string
+	WhisperChunkSeconds     int
+	WhisperChunkOverlapSec  int
+	WhisperTimeoutFactor    int
 	FFmpegBin               string
+	FFprobeBin              string
 	ObsidianBaseURL         string
 	ObsidianAPIKey          string
 	ObsidianAuthHeader      string
@@ -32,14 +47,56 @@ type Config struct {
 	MaxRetryAttempts        int
 	RetryBaseSeconds        int
 	RetryMaxSeconds         int
+	WorkerConcurrency       int
+	DownloadConcurrency     int
+	TranscribeConcurrency   int
+	JournalConcurrency      int
 	StateDBPath             string
 	AudioStoreDir           string
 	LogDir                  string
+	LogFormat               string
+	LogLevel                string
 	LockFilePath            string
+	BackupDir               string
+	BackupKeep              int
 	IngestListenAddr        string
 	IngestAuthToken         string
 	IngestMaxBodyMB         int
 	IngestSourceName        string
+	TasksMode               string
+	TasksNotePath           string
+	TasksTriggers           []string
+	TasksPatterns           []string
+	TagHashtags             bool
+	TagKeywords             []string
+	AttachMode              string
+	AttachDir               string
+	AttachRetentionDays     int
+	LLMEnabled              bool
+	LLMBaseURL              string
+	LLMAPIKey               string
+	LLMModel                string
+	LLMPrompt               string
+	LLMTimeoutSeconds       int
+	LLMMinChars             int
+	DigestAt                string
+	DigestWeekDir           string
+	ConfigFile              string
+	Channels                []Channel
+	Sinks                   []Sink
+	Routes                  []routing.Rule
+}
+
+type Options struct {
+	Command    string
+	ConfigPath string
+	Overrides  map[string]string
+}
+
+type Report struct {
+	File     string         `json:"file,omitempty"`
+	Settings []SettingValue `json:"settings"`
+	Problems []string       `json:"problems,omitempty"`
 }
 
 func Load() (Config, error) {
@@ -47,62 +104,211 @@ func Load() (Config, error) {
 }
 
 func LoadForCommand(command string) (Config, error) {
-	_ = loadDotEnv(".env")
+	return LoadWithOptions(Options{Command: command})
+}
 
+func LoadWithOptions(opts Options) (Config, error) {
+	cfg, report, err := Resolve(opts)
+	if err != nil {
+		return Config{}, err
+	}
+	if err := report.Err(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func Resolve(opts Options) (Config, Report, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		return Config{}, fmt.Errorf("resolve home: %w", err)
+		return Config{}, Report{}, fmt.Errorf("resolve home: %w", err)
+	}
+	path, err := findConfigFile(opts.ConfigPath, home)
+	if err != nil {
+		return Config{}, Report{}, err
+	}
+	file, err := readConfigFile(path)
+	if err != nil {
+		return Config{}, Report{}, err
 	}
+	dotenv, err := readDotEnv(".env")
+	if err != nil {
+		return Config{}, Report{}, fmt.Errorf("read .env: %w", err)
+	}
+	flags, flagProblems := flagLayer(opts.Overrides)
+
+	l := newLoader(flags, envLayer(), mapLayer(SourceDotEnv, dotenv), mapLayer(SourceFile, file.Values))
+	l.required = requiredSecrets(opts.Command)
+	cfg := build(l, home)
+	cfg.ConfigFile = path
+	cfg.Channels = file.Channels
+	if len(cfg.Channels) == 0 {
+		cfg.Channels = []Channel{{ID: cfg.VoiceInboxChannelID, AllowedAuthorIDs: cfg.AllowedAuthorIDsList}}
+	}
+	cfg.Sinks = resolveSinks(cfg, file.Sinks)
+	cfg.Routes = file.Routes
+
+	report := Report{File: path, Settings: l.values()}
+	report.Problems = append(report.Problems, file.Problems...)
+	report.Problems = append(report.Problems, flagProblems...)
+	report.Problems = append(report.Problems, l.problems...)
+	report.Problems = append(report.Problems, validate(cfg, opts.Command)...)
+	return cfg, report, nil
+}
 
-	stateDefault := filepath.Join(home, "Library", "Application Support", "voice-inbox-daemon", "state.db")
-	audioDefault := filepath.Join(home, "Library", "Application Support", "voice-inbox-daemon", "audio")
-	logDefault := filepath.Join(home, "Library", "Logs", "voice-inbox-daemon")
+func build(l *loader, home string) Config {
+	defaults := defaultPaths(runtime.GOOS, home, os.Getenv)
+	l.keyring = l.bool("SECRETS_KEYRING", false)
 
 	cfg := Config{
-		DiscordBotToken:         strings.TrimSpace(os.Getenv("DISCORD_BOT_TOKEN")),
-		DiscordAPIBaseURL:       strings.TrimRight(getEnvDefault("DISCORD_API_BASE_URL", "https://discord.com/api/v10"), "/"),
-		VoiceInboxChannelID:     getEnvDefault("VOICE_INBOX_CHANNEL_ID", "1476388224124325909"),
-		DiscordFetchLimit:       getEnvInt("DISCORD_FETCH_LIMIT", 100),
-		PollIntervalSeconds:     getEnvInt("POLL_INTERVAL_SECONDS", 300),
-		WhisperBin:              getEnvDefault("WHISPER_BIN", "/opt/homebrew/bin/whisper"),
-		WhisperModel:            getEnvDefault("WHISPER_MODEL", "large-v3-turbo"),
-		WhisperLanguage:         getEnvDefault("WHISPER_LANGUAGE", "ja"),
-		FFmpegBin:               getEnvDefault("FFMPEG_BIN", "/opt/homebrew/bin/ffmpeg"),
-		ObsidianBaseURL:         strings.TrimRight(getEnvDefault("OBSIDIAN_BASE_URL", "https://127.0.0.1:27124"), "/"),
-		ObsidianAPIKey:          strings.TrimSpace(os.Getenv("OBSIDIAN_API_KEY")),
-		ObsidianAuthHeader:      getEnvDefault("OBSIDIAN_AUTH_HEADER", "Authorization"),
-		ObsidianVerifyTLS:       getEnvBool("OBSIDIAN_VERIFY_TLS", false),
-		VaultJournalDir:         strings.Trim(getEnvDefault("VAULT_JOURNAL_DIR", "01_Projects/Journal"), "/"),
-		AudioRetentionDays:      getEnvInt("AUDIO_RETENTION_DAYS", 14),
-		TranscriptRetentionDays: getEnvInt("TRANSCRIPT_RETENTION_DAYS", 7),
-		MaxRetryAttempts:        getEnvInt("MAX_RETRY_ATTEMPTS", 8),
-		RetryBaseSeconds:        getEnvInt("RETRY_BASE_SECONDS", 300),
-		RetryMaxSeconds:         getEnvInt("RETRY_MAX_SECONDS", 86400),
-		StateDBPath:             expandPath(getEnvDefault("STATE_DB_PATH", stateDefault), home),
-		AudioStoreDir:           expandPath(getEnvDefault("AUDIO_STORE_DIR", audioDefault), home),
-		LogDir:                  expandPath(getEnvDefault("LOG_DIR", logDefault), home),
-		IngestListenAddr:        getEnvDefault("INGEST_LISTEN_ADDR", "127.0.0.1:8787"),
-		IngestAuthToken:         strings.TrimSpace(os.Getenv("INGEST_AUTH_TOKEN")),
-		IngestMaxBodyMB:         getEnvInt("INGEST_MAX_BODY_MB", 32),
-		IngestSourceName:        getEnvDefault("INGEST_SOURCE_NAME", "android-voice-inbox"),
+		DiscordBotToken:         l.secret("DISCORD_BOT_TOKEN"),
+		DiscordAPIBaseURL:       strings.TrimRight(l.str("DISCORD_API_BASE_URL", "https://discord.com/api/v10"), "/"),
+		VoiceInboxChannelID:     l.str("VOICE_INBOX_CHANNEL_ID", "1476388224124325909"),
+		DiscordFetchLimit:       l.int("DISCORD_FETCH_LIMIT", 100),
+		PollIntervalSeconds:     l.int("POLL_INTERVAL_SECONDS", 300),
+		RetryIntervalSeconds:    l.int("RETRY_INTERVAL_SECONDS", 60),
+		ProbeIntervalSeconds:    l.int("SINK_PROBE_INTERVAL_SECONDS", 30),
+		CleanupAt:               l.str("CLEANUP_AT", "03:20"),
+		LoopBackoffMaxSeconds:   l.int("LOOP_BACKOFF_MAX_SECONDS", 900),
+		WhisperBin:              l.str("WHISPER_BIN", defaultBinary("whisper")),
+		WhisperModel:            l.str("WHISPER_MODEL", "large-v3-turbo"),
+		WhisperLanguage:         l.str("WHISPER_LANGUAGE", "ja"),
+		WhisperChunkSeconds:     l.int("WHISPER_CHUNK_SECONDS", 600),
+		WhisperChunkOverlapSec:  l.int("WHISPER_CHUNK_OVERLAP_SECONDS", 5),
+		WhisperTimeoutFactor:    l.int("WHISPER_TIMEOUT_FACTOR", 3),
+		FFmpegBin:               l.str("FFMPEG_BIN", defaultBinary("ffmpeg")),
+		FFprobeBin:              l.str("FFPROBE_BIN", ""),
+		ObsidianBaseURL:         strings.TrimRight(l.str("OBSIDIAN_BASE_URL", "https://127.0.0.1:27124"), "/"),
+		ObsidianAPIKey:          l.secret("OBSIDIAN_API_KEY"),
+		ObsidianAuthHeader:      l.str("OBSIDIAN_AUTH_HEADER", "Authorization"),
+		ObsidianVerifyTLS:       l.bool("OBSIDIAN_VERIFY_TLS", false),
+		VaultJournalDir:         strings.Trim(l.str("VAULT_JOURNAL_DIR", "01_Projects/Journal"), "/"),
+		AudioRetentionDays:      l.int("AUDIO_RETENTION_DAYS", 14),
+		TranscriptRetentionDays: l.int("TRANSCRIPT_RETENTION_DAYS", 7),
+		MaxRetryAttempts:        l.int("MAX_RETRY_ATTEMPTS", 8),
+		RetryBaseSeconds:        l.int("RETRY_BASE_SECONDS", 300),
+		RetryMaxSeconds:         l.int("RETRY_MAX_SECONDS", 86400),
+		WorkerConcurrency:       l.int("WORKER_CONCURRENCY", 4),
+		DownloadConcurrency:     l.int("DOWNLOAD_CONCURRENCY", 4),
+		TranscribeConcurrency:   l.int("TRANSCRIBE_CONCURRENCY", 1),
+		JournalConcurrency:      l.int("JOURNAL_CONCURRENCY", 2),
+		StateDBPath:             expandPath(l.str("STATE_DB_PATH", defaults.StateDB), home),
+		AudioStoreDir:           expandPath(l.str("AUDIO_STORE_DIR", defaults.AudioDir), home),
+		LogDir:                  expandPath(l.str("LOG_DIR", defaults.LogDir), home),
+		LogFormat:               strings.ToLower(l.str("LOG_FORMAT", "text")),
+		LogLevel:                strings.ToLower(l.str("LOG_LEVEL", "info")),
+		BackupDir:               l.str("BACKUP_DIR", ""),
+		BackupKeep:              l.int("BACKUP_KEEP", 7),
+		IngestListenAddr:        l.str("INGEST_LISTEN_ADDR", "127.0.0.1:8787"),
+		IngestAuthToken:         l.secret("INGEST_AUTH_TOKEN"),
+		IngestMaxBodyMB:         l.int("INGEST_MAX_BODY_MB", 32),
+		IngestSourceName:        l.str("INGEST_SOURCE_NAME", "android-voice-inbox"),
+		TasksMode:               strings.ToLower(l.str("TASKS_MODE", "inline")),
+		TasksNotePath:           strings.Trim(l.str("TASKS_NOTE_PATH", "Tasks/Voice Inbox.md"), "/"),
+		TagHashtags:             l.bool("TAGS_HASHTAGS", true),
+		AttachMode:              strings.ToLower(l.str("AUDIO_ATTACH_MODE", "off")),
+		AttachDir:               strings.Trim(l.str("AUDIO_ATTACH_DIR", "Attachments/Voice Inbox"), "/"),
+		AttachRetentionDays:     l.int("AUDIO_ATTACH_RETENTION_DAYS", 0),
+		LLMEnabled:              l.bool("LLM_ENABLED", false),
+		LLMBaseURL:              strings.TrimRight(l.str("LLM_BASE_URL", "http://127.0.0.1:11434/v1"), "/"),
+		LLMAPIKey:               l.secret("LLM_API_KEY"),
+		LLMModel:                l.str("LLM_MODEL", ""),
+		LLMPrompt:               l.str("LLM_PROMPT", ""),
+		LLMTimeoutSeconds:       l.int("LLM_TIMEOUT_SECONDS", 120),
+		LLMMinChars:             l.int("LLM_MIN_CHARS", 200),
+		DigestAt:                l.str("DIGEST_AT", ""),
 	}
 
-	allowedRaw := getEnvDefault("VOICE_INBOX_ALLOWED_AUTHOR_IDS", "968754117885456425")
+	_, cfg.TasksTriggers = parseCSVSet(l.str("TASKS_TRIGGERS", strings.Join(tasks.DefaultTriggers, ",")))
+	cfg.TasksPatterns = splitLines(l.str("TASKS_PATTERNS", ""))
+	_, cfg.TagKeywords = parseCSVSet(l.str("TAGS_KEYWORDS", ""))
+
+	allowedRaw := l.str("VOICE_INBOX_ALLOWED_AUTHOR_IDS", "968754117885456425")
 	cfg.AllowedAuthorIDs, cfg.AllowedAuthorIDsList = parseCSVSet(allowedRaw)
 	cfg.LockFilePath = cfg.StateDBPath + ".lock"
+	cfg.DigestWeekDir = strings.Trim(l.str("DIGEST_WEEK_DIR", path.Join(strings.Trim(cfg.VaultJournalDir, "/"), "Weekly")), "/")
+	if cfg.BackupDir != "" {
+		cfg.BackupDir = expandPath(cfg.BackupDir, home)
+	}
+	if cfg.FFprobeBin == "" {
+		cfg.FFprobeBin = filepath.Join(filepath.Dir(cfg.FFmpegBin), "ffprobe")
+	}
+	return cfg
+}
 
-	if err := validate(cfg, command); err != nil {
-		return Config{}, err
+func resolveSinks(cfg Config, sinks []Sink) []Sink {
+	out := make([]Sink, 0, len(sinks))
+	for _, s := range sinks {
+		if s.Type == "" {
+			s.Type = "obsidian"
+		}
+		if s.BaseURL == "" {
+			s.BaseURL = cfg.ObsidianBaseURL
+		}
+		if s.APIKey == "" {
+			s.APIKey = cfg.ObsidianAPIKey
+		}
+		if s.AuthHeader == "" {
+			s.AuthHeader = cfg.ObsidianAuthHeader
+		}
+		if s.JournalDir == "" {
+			s.JournalDir = cfg.VaultJournalDir
+		}
+		out = append(out, s)
 	}
+	return out
+}
 
-	return cfg, nil
+func (c Config) PollChannels() []Channel {
+	if len(c.Channels) > 0 {
+		return c.Channels
+	}
+	authors := c.AllowedAuthorIDsList
+	if len(authors) == 0 {
+		for id := range c.AllowedAuthorIDs {
+			authors = append(authors, id)
+		}
+		sort.Strings(authors)
+	}
+	return []Channel{{ID: c.VoiceInboxChannelID, AllowedAuthorIDs: authors}}
+}
+
+func (c Config) SecretValues() []string {
+	out := []string{c.DiscordBotToken, c.ObsidianAPIKey, c.IngestAuthToken, c.LLMAPIKey}
+	for _, s := range c.Sinks {
+		out = append(out, s.APIKey)
+	}
+	return out
+}
+
+var localCommands = map[string]bool{
+	"db":      true,
+	"export":  true,
+	"failed":  true,
+	"requeue": true,
+	"runs":    true,
+	"install": true,
+	"config":  true,
+	"abandon": true,
+	"search":  true,
+}
+
+func requiredSecrets(command string) map[string]bool {
+	local := localCommands[command]
+	return map[string]bool{
+		"DISCORD_BOT_TOKEN": command != "serve" && command != "digest" && !local,
+		"OBSIDIAN_API_KEY":  !local,
+		"INGEST_AUTH_TOKEN": command == "serve" || command == "daemon",
+	}
 }
 
-func validate(cfg Config, command string) error {
+func validate(cfg Config, command string) []string {
 	var problems []string
+	local := localCommands[command]
+	required := requiredSecrets(command)
 
-	if command != "serve" {
-		if cfg.DiscordBotToken == "" {
+	if command != "serve" && command != "digest" && !local {
+		if required["DISCORD_BOT_TOKEN"] && cfg.DiscordBotToken == "" {
 			problems = append(problems, "DISCORD_BOT_TOKEN is required")
 		}
 		if cfg.VoiceInboxChannelID == "" {
@@ -114,9 +320,45 @@ func validate(cfg Config, command string) error {
 		if len(cfg.AllowedAuthorIDs) == 0 {
 			problems = append(problems, "VOICE_INBOX_ALLOWED_AUTHOR_IDS must include at least one author ID")
 		}
+		seen := map[string]bool{}
+		for i, ch := range cfg.Channels {
+			switch {
+			case ch.ID == "":
+				problems = append(problems, fmt.Sprintf("channels[%d].id is required", i))
+			case seen[ch.ID]:
+				problems = append(problems, fmt.Sprintf("channels[%d].id %s is listed twice", i, ch.ID))
+			case len(ch.AllowedAuthorIDs) == 0:
+				problems = append(problems, fmt.Sprintf("channels[%d].allowed_author_ids must include at least one author ID", i))
+			}
+			seen[ch.ID] = true
+		}
+	}
+	sinkNames := map[string]bool{"obsidian": true}
+	for i, sink := range cfg.Sinks {
+		switch {
+		case sink.Name == "":
+			problems = append(problems, fmt.Sprintf("sinks[%d].name is required", i))
+		case sinkNames[sink.Name]:
+			problems = append(problems, fmt.Sprintf("sinks[%d].name %q is already used", i, sink.Name))
+		}
+		sinkNames[sink.Name] = true
+		if sink.Type != "obsidian" {
+			problems = append(problems, fmt.Sprintf("sinks[%d].type %q is not supported (want obsidian)", i, sink.Type))
+		}
+		if sink.JournalDir == "" {
+			problems = append(problems, fmt.Sprintf("sinks[%d].journal_dir must not be empty", i))
+		}
 	}
-	if command == "serve" {
-		if cfg.IngestAuthToken == "" {
+	if _, err := routing.New(cfg.Routes); err != nil {
+		problems = append(problems, err.Error())
+	}
+	for i, route := range cfg.Routes {
+		if route.Sink != "" && !sinkNames[route.Sink] {
+			problems = append(problems, fmt.Sprintf("routes[%d].sink %q is not defined in [[sinks]]", i, route.Sink))
+		}
+	}
+	if command == "serve" || command == "daemon" {
+		if required["INGEST_AUTH_TOKEN"] && cfg.IngestAuthToken == "" {
 			problems = append(problems, "INGEST_AUTH_TOKEN is required")
 		}
 		if strings.TrimSpace(cfg.IngestListenAddr) == "" {
@@ -126,7 +368,7 @@ func validate(cfg Config, command string) error {
 	if cfg.ObsidianBaseURL == "" {
 		problems = append(problems, "OBSIDIAN_BASE_URL is required")
 	}
-	if cfg.ObsidianAPIKey == "" {
+	if required["OBSIDIAN_API_KEY"] && cfg.ObsidianAPIKey == "" {
 		problems = append(problems, "OBSIDIAN_API_KEY is required")
 	}
 	if cfg.DiscordFetchLimit <= 0 {
@@ -135,9 +377,51 @@ func validate(cfg Config, command string) error {
 	if cfg.PollIntervalSeconds <= 0 {
 		problems = append(problems, "POLL_INTERVAL_SECONDS must be > 0")
 	}
+	if cfg.RetryIntervalSeconds <= 0 {
+		problems = append(problems, "RETRY_INTERVAL_SECONDS must be > 0")
+	}
+	if cfg.ProbeIntervalSeconds <= 0 {
+		problems = append(problems, "SINK_PROBE_INTERVAL_SECONDS must be > 0")
+	}
+	if _, _, err := ParseClock(cfg.CleanupAt); err != nil {
+		problems = append(problems, "CLEANUP_AT must be HH:MM")
+	}
+	if cfg.LoopBackoffMaxSeconds <= 0 {
+		problems = append(problems, "LOOP_BACKOFF_MAX_SECONDS must be > 0")
+	}
+	if cfg.WhisperChunkSeconds <= 0 {
+		problems = append(problems, "WHISPER_CHUNK_SECONDS must be > 0")
+	}
+	if cfg.WhisperChunkOverlapSec < 0 || cfg.WhisperChunkOverlapSec*2 >= cfg.WhisperChunkSeconds {
+		problems = append(problems, "WHISPER_CHUNK_OVERLAP_SECONDS must be >= 0 and less than half of WHISPER_CHUNK_SECONDS")
+	}
+	if cfg.WhisperTimeoutFactor <= 0 {
+		problems = append(problems, "WHISPER_TIMEOUT_FACTOR must be > 0")
+	}
+	if cfg.WorkerConcurrency <= 0 {
+		problems = append(problems, "WORKER_CONCURRENCY must be > 0")
+	}
+	if cfg.DownloadConcurrency <= 0 {
+		problems = append(problems, "DOWNLOAD_CONCURRENCY must be > 0")
+	}
+	if cfg.TranscribeConcurrency <= 0 {
+		problems = append(problems, "TRANSCRIBE_CONCURRENCY must be > 0")
+	}
+	if cfg.JournalConcurrency <= 0 {
+		problems = append(problems, "JOURNAL_CONCURRENCY must be > 0")
+	}
 	if cfg.AudioRetentionDays <= 0 {
 		problems = append(problems, "AUDIO_RETENTION_DAYS must be > 0")
 	}
+	if cfg.LogFormat != "text" && cfg.LogFormat != "json" {
+		problems = append(problems, "LOG_FORMAT must be text or json")
+	}
+	if _, err := logging.ParseLevel(cfg.LogLevel); err != nil {
+		problems = append(problems, "LOG_LEVEL must be debug, info, warn or error")
+	}
+	if cfg.BackupKeep <= 0 {
+		problems = append(problems, "BACKUP_KEEP must be > 0")
+	}
 	if cfg.MaxRetryAttempts <= 0 {
 		problems = append(problems, "MAX_RETRY_ATTEMPTS must be > 0")
 	}
@@ -156,43 +440,80 @@ func validate(cfg Config, command string) error {
 	if strings.TrimSpace(cfg.IngestSourceName) == "" {
 		problems = append(problems, "INGEST_SOURCE_NAME must not be empty")
 	}
-
-	if len(problems) > 0 {
-		return errors.New(strings.Join(problems, "; "))
+	switch cfg.TasksMode {
+	case "off", "inline":
+	case "note":
+		if cfg.TasksNotePath == "" {
+			problems = append(problems, "TASKS_NOTE_PATH must not be empty when TASKS_MODE=note")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("TASKS_MODE must be off, inline or note, got %q", cfg.TasksMode))
 	}
-	return nil
-}
-
-func getEnvDefault(key, def string) string {
-	v := strings.TrimSpace(os.Getenv(key))
-	if v == "" {
-		return def
+	if _, err := tasks.New(cfg.TasksTriggers, cfg.TasksPatterns); err != nil {
+		problems = append(problems, "TASKS_PATTERNS: "+err.Error())
+	}
+	switch cfg.AttachMode {
+	case "off":
+	case "original", "opus":
+		if cfg.AttachDir == "" {
+			problems = append(problems, "AUDIO_ATTACH_DIR must not be empty when AUDIO_ATTACH_MODE is set")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("AUDIO_ATTACH_MODE must be off, original or opus, got %q", cfg.AttachMode))
+	}
+	if cfg.AttachRetentionDays < 0 {
+		problems = append(problems, "AUDIO_ATTACH_RETENTION_DAYS must be >= 0")
+	}
+	if cfg.LLMEnabled {
+		if cfg.LLMModel == "" {
+			problems = append(problems, "LLM_MODEL is required when LLM_ENABLED=true")
+		}
+		if cfg.LLMBaseURL == "" {
+			problems = append(problems, "LLM_BASE_URL is required when LLM_ENABLED=true")
+		}
+	}
+	if cfg.DigestAt != "" {
+		if _, _, err := ParseClock(cfg.DigestAt); err != nil {
+			problems = append(problems, "DIGEST_AT must be HH:MM or empty")
+		}
 	}
-	return v
+	if cfg.LLMTimeoutSeconds <= 0 {
+		problems = append(problems, "LLM_TIMEOUT_SECONDS must be > 0")
+	}
+	if cfg.LLMMinChars < 0 {
+		problems = append(problems, "LLM_MIN_CHARS must be >= 0")
+	}
+	if _, err := tagging.New(cfg.TagHashtags, cfg.TagKeywords); err != nil {
+		problems = append(problems, "TAGS_KEYWORDS: "+err.Error())
+	}
+
+	return problems
 }
 
-func getEnvInt(key string, def int) int {
-	raw := strings.TrimSpace(os.Getenv(key))
-	if raw == "" {
-		return def
+func ParseClock(raw string) (int, int, error) {
+	hourRaw, minuteRaw, ok := strings.Cut(strings.TrimSpace(raw), ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid clock %q", raw)
 	}
-	v, err := strconv.Atoi(raw)
-	if err != nil {
-		return def
+	hour, err := strconv.Atoi(hourRaw)
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, 0, fmt.Errorf("invalid hour in %q", raw)
+	}
+	minute, err := strconv.Atoi(minuteRaw)
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, 0, fmt.Errorf("invalid minute in %q", raw)
 	}
-	return v
+	return hour, minute, nil
 }
 
-func getEnvBool(key string, def bool) bool {
-	raw := strings.TrimSpace(os.Getenv(key))
-	if raw == "" {
-		return def
-	}
-	v, err := strconv.ParseBool(raw)
-	if err != nil {
-		return def
+func splitLines(raw string) []string {
+	var out []string
+	for _, line := range strings.Split(raw, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			out = append(out, line)
+		}
 	}
-	return v
+	return out
 }
 
 func parseCSVSet(raw string) (map[string]struct{}, []string) {
@@ -222,13 +543,14 @@ func expandPath(p, home string) string {
 	return p
 }
 
-func loadDotEnv(path string) error {
+func readDotEnv(path string) (map[string]string, error) {
+	values := map[string]string{}
 	f, err := os.Open(path)
 	if err != nil {
 		if os.IsNotExist(err) {
-			return nil
+			return values, nil
 		}
-		return err
+		return nil, err
 	}
 	defer f.Close()
 
@@ -248,11 +570,7 @@ func loadDotEnv(path string) error {
 			continue
 		}
 		value := strings.TrimSpace(parts[1])
-		value = strings.Trim(value, "\"")
-		if _, exists := os.LookupEnv(key); exists {
-			continue
-		}
-		_ = os.Setenv(key, value)
+		values[key] = strings.Trim(value, "\"")
 	}
-	return scanner.Err()
+	return values, scanner.Err()
 }
@@ -0,0 +1,487 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"voice-inbox-daemon/internal/secrets"
+)
+
+func isolate(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, ".config"))
+	t.Setenv("VOICE_INBOX_CONFIG", "")
+	t.Setenv("CREDENTIALS_DIRECTORY", "")
+	useKeyring(t, nil)
+	for _, s := range settings {
+		t.Setenv(s.Env, "")
+		os.Unsetenv(s.Env)
+	}
+	t.Chdir(dir)
+	return dir
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestResolveLayersFileDotEnvEnvAndFlags(t *testing.T) {
+	dir := isolate(t)
+	writeFile(t, filepath.Join(dir, ".config", "voice-inbox", "config.toml"), `
+[discord]
+bot_token = "from-file"
+fetch_limit = 10
+poll_interval_seconds = 1
+
+[retry]
+max_attempts = 3
+base_seconds = 30
+
+[concurrency]
+workers = 9
+`)
+	writeFile(t, filepath.Join(dir, ".env"), "DISCORD_FETCH_LIMIT=20\nMAX_RETRY_ATTEMPTS=4\nRETRY_BASE_SECONDS=\n")
+	t.Setenv("MAX_RETRY_ATTEMPTS", "5")
+
+	cfg, report, err := Resolve(Options{Command: "config", Overrides: map[string]string{"concurrency.workers": "2"}})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if report.File != filepath.Join(dir, ".config", "voice-inbox", "config.toml") {
+		t.Fatalf("unexpected config file: %q", report.File)
+	}
+	if len(report.Problems) != 1 || !strings.Contains(report.Problems[0], "unknown setting discord.poll_interval_seconds") {
+		t.Fatalf("unexpected problems: %v", report.Problems)
+	}
+	if cfg.DiscordBotToken != "from-file" || cfg.DiscordFetchLimit != 20 || cfg.MaxRetryAttempts != 5 || cfg.RetryBaseSeconds != 30 || cfg.WorkerConcurrency != 2 {
+		t.Fatalf("unexpected precedence: %+v", cfg)
+	}
+
+	sources := map[string]string{}
+	for _, s := range report.Settings {
+		sources[s.Key] = s.Source
+	}
+	for key, source := range map[string]string{
+		"DISCORD_BOT_TOKEN":   SourceFile,
+		"DISCORD_FETCH_LIMIT": SourceDotEnv,
+		"MAX_RETRY_ATTEMPTS":  SourceEnv,
+		"RETRY_BASE_SECONDS":  SourceFile,
+		"WORKER_CONCURRENCY":  SourceFlag,
+		"WHISPER_MODEL":       SourceDefault,
+	} {
+		if sources[key] != source {
+			t.Fatalf("%s: expected source %s, got %s", key, source, sources[key])
+		}
+	}
+	if _, ok := os.LookupEnv("DISCORD_FETCH_LIMIT"); ok {
+		t.Fatalf(".env values must not leak into the process environment")
+	}
+}
+
+func TestInvalidIntegerIsAnError(t *testing.T) {
+	isolate(t)
+	t.Setenv("DISCORD_BOT_TOKEN", "t")
+	t.Setenv("OBSIDIAN_API_KEY", "k")
+	t.Setenv("DISCORD_FETCH_LIMIT", "ten")
+
+	_, err := LoadForCommand("poll")
+	if err == nil || !strings.Contains(err.Error(), `DISCORD_FETCH_LIMIT must be an integer, got "ten" (from env)`) {
+		t.Fatalf("expected integer error, got %v", err)
+	}
+}
+
+func TestChannelsAndSinksSections(t *testing.T) {
+	dir := isolate(t)
+	path := filepath.Join(dir, "custom.toml")
+	writeFile(t, path, `
+[obsidian]
+base_url = "https://vault.local:27124/"
+api_key = "primary-key"
+
+[llm]
+api_key = "llm-key"
+
+[[channels]]
+id = "100"
+allowed_author_ids = ["1", "2"]
+
+[[channels]]
+id = "200"
+allowed_author_ids = "3, 4"
+
+[[sinks]]
+name = "work"
+base_url = "https://work.local:27124"
+journal_dir = "/Work/Journal/"
+`)
+
+	cfg, report, err := Resolve(Options{ConfigPath: path, Command: "config"})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if len(report.Problems) != 0 {
+		t.Fatalf("unexpected problems: %v", report.Problems)
+	}
+	if len(cfg.Channels) != 2 || cfg.Channels[1].ID != "200" || strings.Join(cfg.Channels[1].AllowedAuthorIDs, ",") != "3,4" {
+		t.Fatalf("unexpected channels: %+v", cfg.Channels)
+	}
+	if len(cfg.Sinks) != 1 {
+		t.Fatalf("unexpected sinks: %+v", cfg.Sinks)
+	}
+	sink := cfg.Sinks[0]
+	if sink.Type != "obsidian" || sink.APIKey != "primary-key" || sink.JournalDir != "Work/Journal" || sink.BaseURL != "https://work.local:27124" {
+		t.Fatalf("unexpected sink: %+v", sink)
+	}
+	if secrets := strings.Join(cfg.SecretValues(), ","); !strings.Contains(secrets, "primary-key") || !strings.Contains(secrets, "llm-key") {
+		t.Fatalf("expected sink and LLM keys among secrets: %s", secrets)
+	}
+
+	writeFile(t, path, "[[sinks]]\nname = \"obsidian\"\ncolour = \"red\"\n")
+	_, report, err = Resolve(Options{ConfigPath: path, Command: "config"})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	joined := strings.Join(report.Problems, "\n")
+	if !strings.Contains(joined, "unknown key colour") || !strings.Contains(joined, `sinks[0].name "obsidian" is already used`) {
+		t.Fatalf("unexpected problems: %v", report.Problems)
+	}
+
+	if _, _, err := Resolve(Options{ConfigPath: filepath.Join(dir, "missing.toml")}); err == nil {
+		t.Fatalf("expected error for missing explicit config file")
+	}
+}
+
+func TestParseTOMLRejectsMalformedInput(t *testing.T) {
+	for _, src := range []string{
+		"[discord\nx = 1",
+		"[discord]\nx = \"unterminated",
+		"[discord]\nx = 1\nx = 2",
+		"[discord]\nx = [1, [2]]",
+		"[discord]\njust a line",
+	} {
+		if _, err := parseTOML(src); err == nil {
+			t.Fatalf("expected error for %q", src)
+		}
+	}
+	for src, want := range map[string]string{
+		"[a]\ns = \"\"\"x\"\"\"": "multi-line strings are not supported",
+		"[a]\ns = '''x'''":       "multi-line strings are not supported",
+		"[a]\nt = { x = 1 }":     "inline tables are not supported",
+		"[a]\nf = 1.5":           "floats are not supported, got 1.5",
+		"[a]\nf = 1e3":           "floats are not supported",
+		"[a]\nd = 2026-10-18":    "dates and times are not supported",
+		"[a]\nd = [07:30:00]":    "dates and times are not supported",
+	} {
+		_, err := parseTOML(src)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("parse %q: got %v, want %q", src, err, want)
+		}
+	}
+	doc, err := parseTOML("[a]\ns = \"x # not a comment\\n\" # comment\nl = 'C:\\path'\nn = -1_000\nb = true\n")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	a := doc["a"].(map[string]any)
+	if a["s"] != "x # not a comment\n" || a["l"] != `C:\path` || a["n"] != int64(-1000) || a["b"] != true {
+		t.Fatalf("unexpected doc: %#v", a)
+	}
+}
+
+type fakeKeyring map[string]string
+
+func (k fakeKeyring) Name() string { return "fake" }
+
+func (k fakeKeyring) Get(key string) (string, bool, error) {
+	v, ok := k[key]
+	return v, ok, nil
+}
+
+func (k fakeKeyring) Set(key, value string) error {
+	k[key] = value
+	return nil
+}
+
+type brokenKeyring struct{ asked *[]string }
+
+func (k brokenKeyring) Name() string { return "broken" }
+
+func (k brokenKeyring) Get(key string) (string, bool, error) {
+	*k.asked = append(*k.asked, key)
+	return "", false, errors.New("dbus: no session bus")
+}
+
+func (k brokenKeyring) Set(key, value string) error {
+	return errors.New("dbus: no session bus")
+}
+
+func useKeyring(t *testing.T, kr secrets.Keyring) {
+	t.Helper()
+	prev := openKeyring
+	openKeyring = func() (secrets.Keyring, error) {
+		if kr == nil {
+			return nil, secrets.ErrNoKeyring
+		}
+		return kr, nil
+	}
+	t.Cleanup(func() { openKeyring = prev })
+}
+
+func TestResolveSecretsFromFileCommandCredentialsAndKeyring(t *testing.T) {
+	dir := isolate(t)
+	writeFile(t, filepath.Join(dir, "discord-token"), "token-from-file\n")
+	writeFile(t, filepath.Join(dir, "creds", "INGEST_AUTH_TOKEN"), "token-from-credentials\n")
+	t.Setenv("DISCORD_BOT_TOKEN_FILE", filepath.Join(dir, "discord-token"))
+	t.Setenv("CREDENTIALS_DIRECTORY", filepath.Join(dir, "creds"))
+	writeFile(t, filepath.Join(dir, ".config", "voice-inbox", "config.toml"), `
+[obsidian]
+api_key_cmd = "printf 'key-from-command\\n'"
+
+[[sinks]]
+name = "work"
+api_key_cmd = "echo sink-key"
+`)
+
+	cfg, report, err := Resolve(Options{Command: "daemon"})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if len(report.Problems) != 0 {
+		t.Fatalf("unexpected problems: %v", report.Problems)
+	}
+	if cfg.DiscordBotToken != "token-from-file" || cfg.ObsidianAPIKey != "key-from-command" || cfg.IngestAuthToken != "token-from-credentials" {
+		t.Fatalf("unexpected secrets: %q %q %q", cfg.DiscordBotToken, cfg.ObsidianAPIKey, cfg.IngestAuthToken)
+	}
+	if len(cfg.Sinks) != 1 || cfg.Sinks[0].APIKey != "sink-key" {
+		t.Fatalf("unexpected sinks: %+v", cfg.Sinks)
+	}
+	sources := map[string]string{}
+	for _, s := range report.Settings {
+		sources[s.Key] = s.Source
+	}
+	if sources["DISCORD_BOT_TOKEN"] != SourceSecretFile || sources["OBSIDIAN_API_KEY"] != SourceCommand || sources["INGEST_AUTH_TOKEN"] != SourceCredential {
+		t.Fatalf("unexpected sources: %v", sources)
+	}
+
+	t.Setenv("CREDENTIALS_DIRECTORY", "")
+	useKeyring(t, fakeKeyring{"INGEST_AUTH_TOKEN": "token-from-keyring"})
+	cfg, report, err = Resolve(Options{Command: "daemon"})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if cfg.IngestAuthToken != "token-from-keyring" {
+		t.Fatalf("expected keyring token, got %q", cfg.IngestAuthToken)
+	}
+	for _, s := range report.Settings {
+		if s.Key == "INGEST_AUTH_TOKEN" && s.Source != SourceKeyring {
+			t.Fatalf("expected keyring source, got %s", s.Source)
+		}
+	}
+}
+
+func TestResolveSecretProblems(t *testing.T) {
+	dir := isolate(t)
+	t.Setenv("DISCORD_BOT_TOKEN", "direct")
+	t.Setenv("DISCORD_BOT_TOKEN_CMD", "echo other")
+	t.Setenv("OBSIDIAN_API_KEY_FILE", filepath.Join(dir, "missing"))
+	t.Setenv("INGEST_AUTH_TOKEN_CMD", "echo boom >&2; exit 3")
+
+	cfg, report, err := Resolve(Options{Command: "config"})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if cfg.DiscordBotToken != "direct" {
+		t.Fatalf("direct value should win, got %q", cfg.DiscordBotToken)
+	}
+	joined := strings.Join(report.Problems, "\n")
+	for _, want := range []string{
+		"DISCORD_BOT_TOKEN: set only one of",
+		"OBSIDIAN_API_KEY_FILE: open",
+		"INGEST_AUTH_TOKEN_CMD: exit status 3: boom",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("missing problem %q in %v", want, report.Problems)
+		}
+	}
+}
+
+func TestStoreSecretAndWriteDotEnv(t *testing.T) {
+	dir := isolate(t)
+	if _, err := StoreSecret("DISCORD_BOT_TOKEN", "x"); !errors.Is(err, secrets.ErrNoKeyring) {
+		t.Fatalf("expected ErrNoKeyring, got %v", err)
+	}
+	kr := fakeKeyring{}
+	useKeyring(t, kr)
+	if _, err := StoreSecret("WHISPER_MODEL", "x"); err == nil {
+		t.Fatal("expected non-secret key to be rejected")
+	}
+	if backend, err := StoreSecret("DISCORD_BOT_TOKEN", "stored"); err != nil || backend != "fake" || kr["DISCORD_BOT_TOKEN"] != "stored" {
+		t.Fatalf("store: backend=%q err=%v keyring=%v", backend, err, kr)
+	}
+
+	path := filepath.Join(dir, ".env")
+	writeFile(t, path, "# comment\nDISCORD_BOT_TOKEN=old\nLOG_LEVEL=debug\n")
+	if err := os.Chmod(path, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteDotEnv(path, "DISCORD_BOT_TOKEN", "new"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	if err := WriteDotEnv(path, "INGEST_AUTH_TOKEN", "ingest"); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	raw, _ := os.ReadFile(path)
+	if string(raw) != "# comment\nDISCORD_BOT_TOKEN=new\nLOG_LEVEL=debug\nINGEST_AUTH_TOKEN=ingest\n" {
+		t.Fatalf("unexpected .env:\n%s", raw)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Fatalf("expected 0600, got %v", info.Mode().Perm())
+	}
+}
+
+func TestDiffRedactsSecretsAndFlagsRestartKeys(t *testing.T) {
+	dir := isolate(t)
+	t.Setenv("INGEST_AUTH_TOKEN", "old-token")
+	oldCfg, oldReport, err := Resolve(Options{Command: "config"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("INGEST_AUTH_TOKEN", "new-token")
+	t.Setenv("AUDIO_RETENTION_DAYS", "30")
+	t.Setenv("POLL_INTERVAL_SECONDS", "60")
+	writeFile(t, filepath.Join(dir, ".config", "voice-inbox", "config.toml"), `
+[[channels]]
+id = "42"
+allowed_author_ids = ["7"]
+`)
+	newCfg, newReport, err := Resolve(Options{Command: "config"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes := map[string]Change{}
+	for _, c := range Diff(oldCfg, oldReport.Settings, newCfg, newReport.Settings) {
+		changes[c.Key] = c
+	}
+	if c := changes["INGEST_AUTH_TOKEN"]; c.Old != "[REDACTED]" || c.New != "[REDACTED]" {
+		t.Fatalf("secret change not redacted: %+v", c)
+	}
+	if c := changes["AUDIO_RETENTION_DAYS"]; c.Old != "14" || c.New != "30" || c.Restart {
+		t.Fatalf("unexpected retention change: %+v", c)
+	}
+	if c := changes["POLL_INTERVAL_SECONDS"]; !c.Restart {
+		t.Fatalf("poll interval should require restart: %+v", c)
+	}
+	if c := changes["channels"]; c.New != "42(1 authors)" {
+		t.Fatalf("unexpected channel change: %+v", c)
+	}
+	if len(changes) != 4 {
+		t.Fatalf("unexpected changes: %+v", changes)
+	}
+
+	kept := PreserveRestartSettings(newCfg, oldCfg)
+	if kept.PollIntervalSeconds != oldCfg.PollIntervalSeconds || kept.AudioRetentionDays != 30 {
+		t.Fatalf("unexpected preserved config: poll=%d retention=%d", kept.PollIntervalSeconds, kept.AudioRetentionDays)
+	}
+}
+
+func TestResolveRoutesValidatesSinksAndPatterns(t *testing.T) {
+	dir := isolate(t)
+	writeFile(t, filepath.Join(dir, ".config", "voice-inbox", "config.toml"), `
+[[sinks]]
+name = "work"
+
+[[routes]]
+name = "ideas"
+prefix = ["idea:", "アイデア"]
+path = "Inbox/Ideas.md"
+strip_trigger = true
+tags = ["idea"]
+
+[[routes]]
+name = "work"
+device_id = "Pixel 8a"
+sink = "work"
+
+[[routes]]
+name = "broken"
+match = "("
+sink = "missing"
+`)
+	cfg, report, err := Resolve(Options{Command: "config"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Routes) != 3 || cfg.Routes[0].Prefixes[1] != "アイデア" || !cfg.Routes[0].StripTrigger || cfg.Routes[1].DeviceIDs[0] != "Pixel 8a" {
+		t.Fatalf("unexpected routes: %+v", cfg.Routes)
+	}
+	joined := strings.Join(report.Problems, "\n")
+	for _, want := range []string{"route broken: match", `routes[2].sink "missing" is not defined`} {
+		if !strings.Contains(joined, want) {
+			t.Fatalf("missing problem %q in %v", want, report.Problems)
+		}
+	}
+}
+
+func TestResolveTagKeywords(t *testing.T) {
+	dir := isolate(t)
+	writeFile(t, filepath.Join(dir, ".config", "voice-inbox", "config.toml"), `
+[tags]
+hashtags = false
+keywords = ["予算=finance", "standup=work/meetings", "broken"]
+`)
+	cfg, report, err := Resolve(Options{Command: "config"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.TagHashtags || len(cfg.TagKeywords) != 3 || cfg.TagKeywords[0] != "予算=finance" {
+		t.Fatalf("unexpected tag config: hashtags=%v keywords=%v", cfg.TagHashtags, cfg.TagKeywords)
+	}
+	if !strings.Contains(strings.Join(report.Problems, "\n"), `TAGS_KEYWORDS: tag keyword "broken"`) {
+		t.Fatalf("expected keyword problem, got %v", report.Problems)
+	}
+}
+
+func TestResolveQueriesKeyringOnlyForRequiredOrEnabledSecrets(t *testing.T) {
+	isolate(t)
+	t.Setenv("DISCORD_BOT_TOKEN", "token")
+	t.Setenv("OBSIDIAN_API_KEY", "key")
+	var asked []string
+	useKeyring(t, brokenKeyring{asked: &asked})
+
+	_, report, err := Resolve(Options{Command: "poll"})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if len(asked) != 0 || len(report.Problems) != 0 {
+		t.Fatalf("optional secrets should not touch the keyring: asked=%v problems=%v", asked, report.Problems)
+	}
+
+	_, report, err = Resolve(Options{Command: "serve"})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	joined := strings.Join(report.Problems, "\n")
+	if strings.Join(asked, ",") != "INGEST_AUTH_TOKEN" || !strings.Contains(joined, "INGEST_AUTH_TOKEN: keyring broken: dbus: no session bus") {
+		t.Fatalf("a required secret should query the keyring and report its failure: asked=%v problems=%v", asked, report.Problems)
+	}
+
+	asked = nil
+	t.Setenv("SECRETS_KEYRING", "true")
+	if _, _, err := Resolve(Options{Command: "poll"}); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if strings.Join(asked, ",") != "INGEST_AUTH_TOKEN,LLM_API_KEY" {
+		t.Fatalf("SECRETS_KEYRING=true should query every unset secret, asked=%v", asked)
+	}
+}
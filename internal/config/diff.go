@@ -0,0 +1,112 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"voice-inbox-daemon/internal/routing"
+)
+
+type Change struct {
+	Key     string `json:"key"`
+	Old     string `json:"old"`
+	New     string `json:"new"`
+	Restart bool   `json:"restart,omitempty"`
+}
+
+var restartKeys = map[string]bool{
+	"STATE_DB_PATH":               true,
+	"AUDIO_STORE_DIR":             true,
+	"LOG_DIR":                     true,
+	"INGEST_LISTEN_ADDR":          true,
+	"POLL_INTERVAL_SECONDS":       true,
+	"RETRY_INTERVAL_SECONDS":      true,
+	"SINK_PROBE_INTERVAL_SECONDS": true,
+	"CLEANUP_AT":                  true,
+	"DIGEST_AT":                   true,
+	"LOOP_BACKOFF_MAX_SECONDS":    true,
+}
+
+func (r Report) Err() error {
+	if len(r.Problems) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(r.Problems, "; "))
+}
+
+func Diff(oldCfg Config, oldSettings []SettingValue, newCfg Config, newSettings []SettingValue) []Change {
+	previous := make(map[string]SettingValue, len(oldSettings))
+	for _, s := range oldSettings {
+		previous[s.Key] = s
+	}
+	var changes []Change
+	for _, s := range newSettings {
+		old := previous[s.Key]
+		if old.Value == s.Value {
+			continue
+		}
+		c := Change{Key: s.Key, Old: old.Value, New: s.Value, Restart: restartKeys[s.Key]}
+		if s.Secret || old.Secret {
+			c.Old, c.New = RedactValue(c.Old), RedactValue(c.New)
+		}
+		changes = append(changes, c)
+	}
+	if !reflect.DeepEqual(oldCfg.Channels, newCfg.Channels) {
+		changes = append(changes, Change{Key: "channels", Old: channelSummary(oldCfg.Channels), New: channelSummary(newCfg.Channels)})
+	}
+	if !reflect.DeepEqual(oldCfg.Sinks, newCfg.Sinks) {
+		changes = append(changes, Change{Key: "sinks", Old: sinkSummary(oldCfg.Sinks), New: sinkSummary(newCfg.Sinks)})
+	}
+	if !reflect.DeepEqual(oldCfg.Routes, newCfg.Routes) {
+		changes = append(changes, Change{Key: "routes", Old: routeSummary(oldCfg.Routes), New: routeSummary(newCfg.Routes)})
+	}
+	return changes
+}
+
+func RedactValue(value string) string {
+	if value == "" {
+		return ""
+	}
+	return "[REDACTED]"
+}
+
+func PreserveRestartSettings(next, running Config) Config {
+	next.StateDBPath = running.StateDBPath
+	next.LockFilePath = running.LockFilePath
+	next.AudioStoreDir = running.AudioStoreDir
+	next.LogDir = running.LogDir
+	next.IngestListenAddr = running.IngestListenAddr
+	next.PollIntervalSeconds = running.PollIntervalSeconds
+	next.RetryIntervalSeconds = running.RetryIntervalSeconds
+	next.ProbeIntervalSeconds = running.ProbeIntervalSeconds
+	next.CleanupAt = running.CleanupAt
+	next.DigestAt = running.DigestAt
+	next.LoopBackoffMaxSeconds = running.LoopBackoffMaxSeconds
+	return next
+}
+
+func channelSummary(channels []Channel) string {
+	parts := make([]string, 0, len(channels))
+	for _, ch := range channels {
+		parts = append(parts, fmt.Sprintf("%s(%d authors)", ch.ID, len(ch.AllowedAuthorIDs)))
+	}
+	return strings.Join(parts, ",")
+}
+
+func sinkSummary(sinks []Sink) string {
+	parts := make([]string, 0, len(sinks))
+	for _, s := range sinks {
+		parts = append(parts, s.Name+"="+s.BaseURL+"/"+s.JournalDir)
+	}
+	return strings.Join(parts, ",")
+}
+
+func routeSummary(routes []routing.Rule) string {
+	parts := make([]string, 0, len(routes))
+	for _, r := range routes {
+		parts = append(parts, r.Name)
+	}
+	return strings.Join(parts, ",")
+}
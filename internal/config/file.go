@@ -0,0 +1,294 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"voice-inbox-daemon/internal/routing"
+	"voice-inbox-daemon/internal/secrets"
+)
+
+const configFileName = "config.toml"
+
+type Channel struct {
+	ID               string   `json:"id"`
+	AllowedAuthorIDs []string `json:"allowed_author_ids"`
+}
+
+type Sink struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	BaseURL    string `json:"base_url"`
+	APIKey     string `json:"-"`
+	AuthHeader string `json:"auth_header"`
+	VerifyTLS  bool   `json:"verify_tls"`
+	JournalDir string `json:"journal_dir"`
+}
+
+type fileConfig struct {
+	Path     string
+	Values   map[string]string
+	Channels []Channel
+	Sinks    []Sink
+	Routes   []routing.Rule
+	Problems []string
+}
+
+func ConfigSearchPaths(home string) []string {
+	return []string{
+		filepath.Join(ConfigHome(home), "voice-inbox", configFileName),
+		filepath.Join("/etc", "voice-inbox", configFileName),
+	}
+}
+
+func findConfigFile(explicit, home string) (string, error) {
+	if explicit != "" {
+		if _, err := os.Stat(explicit); err != nil {
+			return "", fmt.Errorf("config file: %w", err)
+		}
+		return explicit, nil
+	}
+	if env := strings.TrimSpace(os.Getenv("VOICE_INBOX_CONFIG")); env != "" {
+		if _, err := os.Stat(env); err != nil {
+			return "", fmt.Errorf("VOICE_INBOX_CONFIG: %w", err)
+		}
+		return env, nil
+	}
+	for _, candidate := range ConfigSearchPaths(home) {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("config file %s: %w", candidate, err)
+		}
+	}
+	return "", nil
+}
+
+func readConfigFile(path string) (fileConfig, error) {
+	fc := fileConfig{Path: path, Values: map[string]string{}}
+	if path == "" {
+		return fc, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return fc, err
+	}
+	doc, err := parseTOML(string(raw))
+	if err != nil {
+		return fc, &SyntaxError{Path: path, Err: err}
+	}
+
+	sections := make([]string, 0, len(doc))
+	for name := range doc {
+		sections = append(sections, name)
+	}
+	sort.Strings(sections)
+	for _, name := range sections {
+		switch v := doc[name].(type) {
+		case map[string]any:
+			for key, value := range v {
+				fileKey := name + "." + key
+				s, ok := lookupSetting(fileKey)
+				if !ok || s.File != fileKey {
+					fc.Problems = append(fc.Problems, fmt.Sprintf("%s: unknown setting %s", path, fileKey))
+					continue
+				}
+				str, err := scalarString(value)
+				if list, isList := value.([]any); isList && s.Lines && err == nil {
+					parts := make([]string, len(list))
+					for i, item := range list {
+						parts[i], _ = scalarString(item)
+					}
+					str = strings.Join(parts, "\n")
+				}
+				if err != nil {
+					fc.Problems = append(fc.Problems, fmt.Sprintf("%s: %s: %v", path, fileKey, err))
+					continue
+				}
+				fc.Values[s.Env] = str
+			}
+		case []map[string]any:
+			fc.readTables(name, v)
+		default:
+			fc.Problems = append(fc.Problems, fmt.Sprintf("%s: unknown top-level setting %s (settings belong in a [section])", path, name))
+		}
+	}
+	return fc, nil
+}
+
+func (fc *fileConfig) readTables(name string, tables []map[string]any) {
+	switch name {
+	case "channels":
+		for i, t := range tables {
+			r := tableReader{fc: fc, where: fmt.Sprintf("channels[%d]", i), table: t}
+			fc.Channels = append(fc.Channels, Channel{
+				ID:               r.str("id"),
+				AllowedAuthorIDs: r.list("allowed_author_ids"),
+			})
+			r.done()
+		}
+	case "sinks":
+		for i, t := range tables {
+			r := tableReader{fc: fc, where: fmt.Sprintf("sinks[%d]", i), table: t}
+			sink := Sink{
+				Name:       r.str("name"),
+				Type:       r.str("type"),
+				BaseURL:    strings.TrimRight(r.str("base_url"), "/"),
+				APIKey:     r.str("api_key"),
+				AuthHeader: r.str("auth_header"),
+				JournalDir: strings.Trim(r.str("journal_dir"), "/"),
+				VerifyTLS:  r.boolean("verify_tls"),
+			}
+			sink.APIKey = r.secret(sink.APIKey, "api_key")
+			fc.Sinks = append(fc.Sinks, sink)
+			r.done()
+		}
+	case "routes":
+		for i, t := range tables {
+			r := tableReader{fc: fc, where: fmt.Sprintf("routes[%d]", i), table: t}
+			fc.Routes = append(fc.Routes, routing.Rule{
+				Name:         r.str("name"),
+				Prefixes:     r.list("prefix"),
+				Match:        r.str("match"),
+				Sources:      r.list("source"),
+				DeviceIDs:    r.list("device_id"),
+				ChannelIDs:   r.list("channel_id"),
+				Path:         r.str("path"),
+				Sink:         r.str("sink"),
+				StripTrigger: r.boolean("strip_trigger"),
+				Tags:         r.list("tags"),
+			})
+			r.done()
+		}
+	default:
+		fc.Problems = append(fc.Problems, fmt.Sprintf("%s: unknown section [[%s]]", fc.Path, name))
+	}
+}
+
+type tableReader struct {
+	fc    *fileConfig
+	where string
+	table map[string]any
+	seen  []string
+}
+
+func (r *tableReader) take(key string) (any, bool) {
+	r.seen = append(r.seen, key)
+	v, ok := r.table[key]
+	return v, ok
+}
+
+func (r *tableReader) problem(format string, args ...any) {
+	r.fc.Problems = append(r.fc.Problems, fmt.Sprintf("%s: %s: ", r.fc.Path, r.where)+fmt.Sprintf(format, args...))
+}
+
+func (r *tableReader) str(key string) string {
+	v, ok := r.take(key)
+	if !ok {
+		return ""
+	}
+	s, err := scalarString(v)
+	if err != nil {
+		r.problem("%s: %v", key, err)
+	}
+	return strings.TrimSpace(s)
+}
+
+func (r *tableReader) secret(value, key string) string {
+	file, command := r.str(key+"_file"), r.str(key+"_cmd")
+	var err error
+	switch {
+	case value != "" && (file != "" || command != ""), file != "" && command != "":
+		r.problem("set only one of %s, %s_file and %s_cmd", key, key, key)
+	case file != "":
+		value, err = secrets.ReadFile(file)
+	case command != "":
+		value, err = secrets.RunCommand(command)
+	}
+	if err != nil {
+		r.problem("%s: %v", key, err)
+	}
+	return value
+}
+
+func (r *tableReader) boolean(key string) bool {
+	v, ok := r.take(key)
+	if !ok {
+		return false
+	}
+	b, isBool := v.(bool)
+	if !isBool {
+		r.problem("%s must be true or false", key)
+	}
+	return b
+}
+
+func (r *tableReader) list(key string) []string {
+	v, ok := r.take(key)
+	if !ok {
+		return nil
+	}
+	if s, isString := v.(string); isString {
+		_, out := parseCSVSet(s)
+		return out
+	}
+	items, isList := v.([]any)
+	if !isList {
+		r.problem("%s must be a list", key)
+		return nil
+	}
+	var out []string
+	for _, item := range items {
+		s, err := scalarString(item)
+		if err != nil {
+			r.problem("%s: %v", key, err)
+			continue
+		}
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func (r *tableReader) done() {
+	keys := make([]string, 0, len(r.table))
+	for key := range r.table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !slices.Contains(r.seen, key) {
+			r.problem("unknown key %s", key)
+		}
+	}
+}
+
+func scalarString(v any) (string, error) {
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case int64:
+		return strconv.FormatInt(t, 10), nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	case []any:
+		parts := make([]string, 0, len(t))
+		for _, item := range t {
+			s, err := scalarString(item)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value type %T", v)
+	}
+}
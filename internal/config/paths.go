@@ -0,0 +1,58 @@
+package config
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+const appName = "voice-inbox-daemon"
+
+type platformPaths struct {
+	StateDB  string
+	AudioDir string
+	LogDir   string
+}
+
+func defaultPaths(goos, home string, getenv func(string) string) platformPaths {
+	if goos == "darwin" {
+		support := filepath.Join(home, "Library", "Application Support", appName)
+		return platformPaths{
+			StateDB:  filepath.Join(support, "state.db"),
+			AudioDir: filepath.Join(support, "audio"),
+			LogDir:   filepath.Join(home, "Library", "Logs", appName),
+		}
+	}
+	stateHome := xdgDir(getenv("XDG_STATE_HOME"), filepath.Join(home, ".local", "state"))
+	dataHome := xdgDir(getenv("XDG_DATA_HOME"), filepath.Join(home, ".local", "share"))
+	return platformPaths{
+		StateDB:  filepath.Join(stateHome, appName, "state.db"),
+		AudioDir: filepath.Join(dataHome, appName, "audio"),
+		LogDir:   filepath.Join(stateHome, appName, "logs"),
+	}
+}
+
+func xdgDir(value, fallback string) string {
+	if value == "" || !filepath.IsAbs(value) {
+		return fallback
+	}
+	return value
+}
+
+func defaultBinary(name string) string {
+	if path, err := exec.LookPath(name); err == nil {
+		if abs, err := filepath.Abs(path); err == nil {
+			return abs
+		}
+		return path
+	}
+	if runtime.GOOS == "darwin" {
+		return filepath.Join("/opt/homebrew/bin", name)
+	}
+	return filepath.Join("/usr/bin", name)
+}
+
+func ConfigHome(home string) string {
+	return xdgDir(os.Getenv("XDG_CONFIG_HOME"), filepath.Join(home, ".config"))
+}
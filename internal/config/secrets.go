@@ -0,0 +1,151 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"voice-inbox-daemon/internal/secrets"
+)
+
+const (
+	SourceSecretFile = "secret-file"
+	SourceCredential = "credential"
+	SourceCommand    = "command"
+	SourceKeyring    = "keyring"
+)
+
+var openKeyring = secrets.DefaultKeyring
+
+func SecretKeys() []string {
+	var out []string
+	for _, s := range settings {
+		if s.Secret {
+			out = append(out, s.Env)
+		}
+	}
+	return out
+}
+
+func IsSecretKey(key string) bool {
+	s, ok := lookupSetting(key)
+	return ok && s.Secret
+}
+
+func (l *loader) secret(key string) string {
+	value, source, hasValue := l.lookup(key)
+	file, fileSource, hasFile := l.lookup(key + "_FILE")
+	command, commandSource, hasCommand := l.lookup(key + "_CMD")
+	l.record(key+"_FILE", file, fileSource)
+	l.record(key+"_CMD", command, commandSource)
+
+	set := 0
+	for _, ok := range []bool{hasValue, hasFile, hasCommand} {
+		if ok {
+			set++
+		}
+	}
+	if set > 1 {
+		l.problems = append(l.problems, fmt.Sprintf("%s: set only one of %s, %s_FILE and %s_CMD", key, key, key, key))
+	}
+
+	var err error
+	switch {
+	case hasValue:
+	case hasFile:
+		value, err = secrets.ReadFile(file)
+		source = SourceSecretFile
+		if err != nil {
+			l.problems = append(l.problems, fmt.Sprintf("%s_FILE: %v", key, err))
+		}
+	case hasCommand:
+		value, err = secrets.RunCommand(command)
+		source = SourceCommand
+		if err != nil {
+			l.problems = append(l.problems, fmt.Sprintf("%s_CMD: %v", key, err))
+		}
+	default:
+		value, source = l.storedSecret(key)
+	}
+	l.record(key, value, source)
+	return value
+}
+
+func (l *loader) storedSecret(key string) (string, string) {
+	if dir := os.Getenv("CREDENTIALS_DIRECTORY"); dir != "" {
+		if v, err := secrets.ReadFile(filepath.Join(dir, key)); err == nil && v != "" {
+			return v, SourceCredential
+		}
+	}
+	if !l.keyring && !l.required[key] {
+		return "", SourceDefault
+	}
+	kr, err := openKeyring()
+	if err != nil {
+		if l.keyring {
+			l.problems = append(l.problems, fmt.Sprintf("SECRETS_KEYRING: %v", err))
+		}
+		return "", SourceDefault
+	}
+	v, ok, err := kr.Get(key)
+	if err != nil {
+		l.problems = append(l.problems, fmt.Sprintf("%s: keyring %s: %v", key, kr.Name(), err))
+		return "", SourceDefault
+	}
+	if !ok {
+		return "", SourceDefault
+	}
+	return v, SourceKeyring
+}
+
+func StoreSecret(key, value string) (string, error) {
+	if !IsSecretKey(key) {
+		return "", fmt.Errorf("unknown secret %q (want one of %s)", key, strings.Join(SecretKeys(), ", "))
+	}
+	kr, err := openKeyring()
+	if err != nil {
+		return "", err
+	}
+	if err := kr.Set(key, value); err != nil {
+		return "", err
+	}
+	return kr.Name(), nil
+}
+
+func WriteDotEnv(path, key, value string) error {
+	if strings.ContainsAny(value, "\r\n") {
+		return errors.New("value must be a single line")
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	lines := strings.Split(strings.TrimRight(string(raw), "\n"), "\n")
+	if len(raw) == 0 {
+		lines = nil
+	}
+	entry := key + "=" + value
+	replaced := false
+	for i, line := range lines {
+		name, _, ok := strings.Cut(strings.TrimPrefix(strings.TrimSpace(line), "export "), "=")
+		if ok && strings.TrimSpace(name) == key {
+			lines[i] = entry
+			replaced = true
+		}
+	}
+	if !replaced {
+		lines = append(lines, entry)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	return os.Chmod(path, 0o600)
+}
@@ -0,0 +1,226 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceDotEnv  = ".env"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+type setting struct {
+	Env    string
+	File   string
+	Secret bool
+	Lines  bool
+}
+
+var settings = []setting{
+	{Env: "DISCORD_BOT_TOKEN", File: "discord.bot_token", Secret: true},
+	{Env: "DISCORD_BOT_TOKEN_FILE", File: "discord.bot_token_file"},
+	{Env: "DISCORD_BOT_TOKEN_CMD", File: "discord.bot_token_cmd"},
+	{Env: "DISCORD_API_BASE_URL", File: "discord.api_base_url"},
+	{Env: "VOICE_INBOX_CHANNEL_ID", File: "discord.channel_id"},
+	{Env: "VOICE_INBOX_ALLOWED_AUTHOR_IDS", File: "discord.allowed_author_ids"},
+	{Env: "DISCORD_FETCH_LIMIT", File: "discord.fetch_limit"},
+	{Env: "POLL_INTERVAL_SECONDS", File: "schedule.poll_interval_seconds"},
+	{Env: "RETRY_INTERVAL_SECONDS", File: "schedule.retry_interval_seconds"},
+	{Env: "SINK_PROBE_INTERVAL_SECONDS", File: "schedule.sink_probe_interval_seconds"},
+	{Env: "CLEANUP_AT", File: "schedule.cleanup_at"},
+	{Env: "DIGEST_AT", File: "schedule.digest_at"},
+	{Env: "DIGEST_WEEK_DIR", File: "obsidian.digest_week_dir"},
+	{Env: "LOOP_BACKOFF_MAX_SECONDS", File: "schedule.loop_backoff_max_seconds"},
+	{Env: "WHISPER_BIN", File: "whisper.bin"},
+	{Env: "WHISPER_MODEL", File: "whisper.model"},
+	{Env: "WHISPER_LANGUAGE", File: "whisper.language"},
+	{Env: "WHISPER_CHUNK_SECONDS", File: "whisper.chunk_seconds"},
+	{Env: "WHISPER_CHUNK_OVERLAP_SECONDS", File: "whisper.chunk_overlap_seconds"},
+	{Env: "WHISPER_TIMEOUT_FACTOR", File: "whisper.timeout_factor"},
+	{Env: "FFMPEG_BIN", File: "ffmpeg.bin"},
+	{Env: "FFPROBE_BIN", File: "ffmpeg.ffprobe_bin"},
+	{Env: "OBSIDIAN_BASE_URL", File: "obsidian.base_url"},
+	{Env: "OBSIDIAN_API_KEY", File: "obsidian.api_key", Secret: true},
+	{Env: "OBSIDIAN_API_KEY_FILE", File: "obsidian.api_key_file"},
+	{Env: "OBSIDIAN_API_KEY_CMD", File: "obsidian.api_key_cmd"},
+	{Env: "OBSIDIAN_AUTH_HEADER", File: "obsidian.auth_header"},
+	{Env: "OBSIDIAN_VERIFY_TLS", File: "obsidian.verify_tls"},
+	{Env: "VAULT_JOURNAL_DIR", File: "obsidian.journal_dir"},
+	{Env: "AUDIO_RETENTION_DAYS", File: "retention.audio_days"},
+	{Env: "TRANSCRIPT_RETENTION_DAYS", File: "retention.transcript_days"},
+	{Env: "AUDIO_ATTACH_MODE", File: "attachments.mode"},
+	{Env: "AUDIO_ATTACH_DIR", File: "attachments.dir"},
+	{Env: "AUDIO_ATTACH_RETENTION_DAYS", File: "attachments.retention_days"},
+	{Env: "LLM_ENABLED", File: "llm.enabled"},
+	{Env: "LLM_BASE_URL", File: "llm.base_url"},
+	{Env: "LLM_API_KEY", File: "llm.api_key", Secret: true},
+	{Env: "LLM_API_KEY_FILE", File: "llm.api_key_file"},
+	{Env: "LLM_API_KEY_CMD", File: "llm.api_key_cmd"},
+	{Env: "LLM_MODEL", File: "llm.model"},
+	{Env: "LLM_PROMPT", File: "llm.prompt"},
+	{Env: "LLM_TIMEOUT_SECONDS", File: "llm.timeout_seconds"},
+	{Env: "LLM_MIN_CHARS", File: "llm.min_chars"},
+	{Env: "MAX_RETRY_ATTEMPTS", File: "retry.max_attempts"},
+	{Env: "RETRY_BASE_SECONDS", File: "retry.base_seconds"},
+	{Env: "RETRY_MAX_SECONDS", File: "retry.max_seconds"},
+	{Env: "WORKER_CONCURRENCY", File: "concurrency.workers"},
+	{Env: "DOWNLOAD_CONCURRENCY", File: "concurrency.downloads"},
+	{Env: "TRANSCRIBE_CONCURRENCY", File: "concurrency.transcriptions"},
+	{Env: "JOURNAL_CONCURRENCY", File: "concurrency.journal"},
+	{Env: "STATE_DB_PATH", File: "paths.state_db"},
+	{Env: "AUDIO_STORE_DIR", File: "paths.audio_store_dir"},
+	{Env: "LOG_DIR", File: "paths.log_dir"},
+	{Env: "LOG_FORMAT", File: "log.format"},
+	{Env: "LOG_LEVEL", File: "log.level"},
+	{Env: "BACKUP_DIR", File: "backup.dir"},
+	{Env: "BACKUP_KEEP", File: "backup.keep"},
+	{Env: "INGEST_LISTEN_ADDR", File: "ingest.listen_addr"},
+	{Env: "INGEST_AUTH_TOKEN", File: "ingest.auth_token", Secret: true},
+	{Env: "INGEST_AUTH_TOKEN_FILE", File: "ingest.auth_token_file"},
+	{Env: "INGEST_AUTH_TOKEN_CMD", File: "ingest.auth_token_cmd"},
+	{Env: "SECRETS_KEYRING", File: "secrets.keyring"},
+	{Env: "INGEST_MAX_BODY_MB", File: "ingest.max_body_mb"},
+	{Env: "INGEST_SOURCE_NAME", File: "ingest.source_name"},
+	{Env: "TASKS_MODE", File: "tasks.mode"},
+	{Env: "TASKS_NOTE_PATH", File: "tasks.note_path"},
+	{Env: "TASKS_TRIGGERS", File: "tasks.triggers"},
+	{Env: "TASKS_PATTERNS", File: "tasks.patterns", Lines: true},
+	{Env: "TAGS_HASHTAGS", File: "tags.hashtags"},
+	{Env: "TAGS_KEYWORDS", File: "tags.keywords"},
+}
+
+func lookupSetting(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.Env == key || s.File == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+type SettingValue struct {
+	Key     string `json:"key"`
+	FileKey string `json:"file_key"`
+	Value   string `json:"value"`
+	Source  string `json:"source"`
+	Secret  bool   `json:"secret,omitempty"`
+}
+
+type layer struct {
+	source string
+	lookup func(key string) (string, bool)
+}
+
+func mapLayer(source string, values map[string]string) layer {
+	return layer{source: source, lookup: func(key string) (string, bool) {
+		v, ok := values[key]
+		return v, ok
+	}}
+}
+
+type loader struct {
+	layers   []layer
+	resolved map[string]SettingValue
+	problems []string
+	keyring  bool
+	required map[string]bool
+}
+
+func newLoader(layers ...layer) *loader {
+	return &loader{layers: layers, resolved: map[string]SettingValue{}}
+}
+
+func (l *loader) lookup(key string) (string, string, bool) {
+	for _, ly := range l.layers {
+		if v, ok := ly.lookup(key); ok {
+			if v = strings.TrimSpace(v); v != "" {
+				return v, ly.source, true
+			}
+		}
+	}
+	return "", SourceDefault, false
+}
+
+func (l *loader) record(key, value, source string) {
+	s, _ := lookupSetting(key)
+	l.resolved[key] = SettingValue{Key: key, FileKey: s.File, Value: value, Source: source, Secret: s.Secret}
+}
+
+func (l *loader) str(key, def string) string {
+	v, source, ok := l.lookup(key)
+	if !ok {
+		v = def
+	}
+	l.record(key, v, source)
+	return v
+}
+
+func (l *loader) int(key string, def int) int {
+	raw, source, ok := l.lookup(key)
+	if !ok {
+		l.record(key, strconv.Itoa(def), source)
+		return def
+	}
+	l.record(key, raw, source)
+	v, err := strconv.Atoi(raw)
+	if err != nil {
+		l.problems = append(l.problems, fmt.Sprintf("%s must be an integer, got %q (from %s)", key, raw, source))
+		return def
+	}
+	return v
+}
+
+func (l *loader) bool(key string, def bool) bool {
+	raw, source, ok := l.lookup(key)
+	if !ok {
+		l.record(key, strconv.FormatBool(def), source)
+		return def
+	}
+	l.record(key, raw, source)
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		l.problems = append(l.problems, fmt.Sprintf("%s must be true or false, got %q (from %s)", key, raw, source))
+		return def
+	}
+	return v
+}
+
+func (l *loader) values() []SettingValue {
+	out := make([]SettingValue, 0, len(l.resolved))
+	for _, s := range settings {
+		if v, ok := l.resolved[s.Env]; ok {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func envLayer() layer {
+	return layer{source: SourceEnv, lookup: os.LookupEnv}
+}
+
+func flagLayer(overrides map[string]string) (layer, []string) {
+	values := map[string]string{}
+	var problems []string
+	keys := make([]string, 0, len(overrides))
+	for k := range overrides {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s, ok := lookupSetting(k)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("--set %s: unknown setting", k))
+			continue
+		}
+		values[s.Env] = overrides[k]
+	}
+	return mapLayer(SourceFlag, values), problems
+}
@@ -0,0 +1,305 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const TOMLSubset = `config files use a TOML subset:
+  - [table] and [[array-of-tables]] headers; keys are bare (A-Z a-z 0-9 _ -) or "quoted"
+  - one key = value per line; arrays may continue over several lines
+  - values: "basic" and 'literal' strings, integers (1_000 allowed), true / false, arrays of those
+  - # comments, also after a value
+not supported: multi-line strings (""" / '''), inline tables ({ ... }), floats, dates and times, dotted keys (a.b = 1), nested arrays`
+
+type SyntaxError struct {
+	Path string
+	Err  error
+}
+
+func (e *SyntaxError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	tomlFloat = regexp.MustCompile(`^[+-]?(\d[\d_]*)?(\.\d|[eE][+-]?\d)|^[+-]?(inf|nan)$`)
+	tomlDate  = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}|^\d{2}:\d{2}`)
+)
+
+func parseTOML(src string) (map[string]any, error) {
+	doc := map[string]any{}
+	current := doc
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(stripComment(lines[i]))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[[") {
+			if !strings.HasSuffix(line, "]]") {
+				return nil, fmt.Errorf("line %d: unterminated array table header", lineNo)
+			}
+			path, err := splitTableName(line[2 : len(line)-2])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			parent, err := descend(doc, path[:len(path)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			name := path[len(path)-1]
+			var list []map[string]any
+			switch existing := parent[name].(type) {
+			case nil:
+			case []map[string]any:
+				list = existing
+			default:
+				return nil, fmt.Errorf("line %d: %s is already defined as a non-array", lineNo, name)
+			}
+			current = map[string]any{}
+			parent[name] = append(list, current)
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: unterminated table header", lineNo)
+			}
+			path, err := splitTableName(line[1 : len(line)-1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			if current, err = descend(doc, path); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+			continue
+		}
+
+		key, rest, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNo)
+		}
+		key, err := parseKey(strings.TrimSpace(key))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		raw := strings.TrimSpace(rest)
+		for strings.HasPrefix(raw, "[") && !balanced(raw) && i+1 < len(lines) {
+			i++
+			raw += " " + strings.TrimSpace(stripComment(lines[i]))
+		}
+		value, remainder, err := parseValue(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", lineNo, key, err)
+		}
+		if strings.TrimSpace(remainder) != "" {
+			return nil, fmt.Errorf("line %d: %s: unexpected trailing %q", lineNo, key, strings.TrimSpace(remainder))
+		}
+		if _, exists := current[key]; exists {
+			return nil, fmt.Errorf("line %d: duplicate key %s", lineNo, key)
+		}
+		current[key] = value
+	}
+	return doc, nil
+}
+
+func descend(doc map[string]any, path []string) (map[string]any, error) {
+	table := doc
+	for _, name := range path {
+		switch next := table[name].(type) {
+		case nil:
+			created := map[string]any{}
+			table[name] = created
+			table = created
+		case map[string]any:
+			table = next
+		case []map[string]any:
+			table = next[len(next)-1]
+		default:
+			return nil, fmt.Errorf("%s is already defined as a value", name)
+		}
+	}
+	return table, nil
+}
+
+func splitTableName(raw string) ([]string, error) {
+	var path []string
+	for _, part := range strings.Split(raw, ".") {
+		key, err := parseKey(strings.TrimSpace(part))
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, key)
+	}
+	return path, nil
+}
+
+func parseKey(raw string) (string, error) {
+	if strings.HasPrefix(raw, `"`) {
+		s, rest, err := parseBasicString(raw)
+		if err != nil || rest != "" {
+			return "", fmt.Errorf("invalid quoted key %q", raw)
+		}
+		return s, nil
+	}
+	if raw == "" {
+		return "", fmt.Errorf("empty key")
+	}
+	for _, r := range raw {
+		if !(r == '_' || r == '-' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return "", fmt.Errorf("invalid key %q", raw)
+		}
+	}
+	return raw, nil
+}
+
+func parseValue(raw string) (any, string, error) {
+	switch {
+	case raw == "":
+		return nil, "", fmt.Errorf("missing value")
+	case strings.HasPrefix(raw, `"""`), strings.HasPrefix(raw, `'''`):
+		return nil, "", fmt.Errorf("multi-line strings are not supported")
+	case strings.HasPrefix(raw, "{"):
+		return nil, "", fmt.Errorf("inline tables are not supported (use a [table] section)")
+	case strings.HasPrefix(raw, `"`):
+		return parseBasicString(raw)
+	case strings.HasPrefix(raw, "'"):
+		end := strings.IndexByte(raw[1:], '\'')
+		if end < 0 {
+			return nil, "", fmt.Errorf("unterminated string")
+		}
+		return raw[1 : end+1], raw[end+2:], nil
+	case strings.HasPrefix(raw, "["):
+		return parseArray(raw)
+	case strings.HasPrefix(raw, "true"):
+		return true, raw[4:], nil
+	case strings.HasPrefix(raw, "false"):
+		return false, raw[5:], nil
+	}
+	end := strings.IndexAny(raw, ",] \t")
+	if end < 0 {
+		end = len(raw)
+	}
+	n, err := strconv.ParseInt(strings.ReplaceAll(raw[:end], "_", ""), 10, 64)
+	switch {
+	case err == nil:
+	case tomlDate.MatchString(raw[:end]):
+		return nil, "", fmt.Errorf("dates and times are not supported (quote the value)")
+	case tomlFloat.MatchString(raw[:end]):
+		return nil, "", fmt.Errorf("floats are not supported, got %s", raw[:end])
+	default:
+		return nil, "", fmt.Errorf("unsupported value %q", raw[:end])
+	}
+	return n, raw[end:], nil
+}
+
+func parseBasicString(raw string) (string, string, error) {
+	var b strings.Builder
+	for i := 1; i < len(raw); i++ {
+		c := raw[i]
+		switch c {
+		case '"':
+			return b.String(), raw[i+1:], nil
+		case '\\':
+			if i+1 >= len(raw) {
+				return "", "", fmt.Errorf("unterminated escape")
+			}
+			i++
+			switch raw[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case '"', '\\':
+				b.WriteByte(raw[i])
+			case 'u':
+				if i+4 >= len(raw) {
+					return "", "", fmt.Errorf("invalid unicode escape")
+				}
+				r, err := strconv.ParseUint(raw[i+1:i+5], 16, 32)
+				if err != nil {
+					return "", "", fmt.Errorf("invalid unicode escape")
+				}
+				b.WriteRune(rune(r))
+				i += 4
+			default:
+				return "", "", fmt.Errorf("invalid escape \\%c", raw[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", fmt.Errorf("unterminated string")
+}
+
+func parseArray(raw string) ([]any, string, error) {
+	rest := strings.TrimSpace(raw[1:])
+	out := []any{}
+	for {
+		if strings.HasPrefix(rest, "]") {
+			return out, rest[1:], nil
+		}
+		value, remainder, err := parseValue(rest)
+		if err != nil {
+			return nil, "", err
+		}
+		if _, nested := value.([]any); nested {
+			return nil, "", fmt.Errorf("nested arrays are not supported")
+		}
+		out = append(out, value)
+		rest = strings.TrimSpace(remainder)
+		if strings.HasPrefix(rest, ",") {
+			rest = strings.TrimSpace(rest[1:])
+			continue
+		}
+		if !strings.HasPrefix(rest, "]") {
+			return nil, "", fmt.Errorf("expected , or ] in array")
+		}
+	}
+}
+
+func stripComment(line string) string {
+	inBasic, inLiteral := false, false
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && inBasic:
+			i++
+		case c == '"' && !inLiteral:
+			inBasic = !inBasic
+		case c == '\'' && !inBasic:
+			inLiteral = !inLiteral
+		case c == '#' && !inBasic && !inLiteral:
+			return line[:i]
+		}
+	}
+	return line
+}
+
+func balanced(raw string) bool {
+	depth := 0
+	inBasic, inLiteral := false, false
+	for i := 0; i < len(raw); i++ {
+		switch c := raw[i]; {
+		case c == '\\' && inBasic:
+			i++
+		case c == '"' && !inLiteral:
+			inBasic = !inBasic
+		case c == '\'' && !inBasic:
+			inLiteral = !inLiteral
+		case c == '[' && !inBasic && !inLiteral:
+			depth++
+		case c == ']' && !inBasic && !inLiteral:
+			depth--
+		}
+	}
+	return depth <= 0
+}
@@ -0,0 +1,247 @@
+package daemon
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"voice-inbox-daemon/internal/metrics"
+)
+
+const unhealthyAfter = 3
+
+type Schedule interface {
+	Next(now time.Time) time.Time
+}
+
+type every time.Duration
+
+func Every(d time.Duration) Schedule {
+	return every(d)
+}
+
+func (e every) Next(now time.Time) time.Time {
+	return now.Add(time.Duration(e))
+}
+
+type dailyAt struct {
+	hour, minute int
+}
+
+func DailyAt(hour, minute int) Schedule {
+	return dailyAt{hour: hour, minute: minute}
+}
+
+func (d dailyAt) Next(now time.Time) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), d.hour, d.minute, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+type Loop struct {
+	Name     string
+	Schedule Schedule
+	Timeout  time.Duration
+	First    func(now time.Time) time.Time
+	Run      func(ctx context.Context) error
+}
+
+type Options struct {
+	BackoffMax    time.Duration
+	ShutdownGrace time.Duration
+	Logger        *slog.Logger
+}
+
+type LoopHealth struct {
+	Name                string     `json:"name"`
+	State               string     `json:"state"`
+	Healthy             bool       `json:"healthy"`
+	Runs                int        `json:"runs"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	LastStartedAt       *time.Time `json:"last_started_at,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	NextRunAt           *time.Time `json:"next_run_at,omitempty"`
+}
+
+type Supervisor struct {
+	loops  []Loop
+	opts   Options
+	run    sync.Mutex
+	mu     sync.Mutex
+	health map[string]*LoopHealth
+}
+
+func New(loops []Loop, opts Options) *Supervisor {
+	if opts.BackoffMax <= 0 {
+		opts.BackoffMax = 15 * time.Minute
+	}
+	if opts.ShutdownGrace <= 0 {
+		opts.ShutdownGrace = 30 * time.Second
+	}
+	if opts.Logger == nil {
+		opts.Logger = slog.Default()
+	}
+	s := &Supervisor{loops: loops, opts: opts, health: map[string]*LoopHealth{}}
+	for _, l := range loops {
+		s.health[l.Name] = &LoopHealth{Name: l.Name, State: "idle", Healthy: true}
+	}
+	return s
+}
+
+func (s *Supervisor) Run(ctx context.Context) {
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+	go func() {
+		<-ctx.Done()
+		timer := time.NewTimer(s.opts.ShutdownGrace)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			cancelWork()
+		case <-workCtx.Done():
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for _, l := range s.loops {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.supervise(ctx, workCtx, l)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *Supervisor) supervise(ctx, workCtx context.Context, l Loop) {
+	logger := s.opts.Logger.With("loop", l.Name)
+	now := time.Now()
+	next := now
+	if l.First != nil {
+		next = l.First(now)
+	}
+	failures := 0
+	for {
+		scheduledAt := next
+		s.update(l.Name, func(h *LoopHealth) {
+			h.State = "waiting"
+			if failures > 0 {
+				h.State = "backoff"
+			}
+			h.NextRunAt = &scheduledAt
+		})
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			s.update(l.Name, func(h *LoopHealth) {
+				h.State = "stopped"
+				h.NextRunAt = nil
+			})
+			return
+		case <-timer.C:
+		}
+
+		err := s.runOnce(workCtx, l)
+		now = time.Now()
+		if err != nil {
+			failures++
+			next = now.Add(s.backoff(l.Schedule.Next(now).Sub(now), failures))
+			logger.Warn("loop run failed", "consecutive_failures", failures, "next_run_in", next.Sub(now).Round(time.Second).String(), "error", err)
+			s.update(l.Name, func(h *LoopHealth) {
+				h.ConsecutiveFailures = failures
+				h.Healthy = failures < unhealthyAfter
+				h.LastError = err.Error()
+			})
+			continue
+		}
+		if failures > 0 {
+			logger.Info("loop recovered", "after_failures", failures)
+		}
+		failures = 0
+		next = l.Schedule.Next(now)
+		finishedAt := now
+		s.update(l.Name, func(h *LoopHealth) {
+			h.ConsecutiveFailures = 0
+			h.Healthy = true
+			h.LastError = ""
+			h.LastSuccessAt = &finishedAt
+		})
+	}
+}
+
+func (s *Supervisor) runOnce(workCtx context.Context, l Loop) (err error) {
+	s.run.Lock()
+	defer s.run.Unlock()
+
+	started := time.Now()
+	s.update(l.Name, func(h *LoopHealth) {
+		h.State = "running"
+		h.Runs++
+		h.LastStartedAt = &started
+		h.NextRunAt = nil
+	})
+	ctx := workCtx
+	if l.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(workCtx, l.Timeout)
+		defer cancel()
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return l.Run(ctx)
+}
+
+func (s *Supervisor) backoff(interval time.Duration, failures int) time.Duration {
+	limit := max(s.opts.BackoffMax, interval)
+	delay := max(interval, time.Millisecond)
+	for i := 0; i < failures && delay < limit; i++ {
+		delay *= 2
+	}
+	return min(delay, limit)
+}
+
+func (s *Supervisor) update(name string, fn func(h *LoopHealth)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.health[name])
+}
+
+func (s *Supervisor) Health() ([]LoopHealth, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]LoopHealth, 0, len(s.loops))
+	ok := true
+	for _, l := range s.loops {
+		h := *s.health[l.Name]
+		if !h.Healthy {
+			ok = false
+		}
+		out = append(out, h)
+	}
+	return out, ok
+}
+
+func (s *Supervisor) RegisterMetrics(reg *metrics.Registry) {
+	reg.RegisterCollector(func() []metrics.Gauge {
+		loops, _ := s.Health()
+		gauges := make([]metrics.Gauge, 0, len(loops))
+		for _, h := range loops {
+			gauges = append(gauges, metrics.Gauge{
+				Name:   "voice_inbox_loop_consecutive_failures",
+				Help:   "Consecutive failed runs of a daemon loop.",
+				Labels: map[string]string{"loop": h.Name},
+				Value:  float64(h.ConsecutiveFailures),
+			})
+		}
+		return gauges
+	})
+}
@@ -0,0 +1,143 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSupervisorBacksOffAndReportsUnhealthyLoop(t *testing.T) {
+	var calls atomic.Int32
+	sup := New([]Loop{{
+		Name:     "flaky",
+		Schedule: Every(time.Millisecond),
+		Run: func(ctx context.Context) error {
+			calls.Add(1)
+			return errors.New("discord down")
+		},
+	}}, Options{BackoffMax: 40 * time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	sup.Run(ctx)
+
+	if n := calls.Load(); n < unhealthyAfter || n > 10 {
+		t.Fatalf("expected backoff to limit runs to a handful, got %d", n)
+	}
+	loops, ok := sup.Health()
+	if ok || len(loops) != 1 {
+		t.Fatalf("expected unhealthy supervisor, got ok=%v loops=%+v", ok, loops)
+	}
+	h := loops[0]
+	if h.Healthy || h.ConsecutiveFailures < unhealthyAfter || h.LastError != "discord down" || h.State != "stopped" {
+		t.Fatalf("unexpected health: %+v", h)
+	}
+}
+
+func TestSupervisorRecoversFromPanicAndSerializesRuns(t *testing.T) {
+	var running, overlaps atomic.Int32
+	var panicked sync.Once
+	run := func(ctx context.Context) error {
+		if running.Add(1) > 1 {
+			overlaps.Add(1)
+		}
+		defer running.Add(-1)
+		time.Sleep(2 * time.Millisecond)
+		return nil
+	}
+	sup := New([]Loop{
+		{Name: "a", Schedule: Every(time.Millisecond), Run: run},
+		{Name: "b", Schedule: Every(time.Millisecond), Run: func(ctx context.Context) error {
+			panicked.Do(func() { panic("boom") })
+			return run(ctx)
+		}},
+	}, Options{BackoffMax: time.Millisecond})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	sup.Run(ctx)
+
+	if overlaps.Load() != 0 {
+		t.Fatalf("expected loop runs to be serialized, saw %d overlaps", overlaps.Load())
+	}
+	loops, ok := sup.Health()
+	if !ok {
+		t.Fatalf("expected healthy after recovery: %+v", loops)
+	}
+	for _, h := range loops {
+		if h.Runs < 2 || h.LastSuccessAt == nil || h.ConsecutiveFailures != 0 {
+			t.Fatalf("unexpected health for %s: %+v", h.Name, h)
+		}
+	}
+}
+
+func TestSupervisorLetsInFlightRunFinishOnShutdown(t *testing.T) {
+	started := make(chan struct{})
+	var finished atomic.Bool
+	sup := New([]Loop{{
+		Name:     "slow",
+		Schedule: Every(time.Hour),
+		Run: func(ctx context.Context) error {
+			close(started)
+			select {
+			case <-time.After(30 * time.Millisecond):
+				finished.Store(true)
+			case <-ctx.Done():
+			}
+			return nil
+		},
+	}}, Options{ShutdownGrace: time.Second})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		sup.Run(ctx)
+		close(done)
+	}()
+	<-started
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("supervisor did not stop")
+	}
+	if !finished.Load() {
+		t.Fatalf("expected in-flight run to complete within the grace period")
+	}
+}
+
+func TestBackoffGrowsFromIntervalAndIsCapped(t *testing.T) {
+	sup := New(nil, Options{BackoffMax: 15 * time.Minute})
+	cases := []struct {
+		interval time.Duration
+		failures int
+		want     time.Duration
+	}{
+		{5 * time.Second, 1, 10 * time.Second},
+		{5 * time.Second, 3, 40 * time.Second},
+		{5 * time.Second, 20, 15 * time.Minute},
+		{5 * time.Minute, 1, 10 * time.Minute},
+		{5 * time.Minute, 2, 15 * time.Minute},
+		{20 * time.Hour, 4, 20 * time.Hour},
+	}
+	for _, c := range cases {
+		if got := sup.backoff(c.interval, c.failures); got != c.want {
+			t.Fatalf("backoff(%v, %d) = %v, want %v", c.interval, c.failures, got, c.want)
+		}
+	}
+}
+
+func TestDailyAtNext(t *testing.T) {
+	s := DailyAt(3, 20)
+	now := time.Date(2026, 3, 19, 2, 0, 0, 0, time.UTC)
+	if got := s.Next(now); !got.Equal(time.Date(2026, 3, 19, 3, 20, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected next: %v", got)
+	}
+	now = time.Date(2026, 3, 19, 3, 20, 0, 0, time.UTC)
+	if got := s.Next(now); !got.Equal(time.Date(2026, 3, 20, 3, 20, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected next after slot: %v", got)
+	}
+}
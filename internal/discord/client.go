@@ -12,6 +12,8 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"voice-inbox-daemon/internal/metrics"
 )
 
 type Client struct {
@@ -64,7 +66,7 @@ func (c *Client) Me(ctx context.Context) (User, error) {
 	if err != nil {
 		return User{}, err
 	}
-	resp, err := c.do(req)
+	resp, err := c.do("me", req)
 	if err != nil {
 		return User{}, err
 	}
@@ -97,7 +99,7 @@ func (c *Client) FetchMessages(ctx context.Context, channelID, after string, lim
 	if err != nil {
 		return nil, err
 	}
-	resp, err := c.do(req)
+	resp, err := c.do("fetch_messages", req)
 	if err != nil {
 		return nil, err
 	}
@@ -123,7 +125,7 @@ func (c *Client) AddReaction(ctx context.Context, channelID, messageID, emojiEsc
 	if err != nil {
 		return err
 	}
-	resp, err := c.do(req)
+	resp, err := c.do("add_reaction", req)
 	if err != nil {
 		return err
 	}
@@ -141,7 +143,7 @@ func (c *Client) GetChannel(ctx context.Context, channelID string) (Channel, err
 	if err != nil {
 		return Channel{}, err
 	}
-	resp, err := c.do(req)
+	resp, err := c.do("get_channel", req)
 	if err != nil {
 		return Channel{}, err
 	}
@@ -162,7 +164,7 @@ func (c *Client) DownloadAttachment(ctx context.Context, sourceURL, destPath str
 	if err != nil {
 		return err
 	}
-	resp, err := c.do(req)
+	resp, err := c.do("download_attachment", req)
 	if err != nil {
 		return err
 	}
@@ -199,9 +201,12 @@ func compareSnowflake(a, b string) int {
 	return 0
 }
 
-func (c *Client) do(req *http.Request) (*http.Response, error) {
+func (c *Client) do(op string, req *http.Request) (*http.Response, error) {
 	req.Header.Set("Authorization", "Bot "+c.token)
-	return c.httpClient.Do(req)
+	started := time.Now()
+	resp, err := c.httpClient.Do(req)
+	metrics.ExternalCallDuration.ObserveSince(started, "discord", op, metrics.HTTPResult(resp, err))
+	return resp, err
 }
 
 func IsAudioContentType(ct string) bool {
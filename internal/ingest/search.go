@@ -0,0 +1,81 @@
+package ingest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"voice-inbox-daemon/internal/logging"
+	"voice-inbox-daemon/internal/state"
+)
+
+const maxSearchLimit = 100
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !authorized(r, s.current().IngestAuthToken) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	params := r.URL.Query()
+	q := state.SearchQuery{
+		Text:   strings.TrimSpace(params.Get("q")),
+		Source: strings.TrimSpace(params.Get("source")),
+	}
+	if q.Text == "" {
+		http.Error(w, "q is required", http.StatusBadRequest)
+		return
+	}
+	switch q.Source {
+	case "", state.SourceDiscord, state.SourceCapture:
+	default:
+		http.Error(w, "source must be discord or capture", http.StatusBadRequest)
+		return
+	}
+	for _, p := range []struct {
+		name string
+		dest **time.Time
+	}{{"since", &q.Since}, {"until", &q.Until}} {
+		raw := strings.TrimSpace(params.Get(p.name))
+		if raw == "" {
+			continue
+		}
+		t, err := parseQueryTime(raw)
+		if err != nil {
+			http.Error(w, p.name+" must be RFC3339 or YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		*p.dest = &t
+	}
+	if raw := strings.TrimSpace(params.Get("limit")); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		q.Limit = min(n, maxSearchLimit)
+	}
+
+	hits, err := s.store.SearchTranscripts(q)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("search failed", "error", err)
+		http.Error(w, "search failed", http.StatusInternalServerError)
+		return
+	}
+	if hits == nil {
+		hits = []state.SearchHit{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"query": q.Text, "results": hits})
+}
+
+func parseQueryTime(raw string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation(time.DateOnly, raw, time.Local)
+}
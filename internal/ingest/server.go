@@ -4,22 +4,30 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"mime"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"voice-inbox-daemon/internal/config"
+	"voice-inbox-daemon/internal/logging"
+	"voice-inbox-daemon/internal/metrics"
 	"voice-inbox-daemon/internal/state"
 )
 
 type Server struct {
-	cfg   config.Config
-	store *state.Store
+	cfg    atomic.Pointer[config.Config]
+	store  *state.Store
+	logger *slog.Logger
+	health HealthReporter
 }
 
+type HealthReporter func() (any, bool)
+
 type captureResponse struct {
 	CaptureID string `json:"capture_id"`
 	Status    string `json:"status"`
@@ -37,14 +45,30 @@ type persistedUpload struct {
 var renameFile = os.Rename
 
 func NewServer(cfg config.Config, store *state.Store) *Server {
-	return &Server{cfg: cfg, store: store}
+	s := &Server{store: store, logger: slog.Default()}
+	s.SetConfig(cfg)
+	return s
+}
+
+func (s *Server) SetConfig(cfg config.Config) {
+	s.cfg.Store(&cfg)
+}
+
+func (s *Server) current() config.Config {
+	return *s.cfg.Load()
+}
+
+func (s *Server) SetHealthReporter(fn HealthReporter) {
+	s.health = fn
 }
 
 func (s *Server) Handler() http.Handler {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/healthz", s.handleHealth)
 	mux.HandleFunc("/v0/captures", s.handleCapture)
-	return mux
+	mux.HandleFunc("/v0/search", s.handleSearch)
+	mux.Handle("/metrics", metrics.Default.Handler())
+	return s.withRequestLogging(mux)
 }
 
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -52,7 +76,16 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+	if s.health == nil {
+		writeJSON(w, http.StatusOK, map[string]any{"ok": true})
+		return
+	}
+	loops, ok := s.health()
+	status := http.StatusOK
+	if !ok {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, map[string]any{"ok": ok, "loops": loops})
 }
 
 func (s *Server) handleCapture(w http.ResponseWriter, r *http.Request) {
@@ -60,12 +93,13 @@ func (s *Server) handleCapture(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
-	if !s.authorized(r) {
+	cfg := s.current()
+	if !authorized(r, cfg.IngestAuthToken) {
 		http.Error(w, "unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	r.Body = http.MaxBytesReader(w, r.Body, int64(s.cfg.IngestMaxBodyMB)*1024*1024)
+	r.Body = http.MaxBytesReader(w, r.Body, int64(cfg.IngestMaxBodyMB)*1024*1024)
 	if err := r.ParseMultipartForm(1 << 20); err != nil {
 		http.Error(w, fmt.Sprintf("invalid multipart body: %v", err), http.StatusBadRequest)
 		return
@@ -123,7 +157,7 @@ func (s *Server) handleCapture(w http.ResponseWriter, r *http.Request) {
 	defer file.Close()
 
 	receivedAt := time.Now().UTC()
-	upload, err := s.persistUpload(file, header.Filename, header.Header.Get("Content-Type"), captureID, receivedAt)
+	upload, err := s.persistUpload(cfg.AudioStoreDir, file, header.Filename, header.Header.Get("Content-Type"), captureID, receivedAt)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("persist upload: %v", err), http.StatusInternalServerError)
 		return
@@ -137,7 +171,7 @@ func (s *Server) handleCapture(w http.ResponseWriter, r *http.Request) {
 
 	rec := state.CaptureRecord{
 		CaptureID:       captureID,
-		Source:          s.cfg.IngestSourceName,
+		Source:          cfg.IngestSourceName,
 		SourceDedupeKey: dedupeKey,
 		DeviceID:        deviceID,
 		CapturedAt:      capturedAt,
@@ -168,22 +202,28 @@ func (s *Server) handleCapture(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("create capture: %v", err), http.StatusInternalServerError)
 		return
 	}
+	logging.FromContext(r.Context()).Info("capture accepted",
+		"capture_key", captureID,
+		"source", cfg.IngestSourceName,
+		"stage", "received",
+		"device_id", deviceID,
+	)
 
 	writeJSON(w, http.StatusCreated, captureResponse{
 		CaptureID: captureID,
 		Status:    "pending",
-		Source:    s.cfg.IngestSourceName,
+		Source:    cfg.IngestSourceName,
 		Duplicate: false,
 		Received:  receivedAt.Format(time.RFC3339),
 	})
 }
 
-func (s *Server) authorized(r *http.Request) bool {
+func authorized(r *http.Request, token string) bool {
 	header := strings.TrimSpace(r.Header.Get("Authorization"))
 	if header == "" {
 		return false
 	}
-	return header == "Bearer "+s.cfg.IngestAuthToken
+	return header == "Bearer "+token
 }
 
 func (s *Server) findDuplicate(captureID, dedupeKey string) (state.CaptureRecord, bool, error) {
@@ -208,8 +248,8 @@ func (s *Server) findDuplicate(captureID, dedupeKey string) (state.CaptureRecord
 	return state.CaptureRecord{}, false, nil
 }
 
-func (s *Server) persistUpload(src io.Reader, filename, headerContentType, captureID string, receivedAt time.Time) (persistedUpload, error) {
-	subdir := filepath.Join(s.cfg.AudioStoreDir, "ingest", receivedAt.Format("2006/01/02"))
+func (s *Server) persistUpload(root string, src io.Reader, filename, headerContentType, captureID string, receivedAt time.Time) (persistedUpload, error) {
+	subdir := filepath.Join(root, "ingest", receivedAt.Format("2006/01/02"))
 	if err := os.MkdirAll(subdir, 0o755); err != nil {
 		return persistedUpload{}, err
 	}
@@ -230,7 +270,7 @@ func (s *Server) persistUpload(src io.Reader, filename, headerContentType, captu
 
 	baseCaptureID := filepath.Base(captureID)
 	finalPath := filepath.Join(subdir, baseCaptureID+ext)
-	if !pathWithinRoot(s.cfg.AudioStoreDir, finalPath) {
+	if !pathWithinRoot(root, finalPath) {
 		return persistedUpload{}, fmt.Errorf("capture path escapes audio store")
 	}
 	tmp, err := os.CreateTemp(subdir, baseCaptureID+".*.tmp")
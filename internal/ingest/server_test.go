@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
@@ -263,6 +264,35 @@ func TestCapturesDBFailureCleansRenamedFile(t *testing.T) {
 	}
 }
 
+func TestSetConfigSwapsAuthTokenAndSourceName(t *testing.T) {
+	srv, st, audioRoot := newTestServer(t)
+	srv.SetConfig(config.Config{
+		AudioStoreDir:    audioRoot,
+		IngestAuthToken:  "rotated-token",
+		IngestMaxBodyMB:  8,
+		IngestSourceName: "renamed-source",
+	})
+
+	req := newCaptureRequest(t, "cap-reload", "pixel-8a", "2026-03-19T11:00:00Z", "audio/ogg", []byte("audio-bytes"))
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected old token to be rejected, got %d", rec.Code)
+	}
+
+	req = newCaptureRequest(t, "cap-reload", "pixel-8a", "2026-03-19T11:00:00Z", "audio/ogg", []byte("audio-bytes"))
+	req.Header.Set("Authorization", "Bearer rotated-token")
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 with rotated token, got %d: %s", rec.Code, rec.Body.String())
+	}
+	capture, found, err := st.GetCapture("cap-reload")
+	if err != nil || !found || capture.Source != "renamed-source" {
+		t.Fatalf("expected capture from renamed source: %+v found=%v err=%v", capture, found, err)
+	}
+}
+
 func newTestServer(t *testing.T) (*Server, *state.Store, string) {
 	t.Helper()
 	tmp := t.TempDir()
@@ -364,3 +394,141 @@ func TestCaptureResponseJSONShape(t *testing.T) {
 		t.Fatalf("unexpected payload: %+v", payload)
 	}
 }
+
+func TestSearchRequiresAuthAndReturnsResults(t *testing.T) {
+	srv, st, _ := newTestServer(t)
+	if err := st.IndexTranscript(state.TranscriptDoc{
+		ItemID:     "cap-001",
+		Source:     "android-voice-inbox",
+		CapturedAt: time.Date(2026, 3, 19, 11, 0, 0, 0, time.UTC),
+		Text:       "call the dentist tomorrow",
+	}); err != nil {
+		t.Fatalf("index transcript: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v0/search?q=dentist", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v0/search", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 without q, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v0/search?q=dentist&source=android-voice-inbox", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a source other than discord or capture, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/v0/search?q=dentist&since=2026-03-19&source=capture", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var body struct {
+		Results []state.SearchHit `json:"results"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(body.Results) != 1 || body.Results[0].ItemID != "cap-001" || !strings.Contains(body.Results[0].Snippet, "[dentist]") {
+		t.Fatalf("unexpected results: %+v", body.Results)
+	}
+
+	_ = st.Close()
+	req = httptest.NewRequest(http.MethodGet, "/v0/search?q=dentist&source=capture", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError || strings.TrimSpace(rec.Body.String()) != "search failed" {
+		t.Fatalf("expected generic 500, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestMetricsEndpointServesPrometheusText(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "# TYPE voice_inbox_external_call_duration_seconds histogram") {
+		t.Fatalf("expected external call histogram in:\n%s", rec.Body.String())
+	}
+}
+
+func TestRequestIDIsEchoedAndAccessLogged(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+	var buf bytes.Buffer
+	srv.logger = slog.New(slog.NewJSONHandler(&buf, nil))
+
+	req := newCaptureRequest(t, "cap-reqid", "pixel-8a", "2026-03-19T11:00:00Z", "audio/ogg", []byte("audio"))
+	req.Header.Set("X-Request-ID", "abc-123")
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("X-Request-ID"); got != "abc-123" {
+		t.Fatalf("expected echoed request id, got %q", got)
+	}
+
+	var sawAccept, sawAccess bool
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("decode log line %q: %v", line, err)
+		}
+		if entry["request_id"] != "abc-123" {
+			t.Fatalf("expected request_id on every line, got %v", entry)
+		}
+		switch entry["msg"] {
+		case "capture accepted":
+			sawAccept = entry["capture_key"] == "cap-reqid"
+		case "http request":
+			sawAccess = entry["status"] == float64(http.StatusCreated) && entry["path"] == "/v0/captures" && entry["method"] == http.MethodPost
+		}
+	}
+	if !sawAccept || !sawAccess {
+		t.Fatalf("expected capture and access log lines, got:\n%s", buf.String())
+	}
+
+	rec = httptest.NewRecorder()
+	bad := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	bad.Header.Set("X-Request-ID", "has space\n")
+	srv.Handler().ServeHTTP(rec, bad)
+	if got := rec.Header().Get("X-Request-ID"); got == "" || strings.ContainsAny(got, " \n") {
+		t.Fatalf("expected generated request id, got %q", got)
+	}
+}
+
+func TestHealthzReportsLoopHealth(t *testing.T) {
+	srv, _, _ := newTestServer(t)
+	healthy := true
+	srv.SetHealthReporter(func() (any, bool) {
+		return []map[string]any{{"name": "poll", "healthy": healthy}}, healthy
+	})
+
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"name":"poll"`) {
+		t.Fatalf("expected 200 with loops, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	healthy = false
+	rec = httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), `"ok":false`) {
+		t.Fatalf("expected 503 when a loop is unhealthy, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
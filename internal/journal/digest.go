@@ -0,0 +1,213 @@
+package journal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const digestHeadingPrefix = "## 📊 ダイジェスト "
+
+type EntryRef struct {
+	Heading string
+	Anchor  string
+	Tasks   []string
+}
+
+type Digest struct {
+	Label   string
+	Weekly  bool
+	Entries []DigestEntry
+}
+
+type DigestEntry struct {
+	Time     time.Time
+	Label    string
+	Duration time.Duration
+	NotePath string
+	Heading  string
+	Anchor   string
+	Tasks    []string
+}
+
+type DeviceStat struct {
+	Label    string        `json:"label"`
+	Count    int           `json:"count"`
+	Duration time.Duration `json:"duration_ns"`
+}
+
+func SourceLabel(source, deviceID string) string {
+	if label := strings.TrimSpace(deviceID); label != "" {
+		return label
+	}
+	switch source = strings.TrimSpace(source); source {
+	case "", "discord":
+		return "Discord"
+	default:
+		return source
+	}
+}
+
+func FindEntry(content, captureKey string) (EntryRef, bool) {
+	markerAt := strings.Index(content, "<!-- vi:"+captureKey+" -->")
+	if markerAt < 0 {
+		markerAt = strings.Index(content, fmt.Sprintf("capture_key: \"%s\"", captureKey))
+	}
+	if markerAt < 0 {
+		return EntryRef{}, false
+	}
+	before := content[:markerAt]
+	start := 0
+	if i := strings.LastIndex(before, "<!-- vi:"); i >= 0 {
+		start = i
+	}
+	var ref EntryRef
+	if i := strings.LastIndex(before, "\n## "); i >= start {
+		start = i
+		line, _, _ := strings.Cut(before[i+len("\n## "):], "\n")
+		ref.Heading = strings.TrimSpace(line)
+	}
+	blockRef := "^" + BlockID(captureKey)
+	ref.Anchor = ref.Heading
+	for _, line := range strings.Split(before[start:], "\n") {
+		line = strings.TrimSpace(line)
+		if task, ok := strings.CutPrefix(line, "- [ ] "); ok {
+			ref.Tasks = append(ref.Tasks, strings.TrimSpace(task))
+		}
+		if strings.HasSuffix(line, " "+blockRef) {
+			ref.Anchor = blockRef
+		}
+	}
+	return ref, true
+}
+
+func (d Digest) Marker() string {
+	return "<!-- vi:digest:" + d.Label + " -->"
+}
+
+func (d Digest) TotalDuration() time.Duration {
+	var total time.Duration
+	for _, e := range d.Entries {
+		total += e.Duration
+	}
+	return total
+}
+
+func (d Digest) Devices() []DeviceStat {
+	index := map[string]int{}
+	var out []DeviceStat
+	for _, e := range d.Entries {
+		i, ok := index[e.Label]
+		if !ok {
+			i = len(out)
+			index[e.Label] = i
+			out = append(out, DeviceStat{Label: e.Label})
+		}
+		out[i].Count++
+		out[i].Duration += e.Duration
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Count > out[j].Count })
+	return out
+}
+
+func (d Digest) Tasks() []string {
+	var out []string
+	for _, e := range d.Entries {
+		for _, task := range e.Tasks {
+			if !strings.HasSuffix(task, "]])") {
+				task += " (" + NoteLink(e.NotePath, e.Anchor) + ")"
+			}
+			out = append(out, task)
+		}
+	}
+	return out
+}
+
+func (d Digest) Render() string {
+	return "\n" + digestHeadingPrefix + d.Label + "\n" + d.RenderBody()
+}
+
+func (d Digest) RenderBody() string {
+	var b strings.Builder
+	b.WriteString("\n")
+	b.WriteString(fmt.Sprintf("- エントリ: %d 件\n", len(d.Entries)))
+	b.WriteString("- 録音時間: " + FormatDuration(d.TotalDuration()) + "\n")
+
+	if devices := d.Devices(); len(devices) > 0 {
+		b.WriteString("\n### 端末別\n\n| 端末 | 件数 | 録音時間 |\n| --- | ---: | ---: |\n")
+		for _, st := range devices {
+			b.WriteString(fmt.Sprintf("| %s | %d | %s |\n", strings.ReplaceAll(st.Label, "|", "\\|"), st.Count, FormatDuration(st.Duration)))
+		}
+	}
+	if tasks := d.Tasks(); len(tasks) > 0 {
+		b.WriteString("\n### タスク\n\n")
+		for _, task := range tasks {
+			b.WriteString("- " + task + "\n")
+		}
+	}
+	if len(d.Entries) > 0 {
+		b.WriteString("\n### エントリ\n\n")
+		layout := "15:04"
+		if d.Weekly {
+			layout = "01-02 Mon 15:04"
+		}
+		for _, e := range d.Entries {
+			link := NoteLink(e.NotePath, e.Anchor)
+			if e.Heading != "" {
+				link = strings.TrimSuffix(link, "]]") + "|" + e.Heading + "]]"
+			}
+			line := "- " + e.Time.Format(layout) + " " + link + " " + e.Label
+			if e.Duration > 0 {
+				line += " (" + FormatDuration(e.Duration) + ")"
+			}
+			b.WriteString(line + "\n")
+		}
+	}
+	b.WriteString("\n" + d.Marker() + "\n")
+	return b.String()
+}
+
+func DigestHeadingTarget(content string, d Digest) (string, bool) {
+	heading := digestHeadingPrefix + d.Label
+	type section struct {
+		level int
+		title string
+	}
+	var parents []section
+	for _, line := range strings.Split(content, "\n") {
+		level := len(line) - len(strings.TrimLeft(line, "#"))
+		if level == 0 || level > 6 || !strings.HasPrefix(line[level:], " ") {
+			continue
+		}
+		for len(parents) > 0 && parents[len(parents)-1].level >= level {
+			parents = parents[:len(parents)-1]
+		}
+		if strings.TrimSpace(line) == heading {
+			var path []string
+			for _, p := range parents {
+				path = append(path, p.title)
+			}
+			return strings.Join(append(path, strings.TrimSpace(line[level:])), "::"), true
+		}
+		parents = append(parents, section{level: level, title: strings.TrimSpace(line[level:])})
+	}
+	return "", false
+}
+
+func ReplaceDigest(content string, d Digest) (string, bool) {
+	marker := d.Marker()
+	end := strings.Index(content, marker)
+	if end < 0 {
+		return content, false
+	}
+	start := strings.LastIndex(content[:end], "\n"+digestHeadingPrefix+d.Label+"\n")
+	if start < 0 {
+		return content, false
+	}
+	end += len(marker)
+	if strings.HasPrefix(content[end:], "\n") {
+		end++
+	}
+	return content[:start] + d.Render() + content[end:], true
+}
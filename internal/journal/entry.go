@@ -0,0 +1,130 @@
+package journal
+
+import (
+	"strings"
+	"time"
+)
+
+const dueDateFormat = "2006-01-02"
+
+type Task struct {
+	Text string
+	Due  *time.Time
+	Link string
+}
+
+type Entry struct {
+	Time       time.Time
+	Heading    string
+	Title      string
+	Body       string
+	Cleaned    string
+	Raw        string
+	Audio      string
+	Tasks      []Task
+	Tags       []string
+	Label      string
+	CaptureKey string
+}
+
+func NewEntry(in EntryInput) Entry {
+	body := strings.TrimSpace(in.Transcript)
+	if body == "" {
+		body = "(transcript is empty)"
+	}
+	source := strings.TrimSpace(in.Source)
+	if source == "" {
+		source = "discord"
+	}
+	label := SourceLabel(source, in.DeviceID)
+	if in.Duration > 0 {
+		label += " (" + FormatDuration(in.Duration) + ")"
+	}
+	title := "🎤 Voice Inbox"
+	if t := strings.Join(strings.Fields(in.Title), " "); t != "" {
+		title = "🎤 " + t
+	}
+	var cleaned, raw string
+	if summary := strings.TrimSpace(in.Summary); summary != "" {
+		cleaned = strings.TrimSpace(in.Cleaned)
+		raw = body
+		body = summary
+	}
+	return Entry{
+		Time:       in.Now,
+		Heading:    "ログ - " + in.Now.Format("15:04"),
+		Title:      title,
+		Body:       body,
+		Cleaned:    cleaned,
+		Raw:        raw,
+		Audio:      in.Audio,
+		Tasks:      in.Tasks,
+		Tags:       in.Tags,
+		Label:      label,
+		CaptureKey: CaptureKey(source, in.CaptureID),
+	}
+}
+
+func (e Entry) Render() string {
+	var b strings.Builder
+	b.WriteString("\n## " + e.Heading + "\n")
+	b.WriteString("### " + e.Title + "\n\n")
+	b.WriteString(e.Body + "\n")
+	if e.Cleaned != "" {
+		b.WriteString("\n" + Callout("note", "整形版", e.Cleaned))
+	}
+	if e.Raw != "" {
+		b.WriteString("\n" + Callout("quote", "文字起こし（原文）", e.Raw))
+	}
+	if e.Audio != "" {
+		b.WriteString("\n" + Embed(e.Audio) + "\n")
+	}
+	if len(e.Tasks) > 0 {
+		b.WriteString("\n")
+		for _, t := range e.Tasks {
+			b.WriteString(t.Line() + "\n")
+		}
+	}
+	if tags := FormatTags(e.Tags); tags != "" {
+		b.WriteString("\n" + tags + "\n")
+	}
+	b.WriteString("\n_" + e.Time.Format("15:04") + " via " + e.Label + "_ ^" + BlockID(e.CaptureKey) + "\n")
+	b.WriteString("<!-- vi:" + e.CaptureKey + " -->\n")
+	return b.String()
+}
+
+func (t Task) Line() string {
+	line := "- [ ] " + strings.Join(strings.Fields(t.Text), " ")
+	if t.Due != nil {
+		line += " 📅 " + t.Due.Format(dueDateFormat)
+	}
+	if t.Link != "" {
+		line += " (" + t.Link + ")"
+	}
+	return line
+}
+
+func Callout(kind, title, text string) string {
+	var b strings.Builder
+	b.WriteString("> [!" + kind + "]- " + title + "\n")
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		if line = strings.TrimRight(line, " \t"); line == "" {
+			b.WriteString(">\n")
+			continue
+		}
+		b.WriteString("> " + line + "\n")
+	}
+	return b.String()
+}
+
+func Embed(vaultPath string) string {
+	return "![[" + vaultPath + "]]"
+}
+
+func NoteLink(notePath, heading string) string {
+	target := strings.TrimSuffix(notePath, ".md")
+	if heading != "" {
+		target += "#" + heading
+	}
+	return "[[" + target + "]]"
+}
@@ -0,0 +1,63 @@
+package journal
+
+import "strings"
+
+func HasFrontmatter(content string) bool {
+	_, ok := frontmatterLines(content)
+	return ok
+}
+
+func FrontmatterTags(content string) []string {
+	lines, ok := frontmatterLines(content)
+	if !ok {
+		return nil
+	}
+	var tags []string
+	for i := 0; i < len(lines); i++ {
+		key, value, found := strings.Cut(lines[i], ":")
+		if !found || strings.TrimSpace(key) != "tags" || strings.HasPrefix(lines[i], " ") {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+			for _, item := range strings.Split(strings.Trim(value, "[]"), ",") {
+				tags = append(tags, unquote(item))
+			}
+		case value != "":
+			for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+				tags = append(tags, unquote(item))
+			}
+		default:
+			for i+1 < len(lines) {
+				item := strings.TrimSpace(lines[i+1])
+				if !strings.HasPrefix(item, "- ") {
+					break
+				}
+				tags = append(tags, unquote(strings.TrimPrefix(item, "- ")))
+				i++
+			}
+		}
+	}
+	return MergeTags(nil, tags)
+}
+
+func frontmatterLines(content string) ([]string, bool) {
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	if !strings.HasPrefix(content, "---\n") {
+		return nil, false
+	}
+	rest := content[len("---\n"):]
+	if strings.HasPrefix(rest, "---") {
+		return nil, true
+	}
+	end := strings.Index(rest, "\n---")
+	if end < 0 {
+		return nil, false
+	}
+	return strings.Split(rest[:end], "\n"), true
+}
+
+func unquote(s string) string {
+	return strings.Trim(strings.TrimSpace(s), `"'`)
+}
@@ -5,6 +5,7 @@ import (
 	"path"
 	"strings"
 	"time"
+	"unicode"
 )
 
 type EntryInput struct {
@@ -13,13 +14,20 @@ type EntryInput struct {
 	Source     string
 	CaptureID  string
 	DeviceID   string
+	Duration   time.Duration
+	Tags       []string
+	Tasks      []Task
+	Audio      string
+	Title      string
+	Summary    string
+	Cleaned    string
 }
 
 func FilePath(journalDir string, t time.Time) string {
 	return path.Join(strings.Trim(journalDir, "/"), t.Format("2006-01-02")+".md")
 }
 
-func NewJournalContent(t time.Time) string {
+func NewJournalContent(t time.Time, tags ...string) string {
 	titleUnderscore := t.Format("2006_01_02")
 	date := t.Format("2006-01-02")
 	created := t.Format(time.RFC3339)
@@ -28,43 +36,76 @@ title: "%s"
 type: journal
 date: %s
 created: %s
-tags: [journal]
+tags: [%s]
 source: voice-inbox-daemon
 ---
 # %s
-`, titleUnderscore, date, created, titleUnderscore)
+`, titleUnderscore, date, created, strings.Join(MergeTags([]string{"journal"}, tags), ", "), titleUnderscore)
+}
+
+func NewNoteContent(notePath string, t time.Time, tags ...string) string {
+	title := strings.TrimSuffix(path.Base(notePath), path.Ext(notePath))
+	return fmt.Sprintf(`---
+title: "%s"
+type: voice-inbox
+created: %s
+tags: [%s]
+source: voice-inbox-daemon
+---
+# %s
+`, strings.ReplaceAll(title, `"`, `\"`), t.Format(time.RFC3339), strings.Join(MergeTags([]string{"voice-inbox"}, tags), ", "), title)
 }
 
 func BuildEntry(in EntryInput) string {
-	headlineTime := in.Now.Format("15:04")
+	return NewEntry(in).Render()
+}
 
-	transcript := strings.TrimSpace(in.Transcript)
-	if transcript == "" {
-		transcript = "(transcript is empty)"
+func FormatTags(tags []string) string {
+	var out []string
+	for _, tag := range MergeTags(nil, tags) {
+		out = append(out, "#"+tag)
 	}
-	source := strings.TrimSpace(in.Source)
-	if source == "" {
-		source = "discord"
+	return strings.Join(out, " ")
+}
+
+func MergeTags(existing, add []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, list := range [][]string{existing, add} {
+		for _, tag := range list {
+			tag = NormalizeTag(tag)
+			if tag == "" || seen[tag] {
+				continue
+			}
+			seen[tag] = true
+			out = append(out, tag)
+		}
 	}
-	captureKey := CaptureKey(source, in.CaptureID)
-	label := strings.TrimSpace(in.DeviceID)
-	if label == "" {
-		switch source {
-		case "discord":
-			label = "Discord"
-		default:
-			label = source
+	return out
+}
+
+func NormalizeTag(tag string) string {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+	tag = strings.ToLower(strings.Join(strings.Fields(tag), "-"))
+	tag = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '-' || r == '/' {
+			return r
 		}
+		return -1
+	}, tag)
+	tag = strings.Trim(tag, "-/")
+	if strings.IndexFunc(tag, func(r rune) bool { return !unicode.IsDigit(r) }) < 0 {
+		return ""
 	}
+	return tag
+}
 
-	return fmt.Sprintf(
-		"\n## ログ - %s\n### 🎤 Voice Inbox\n\n%s\n\n_%s via %s_\n<!-- vi:%s -->\n",
-		headlineTime,
-		transcript,
-		headlineTime,
-		label,
-		captureKey,
-	)
+func FormatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	if d < time.Second {
+		return "0s"
+	}
+	return d.String()
 }
 
 func CaptureKey(source, captureID string) string {
@@ -75,6 +116,23 @@ func CaptureKey(source, captureID string) string {
 	return source + ":" + strings.TrimSpace(captureID)
 }
 
+func BlockID(captureKey string) string {
+	var b strings.Builder
+	b.WriteString("vi-")
+	for _, r := range strings.ToLower(captureKey) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' {
+			b.WriteRune(r)
+			continue
+		}
+		b.WriteByte('-')
+	}
+	return b.String()
+}
+
+func EntryLink(notePath, captureKey string) string {
+	return NoteLink(notePath, "^"+BlockID(captureKey))
+}
+
 func DiscordJumpURL(guildID, channelID, messageID string) string {
 	return fmt.Sprintf("https://discord.com/channels/%s/%s/%s", guildID, channelID, messageID)
 }
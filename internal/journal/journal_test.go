@@ -83,3 +83,159 @@ func TestBuildEntryUsesSourceLabelWhenDeviceIDMissing(t *testing.T) {
 		t.Fatalf("expected source label, got %q", entry)
 	}
 }
+
+func TestBuildEntryIncludesDuration(t *testing.T) {
+	now := time.Date(2026, 2, 26, 15, 42, 1, 0, time.UTC)
+	entry := BuildEntry(EntryInput{
+		Now:        now,
+		Transcript: "テスト音声",
+		Source:     "android-voice-inbox",
+		CaptureID:  "123",
+		DeviceID:   "Pixel 8a",
+		Duration:   134*time.Second + 400*time.Millisecond,
+	})
+	if !strings.Contains(entry, "_15:42 via Pixel 8a (2m14s)_") {
+		t.Fatalf("expected duration in footer, got %q", entry)
+	}
+}
+
+func TestBuildEntryRendersTasksAndTags(t *testing.T) {
+	now := time.Date(2026, 3, 19, 9, 5, 0, 0, time.UTC)
+	due := time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC)
+	entry := BuildEntry(EntryInput{
+		Now:        now,
+		Transcript: "TODO 請求書を送る",
+		Source:     "discord",
+		CaptureID:  "42",
+		Tags:       []string{"work"},
+		Tasks: []Task{
+			{Text: "請求書を送る", Due: &due},
+			{Text: "牛乳  を\n買う"},
+		},
+	})
+	want := "\n## ログ - 09:05\n### 🎤 Voice Inbox\n\nTODO 請求書を送る\n\n" +
+		"- [ ] 請求書を送る 📅 2026-03-20\n- [ ] 牛乳 を 買う\n\n#work\n\n" +
+		"_09:05 via Discord_ ^vi-discord-42\n<!-- vi:discord:42 -->\n"
+	if entry != want {
+		t.Fatalf("unexpected entry:\n%q\nwant:\n%q", entry, want)
+	}
+}
+
+func TestNewJournalContentMergesTags(t *testing.T) {
+	content := NewJournalContent(time.Date(2026, 2, 26, 9, 0, 0, 0, time.UTC), "#Project Alpha", "journal")
+	if !strings.Contains(content, "tags: [journal, project-alpha]") {
+		t.Fatalf("expected merged tags, got %q", content)
+	}
+}
+
+func TestNormalizeTag(t *testing.T) {
+	cases := map[string]string{
+		"#Alpha":        "alpha",
+		"project alpha": "project-alpha",
+		"work/Meetings": "work/meetings",
+		"仕事":            "仕事",
+		"#2026":         "",
+		"alpha!":        "alpha",
+		" -trailing- ":  "trailing",
+		"#y2026":        "y2026",
+	}
+	for in, want := range cases {
+		if got := NormalizeTag(in); got != want {
+			t.Fatalf("NormalizeTag(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFrontmatterTags(t *testing.T) {
+	cases := []struct {
+		content string
+		want    []string
+	}{
+		{NewJournalContent(time.Now(), "alpha"), []string{"journal", "alpha"}},
+		{"---\ntitle: x\ntags:\n  - Journal\n  - \"work/meetings\"\nsource: y\n---\nbody\n", []string{"journal", "work/meetings"}},
+		{"---\ntags: alpha, beta\n---\n", []string{"alpha", "beta"}},
+		{"# no frontmatter\ntags: [alpha]\n", nil},
+	}
+	for _, tc := range cases {
+		got := FrontmatterTags(tc.content)
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Fatalf("FrontmatterTags(%q) = %v, want %v", tc.content, got, tc.want)
+		}
+	}
+	if HasFrontmatter("# no frontmatter\n") || !HasFrontmatter("---\n---\nbody") {
+		t.Fatal("HasFrontmatter detection mismatch")
+	}
+}
+
+func TestBuildEntryWithSummaryFoldsTranscripts(t *testing.T) {
+	entry := BuildEntry(EntryInput{
+		Now:        time.Date(2026, 2, 26, 15, 42, 1, 0, time.UTC),
+		Transcript: "えーと、あの、明日の会議は\n\n十時からです",
+		Source:     "discord",
+		CaptureID:  "123",
+		Title:      "明日の会議",
+		Summary:    "明日の会議は10時から。",
+		Cleaned:    "明日の会議は10時からです。",
+	})
+	for _, want := range []string{
+		"### 🎤 明日の会議\n\n明日の会議は10時から。\n",
+		"> [!note]- 整形版\n> 明日の会議は10時からです。\n",
+		"> [!quote]- 文字起こし（原文）\n> えーと、あの、明日の会議は\n>\n> 十時からです\n",
+	} {
+		if !strings.Contains(entry, want) {
+			t.Fatalf("expected entry to include %q, got:\n%s", want, entry)
+		}
+	}
+}
+
+func TestFindEntryAndDigestRender(t *testing.T) {
+	at := time.Date(2026, 3, 19, 9, 5, 0, 0, time.UTC)
+	content := NewJournalContent(at) +
+		BuildEntry(EntryInput{Now: at, Transcript: "一件目", Source: "discord", CaptureID: "1"}) +
+		BuildEntry(EntryInput{Now: at.Add(time.Hour), Transcript: "二件目", Source: "android-voice-inbox", CaptureID: "2", DeviceID: "Pixel 8a",
+			Tasks: []Task{{Text: "請求書を送る"}}})
+
+	ref, ok := FindEntry(content, "android-voice-inbox:2")
+	if !ok || ref.Heading != "ログ - 10:05" || ref.Anchor != "^vi-android-voice-inbox-2" || len(ref.Tasks) != 1 || ref.Tasks[0] != "請求書を送る" {
+		t.Fatalf("unexpected entry ref: %+v ok=%v", ref, ok)
+	}
+	first, _ := FindEntry(content, "discord:1")
+	if first.Heading != "ログ - 09:05" || first.Anchor != "^vi-discord-1" || len(first.Tasks) != 0 {
+		t.Fatalf("unexpected first entry ref: %+v", first)
+	}
+	legacy := "\n## ログ - 08:00\n### 🎤 Voice Inbox\n\n古い\n\n_08:00 via Discord_\n<!-- vi:discord:0 -->\n"
+	if old, _ := FindEntry(legacy, "discord:0"); old.Anchor != "ログ - 08:00" {
+		t.Fatalf("entries without a block id should fall back to the heading, got %+v", old)
+	}
+
+	d := Digest{Label: "2026-03-19", Entries: []DigestEntry{
+		{Time: at, Label: "Discord", NotePath: "Journal/2026-03-19.md", Heading: first.Heading, Anchor: first.Anchor},
+		{Time: at.Add(time.Hour), Label: "Pixel 8a", Duration: 90 * time.Second, NotePath: "Journal/2026-03-19.md", Heading: ref.Heading, Anchor: ref.Anchor, Tasks: ref.Tasks},
+	}}
+	out := d.Render()
+	for _, want := range []string{
+		"## 📊 ダイジェスト 2026-03-19\n",
+		"- エントリ: 2 件\n- 録音時間: 1m30s\n",
+		"| Pixel 8a | 1 | 1m30s |\n",
+		"- 請求書を送る ([[Journal/2026-03-19#^vi-android-voice-inbox-2]])\n",
+		"- 10:05 [[Journal/2026-03-19#^vi-android-voice-inbox-2|ログ - 10:05]] Pixel 8a (1m30s)\n",
+		"<!-- vi:digest:2026-03-19 -->\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("digest should include %q:\n%s", want, out)
+		}
+	}
+
+	note := content + out + "\n## 手書きメモ\n"
+	d.Entries = d.Entries[:1]
+	replaced, ok := ReplaceDigest(note, d)
+	if !ok || strings.Count(replaced, "ダイジェスト 2026-03-19") != 1 || !strings.Contains(replaced, "- エントリ: 1 件") || !strings.HasSuffix(replaced, "\n## 手書きメモ\n") {
+		t.Fatalf("unexpected replaced note:\n%s", replaced)
+	}
+	if target, ok := DigestHeadingTarget(note, d); !ok || target != "2026_03_19::📊 ダイジェスト 2026-03-19" {
+		t.Fatalf("unexpected heading target %q ok=%v", target, ok)
+	}
+	if !strings.HasSuffix(d.Render(), d.RenderBody()) || !strings.HasPrefix(d.RenderBody(), "\n- エントリ: 1 件") {
+		t.Fatalf("render should be the heading plus the body:\n%s", d.RenderBody())
+	}
+}
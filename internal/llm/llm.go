@@ -0,0 +1,153 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"voice-inbox-daemon/internal/metrics"
+)
+
+const DefaultPrompt = `You tidy up voice memos that were transcribed by speech recognition.
+Write in the same language as the memo. Keep names, numbers and commitments exactly.
+The title is at most 8 words. The summary is 1-3 sentences.
+The cleaned version removes filler words, false starts and repetitions, fixes obvious recognition errors and adds punctuation and paragraphs, without adding information.`
+
+const formatInstruction = `Reply with a single JSON object and nothing else: {"title": "...", "summary": "...", "cleaned": "..."}`
+
+type Options struct {
+	BaseURL string
+	APIKey  string
+	Model   string
+	Prompt  string
+	Timeout time.Duration
+}
+
+type Result struct {
+	Title   string `json:"title"`
+	Summary string `json:"summary"`
+	Cleaned string `json:"cleaned"`
+}
+
+type Client struct {
+	opts       Options
+	httpClient *http.Client
+}
+
+func New(opts Options) *Client {
+	opts.BaseURL = strings.TrimRight(opts.BaseURL, "/")
+	if strings.TrimSpace(opts.Prompt) == "" {
+		opts.Prompt = DefaultPrompt
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 60 * time.Second
+	}
+	return &Client{opts: opts, httpClient: &http.Client{Timeout: opts.Timeout}}
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatRequest struct {
+	Model          string            `json:"model"`
+	Messages       []chatMessage     `json:"messages"`
+	Temperature    float64           `json:"temperature"`
+	Stream         bool              `json:"stream"`
+	ResponseFormat map[string]string `json:"response_format,omitempty"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+func (c *Client) Summarize(ctx context.Context, transcript string) (Result, error) {
+	payload, err := json.Marshal(chatRequest{
+		Model: c.opts.Model,
+		Messages: []chatMessage{
+			{Role: "system", Content: c.opts.Prompt + "\n\n" + formatInstruction},
+			{Role: "user", Content: transcript},
+		},
+		Temperature:    0.2,
+		ResponseFormat: map[string]string{"type": "json_object"},
+	})
+	if err != nil {
+		return Result{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.opts.BaseURL+"/chat/completions", bytes.NewReader(payload))
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.do("chat", req)
+	if err != nil {
+		return Result{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+		return Result{}, fmt.Errorf("llm chat completion failed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var out chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return Result{}, fmt.Errorf("decode llm response: %w", err)
+	}
+	if len(out.Choices) == 0 {
+		return Result{}, errors.New("llm response has no choices")
+	}
+	return ParseResult(out.Choices[0].Message.Content)
+}
+
+func (c *Client) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.opts.BaseURL+"/models", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do("models", req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 2048))
+		return fmt.Errorf("llm models failed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func ParseResult(content string) (Result, error) {
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return Result{}, errors.New("llm reply is not a JSON object")
+	}
+	var res Result
+	if err := json.Unmarshal([]byte(content[start:end+1]), &res); err != nil {
+		return Result{}, fmt.Errorf("parse llm reply: %w", err)
+	}
+	res.Title = strings.TrimSpace(strings.Join(strings.Fields(res.Title), " "))
+	res.Summary = strings.TrimSpace(res.Summary)
+	res.Cleaned = strings.TrimSpace(res.Cleaned)
+	if res.Summary == "" && res.Cleaned == "" {
+		return Result{}, errors.New("llm reply has neither summary nor cleaned text")
+	}
+	return res, nil
+}
+
+func (c *Client) do(op string, req *http.Request) (*http.Response, error) {
+	if c.opts.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.opts.APIKey)
+	}
+	started := time.Now()
+	resp, err := c.httpClient.Do(req)
+	metrics.ExternalCallDuration.ObserveSince(started, "llm", op, metrics.HTTPResult(resp, err))
+	return resp, err
+}
@@ -0,0 +1,61 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarizeSendsChatCompletion(t *testing.T) {
+	var got chatRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"Sure!\n` + "```json" + `\n{\"title\": \" Weekly  plan \", \"summary\": \"Plan the week.\", \"cleaned\": \"We plan the week.\"}\n` + "```" + `"}}]}`))
+	}))
+	defer srv.Close()
+
+	c := New(Options{BaseURL: srv.URL + "/v1/", APIKey: "secret", Model: "llama3", Prompt: "Be brief."})
+	res, err := c.Summarize(context.Background(), "uh so we, we plan the week")
+	if err != nil {
+		t.Fatalf("summarize: %v", err)
+	}
+	if res.Title != "Weekly plan" || res.Summary != "Plan the week." || res.Cleaned != "We plan the week." {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if got.Model != "llama3" || len(got.Messages) != 2 || !strings.HasPrefix(got.Messages[0].Content, "Be brief.") || got.Messages[1].Content != "uh so we, we plan the week" {
+		t.Fatalf("unexpected request: %+v", got)
+	}
+}
+
+func TestSummarizeReportsFailures(t *testing.T) {
+	cases := map[string]http.HandlerFunc{
+		"status": func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "model not loaded", http.StatusServiceUnavailable)
+		},
+		"not json": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"I cannot help with that."}}]}`))
+		},
+		"empty": func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"title\":\"x\"}"}}]}`))
+		},
+		"slow": func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(200 * time.Millisecond)
+		},
+	}
+	for name, h := range cases {
+		srv := httptest.NewServer(h)
+		c := New(Options{BaseURL: srv.URL, Model: "m", Timeout: 50 * time.Millisecond})
+		if _, err := c.Summarize(context.Background(), "memo"); err == nil {
+			t.Fatalf("%s: expected error", name)
+		}
+		srv.Close()
+	}
+}
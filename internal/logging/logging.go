@@ -0,0 +1,184 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+const redacted = "[REDACTED]"
+
+type Options struct {
+	Format  string
+	Level   string
+	Secrets []string
+}
+
+var defaultRedactor atomic.Pointer[Redactor]
+
+func init() {
+	defaultRedactor.Store(NewRedactor())
+}
+
+func New(w io.Writer, opts Options) (*slog.Logger, error) {
+	level, err := ParseLevel(opts.Level)
+	if err != nil {
+		return nil, err
+	}
+	handlerOpts := &slog.HandlerOptions{Level: level}
+	var inner slog.Handler
+	switch strings.ToLower(strings.TrimSpace(opts.Format)) {
+	case "", "text":
+		inner = slog.NewTextHandler(w, handlerOpts)
+	case "json":
+		inner = slog.NewJSONHandler(w, handlerOpts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q (want text or json)", opts.Format)
+	}
+	return slog.New(NewRedactingHandler(inner, NewRedactor(opts.Secrets...))), nil
+}
+
+func Setup(w io.Writer, opts Options) (*slog.Logger, error) {
+	logger, err := New(w, opts)
+	if err != nil {
+		return nil, err
+	}
+	defaultRedactor.Store(NewRedactor(opts.Secrets...))
+	slog.SetDefault(logger)
+	return logger, nil
+}
+
+func ParseLevel(raw string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(raw)) {
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q (want debug, info, warn or error)", raw)
+	}
+}
+
+func Redact(s string) string {
+	return defaultRedactor.Load().Redact(s)
+}
+
+type contextKey struct{}
+
+func NewContext(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok && logger != nil {
+		return logger
+	}
+	return slog.Default()
+}
+
+var (
+	credentialPattern = regexp.MustCompile(`(?i)\b(Bot|Bearer)\s+[^\s"',;]+`)
+	sensitiveKeys     = []string{"token", "authorization", "api_key", "apikey", "password", "secret"}
+)
+
+type Redactor struct {
+	secrets []string
+}
+
+func NewRedactor(secrets ...string) *Redactor {
+	r := &Redactor{}
+	for _, s := range secrets {
+		if s = strings.TrimSpace(s); len(s) >= 4 {
+			r.secrets = append(r.secrets, s)
+		}
+	}
+	return r
+}
+
+func (r *Redactor) Redact(s string) string {
+	if s == "" {
+		return s
+	}
+	for _, secret := range r.secrets {
+		s = strings.ReplaceAll(s, secret, redacted)
+	}
+	return credentialPattern.ReplaceAllString(s, "$1 "+redacted)
+}
+
+func sensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, k := range sensitiveKeys {
+		if strings.Contains(key, k) {
+			return true
+		}
+	}
+	return false
+}
+
+type RedactingHandler struct {
+	inner    slog.Handler
+	redactor *Redactor
+}
+
+func NewRedactingHandler(inner slog.Handler, redactor *Redactor) *RedactingHandler {
+	return &RedactingHandler{inner: inner, redactor: redactor}
+}
+
+func (h *RedactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+func (h *RedactingHandler) Handle(ctx context.Context, rec slog.Record) error {
+	out := slog.NewRecord(rec.Time, rec.Level, h.redactor.Redact(rec.Message), rec.PC)
+	rec.Attrs(func(a slog.Attr) bool {
+		out.AddAttrs(h.redactAttr(a))
+		return true
+	})
+	return h.inner.Handle(ctx, out)
+}
+
+func (h *RedactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	cleaned := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		cleaned[i] = h.redactAttr(a)
+	}
+	return &RedactingHandler{inner: h.inner.WithAttrs(cleaned), redactor: h.redactor}
+}
+
+func (h *RedactingHandler) WithGroup(name string) slog.Handler {
+	return &RedactingHandler{inner: h.inner.WithGroup(name), redactor: h.redactor}
+}
+
+func (h *RedactingHandler) redactAttr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	if sensitiveKey(a.Key) && v.Kind() != slog.KindGroup {
+		return slog.String(a.Key, redacted)
+	}
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, h.redactor.Redact(v.String()))
+	case slog.KindGroup:
+		group := v.Group()
+		cleaned := make([]any, len(group))
+		for i, ga := range group {
+			cleaned[i] = h.redactAttr(ga)
+		}
+		return slog.Group(a.Key, cleaned...)
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return slog.String(a.Key, h.redactor.Redact(err.Error()))
+		}
+		if s, ok := v.Any().(fmt.Stringer); ok {
+			return slog.String(a.Key, h.redactor.Redact(s.String()))
+		}
+	}
+	return slog.Attr{Key: a.Key, Value: v}
+}
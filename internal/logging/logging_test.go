@@ -0,0 +1,63 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestJSONLoggerRedactsSecretsAndSensitiveKeys(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, Options{Format: "json", Secrets: []string{"s3cr3t-token", "ab"}})
+	if err != nil {
+		t.Fatalf("new logger: %v", err)
+	}
+
+	logger.With("run_id", "run-1").Info("calling s3cr3t-token endpoint",
+		"error", errors.New("401 for Authorization: Bot abc.def.ghi"),
+		"api_key", "plain-value",
+		"header", "Bearer xyz",
+		"note", "ab stays",
+	)
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("decode: %v (%s)", err, buf.String())
+	}
+	if strings.Contains(buf.String(), "s3cr3t-token") || strings.Contains(buf.String(), "abc.def.ghi") || strings.Contains(buf.String(), "xyz") {
+		t.Fatalf("secret leaked: %s", buf.String())
+	}
+	if entry["msg"] != "calling [REDACTED] endpoint" {
+		t.Fatalf("unexpected msg: %v", entry["msg"])
+	}
+	if entry["api_key"] != "[REDACTED]" || entry["header"] != "Bearer [REDACTED]" {
+		t.Fatalf("unexpected attrs: %v", entry)
+	}
+	if entry["note"] != "ab stays" || entry["run_id"] != "run-1" {
+		t.Fatalf("short secrets must not be redacted: %v", entry)
+	}
+}
+
+func TestNewRejectsUnknownFormatAndLevel(t *testing.T) {
+	if _, err := New(&bytes.Buffer{}, Options{Format: "xml"}); err == nil {
+		t.Fatalf("expected error for unknown format")
+	}
+	if _, err := New(&bytes.Buffer{}, Options{Level: "verbose"}); err == nil {
+		t.Fatalf("expected error for unknown level")
+	}
+}
+
+func TestLevelFiltersDebug(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, Options{Level: "warn"})
+	if err != nil {
+		t.Fatalf("new logger: %v", err)
+	}
+	logger.Info("hidden")
+	logger.Warn("shown")
+	if strings.Contains(buf.String(), "hidden") || !strings.Contains(buf.String(), "shown") {
+		t.Fatalf("unexpected output: %s", buf.String())
+	}
+}
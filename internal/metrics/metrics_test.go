@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistryWritesPrometheusText(t *testing.T) {
+	reg := NewRegistry()
+	counter := reg.NewCounterVec("test_items_total", "Items.", "source")
+	hist := reg.NewHistogramVec("test_call_seconds", "Calls.", []float64{0.5, 1}, "dependency")
+	reg.RegisterCollector(func() []Gauge {
+		return []Gauge{
+			{Name: "test_queue", Help: "Queue.", Labels: map[string]string{"status": "pending"}, Value: 3},
+			{Name: "test_queue", Labels: map[string]string{"status": `we"ird`}, Value: 1},
+		}
+	})
+
+	counter.Inc("discord")
+	counter.Add(2, "discord")
+	hist.Observe(0.2, "whisper")
+	hist.Observe(0.7, "whisper")
+	hist.Observe(5, "whisper")
+
+	rec := httptest.NewRecorder()
+	reg.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE test_items_total counter\n",
+		`test_items_total{source="discord"} 3` + "\n",
+		"# TYPE test_call_seconds histogram\n",
+		`test_call_seconds_bucket{dependency="whisper",le="0.5"} 1` + "\n",
+		`test_call_seconds_bucket{dependency="whisper",le="1"} 2` + "\n",
+		`test_call_seconds_bucket{dependency="whisper",le="+Inf"} 3` + "\n",
+		`test_call_seconds_sum{dependency="whisper"} 5.9` + "\n",
+		`test_call_seconds_count{dependency="whisper"} 3` + "\n",
+		"# HELP test_queue Queue.\n# TYPE test_queue gauge\n",
+		`test_queue{status="pending"} 3` + "\n",
+		`test_queue{status="we\"ird"} 1` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("missing %q in:\n%s", want, body)
+		}
+	}
+	if strings.Count(body, "# TYPE test_queue gauge") != 1 {
+		t.Fatalf("expected a single TYPE line per gauge family:\n%s", body)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+}
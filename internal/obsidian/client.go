@@ -4,14 +4,19 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strings"
 	"time"
+
+	"voice-inbox-daemon/internal/metrics"
 )
 
+var ErrUnavailable = errors.New("obsidian unavailable")
+
 type Client struct {
 	baseURL    string
 	authHeader string
@@ -45,7 +50,7 @@ func (c *Client) Health(ctx context.Context) (Health, error) {
 	if err != nil {
 		return Health{}, err
 	}
-	resp, err := c.do(req)
+	resp, err := c.do("health", req)
 	if err != nil {
 		return Health{}, err
 	}
@@ -67,7 +72,7 @@ func (c *Client) FileExists(ctx context.Context, vaultPath string) (bool, error)
 	if err != nil {
 		return false, err
 	}
-	resp, err := c.do(req)
+	resp, err := c.do("file_exists", req)
 	if err != nil {
 		return false, err
 	}
@@ -83,20 +88,46 @@ func (c *Client) FileExists(ctx context.Context, vaultPath string) (bool, error)
 }
 
 func (c *Client) CreateFile(ctx context.Context, vaultPath, content string) error {
+	return c.put(ctx, "create_file", vaultPath, "text/markdown; charset=utf-8", strings.NewReader(content))
+}
+
+func (c *Client) UploadFile(ctx context.Context, vaultPath, contentType string, body io.Reader) error {
+	return c.put(ctx, "upload_file", vaultPath, contentType, body)
+}
+
+func (c *Client) put(ctx context.Context, op, vaultPath, contentType string, content io.Reader) error {
 	endpoint := c.baseURL + "/vault/" + encodeVaultPath(vaultPath)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, strings.NewReader(content))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, endpoint, content)
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Content-Type", "text/markdown; charset=utf-8")
-	resp, err := c.do(req)
+	req.Header.Set("Content-Type", contentType)
+	resp, err := c.do(op, req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent {
 		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
-		return fmt.Errorf("obsidian create file failed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+		return fmt.Errorf("obsidian %s failed: %s: %s", strings.ReplaceAll(op, "_", " "), resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (c *Client) DeleteFile(ctx context.Context, vaultPath string) error {
+	endpoint := c.baseURL + "/vault/" + encodeVaultPath(vaultPath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.do("delete_file", req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("obsidian delete failed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
 	}
 	return nil
 }
@@ -108,7 +139,7 @@ func (c *Client) AppendFile(ctx context.Context, vaultPath, content string) erro
 		return err
 	}
 	req.Header.Set("Content-Type", "text/markdown; charset=utf-8")
-	resp, err := c.do(req)
+	resp, err := c.do("append_file", req)
 	if err != nil {
 		return err
 	}
@@ -120,13 +151,90 @@ func (c *Client) AppendFile(ctx context.Context, vaultPath, content string) erro
 	return nil
 }
 
+func (c *Client) SetFrontmatter(ctx context.Context, vaultPath, key string, value any) error {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	endpoint := c.baseURL + "/vault/" + encodeVaultPath(vaultPath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, endpoint, strings.NewReader(string(payload)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Operation", "replace")
+	req.Header.Set("Target-Type", "frontmatter")
+	req.Header.Set("Target", key)
+	req.Header.Set("Create-Target-If-Missing", "true")
+	resp, err := c.do("set_frontmatter", req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("obsidian frontmatter update failed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (c *Client) ReplaceHeading(ctx context.Context, vaultPath, heading, content string) error {
+	endpoint := c.baseURL + "/vault/" + encodeVaultPath(vaultPath)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, endpoint, strings.NewReader(content))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/markdown; charset=utf-8")
+	req.Header.Set("Operation", "replace")
+	req.Header.Set("Target-Type", "heading")
+	req.Header.Set("Target", url.PathEscape(heading))
+	resp, err := c.do("replace_heading", req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("obsidian heading update failed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+func (c *Client) SearchSimple(ctx context.Context, query string) ([]string, error) {
+	endpoint := c.baseURL + "/search/simple/?query=" + url.QueryEscape(query) + "&contextLength=0"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.do("search", req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("obsidian search failed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var results []struct {
+		Filename string `json:"filename"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(results))
+	for _, r := range results {
+		files = append(files, r.Filename)
+	}
+	return files, nil
+}
+
 func (c *Client) ReadFile(ctx context.Context, vaultPath string) (string, error) {
 	endpoint := c.baseURL + "/vault/" + encodeVaultPath(vaultPath)
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
 	if err != nil {
 		return "", err
 	}
-	resp, err := c.do(req)
+	resp, err := c.do("read_file", req)
 	if err != nil {
 		return "", err
 	}
@@ -150,9 +258,23 @@ func encodeVaultPath(vaultPath string) string {
 	return strings.Join(parts, "/")
 }
 
-func (c *Client) do(req *http.Request) (*http.Response, error) {
+func (c *Client) do(op string, req *http.Request) (*http.Response, error) {
 	if c.authHeader != "" {
 		req.Header.Set(c.authHeader, "Bearer "+c.apiKey)
 	}
-	return c.httpClient.Do(req)
+	started := time.Now()
+	resp, err := c.httpClient.Do(req)
+	metrics.ExternalCallDuration.ObserveSince(started, "obsidian", op, metrics.HTTPResult(resp, err))
+	if err != nil {
+		if req.Context().Err() != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %s %s", ErrUnavailable, op, resp.Status)
+	}
+	return resp, nil
 }
@@ -2,8 +2,11 @@ package obsidian
 
 import (
 	"context"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 )
 
@@ -34,3 +37,89 @@ func TestCreateAndAppendAcceptNoContent(t *testing.T) {
 		t.Fatalf("append should accept 204: %v", err)
 	}
 }
+
+func TestSetFrontmatterSendsPatch(t *testing.T) {
+	var got *http.Request
+	var body string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, "Authorization", "key", false)
+	if err := c.SetFrontmatter(context.Background(), "Journal/2026-02-26.md", "tags", []string{"journal", "alpha"}); err != nil {
+		t.Fatalf("set frontmatter: %v", err)
+	}
+	if got.Method != http.MethodPatch || got.URL.Path != "/vault/Journal/2026-02-26.md" {
+		t.Fatalf("unexpected request %s %s", got.Method, got.URL.Path)
+	}
+	if got.Header.Get("Target-Type") != "frontmatter" || got.Header.Get("Target") != "tags" || got.Header.Get("Operation") != "replace" {
+		t.Fatalf("unexpected patch headers: %v", got.Header)
+	}
+	if body != `["journal","alpha"]` {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestReplaceHeadingPatchesOnlyTheSection(t *testing.T) {
+	var got *http.Request
+	var body string
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		b, _ := io.ReadAll(r.Body)
+		body = string(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, "Authorization", "key", false)
+	if err := c.ReplaceHeading(context.Background(), "Journal/2026-03-19.md", "2026_03_19::📊 ダイジェスト 2026-03-19", "\n- エントリ: 1 件\n"); err != nil {
+		t.Fatalf("replace heading: %v", err)
+	}
+	if got.Method != http.MethodPatch || got.URL.Path != "/vault/Journal/2026-03-19.md" {
+		t.Fatalf("unexpected request %s %s", got.Method, got.URL.Path)
+	}
+	target, err := url.PathUnescape(got.Header.Get("Target"))
+	if err != nil || target != "2026_03_19::📊 ダイジェスト 2026-03-19" || got.Header.Get("Target-Type") != "heading" || got.Header.Get("Operation") != "replace" {
+		t.Fatalf("unexpected patch headers: %v", got.Header)
+	}
+	if body != "\n- エントリ: 1 件\n" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+func TestUnavailableSinkIsDistinguishedFromContentErrors(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/vault/gateway.md" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	c := New(srv.URL, "Authorization", "key", false)
+	ctx := context.Background()
+
+	if err := c.AppendFile(ctx, "bad.md", "x"); err == nil || errors.Is(err, ErrUnavailable) {
+		t.Fatalf("400 should be a content error, got %v", err)
+	}
+	if _, err := c.FileExists(ctx, "gateway.md"); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("502 should be unavailable, got %v", err)
+	}
+
+	srv.Close()
+	if _, err := c.FileExists(ctx, "note.md"); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("connection refused should be unavailable, got %v", err)
+	}
+	if _, err := c.Health(ctx); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("health against a closed server should be unavailable, got %v", err)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := c.FileExists(canceled, "note.md"); errors.Is(err, ErrUnavailable) {
+		t.Fatalf("canceled request should not count as unavailable: %v", err)
+	}
+}
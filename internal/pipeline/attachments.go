@@ -0,0 +1,189 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"voice-inbox-daemon/internal/journal"
+	"voice-inbox-daemon/internal/logging"
+	"voice-inbox-daemon/internal/obsidian"
+	"voice-inbox-daemon/internal/state"
+	"voice-inbox-daemon/internal/transcribe"
+)
+
+var embeddableAudio = map[string]string{
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/mp4",
+	".ogg":  "audio/ogg",
+	".webm": "audio/webm",
+	".wav":  "audio/wav",
+	".flac": "audio/flac",
+	".3gp":  "audio/3gpp",
+}
+
+var audioExtByContentType = map[string]string{
+	"audio/mpeg":  ".mp3",
+	"audio/mp3":   ".mp3",
+	"audio/mp4":   ".m4a",
+	"audio/m4a":   ".m4a",
+	"audio/x-m4a": ".m4a",
+	"audio/ogg":   ".ogg",
+	"audio/webm":  ".webm",
+	"audio/wav":   ".wav",
+	"audio/x-wav": ".wav",
+	"audio/wave":  ".wav",
+	"audio/flac":  ".flac",
+	"audio/3gpp":  ".3gp",
+}
+
+func (r *Runner) attachAudio(ctx context.Context, target processTarget, dest destination, audioPath string, now time.Time) (string, error) {
+	if r.cfg.AttachMode == "" || r.cfg.AttachMode == "off" || strings.TrimSpace(audioPath) == "" || !fileExists(audioPath) {
+		return "", nil
+	}
+	ext := attachmentExt(target, audioPath)
+	uploadPath := audioPath
+	if r.cfg.AttachMode == "opus" || ext == "" {
+		ext = ".ogg"
+		uploadPath = strings.TrimSuffix(audioPath, filepath.Ext(audioPath)) + ".attach.ogg"
+		if err := transcribe.EncodeOpus(ctx, r.cfg.FFmpegBin, audioPath, uploadPath); err != nil {
+			return "", err
+		}
+		defer os.Remove(uploadPath)
+	}
+
+	at := itemTime(target, now)
+	name := at.Format("2006-01-02_150405") + "_" + vaultSafeName(journal.CaptureKey(target.Source, target.CaptureID)) + ext
+	vaultPath := path.Join(r.cfg.AttachDir, at.Format("2006"), name)
+
+	exists, err := dest.Client.FileExists(ctx, vaultPath)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(uploadPath)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		f, err := os.Open(uploadPath)
+		if err != nil {
+			return "", err
+		}
+		err = dest.Client.UploadFile(ctx, vaultPath, embeddableAudio[ext], f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+	}
+	if err := r.store.RecordAttachment(state.VaultAttachment{
+		Sink:      dest.Sink,
+		VaultPath: vaultPath,
+		Source:    target.Source,
+		ItemID:    target.CaptureID,
+		NotePath:  dest.Path,
+		SizeBytes: info.Size(),
+	}); err != nil {
+		return "", fmt.Errorf("record attachment: %w", err)
+	}
+	logging.FromContext(ctx).Info("audio attached", "vault_path", vaultPath, "mode", r.cfg.AttachMode, "size_bytes", info.Size())
+	return vaultPath, nil
+}
+
+func attachmentExt(target processTarget, audioPath string) string {
+	mediaType, _, _ := strings.Cut(strings.ToLower(target.ContentType), ";")
+	if ext, ok := audioExtByContentType[strings.TrimSpace(mediaType)]; ok {
+		return ext
+	}
+	for _, name := range []string{target.AttachmentName, audioPath} {
+		ext := strings.ToLower(filepath.Ext(name))
+		if _, ok := embeddableAudio[ext]; ok {
+			return ext
+		}
+	}
+	return ""
+}
+
+func vaultSafeName(s string) string {
+	return strings.NewReplacer(":", "-", "/", "-", "\\", "-", " ", "-", "#", "-", "^", "-", "[", "", "]", "", "|", "-").Replace(s)
+}
+
+func (r *Runner) sinkClient(name string) (*obsidian.Client, bool) {
+	if name == "" || name == "obsidian" {
+		return r.obsidian, true
+	}
+	client, ok := r.sinks[name]
+	return client, ok
+}
+
+func (r *Runner) cleanupAttachments(ctx context.Context, res *Result) {
+	const pageSize = 1000
+	cutoff := time.Now().AddDate(0, 0, -r.cfg.AttachRetentionDays)
+	removed, kept := 0, 0
+	var after state.VaultAttachment
+	for ctx.Err() == nil {
+		rows, err := r.store.ListAttachmentsBefore(cutoff, after, pageSize)
+		if err != nil {
+			res.Failed++
+			res.Errors = append(res.Errors, fmt.Sprintf("list vault attachments: %v", err))
+			break
+		}
+		for _, rec := range rows {
+			res.Processed++
+			switch r.expireAttachment(ctx, rec, res) {
+			case attachmentRemoved:
+				removed++
+				res.Succeeded++
+			case attachmentReferenced:
+				kept++
+				res.Succeeded++
+			}
+		}
+		if len(rows) < pageSize {
+			break
+		}
+		after = rows[len(rows)-1]
+	}
+	res.Data["vault_attachments_removed"] = removed
+	res.Data["vault_attachments_referenced"] = kept
+}
+
+type attachmentFate int
+
+const (
+	attachmentFailed attachmentFate = iota
+	attachmentReferenced
+	attachmentRemoved
+)
+
+func (r *Runner) expireAttachment(ctx context.Context, rec state.VaultAttachment, res *Result) attachmentFate {
+	client, ok := r.sinkClient(rec.Sink)
+	if !ok {
+		res.Failed++
+		res.Errors = append(res.Errors, fmt.Sprintf("vault attachment %s: unknown sink %q", rec.VaultPath, rec.Sink))
+		return attachmentFailed
+	}
+	referenced, err := client.SearchSimple(ctx, path.Base(rec.VaultPath))
+	if err != nil {
+		res.Failed++
+		res.Errors = append(res.Errors, fmt.Sprintf("check references to %s: %v", rec.VaultPath, err))
+		return attachmentFailed
+	}
+	if len(referenced) > 0 {
+		return attachmentReferenced
+	}
+	if err := client.DeleteFile(ctx, rec.VaultPath); err != nil {
+		res.Failed++
+		res.Errors = append(res.Errors, fmt.Sprintf("remove vault attachment %s: %v", rec.VaultPath, err))
+		return attachmentFailed
+	}
+	if err := r.store.DeleteAttachment(rec.Sink, rec.VaultPath); err != nil {
+		res.Failed++
+		res.Errors = append(res.Errors, fmt.Sprintf("forget vault attachment %s: %v", rec.VaultPath, err))
+		return attachmentFailed
+	}
+	return attachmentRemoved
+}
@@ -0,0 +1,30 @@
+package pipeline
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"voice-inbox-daemon/internal/state"
+)
+
+func (r *Runner) scheduledBackup(res *Result, now time.Time) {
+	res.Processed++
+	path := filepath.Join(r.cfg.BackupDir, state.BackupFileName(now))
+	info, err := r.store.BackupTo(path)
+	if err != nil {
+		res.Failed++
+		res.Errors = append(res.Errors, fmt.Sprintf("backup state db: %v", err))
+		return
+	}
+	res.Succeeded++
+	res.Data["backup_path"] = info.Path
+	res.Data["backup_size_bytes"] = info.SizeBytes
+
+	removed, err := state.RotateBackups(r.cfg.BackupDir, r.cfg.BackupKeep)
+	if err != nil {
+		res.Failed++
+		res.Errors = append(res.Errors, fmt.Sprintf("rotate backups: %v", err))
+	}
+	res.Data["backups_pruned"] = len(removed)
+}
@@ -0,0 +1,117 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"voice-inbox-daemon/internal/state"
+)
+
+var ErrNotRequeueable = errors.New("no failed or abandoned item with that id")
+
+func (r *Runner) FailedList(_ context.Context, source string) (Result, error) {
+	started := time.Now()
+	res := Result{Command: "failed list", Data: map[string]any{}}
+
+	switch source {
+	case "", state.SourceDiscord, state.SourceCapture:
+	default:
+		err := fmt.Errorf("unknown source %q (want discord or capture)", source)
+		res.Failed = 1
+		res.Errors = append(res.Errors, err.Error())
+		finalizeResult(&res, started)
+		return res, err
+	}
+
+	items, err := r.store.ListFailed(source)
+	if err != nil {
+		res.Failed = 1
+		res.Errors = append(res.Errors, err.Error())
+		finalizeResult(&res, started)
+		return res, err
+	}
+	res.Processed = len(items)
+	res.Data["items"] = items
+	finalizeResult(&res, started)
+	return res, nil
+}
+
+func (r *Runner) Requeue(_ context.Context, id string) (Result, error) {
+	started := time.Now()
+	res := Result{Command: "requeue", Data: map[string]any{}}
+
+	source, found, err := r.store.Requeue(id, time.Now())
+	if err == nil && !found {
+		err = fmt.Errorf("%w: %s", ErrNotRequeueable, id)
+	}
+	if err != nil {
+		res.Failed = 1
+		res.Errors = append(res.Errors, err.Error())
+		finalizeResult(&res, started)
+		return res, err
+	}
+	res.Processed = 1
+	res.Requeued = 1
+	res.Data["id"] = id
+	res.Data["source"] = source
+	finalizeResult(&res, started)
+	return res, nil
+}
+
+func (r *Runner) RequeueAllFailed(_ context.Context, since *time.Time) (Result, error) {
+	started := time.Now()
+	res := Result{Command: "requeue", Data: map[string]any{}}
+
+	n, err := r.store.RequeueAllFailed(time.Now(), since)
+	if err != nil {
+		res.Failed = 1
+		res.Errors = append(res.Errors, err.Error())
+		finalizeResult(&res, started)
+		return res, err
+	}
+	res.Processed = n
+	res.Requeued = n
+	finalizeResult(&res, started)
+	return res, nil
+}
+
+func (r *Runner) Abandon(_ context.Context, id string) (Result, error) {
+	started := time.Now()
+	res := Result{Command: "abandon", Data: map[string]any{}}
+
+	source, found, err := r.store.Abandon(id, time.Now())
+	if err == nil && !found {
+		err = r.notAbandonable(id)
+	}
+	if err != nil {
+		res.Failed = 1
+		res.Errors = append(res.Errors, err.Error())
+		finalizeResult(&res, started)
+		return res, err
+	}
+	res.Processed = 1
+	res.Succeeded = 1
+	res.Data["id"] = id
+	res.Data["source"] = source
+	finalizeResult(&res, started)
+	return res, nil
+}
+
+func (r *Runner) notAbandonable(id string) error {
+	status := ""
+	if msg, found, err := r.store.GetMessage(id); err != nil {
+		return err
+	} else if found {
+		status = msg.Status
+	} else if capture, found, err := r.store.GetCapture(id); err != nil {
+		return err
+	} else if found {
+		status = capture.Status
+	}
+	if status == "" {
+		return fmt.Errorf("no item with id %s", id)
+	}
+	return fmt.Errorf("item %s is %s; only pending, failed or journal_pending items can be abandoned", id, status)
+}
@@ -0,0 +1,231 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"voice-inbox-daemon/internal/journal"
+	"voice-inbox-daemon/internal/logging"
+	"voice-inbox-daemon/internal/state"
+)
+
+type DigestPeriod struct {
+	Label  string
+	Weekly bool
+	From   time.Time
+	To     time.Time
+}
+
+func DayPeriod(t time.Time) DigestPeriod {
+	from := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	return DigestPeriod{Label: from.Format("2006-01-02"), From: from, To: from.AddDate(0, 0, 1)}
+}
+
+func WeekPeriod(t time.Time) DigestPeriod {
+	day := DayPeriod(t).From
+	from := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	year, week := from.ISOWeek()
+	return DigestPeriod{Label: fmt.Sprintf("%04d-W%02d", year, week), Weekly: true, From: from, To: from.AddDate(0, 0, 7)}
+}
+
+func ParseDigestPeriod(day, week string, now time.Time) (DigestPeriod, error) {
+	switch {
+	case day != "" && week != "":
+		return DigestPeriod{}, errors.New("use either --day or --week, not both")
+	case week != "":
+		week = strings.ToUpper(week)
+		var year, num int
+		if _, err := fmt.Sscanf(week, "%d-W%d", &year, &num); err != nil || num < 1 || num > 53 {
+			return DigestPeriod{}, fmt.Errorf("invalid week %q (want YYYY-Www)", week)
+		}
+		p := WeekPeriod(time.Date(year, 1, 4, 0, 0, 0, 0, now.Location()).AddDate(0, 0, (num-1)*7))
+		if p.Label != week {
+			return DigestPeriod{}, fmt.Errorf("invalid week %q (want YYYY-Www)", week)
+		}
+		return p, nil
+	case day != "":
+		t, err := time.ParseInLocation("2006-01-02", day, now.Location())
+		if err != nil {
+			return DigestPeriod{}, fmt.Errorf("invalid day %q (want YYYY-MM-DD)", day)
+		}
+		return DayPeriod(t), nil
+	default:
+		return DayPeriod(now), nil
+	}
+}
+
+func (r *Runner) ScheduledDigest(ctx context.Context) (Result, error) {
+	now := time.Now()
+	res, err := r.Digest(ctx, DayPeriod(now), false)
+	if now.Weekday() != time.Sunday {
+		return res, err
+	}
+	weekly, weeklyErr := r.Digest(ctx, WeekPeriod(now), false)
+	res.Processed += weekly.Processed
+	res.Succeeded += weekly.Succeeded
+	res.Failed += weekly.Failed
+	res.Errors = append(res.Errors, weekly.Errors...)
+	res.Data["weekly"] = weekly.Data
+	return res, errors.Join(err, weeklyErr)
+}
+
+func (r *Runner) Digest(ctx context.Context, period DigestPeriod, dryRun bool) (Result, error) {
+	started := time.Now()
+	res := Result{Command: "digest", Data: map[string]any{"period": period.Label}}
+
+	if !dryRun {
+		lock, err := state.AcquireFileLock(r.cfg.LockFilePath)
+		if err != nil {
+			res.Failed = 1
+			res.Errors = append(res.Errors, err.Error())
+			finalizeResult(&res, started)
+			return res, err
+		}
+		defer lock.Release()
+		if runID, err := r.store.BeginRun("digest", started); err == nil {
+			res.RunID = runID
+			defer func() {
+				_ = r.store.FinishRun(runID, time.Now(), res.Processed, res.Succeeded, res.Failed, res.Errors)
+			}()
+		}
+	}
+
+	items, err := r.store.ListJournaledBetween(period.From, period.To, snowflakeAt(period.From), snowflakeAt(period.To))
+	if err != nil {
+		res.Failed = 1
+		res.Errors = append(res.Errors, fmt.Sprintf("list journaled items: %v", err))
+		finalizeResult(&res, started)
+		return res, err
+	}
+
+	digest := journal.Digest{Label: period.Label, Weekly: period.Weekly}
+	notes := map[string]string{}
+	for _, item := range items {
+		at := item.CapturedAt
+		if item.Source == state.SourceDiscord {
+			if t, ok := snowflakeTime(item.ItemID); ok {
+				at = t
+			}
+		}
+		if at.Before(period.From) || !at.Before(period.To) {
+			continue
+		}
+		res.Processed++
+		entry := journal.DigestEntry{
+			Time:     at.In(period.From.Location()),
+			Label:    journal.SourceLabel(item.Source, item.DeviceID),
+			Duration: time.Duration(item.DurationMS) * time.Millisecond,
+			NotePath: item.JournalPath,
+		}
+		key := journal.CaptureKey(item.Source, item.ItemID)
+		if ref, ok := journal.FindEntry(r.readNote(ctx, notes, item.JournalPath), key); ok {
+			entry.Heading, entry.Anchor, entry.Tasks = ref.Heading, ref.Anchor, ref.Tasks
+		}
+		if r.cfg.TasksMode == "note" {
+			if ref, ok := journal.FindEntry(r.readNote(ctx, notes, r.tasksNotePath()), key); ok {
+				entry.Tasks = append(entry.Tasks, ref.Tasks...)
+			}
+		}
+		digest.Entries = append(digest.Entries, entry)
+	}
+
+	notePath := journal.FilePath(r.cfg.VaultJournalDir, period.From)
+	if period.Weekly {
+		notePath = path.Join(r.cfg.DigestWeekDir, period.Label+".md")
+	}
+	res.Data["journal_path"] = notePath
+	res.Data["entries"] = len(digest.Entries)
+	res.Data["duration_seconds"] = int64(digest.TotalDuration().Seconds())
+	res.Data["devices"] = digest.Devices()
+	res.Data["tasks"] = len(digest.Tasks())
+
+	switch {
+	case dryRun:
+		res.Data["markdown"] = digest.Render()
+		res.Succeeded = res.Processed
+	case len(digest.Entries) == 0:
+		res.Data["skipped"] = "no entries"
+	default:
+		if err := r.writeDigest(ctx, notePath, digest, period); err != nil {
+			res.Failed = 1
+			res.Errors = append(res.Errors, fmt.Sprintf("write digest %s: %v", notePath, err))
+			finalizeResult(&res, started)
+			return res, err
+		}
+		res.Succeeded = res.Processed
+		logging.FromContext(ctx).Info("digest written", "period", period.Label, "journal_path", notePath, "entries", len(digest.Entries))
+	}
+	finalizeResult(&res, started)
+	return res, nil
+}
+
+func (r *Runner) writeDigest(ctx context.Context, notePath string, digest journal.Digest, period DigestPeriod) error {
+	exists, err := r.obsidian.FileExists(ctx, notePath)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		content := journal.NewJournalContent(period.From)
+		if period.Weekly {
+			content = journal.NewNoteContent(notePath, time.Now(), "digest")
+		}
+		if err := r.obsidian.CreateFile(ctx, notePath, content); err != nil {
+			return err
+		}
+	}
+	content, err := r.obsidian.ReadFile(ctx, notePath)
+	if err != nil {
+		return err
+	}
+	if replaced, ok := journal.ReplaceDigest(content, digest); ok {
+		if replaced == content {
+			return nil
+		}
+		target, ok := journal.DigestHeadingTarget(content, digest)
+		if !ok {
+			return fmt.Errorf("digest heading not found in %s", notePath)
+		}
+		return r.obsidian.ReplaceHeading(ctx, notePath, target, digest.RenderBody())
+	}
+	return r.obsidian.AppendFile(ctx, notePath, digest.Render())
+}
+
+func (r *Runner) readNote(ctx context.Context, cache map[string]string, notePath string) string {
+	if content, ok := cache[notePath]; ok {
+		return content
+	}
+	clients := []string{""}
+	for _, sink := range r.cfg.Sinks {
+		clients = append(clients, sink.Name)
+	}
+	for _, name := range clients {
+		client, ok := r.sinkClient(name)
+		if !ok {
+			continue
+		}
+		if exists, err := client.FileExists(ctx, notePath); err != nil || !exists {
+			continue
+		}
+		content, err := client.ReadFile(ctx, notePath)
+		if err != nil {
+			logging.FromContext(ctx).Debug("digest note unreadable", "journal_path", notePath, "sink", orPrimary(name), "error", err)
+			continue
+		}
+		cache[notePath] = content
+		return content
+	}
+	cache[notePath] = ""
+	return ""
+}
+
+func (r *Runner) tasksNotePath() string {
+	notePath := r.cfg.TasksNotePath
+	if !strings.HasSuffix(notePath, ".md") {
+		notePath += ".md"
+	}
+	return notePath
+}
@@ -0,0 +1,38 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDigestPeriod(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	now := time.Date(2026, 3, 19, 22, 0, 0, 0, jst)
+
+	cases := []struct {
+		day, week string
+		label     string
+		from, to  string
+	}{
+		{"", "", "2026-03-19", "2026-03-19", "2026-03-20"},
+		{"2026-03-01", "", "2026-03-01", "2026-03-01", "2026-03-02"},
+		{"", "2026-W12", "2026-W12", "2026-03-16", "2026-03-23"},
+		{"", "2026-w01", "2026-W01", "2025-12-29", "2026-01-05"},
+		{"", "2020-W53", "2020-W53", "2020-12-28", "2021-01-04"},
+	}
+	for _, tc := range cases {
+		p, err := ParseDigestPeriod(tc.day, tc.week, now)
+		if err != nil {
+			t.Fatalf("ParseDigestPeriod(%q, %q): %v", tc.day, tc.week, err)
+		}
+		if p.Label != tc.label || p.From.Format("2006-01-02") != tc.from || p.To.Format("2006-01-02") != tc.to || p.From.Location() != jst {
+			t.Fatalf("ParseDigestPeriod(%q, %q) = %s %s..%s", tc.day, tc.week, p.Label, p.From, p.To)
+		}
+	}
+
+	for _, bad := range [][2]string{{"2026-3-1x", ""}, {"", "2026-12"}, {"", "2026-W54"}, {"", "2025-W53"}, {"2026-03-01", "2026-W12"}} {
+		if _, err := ParseDigestPeriod(bad[0], bad[1], now); err == nil {
+			t.Fatalf("expected error for %v", bad)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package pipeline
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const (
+	ExportJSONL = "jsonl"
+	ExportCSV   = "csv"
+)
+
+func (r *Runner) ExportTable(w io.Writer, format, table string) (int, error) {
+	switch format {
+	case ExportJSONL:
+		enc := json.NewEncoder(w)
+		return r.store.ExportRows(table, func(columns []string, values []any) error {
+			row := make(map[string]any, len(columns)+1)
+			row["table"] = table
+			for i, col := range columns {
+				row[col] = values[i]
+			}
+			return enc.Encode(row)
+		})
+	case ExportCSV:
+		cw := csv.NewWriter(w)
+		wroteHeader := false
+		n, err := r.store.ExportRows(table, func(columns []string, values []any) error {
+			if !wroteHeader {
+				if err := cw.Write(columns); err != nil {
+					return err
+				}
+				wroteHeader = true
+			}
+			record := make([]string, len(values))
+			for i, v := range values {
+				record[i] = csvValue(v)
+			}
+			return cw.Write(record)
+		})
+		cw.Flush()
+		if err == nil {
+			err = cw.Error()
+		}
+		return n, err
+	default:
+		return 0, fmt.Errorf("unknown export format %q (want jsonl or csv)", format)
+	}
+}
+
+func csvValue(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case int64:
+		return strconv.FormatInt(t, 10)
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	default:
+		return fmt.Sprint(t)
+	}
+}
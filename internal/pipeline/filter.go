@@ -2,12 +2,16 @@ package pipeline
 
 import (
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"voice-inbox-daemon/internal/discord"
 	"voice-inbox-daemon/internal/journal"
 )
 
+const discordEpochMS = 1420070400000
+
 type CandidateKind string
 
 const (
@@ -90,3 +94,19 @@ func snowflakeCompare(a, b string) int {
 	}
 	return 0
 }
+
+func snowflakeTime(id string) (time.Time, bool) {
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(int64(n>>22) + discordEpochMS), true
+}
+
+func snowflakeAt(t time.Time) int64 {
+	ms := t.UnixMilli() - discordEpochMS
+	if ms < 0 {
+		ms = 0
+	}
+	return ms << 22
+}
@@ -0,0 +1,111 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"voice-inbox-daemon/internal/state"
+)
+
+type InspectArtifact struct {
+	Kind   string `json:"kind"`
+	Path   string `json:"path"`
+	Exists bool   `json:"exists"`
+}
+
+type InspectJournal struct {
+	Path        string `json:"path"`
+	Sink        string `json:"sink"`
+	MarkerFound bool   `json:"marker_found"`
+	Error       string `json:"error,omitempty"`
+}
+
+type InspectReport struct {
+	Source    string               `json:"source"`
+	ID        string               `json:"id"`
+	Message   *state.MessageRecord `json:"message,omitempty"`
+	Capture   *state.CaptureRecord `json:"capture,omitempty"`
+	Artifacts []InspectArtifact    `json:"artifacts,omitempty"`
+	Journal   *InspectJournal      `json:"journal,omitempty"`
+	Events    []state.ItemEvent    `json:"events"`
+}
+
+func (r *Runner) Inspect(ctx context.Context, id string) (Result, error) {
+	started := time.Now()
+	res := Result{Command: "inspect", Data: map[string]any{}}
+	fail := func(err error) (Result, error) {
+		res.Failed = 1
+		res.Errors = append(res.Errors, err.Error())
+		finalizeResult(&res, started)
+		return res, err
+	}
+
+	report := InspectReport{ID: id}
+	var markerSource, journalPath string
+	var paths []InspectArtifact
+
+	msg, found, err := r.store.GetMessage(id)
+	if err != nil {
+		return fail(err)
+	}
+	if found {
+		report.Source = state.SourceDiscord
+		report.Message = &msg
+		markerSource = "discord"
+		journalPath = msg.JournalPath
+		paths = []InspectArtifact{{Kind: "audio", Path: msg.AudioPath}, {Kind: "transcript", Path: msg.TranscriptPath}}
+	} else {
+		capture, found, err := r.store.GetCapture(id)
+		if err != nil {
+			return fail(err)
+		}
+		if !found {
+			return fail(fmt.Errorf("no message or capture with id %s", id))
+		}
+		report.Source = state.SourceCapture
+		report.Capture = &capture
+		markerSource = capture.Source
+		journalPath = capture.JournalPath
+		paths = []InspectArtifact{{Kind: "audio", Path: capture.RawAudioPath}, {Kind: "transcript", Path: capture.TranscriptPath}}
+	}
+
+	for _, a := range paths {
+		if strings.TrimSpace(a.Path) == "" {
+			continue
+		}
+		_, statErr := os.Stat(a.Path)
+		a.Exists = statErr == nil
+		report.Artifacts = append(report.Artifacts, a)
+	}
+
+	if journalPath != "" {
+		report.Journal = &InspectJournal{Path: journalPath}
+		sink, err := r.store.JournalSink(report.Source, id)
+		if err != nil {
+			return fail(err)
+		}
+		report.Journal.Sink = orPrimary(sink)
+		if client, ok := r.sinkClient(sink); !ok {
+			report.Journal.Error = fmt.Sprintf("sink %s is not configured", sink)
+		} else {
+			found, err := r.journalContainsCapture(ctx, client, journalPath, markerSource, id)
+			if err != nil {
+				report.Journal.Error = err.Error()
+			}
+			report.Journal.MarkerFound = found
+		}
+	}
+
+	report.Events, err = r.store.ListItemEvents(id)
+	if err != nil {
+		return fail(err)
+	}
+
+	res.Processed = 1
+	res.Data["item"] = report
+	finalizeResult(&res, started)
+	return res, nil
+}
@@ -0,0 +1,275 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"voice-inbox-daemon/internal/obsidian"
+	"voice-inbox-daemon/internal/state"
+)
+
+var errCircuitOpen = errors.New("circuit open")
+
+type sinkUnavailableError struct {
+	sink string
+	err  error
+}
+
+func (e sinkUnavailableError) Error() string {
+	return fmt.Sprintf("journal sink %s unavailable: %v", e.sink, e.err)
+}
+
+func (e sinkUnavailableError) Unwrap() error {
+	return e.err
+}
+
+func isSinkUnavailable(err error) bool {
+	_, ok := unavailableSink(err)
+	return ok
+}
+
+func unavailableSink(err error) (string, bool) {
+	var se sinkUnavailableError
+	if !errors.As(err, &se) {
+		return "", false
+	}
+	return se.sink, true
+}
+
+type circuits struct {
+	mu   sync.Mutex
+	open map[string]bool
+}
+
+func newCircuits(sinks []string, open bool) *circuits {
+	c := &circuits{open: map[string]bool{}}
+	for _, sink := range sinks {
+		c.open[sink] = open
+	}
+	return c
+}
+
+func (c *circuits) isOpen(sink string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.open[sink]
+}
+
+func (c *circuits) set(sink string, open bool) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	was := c.open[sink]
+	c.open[sink] = open
+	return was != open
+}
+
+func (r *Runner) sinkNames() []string {
+	names := []string{"obsidian"}
+	for _, sink := range r.cfg.Sinks {
+		names = append(names, sink.Name)
+	}
+	return names
+}
+
+func (r *Runner) checkCircuit(dest destination) error {
+	sink := orPrimary(dest.Sink)
+	if r.circuits.isOpen(sink) {
+		return sinkUnavailableError{sink: sink, err: errCircuitOpen}
+	}
+	return nil
+}
+
+func (r *Runner) sinkErr(dest destination, err error) error {
+	if err == nil || !errors.Is(err, obsidian.ErrUnavailable) {
+		return err
+	}
+	sink := orPrimary(dest.Sink)
+	if r.circuits.set(sink, true) {
+		r.logger.Warn("journal sink unavailable; parking items until it is reachable", "sink", sink, "error", err)
+	}
+	return sinkUnavailableError{sink: sink, err: err}
+}
+
+func (r *Runner) ProbeSinks(ctx context.Context) (Result, error) {
+	started := time.Now()
+	res := Result{Command: "sink-probe"}
+
+	pending, err := r.store.CountJournalPending()
+	if err != nil {
+		res.Errors = append(res.Errors, err.Error())
+		res.Failed = 1
+		finalizeResult(&res, started)
+		return res, err
+	}
+	if pending == 0 {
+		finalizeResult(&res, started)
+		return res, nil
+	}
+
+	lock, err := state.AcquireFileLock(r.cfg.LockFilePath)
+	if err != nil {
+		res.Errors = append(res.Errors, err.Error())
+		res.Failed = 1
+		finalizeResult(&res, started)
+		return res, err
+	}
+	defer lock.Release()
+
+	runID, err := r.store.BeginRun("sink-probe", started)
+	if err != nil {
+		res.Errors = append(res.Errors, err.Error())
+		res.Failed = 1
+		finalizeResult(&res, started)
+		return res, err
+	}
+	res.RunID = runID
+	defer func() {
+		_ = r.store.FinishRun(runID, time.Now(), res.Processed, res.Succeeded, res.Failed, res.Errors)
+	}()
+	r = r.forRun(runID)
+
+	r.drainJournalQueue(ctx, &res)
+
+	finalizeResult(&res, started)
+	if res.Failed > 0 {
+		return res, errors.New("journal drain completed with failures")
+	}
+	return res, nil
+}
+
+func (r *Runner) drainJournalQueue(ctx context.Context, res *Result) {
+	pending, err := r.store.CountJournalPending()
+	if err != nil {
+		res.Errors = append(res.Errors, fmt.Sprintf("count journal_pending: %v", err))
+		res.Failed++
+		return
+	}
+	if pending == 0 {
+		return
+	}
+	res.Data = ensureData(res.Data)
+	res.Data["journal_pending"] = pending
+
+	var down []string
+	for _, name := range r.sinkNames() {
+		client, _ := r.sinkClient(name)
+		if _, err := client.Health(ctx); err != nil {
+			if r.circuits.set(name, true) {
+				r.logger.Warn("journal sink unavailable; parking items until it is reachable", "sink", name, "error", err)
+			}
+			down = append(down, name)
+			continue
+		}
+		if r.circuits.set(name, false) {
+			r.logger.Info("journal sink reachable; draining parked items", "sink", name, "journal_pending", pending)
+		}
+	}
+	if len(down) > 0 {
+		res.Data["sinks_unavailable"] = down
+	}
+	for ctx.Err() == nil {
+		healthy := r.healthySinks()
+		if len(healthy) == 0 {
+			return
+		}
+		full, progressed, ok := r.drainBatch(ctx, healthy, res)
+		if !ok || !full || !progressed {
+			return
+		}
+	}
+}
+
+func (r *Runner) healthySinks() []string {
+	var healthy []string
+	for _, name := range r.sinkNames() {
+		if !r.circuits.isOpen(name) {
+			healthy = append(healthy, name)
+		}
+	}
+	return healthy
+}
+
+func (r *Runner) drainBatch(ctx context.Context, sinks []string, res *Result) (bool, bool, bool) {
+	limit := r.cfg.DiscordFetchLimit
+	messages, err := r.store.ListJournalPendingMessages(sinks, limit)
+	if err != nil {
+		res.Errors = append(res.Errors, fmt.Sprintf("list journal_pending messages: %v", err))
+		res.Failed++
+		return false, false, false
+	}
+	captures, err := r.store.ListJournalPendingCaptures(sinks, limit)
+	if err != nil {
+		res.Errors = append(res.Errors, fmt.Sprintf("list journal_pending captures: %v", err))
+		res.Failed++
+		return false, false, false
+	}
+
+	type parkedItem struct {
+		at      time.Time
+		message *state.MessageRecord
+		capture *state.CaptureRecord
+	}
+	items := make([]parkedItem, 0, len(messages)+len(captures))
+	for i := range messages {
+		at, ok := snowflakeTime(messages[i].MessageID)
+		if !ok {
+			at = messages[i].CreatedAt
+		}
+		items = append(items, parkedItem{at: at, message: &messages[i]})
+	}
+	for i := range captures {
+		items = append(items, parkedItem{at: captureTime(captures[i]), capture: &captures[i]})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].at.Before(items[j].at)
+	})
+
+	seq := newJournalSequencer(len(items))
+	outcomes := runPool(r.cfg.WorkerConcurrency, len(items), func(i int) outcome {
+		item := items[i]
+		turn := seq.turn(i)
+		defer turn.release()
+
+		out := outcome{processed: 1}
+		var (
+			id        string
+			succeeded bool
+			requeued  bool
+			procErr   error
+		)
+		if rec := item.message; rec != nil {
+			id = "message " + rec.MessageID
+			succeeded, requeued, procErr = r.processCandidate(ctx, candidateFromRecord(*rec), *rec, turn)
+		} else {
+			rec := item.capture
+			id = "capture " + rec.CaptureID
+			if err := r.store.MarkCaptureProcessing(rec.CaptureID); err != nil {
+				out.fail(false, fmt.Sprintf("%s mark processing: %v", id, err))
+				return out
+			}
+			succeeded, requeued, procErr = r.processStoredCapture(ctx, *rec, turn)
+		}
+		if procErr != nil {
+			if isSinkUnavailable(procErr) {
+				out.parked++
+				return out
+			}
+			out.fail(requeued, fmt.Sprintf("%s: %v", id, procErr))
+			return out
+		}
+		if succeeded {
+			out.succeeded++
+		}
+		return out
+	})
+	progressed := false
+	for _, o := range outcomes {
+		o.applyTo(res)
+		progressed = progressed || o.parked == 0
+	}
+	return len(messages) == limit || len(captures) == limit, progressed, true
+}
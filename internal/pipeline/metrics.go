@@ -0,0 +1,171 @@
+package pipeline
+
+import (
+	"io/fs"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"voice-inbox-daemon/internal/metrics"
+	"voice-inbox-daemon/internal/state"
+)
+
+var (
+	itemsProcessed = metrics.Default.NewCounterVec(
+		"voice_inbox_items_processed_total",
+		"Items picked up for processing.",
+		"source",
+	)
+	itemsSucceeded = metrics.Default.NewCounterVec(
+		"voice_inbox_items_succeeded_total",
+		"Items journaled and acknowledged.",
+		"source",
+	)
+	itemsFailed = metrics.Default.NewCounterVec(
+		"voice_inbox_items_failed_total",
+		"Processing attempts that failed, by the last stage the item had reached.",
+		"source", "stage",
+	)
+	stagesCompleted = metrics.Default.NewCounterVec(
+		"voice_inbox_stage_completed_total",
+		"Processing stages completed.",
+		"source", "stage",
+	)
+)
+
+const diskUsageTTL = time.Minute
+
+func recordOutcome(source, reachedStage string, succeeded bool) {
+	itemsProcessed.Inc(source)
+	if succeeded {
+		itemsSucceeded.Inc(source)
+		return
+	}
+	if reachedStage == "" {
+		reachedStage = "none"
+	}
+	itemsFailed.Inc(source, reachedStage)
+}
+
+func (r *Runner) reachedStage(source, id string) string {
+	if source == state.SourceDiscord {
+		rec, _, _ := r.store.GetMessage(id)
+		return rec.Stage
+	}
+	rec, _, _ := r.store.GetCapture(id)
+	return rec.Stage
+}
+
+func sourceKind(targetSource string) string {
+	if targetSource == state.SourceDiscord {
+		return state.SourceDiscord
+	}
+	return state.SourceCapture
+}
+
+func (r *Runner) RegisterMetrics(reg *metrics.Registry) {
+	RegisterMetrics(reg, func() *Runner { return r })
+}
+
+func RegisterMetrics(reg *metrics.Registry, current func() *Runner) {
+	var mu sync.Mutex
+	var diskBytes int64
+	var diskCheckedAt time.Time
+
+	reg.RegisterCollector(func() []metrics.Gauge {
+		r := current()
+		now := time.Now()
+		var gauges []metrics.Gauge
+
+		summary, err := r.store.Summary(now, r.cfg.MaxRetryAttempts)
+		if err != nil {
+			r.logger.Warn("metrics: read summary failed", "error", err)
+		} else {
+			for status, n := range summary.ByStatus {
+				gauges = append(gauges, queueGauge(state.SourceDiscord, status, n))
+			}
+			for status, n := range summary.CaptureByStatus {
+				gauges = append(gauges, queueGauge(state.SourceCapture, status, n))
+			}
+			gauges = append(gauges,
+				retryDueGauge(state.SourceDiscord, summary.RetryDue),
+				retryDueGauge(state.SourceCapture, summary.CaptureRetryDue),
+			)
+		}
+
+		oldest, err := r.store.OldestPending()
+		if err != nil {
+			r.logger.Warn("metrics: read oldest pending failed", "error", err)
+		}
+		for _, source := range []string{state.SourceDiscord, state.SourceCapture} {
+			age := 0.0
+			if t, ok := oldest[source]; ok {
+				age = now.Sub(t).Seconds()
+			}
+			gauges = append(gauges, metrics.Gauge{
+				Name:   "voice_inbox_oldest_pending_age_seconds",
+				Help:   "Age of the oldest pending item (0 when nothing is pending).",
+				Labels: map[string]string{"source": source},
+				Value:  age,
+			})
+		}
+
+		if last, ok, err := r.store.LastSuccessfulRun("poll"); err != nil {
+			r.logger.Warn("metrics: read last poll failed", "error", err)
+		} else if ok {
+			gauges = append(gauges, metrics.Gauge{
+				Name:  "voice_inbox_last_successful_poll_timestamp_seconds",
+				Help:  "Unix time at which the last poll without failures finished.",
+				Value: float64(last.Unix()),
+			})
+		}
+
+		mu.Lock()
+		if now.Sub(diskCheckedAt) >= diskUsageTTL {
+			diskBytes = dirSize(r.cfg.AudioStoreDir)
+			diskCheckedAt = now
+		}
+		usage := diskBytes
+		mu.Unlock()
+		gauges = append(gauges, metrics.Gauge{
+			Name:  "voice_inbox_audio_store_bytes",
+			Help:  "Disk usage of AUDIO_STORE_DIR.",
+			Value: float64(usage),
+		})
+		return gauges
+	})
+}
+
+func queueGauge(source, status string, n int) metrics.Gauge {
+	return metrics.Gauge{
+		Name:   "voice_inbox_queue_items",
+		Help:   "Items in the state db by status.",
+		Labels: map[string]string{"source": source, "status": status},
+		Value:  float64(n),
+	}
+}
+
+func retryDueGauge(source string, n int) metrics.Gauge {
+	return metrics.Gauge{
+		Name:   "voice_inbox_retry_due_items",
+		Help:   "Items whose next retry is due now.",
+		Labels: map[string]string{"source": source},
+		Value:  float64(n),
+	}
+}
+
+func dirSize(root string) int64 {
+	var total int64
+	_ = filepath.WalkDir(root, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
@@ -0,0 +1,172 @@
+package pipeline
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+type limiter chan struct{}
+
+func newLimiter(n int) limiter {
+	if n <= 0 {
+		return nil
+	}
+	return make(limiter, n)
+}
+
+func (l limiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	select {
+	case l <- struct{}{}:
+		return func() { <-l }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type outcome struct {
+	processed int
+	succeeded int
+	failed    int
+	requeued  int
+	skipped   int
+	parked    int
+	errors    []string
+}
+
+func (o *outcome) fail(requeued bool, msg string) {
+	o.failed++
+	if requeued {
+		o.requeued++
+	}
+	o.errors = append(o.errors, msg)
+}
+
+func (o outcome) applyTo(res *Result) {
+	res.Processed += o.processed
+	res.Succeeded += o.succeeded
+	res.Failed += o.failed
+	res.Requeued += o.requeued
+	res.Skipped += o.skipped
+	res.Errors = append(res.Errors, o.errors...)
+	if o.parked > 0 {
+		res.Data = ensureData(res.Data)
+		n, _ := res.Data["journal_parked"].(int)
+		res.Data["journal_parked"] = n + o.parked
+	}
+}
+
+func runPool(workers, n int, fn func(i int) outcome) []outcome {
+	out := make([]outcome, n)
+	if workers <= 1 || n <= 1 {
+		for i := 0; i < n; i++ {
+			out[i] = fn(i)
+		}
+		return out
+	}
+	if workers > n {
+		workers = n
+	}
+
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				out[i] = fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return out
+}
+
+type journalSequencer struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	tickets []journalTicket
+}
+
+type journalTicket struct {
+	paths []string
+	done  bool
+}
+
+func newJournalSequencer(n int) *journalSequencer {
+	s := &journalSequencer{tickets: make([]journalTicket, n)}
+	s.cond = sync.NewCond(&s.mu)
+	return s
+}
+
+func (s *journalSequencer) turn(i int) *journalTurn {
+	return &journalTurn{seq: s, index: i}
+}
+
+type journalTurn struct {
+	seq   *journalSequencer
+	index int
+}
+
+func (t *journalTurn) claim(paths ...string) {
+	if t == nil {
+		return
+	}
+	s := t.seq
+	s.mu.Lock()
+	s.tickets[t.index].paths = paths
+	s.cond.Broadcast()
+	s.mu.Unlock()
+}
+
+func (t *journalTurn) wait(paths ...string) {
+	if t == nil {
+		return
+	}
+	s := t.seq
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tickets[t.index].paths = paths
+	s.cond.Broadcast()
+	for s.blocked(t.index, paths) {
+		s.cond.Wait()
+	}
+}
+
+func (s *journalSequencer) blocked(index int, paths []string) bool {
+	for _, earlier := range s.tickets[:index] {
+		if earlier.done {
+			continue
+		}
+		if len(earlier.paths) == 0 {
+			return true
+		}
+		for _, p := range earlier.paths {
+			if slices.Contains(paths, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (t *journalTurn) release() {
+	if t == nil {
+		return
+	}
+	s := t.seq
+	s.mu.Lock()
+	if !s.tickets[t.index].done {
+		s.tickets[t.index].done = true
+		s.cond.Broadcast()
+	}
+	s.mu.Unlock()
+}
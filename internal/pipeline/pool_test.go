@@ -0,0 +1,108 @@
+package pipeline
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestJournalSequencerOrdersSameNote(t *testing.T) {
+	seq := newJournalSequencer(3)
+	var mu sync.Mutex
+	var order []int
+	var wg sync.WaitGroup
+
+	appendAs := func(i int, path string, delay time.Duration) {
+		defer wg.Done()
+		turn := seq.turn(i)
+		defer turn.release()
+		time.Sleep(delay)
+		turn.wait(path)
+		mu.Lock()
+		order = append(order, i)
+		mu.Unlock()
+	}
+
+	wg.Add(3)
+	go appendAs(0, "a.md", 60*time.Millisecond)
+	go appendAs(1, "b.md", 0)
+	go appendAs(2, "a.md", 0)
+	wg.Wait()
+
+	pos := map[int]int{}
+	for p, i := range order {
+		pos[i] = p
+	}
+	if pos[2] < pos[0] {
+		t.Fatalf("expected later append to a.md to wait for earlier one, got %v", order)
+	}
+}
+
+func TestJournalSequencerSkipsReleasedTickets(t *testing.T) {
+	seq := newJournalSequencer(2)
+	seq.turn(0).release()
+
+	done := make(chan struct{})
+	go func() {
+		seq.turn(1).wait("a.md")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("expected released ticket not to block later appends")
+	}
+}
+
+func TestJournalSequencerLetsOtherNotesPassProvisionalClaims(t *testing.T) {
+	seq := newJournalSequencer(2)
+	slow := seq.turn(0)
+	slow.claim("Journal/today.md")
+	defer slow.release()
+
+	done := make(chan struct{})
+	go func() {
+		seq.turn(1).wait("Notes/ideas.md")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("an append to another note should not wait for an item still transcribing")
+	}
+}
+
+func TestJournalSequencerOrdersSharedTasksNote(t *testing.T) {
+	seq := newJournalSequencer(2)
+	first := seq.turn(0)
+	first.claim("Journal/today.md", "Tasks.md")
+
+	done := make(chan struct{})
+	go func() {
+		seq.turn(1).wait("Notes/ideas.md", "Tasks.md")
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatalf("task lines must wait for the earlier entry that also writes the tasks note")
+	case <-time.After(50 * time.Millisecond):
+	}
+	first.release()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("expected the later append to proceed after release")
+	}
+}
+
+func TestRunPoolKeepsOutcomeOrder(t *testing.T) {
+	outcomes := runPool(4, 10, func(i int) outcome {
+		time.Sleep(time.Duration(10-i) * time.Millisecond)
+		return outcome{processed: i}
+	})
+	for i, o := range outcomes {
+		if o.processed != i {
+			t.Fatalf("outcome %d out of order: %+v", i, o)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+	"unicode/utf8"
+
+	"voice-inbox-daemon/internal/llm"
+	"voice-inbox-daemon/internal/logging"
+)
+
+func (r *Runner) postProcess(ctx context.Context, transcript string) llm.Result {
+	if r.llm == nil || utf8.RuneCountInString(transcript) < r.cfg.LLMMinChars {
+		return llm.Result{}
+	}
+	started := time.Now()
+	res, err := r.llm.Summarize(ctx, transcript)
+	if err != nil {
+		logging.FromContext(ctx).Warn("post-processing failed; using raw transcript", "model", r.cfg.LLMModel, "error", err)
+		return llm.Result{}
+	}
+	logging.FromContext(ctx).Info("transcript post-processed", "model", r.cfg.LLMModel, "duration_ms", time.Since(started).Milliseconds())
+	return res
+}
@@ -28,3 +28,7 @@ func (r Result) ExitCode() int {
 func finalizeResult(r *Result, startedAt time.Time) {
 	r.DurationMS = time.Since(startedAt).Milliseconds()
 }
+
+func (r Result) Aborted() bool {
+	return r.Failed > 0 && r.Processed == 0
+}
@@ -4,27 +4,42 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"voice-inbox-daemon/internal/config"
 	"voice-inbox-daemon/internal/discord"
 	"voice-inbox-daemon/internal/journal"
+	"voice-inbox-daemon/internal/llm"
+	"voice-inbox-daemon/internal/logging"
 	"voice-inbox-daemon/internal/obsidian"
+	"voice-inbox-daemon/internal/routing"
 	"voice-inbox-daemon/internal/state"
-	"voice-inbox-daemon/internal/transcribe"
+	"voice-inbox-daemon/internal/tagging"
+	"voice-inbox-daemon/internal/tasks"
 )
 
 const checkMarkEmojiEscaped = "%E2%9C%85"
 
 type Runner struct {
-	cfg      config.Config
-	store    *state.Store
-	discord  *discord.Client
-	obsidian *obsidian.Client
+	cfg            config.Config
+	store          *state.Store
+	discord        *discord.Client
+	obsidian       *obsidian.Client
+	sinks          map[string]*obsidian.Client
+	router         *routing.Router
+	tasks          *tasks.Extractor
+	tagger         *tagging.Tagger
+	llm            *llm.Client
+	circuits       *circuits
+	downloads      limiter
+	transcriptions limiter
+	appends        limiter
+	logger         *slog.Logger
 }
 
 type processTarget struct {
@@ -45,6 +60,11 @@ type processTarget struct {
 	AttachmentName     string
 	ContentType        string
 	RawAudioPath       string
+	TranscriptPath     string
+	JournalPath        string
+	Stage              string
+	Audio              state.AudioMetadata
+	Turn               *journalTurn
 }
 
 type processArtifacts struct {
@@ -54,12 +74,66 @@ type processArtifacts struct {
 }
 
 func New(cfg config.Config, store *state.Store, discordClient *discord.Client, obsidianClient *obsidian.Client) *Runner {
-	return &Runner{
-		cfg:      cfg,
-		store:    store,
-		discord:  discordClient,
-		obsidian: obsidianClient,
+	sinks := make(map[string]*obsidian.Client, len(cfg.Sinks))
+	for _, sink := range cfg.Sinks {
+		sinks[sink.Name] = obsidian.New(sink.BaseURL, sink.AuthHeader, sink.APIKey, sink.VerifyTLS)
 	}
+	router, err := routing.New(cfg.Routes)
+	if err != nil {
+		slog.Error("routing rules disabled", "error", err)
+	}
+	var extractor *tasks.Extractor
+	if cfg.TasksMode != "off" {
+		if extractor, err = tasks.New(cfg.TasksTriggers, cfg.TasksPatterns); err != nil {
+			slog.Error("task extraction disabled", "error", err)
+		}
+	}
+	tagger, err := tagging.New(cfg.TagHashtags, cfg.TagKeywords)
+	if err != nil {
+		slog.Error("tagging disabled", "error", err)
+	}
+	var postProcessor *llm.Client
+	if cfg.LLMEnabled {
+		postProcessor = llm.New(llm.Options{
+			BaseURL: cfg.LLMBaseURL,
+			APIKey:  cfg.LLMAPIKey,
+			Model:   cfg.LLMModel,
+			Prompt:  cfg.LLMPrompt,
+			Timeout: time.Duration(cfg.LLMTimeoutSeconds) * time.Second,
+		})
+	}
+	r := &Runner{
+		cfg:            cfg,
+		store:          store,
+		discord:        discordClient,
+		obsidian:       obsidianClient,
+		sinks:          sinks,
+		router:         router,
+		tasks:          extractor,
+		tagger:         tagger,
+		llm:            postProcessor,
+		downloads:      newLimiter(cfg.DownloadConcurrency),
+		transcriptions: newLimiter(cfg.TranscribeConcurrency),
+		appends:        newLimiter(cfg.JournalConcurrency),
+		logger:         slog.Default(),
+	}
+	parked, err := store.CountJournalPending()
+	if err != nil {
+		slog.Error("count journal_pending failed; parking new items until the first sink probe", "error", err)
+	}
+	r.circuits = newCircuits(r.sinkNames(), err != nil || parked > 0)
+	return r
+}
+
+func (r *Runner) forRun(runID string) *Runner {
+	scoped := *r
+	scoped.store = r.store.ForRun(runID)
+	scoped.logger = r.logger.With("run_id", runID)
+	return &scoped
+}
+
+func (r *Runner) itemLogger(source, id string) *slog.Logger {
+	return r.logger.With("capture_key", journal.CaptureKey(source, id), "source", source)
 }
 
 func (r *Runner) Doctor(ctx context.Context) (Result, error) {
@@ -69,6 +143,7 @@ func (r *Runner) Doctor(ctx context.Context) (Result, error) {
 	type check struct {
 		Name   string `json:"name"`
 		Pass   bool   `json:"pass"`
+		Warn   bool   `json:"warn,omitempty"`
 		Detail string `json:"detail"`
 	}
 	checks := make([]check, 0, 8)
@@ -83,9 +158,17 @@ func (r *Runner) Doctor(ctx context.Context) (Result, error) {
 		}
 		checks = append(checks, check{Name: name, Pass: true, Detail: okDetail})
 	}
+	addAdvisory := func(name string, err error, okDetail, impact string) {
+		if err != nil {
+			checks = append(checks, check{Name: name, Pass: false, Warn: true, Detail: err.Error() + "; " + impact})
+			return
+		}
+		checks = append(checks, check{Name: name, Pass: true, Detail: okDetail})
+	}
 
 	addCheck("whisper_bin", checkExecutable(r.cfg.WhisperBin), "found")
 	addCheck("ffmpeg_bin", checkExecutable(r.cfg.FFmpegBin), "found")
+	addAdvisory("ffprobe_bin", checkExecutable(r.cfg.FFprobeBin), "found", "audio metadata is not probed")
 	addCheck("db_writable", r.store.SetKV("doctor_last_run", time.Now().UTC().Format(time.RFC3339)), "ok")
 
 	if me, err := r.discord.Me(ctx); err != nil {
@@ -101,6 +184,20 @@ func (r *Runner) Doctor(ctx context.Context) (Result, error) {
 	} else {
 		addCheck("obsidian_api", nil, "authenticated=true")
 	}
+	for _, sink := range r.cfg.Sinks {
+		name := "sink:" + sink.Name
+		if health, err := r.sinks[sink.Name].Health(ctx); err != nil {
+			addCheck(name, err, "")
+		} else if !health.Authenticated {
+			addCheck(name, errors.New("authenticated=false"), "")
+		} else {
+			addCheck(name, nil, "authenticated=true")
+		}
+	}
+
+	if r.llm != nil {
+		addAdvisory("llm_api", r.llm.Ping(ctx), r.cfg.LLMBaseURL+" reachable", "transcripts are journaled without post-processing")
+	}
 
 	res.Data["checks"] = checks
 	res.Failed = failures
@@ -133,20 +230,54 @@ func (r *Runner) PollOnce(ctx context.Context) (Result, error) {
 	}
 	res.RunID = runID
 	defer func() {
-		_ = r.store.FinishRun(runID, time.Now(), res.Processed, res.Succeeded, res.Failed)
+		_ = r.store.FinishRun(runID, time.Now(), res.Processed, res.Succeeded, res.Failed, res.Errors)
 	}()
+	r = r.forRun(runID)
+
+	r.drainJournalQueue(ctx, &res)
+
+	channels := r.cfg.PollChannels()
+	fetchFailures := 0
+	var fetchErr error
+	for _, ch := range channels {
+		if err := r.pollChannel(ctx, ch, &res); err != nil {
+			fetchFailures++
+			fetchErr = err
+		}
+	}
+	if fetchFailures == len(channels) {
+		finalizeResult(&res, started)
+		return res, fetchErr
+	}
+
+	retryCandidates, err := r.store.ListRetryCandidates(time.Now(), r.cfg.DiscordFetchLimit)
+	if err != nil {
+		res.Errors = append(res.Errors, fmt.Sprintf("list retry candidates: %v", err))
+		res.Failed++
+	} else {
+		r.processRetryCandidates(ctx, retryCandidates, &res)
+	}
+	r.processReadyCaptures(ctx, &res)
+
+	finalizeResult(&res, started)
+	if res.Failed > 0 {
+		return res, errors.New("poll completed with failures")
+	}
+	return res, nil
+}
 
-	lastSeen, _, err := r.store.GetKV("last_seen_message_id")
+func (r *Runner) pollChannel(ctx context.Context, ch config.Channel, res *Result) error {
+	seenKey := r.lastSeenKey(ch.ID)
+	lastSeen, _, err := r.store.GetKV(seenKey)
 	if err != nil {
-		res.Errors = append(res.Errors, fmt.Sprintf("read last_seen_message_id: %v", err))
+		res.Errors = append(res.Errors, fmt.Sprintf("read %s: %v", seenKey, err))
 	}
 
-	messages, err := r.discord.FetchMessages(ctx, r.cfg.VoiceInboxChannelID, lastSeen, r.cfg.DiscordFetchLimit)
+	messages, err := r.discord.FetchMessages(ctx, ch.ID, lastSeen, r.cfg.DiscordFetchLimit)
 	if err != nil {
 		res.Errors = append(res.Errors, err.Error())
-		res.Failed = 1
-		finalizeResult(&res, started)
-		return res, err
+		res.Failed++
+		return err
 	}
 
 	maxSeen := lastSeen
@@ -156,7 +287,11 @@ func (r *Runner) PollOnce(ctx context.Context) (Result, error) {
 		}
 	}
 
-	candidates := FilterMessages(messages, r.cfg.AllowedAuthorIDs)
+	allowed := make(map[string]struct{}, len(ch.AllowedAuthorIDs))
+	for _, id := range ch.AllowedAuthorIDs {
+		allowed[id] = struct{}{}
+	}
+	candidates := FilterMessages(messages, allowed)
 	fallbackGuildID := ""
 	for _, m := range messages {
 		if strings.TrimSpace(m.GuildID) != "" {
@@ -165,7 +300,7 @@ func (r *Runner) PollOnce(ctx context.Context) (Result, error) {
 		}
 	}
 	if fallbackGuildID == "" {
-		if ch, chErr := r.discord.GetChannel(ctx, r.cfg.VoiceInboxChannelID); chErr == nil {
+		if ch, chErr := r.discord.GetChannel(ctx, ch.ID); chErr == nil {
 			fallbackGuildID = ch.GuildID
 		}
 	}
@@ -176,17 +311,25 @@ func (r *Runner) PollOnce(ctx context.Context) (Result, error) {
 			}
 		}
 	}
-	for _, c := range candidates {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return snowflakeCompare(candidates[i].Message.ID, candidates[j].Message.ID) < 0
+	})
+	seq := newJournalSequencer(len(candidates))
+	outcomes := runPool(r.cfg.WorkerConcurrency, len(candidates), func(i int) outcome {
+		c := candidates[i]
+		turn := seq.turn(i)
+		defer turn.release()
+
+		var out outcome
 		rec, found, getErr := r.store.GetMessage(c.Message.ID)
 		if getErr != nil {
-			res.Processed++
-			res.Failed++
-			res.Errors = append(res.Errors, fmt.Sprintf("message %s lookup: %v", c.Message.ID, getErr))
-			continue
+			out.processed++
+			out.fail(false, fmt.Sprintf("message %s lookup: %v", c.Message.ID, getErr))
+			return out
 		}
 		if found && rec.Status == "done" {
-			res.Skipped++
-			continue
+			out.skipped++
+			return out
 		}
 
 		recUpsert := state.MessageRecord{
@@ -201,52 +344,47 @@ func (r *Runner) PollOnce(ctx context.Context) (Result, error) {
 			DiscordJumpURL:     c.JumpURL,
 		}
 		if err := r.store.UpsertPending(recUpsert); err != nil {
-			res.Processed++
-			res.Failed++
-			res.Errors = append(res.Errors, fmt.Sprintf("message %s upsert: %v", c.Message.ID, err))
-			continue
+			out.processed++
+			out.fail(false, fmt.Sprintf("message %s upsert: %v", c.Message.ID, err))
+			return out
 		}
 
-		attempts := 0
-		if found {
-			attempts = rec.Attempts
+		if !found {
+			rec = state.MessageRecord{}
 		}
 
-		res.Processed++
-		succeeded, requeued, procErr := r.processCandidate(ctx, c, attempts)
+		out.processed++
+		succeeded, requeued, procErr := r.processCandidate(ctx, c, rec, turn)
 		if procErr != nil {
-			res.Failed++
-			if requeued {
-				res.Requeued++
+			if isSinkUnavailable(procErr) {
+				out.parked++
+				return out
 			}
-			res.Errors = append(res.Errors, fmt.Sprintf("message %s: %v", c.Message.ID, procErr))
-			continue
+			out.fail(requeued, fmt.Sprintf("message %s: %v", c.Message.ID, procErr))
+			return out
 		}
 		if succeeded {
-			res.Succeeded++
+			out.succeeded++
 		}
+		return out
+	})
+	for _, o := range outcomes {
+		o.applyTo(res)
 	}
 
 	if maxSeen != "" && maxSeen != lastSeen {
-		if err := r.store.SetKV("last_seen_message_id", maxSeen); err != nil {
-			res.Errors = append(res.Errors, fmt.Sprintf("update last_seen_message_id: %v", err))
+		if err := r.store.SetKV(seenKey, maxSeen); err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("update %s: %v", seenKey, err))
 		}
 	}
+	return nil
+}
 
-	retryCandidates, err := r.store.ListRetryCandidates(time.Now(), r.cfg.DiscordFetchLimit)
-	if err != nil {
-		res.Errors = append(res.Errors, fmt.Sprintf("list retry candidates: %v", err))
-		res.Failed++
-	} else {
-		r.processRetryCandidates(ctx, retryCandidates, &res)
-	}
-	r.processReadyCaptures(ctx, &res)
-
-	finalizeResult(&res, started)
-	if res.Failed > 0 {
-		return res, errors.New("poll completed with failures")
+func (r *Runner) lastSeenKey(channelID string) string {
+	if channelID == r.cfg.VoiceInboxChannelID {
+		return "last_seen_message_id"
 	}
-	return res, nil
+	return "last_seen_message_id:" + channelID
 }
 
 func (r *Runner) Retry(ctx context.Context) (Result, error) {
@@ -271,8 +409,9 @@ func (r *Runner) Retry(ctx context.Context) (Result, error) {
 	}
 	res.RunID = runID
 	defer func() {
-		_ = r.store.FinishRun(runID, time.Now(), res.Processed, res.Succeeded, res.Failed)
+		_ = r.store.FinishRun(runID, time.Now(), res.Processed, res.Succeeded, res.Failed, res.Errors)
 	}()
+	r = r.forRun(runID)
 
 	candidates, err := r.store.ListRetryCandidates(time.Now(), r.cfg.DiscordFetchLimit)
 	if err != nil {
@@ -282,6 +421,7 @@ func (r *Runner) Retry(ctx context.Context) (Result, error) {
 		return res, err
 	}
 
+	r.drainJournalQueue(ctx, &res)
 	r.processRetryCandidates(ctx, candidates, &res)
 	r.processReadyCaptures(ctx, &res)
 
@@ -314,8 +454,9 @@ func (r *Runner) ProcessCapturesOnce(ctx context.Context) (Result, error) {
 	}
 	res.RunID = runID
 	defer func() {
-		_ = r.store.FinishRun(runID, time.Now(), res.Processed, res.Succeeded, res.Failed)
+		_ = r.store.FinishRun(runID, time.Now(), res.Processed, res.Succeeded, res.Failed, res.Errors)
 	}()
+	r = r.forRun(runID)
 
 	r.processReadyCaptures(ctx, &res)
 
@@ -327,18 +468,25 @@ func (r *Runner) ProcessCapturesOnce(ctx context.Context) (Result, error) {
 }
 
 func (r *Runner) processRetryCandidates(ctx context.Context, candidates []state.MessageRecord, res *Result) {
-	for _, rec := range candidates {
-		res.Processed++
+	seq := newJournalSequencer(len(candidates))
+	outcomes := runPool(r.cfg.WorkerConcurrency, len(candidates), func(i int) outcome {
+		rec := candidates[i]
+		turn := seq.turn(i)
+		defer turn.release()
+
+		var out outcome
+		out.processed++
 		if rec.Status == "reaction_pending" {
+			logger := r.itemLogger(state.SourceDiscord, rec.MessageID)
 			if err := r.discord.AddReaction(ctx, rec.ChannelID, rec.MessageID, checkMarkEmojiEscaped); err != nil {
 				attempts := rec.Attempts + 1
+				logger.Warn("reaction retry failed", "stage", state.StageJournaled, "attempt", attempts, "requeued", attempts < r.cfg.MaxRetryAttempts, "error", err)
 				if attempts >= r.cfg.MaxRetryAttempts {
 					if markErr := r.store.MarkFailed(rec.MessageID, err.Error(), attempts, nil); markErr != nil {
-						res.Errors = append(res.Errors, fmt.Sprintf("message %s mark permanent failed: %v", rec.MessageID, markErr))
+						out.errors = append(out.errors, fmt.Sprintf("message %s mark permanent failed: %v", rec.MessageID, markErr))
 					}
-					res.Failed++
-					res.Errors = append(res.Errors, fmt.Sprintf("message %s reaction retry exhausted: %v", rec.MessageID, err))
-					continue
+					out.fail(false, fmt.Sprintf("message %s reaction retry exhausted: %v", rec.MessageID, err))
+					return out
 				}
 				next := NextRetryAt(time.Now(), attempts, r.cfg.RetryBaseSeconds, r.cfg.RetryMaxSeconds)
 				if markErr := r.store.MarkReactionPending(
@@ -351,56 +499,61 @@ func (r *Runner) processRetryCandidates(ctx context.Context, candidates []state.
 					rec.TranscriptPath,
 					rec.DiscordJumpURL,
 				); markErr != nil {
-					res.Errors = append(res.Errors, fmt.Sprintf("message %s mark reaction_pending: %v", rec.MessageID, markErr))
+					out.errors = append(out.errors, fmt.Sprintf("message %s mark reaction_pending: %v", rec.MessageID, markErr))
 				}
-				res.Failed++
-				res.Requeued++
-				res.Errors = append(res.Errors, fmt.Sprintf("message %s reaction retry: %v", rec.MessageID, err))
-				continue
+				out.fail(true, fmt.Sprintf("message %s reaction retry: %v", rec.MessageID, err))
+				return out
 			}
 
 			if err := r.store.MarkDone(rec.MessageID, rec.JournalPath, rec.AudioPath, rec.TranscriptPath, rec.DiscordJumpURL); err != nil {
-				res.Failed++
-				res.Errors = append(res.Errors, fmt.Sprintf("message %s mark done after reaction retry: %v", rec.MessageID, err))
-				continue
+				out.fail(false, fmt.Sprintf("message %s mark done after reaction retry: %v", rec.MessageID, err))
+				return out
 			}
-			res.Succeeded++
-			continue
+			logger.Info("item done", "stage", state.StageAcknowledged, "journal_path", rec.JournalPath)
+			out.succeeded++
+			return out
 		}
 
 		if rec.Attempts >= r.cfg.MaxRetryAttempts {
-			res.Skipped++
-			continue
+			out.skipped++
+			return out
 		}
 
-		c := Candidate{
-			Message: discord.Message{
-				ID:        rec.MessageID,
-				ChannelID: rec.ChannelID,
-				Content:   rec.MessageContent,
-				Author:    discord.User{ID: rec.AuthorID},
-			},
-			Attachment: discord.Attachment{
-				ID:          rec.AttachmentID,
-				URL:         rec.AttachmentURL,
-				Filename:    rec.AttachmentFilename,
-				ContentType: rec.ContentType,
-			},
-			Kind:    kindFromContentType(rec.ContentType),
-			JumpURL: rec.DiscordJumpURL,
-		}
-		succeeded, requeued, procErr := r.processCandidate(ctx, c, rec.Attempts)
+		succeeded, requeued, procErr := r.processCandidate(ctx, candidateFromRecord(rec), rec, turn)
 		if procErr != nil {
-			res.Failed++
-			if requeued {
-				res.Requeued++
+			if isSinkUnavailable(procErr) {
+				out.parked++
+				return out
 			}
-			res.Errors = append(res.Errors, fmt.Sprintf("message %s retry: %v", rec.MessageID, procErr))
-			continue
+			out.fail(requeued, fmt.Sprintf("message %s retry: %v", rec.MessageID, procErr))
+			return out
 		}
 		if succeeded {
-			res.Succeeded++
+			out.succeeded++
 		}
+		return out
+	})
+	for _, o := range outcomes {
+		o.applyTo(res)
+	}
+}
+
+func candidateFromRecord(rec state.MessageRecord) Candidate {
+	return Candidate{
+		Message: discord.Message{
+			ID:        rec.MessageID,
+			ChannelID: rec.ChannelID,
+			Content:   rec.MessageContent,
+			Author:    discord.User{ID: rec.AuthorID},
+		},
+		Attachment: discord.Attachment{
+			ID:          rec.AttachmentID,
+			URL:         rec.AttachmentURL,
+			Filename:    rec.AttachmentFilename,
+			ContentType: rec.ContentType,
+		},
+		Kind:    kindFromContentType(rec.ContentType),
+		JumpURL: rec.DiscordJumpURL,
 	}
 }
 
@@ -422,31 +575,48 @@ func (r *Runner) processReadyCaptures(ctx context.Context, res *Result) {
 		res.Failed++
 		return
 	}
-	for _, rec := range captures {
-		res.Processed++
+	sort.SliceStable(captures, func(i, j int) bool {
+		return captureTime(captures[i]).Before(captureTime(captures[j]))
+	})
+	seq := newJournalSequencer(len(captures))
+	outcomes := runPool(r.cfg.WorkerConcurrency, len(captures), func(i int) outcome {
+		rec := captures[i]
+		turn := seq.turn(i)
+		defer turn.release()
+
+		out := outcome{processed: 1}
 		if err := r.store.MarkCaptureProcessing(rec.CaptureID); err != nil {
-			res.Failed++
-			res.Errors = append(res.Errors, fmt.Sprintf("capture %s mark processing: %v", rec.CaptureID, err))
-			continue
+			out.fail(false, fmt.Sprintf("capture %s mark processing: %v", rec.CaptureID, err))
+			return out
 		}
 
-		succeeded, requeued, procErr := r.processStoredCapture(ctx, rec)
+		succeeded, requeued, procErr := r.processStoredCapture(ctx, rec, turn)
 		if procErr != nil {
-			res.Failed++
-			if requeued {
-				res.Requeued++
+			if isSinkUnavailable(procErr) {
+				out.parked++
+				return out
 			}
-			res.Errors = append(res.Errors, fmt.Sprintf("capture %s: %v", rec.CaptureID, procErr))
-			continue
+			out.fail(requeued, fmt.Sprintf("capture %s: %v", rec.CaptureID, procErr))
+			return out
 		}
 		if succeeded {
-			res.Succeeded++
+			out.succeeded++
 		}
+		return out
+	})
+	for _, o := range outcomes {
+		o.applyTo(res)
 	}
 }
 
+func captureTime(rec state.CaptureRecord) time.Time {
+	if rec.CapturedAt != nil {
+		return *rec.CapturedAt
+	}
+	return rec.ReceivedAt
+}
+
 func (r *Runner) Cleanup(ctx context.Context) (Result, error) {
-	_ = ctx
 	started := time.Now()
 	res := Result{Command: "cleanup", Data: map[string]any{}}
 
@@ -463,7 +633,7 @@ func (r *Runner) Cleanup(ctx context.Context) (Result, error) {
 	if err == nil {
 		res.RunID = runID
 		defer func() {
-			_ = r.store.FinishRun(runID, time.Now(), res.Processed, res.Succeeded, res.Failed)
+			_ = r.store.FinishRun(runID, time.Now(), res.Processed, res.Succeeded, res.Failed, res.Errors)
 		}()
 	}
 
@@ -590,6 +760,21 @@ func (r *Runner) Cleanup(ctx context.Context) (Result, error) {
 
 	res.Data["capture_audio_removed"] = captureAudioRemoved
 	res.Data["capture_transcript_removed"] = captureTranscriptRemoved
+
+	cachePruned, err := r.store.PruneTranscriptCache(transcriptCutoff)
+	if err != nil {
+		res.Failed++
+		res.Errors = append(res.Errors, fmt.Sprintf("prune transcript cache: %v", err))
+	}
+	res.Data["transcript_cache_pruned"] = cachePruned
+
+	if r.cfg.AttachRetentionDays > 0 {
+		r.cleanupAttachments(ctx, &res)
+	}
+
+	if r.cfg.BackupDir != "" {
+		r.scheduledBackup(&res, started)
+	}
 	finalizeResult(&res, started)
 	if res.Failed > 0 {
 		return res, errors.New("cleanup completed with failures")
@@ -614,24 +799,44 @@ func (r *Runner) Status(_ context.Context) (Result, error) {
 	return res, nil
 }
 
-func (r *Runner) processCandidate(ctx context.Context, c Candidate, previousAttempts int) (bool, bool, error) {
+func (r *Runner) processCandidate(ctx context.Context, c Candidate, prev state.MessageRecord, turn *journalTurn) (bool, bool, error) {
+	previousAttempts := prev.Attempts
+	logger := r.itemLogger(state.SourceDiscord, c.Message.ID)
 	artifacts, err := r.processTarget(ctx, processTarget{
-		Source:         "discord",
-		CaptureID:      c.Message.ID,
-		Kind:           c.Kind,
-		TextContent:    c.Message.Content,
-		ChannelID:      c.Message.ChannelID,
-		MessageID:      c.Message.ID,
-		AuthorID:       c.Message.Author.ID,
-		GuildID:        c.Message.GuildID,
-		JumpURL:        c.JumpURL,
-		AttachmentID:   c.Attachment.ID,
-		AttachmentURL:  c.Attachment.URL,
-		AttachmentName: c.Attachment.Filename,
-		ContentType:    c.Attachment.ContentType,
+		Source:             "discord",
+		CaptureID:          c.Message.ID,
+		PreTranscribedText: prev.TranscriptText,
+		Kind:               c.Kind,
+		TextContent:        c.Message.Content,
+		ChannelID:          c.Message.ChannelID,
+		MessageID:          c.Message.ID,
+		AuthorID:           c.Message.Author.ID,
+		GuildID:            c.Message.GuildID,
+		JumpURL:            c.JumpURL,
+		AttachmentID:       c.Attachment.ID,
+		AttachmentURL:      c.Attachment.URL,
+		AttachmentName:     c.Attachment.Filename,
+		ContentType:        c.Attachment.ContentType,
+		RawAudioPath:       prev.AudioPath,
+		TranscriptPath:     prev.TranscriptPath,
+		JournalPath:        prev.JournalPath,
+		Stage:              prev.Stage,
+		Audio:              prev.Audio,
+		Turn:               turn,
 	})
 	if err != nil {
-		return false, r.scheduleFailure(c.Message.ID, previousAttempts, err), err
+		if sink, ok := unavailableSink(err); ok {
+			if markErr := r.store.MarkJournalPending(c.Message.ID, sink, err.Error()); markErr != nil {
+				return false, false, fmt.Errorf("%v; and mark journal_pending failed: %w", err, markErr)
+			}
+			logger.Warn("item parked", "stage", r.reachedStage(state.SourceDiscord, c.Message.ID), "status", "journal_pending", "error", err)
+			return false, false, err
+		}
+		requeued := r.scheduleFailure(c.Message.ID, previousAttempts, err)
+		stage := r.reachedStage(state.SourceDiscord, c.Message.ID)
+		recordOutcome(state.SourceDiscord, stage, false)
+		logger.Warn("item failed", "stage", stage, "attempt", previousAttempts+1, "requeued", requeued, "error", err)
+		return false, requeued, err
 	}
 
 	jumpURL := c.JumpURL
@@ -652,6 +857,8 @@ func (r *Runner) processCandidate(ctx context.Context, c Candidate, previousAtte
 			artifacts.TranscriptPath,
 			jumpURL,
 		)
+		recordOutcome(state.SourceDiscord, state.StageJournaled, false)
+		logger.Warn("reaction failed", "stage", state.StageJournaled, "attempt", attempts, "requeued", true, "error", err)
 		if markErr != nil {
 			return false, true, fmt.Errorf("reaction failed: %v; and mark reaction_pending failed: %w", err, markErr)
 		}
@@ -659,12 +866,16 @@ func (r *Runner) processCandidate(ctx context.Context, c Candidate, previousAtte
 	}
 
 	if err := r.store.MarkDone(c.Message.ID, artifacts.JournalPath, artifacts.RawAudioPath, artifacts.TranscriptPath, jumpURL); err != nil {
+		recordOutcome(state.SourceDiscord, state.StageJournaled, false)
 		return false, false, err
 	}
+	recordOutcome(state.SourceDiscord, "", true)
+	logger.Info("item done", "stage", state.StageAcknowledged, "journal_path", artifacts.JournalPath)
 	return true, false, nil
 }
 
-func (r *Runner) processStoredCapture(ctx context.Context, rec state.CaptureRecord) (bool, bool, error) {
+func (r *Runner) processStoredCapture(ctx context.Context, rec state.CaptureRecord, turn *journalTurn) (bool, bool, error) {
+	logger := r.itemLogger(rec.Source, rec.CaptureID)
 	kind := kindFromContentType(rec.ContentType)
 	if strings.TrimSpace(rec.RawAudioPath) != "" {
 		kind = CandidateKindAudio
@@ -679,17 +890,38 @@ func (r *Runner) processStoredCapture(ctx context.Context, rec state.CaptureReco
 		Kind:               kind,
 		ContentType:        rec.ContentType,
 		RawAudioPath:       rec.RawAudioPath,
+		TranscriptPath:     rec.TranscriptPath,
+		JournalPath:        rec.JournalPath,
+		Stage:              rec.Stage,
+		Audio:              rec.Audio,
+		Turn:               turn,
 	})
 	if err != nil {
-		return false, r.scheduleCaptureFailure(rec.CaptureID, rec.Attempts, err), err
+		if sink, ok := unavailableSink(err); ok {
+			if markErr := r.store.MarkCaptureJournalPending(rec.CaptureID, sink, err.Error()); markErr != nil {
+				return false, false, fmt.Errorf("%v; and mark journal_pending failed: %w", err, markErr)
+			}
+			logger.Warn("item parked", "stage", r.reachedStage(state.SourceCapture, rec.CaptureID), "status", "journal_pending", "error", err)
+			return false, false, err
+		}
+		requeued := r.scheduleCaptureFailure(rec.CaptureID, rec.Attempts, err)
+		stage := r.reachedStage(state.SourceCapture, rec.CaptureID)
+		recordOutcome(state.SourceCapture, stage, false)
+		logger.Warn("item failed", "stage", stage, "attempt", rec.Attempts+1, "requeued", requeued, "error", err)
+		return false, requeued, err
 	}
 	if err := r.store.MarkCaptureDone(rec.CaptureID, artifacts.JournalPath, artifacts.TranscriptPath); err != nil {
+		recordOutcome(state.SourceCapture, state.StageJournaled, false)
 		return false, false, err
 	}
+	recordOutcome(state.SourceCapture, "", true)
+	logger.Info("item done", "stage", state.StageAcknowledged, "journal_path", artifacts.JournalPath)
 	return true, false, nil
 }
 
 func (r *Runner) processTarget(ctx context.Context, target processTarget) (processArtifacts, error) {
+	defer target.Turn.release()
+	ctx = logging.NewContext(ctx, r.itemLogger(target.Source, target.CaptureID))
 	now := time.Now()
 
 	kind := target.Kind
@@ -706,82 +938,144 @@ func (r *Runner) processTarget(ctx context.Context, target processTarget) (proce
 		return processArtifacts{}, errors.New("unsupported capture kind")
 	}
 
+	if state.StageReached(target.Stage, state.StageJournaled) && strings.TrimSpace(target.JournalPath) != "" {
+		return processArtifacts{
+			JournalPath:    target.JournalPath,
+			RawAudioPath:   target.RawAudioPath,
+			TranscriptPath: target.TranscriptPath,
+		}, nil
+	}
+
+	target.Turn.claim(r.notePaths(journal.FilePath(r.cfg.VaultJournalDir, now), r.cfg.TasksMode == "note")...)
+
 	var transcriptText string
 	transcriptPath := ""
 	audioPath := target.RawAudioPath
+	audioMeta := target.Audio
 
 	if preTranscribed := strings.TrimSpace(target.PreTranscribedText); preTranscribed != "" {
 		transcriptText = preTranscribed
+		transcriptPath = target.TranscriptPath
+		if strings.TrimSpace(target.RawAudioPath) != "" {
+			if meta, err := r.probeAudio(ctx, target, target.RawAudioPath); err == nil {
+				audioMeta = meta
+			}
+		}
 	} else if kind == CandidateKindText {
 		transcriptText = strings.TrimSpace(target.TextContent)
 	} else {
 		origPath := target.RawAudioPath
-		if strings.TrimSpace(origPath) == "" {
+		if strings.TrimSpace(origPath) == "" || (target.Source == "discord" && !fileExists(origPath)) {
 			subdir := now.Format("2006/01/02")
 			prefix := fmt.Sprintf("%s_%s", target.CaptureID, target.AttachmentID)
 			origPath = filepath.Join(r.cfg.AudioStoreDir, subdir, prefix+".orig")
-			if err := r.discord.DownloadAttachment(ctx, target.AttachmentURL, origPath); err != nil {
+			release, err := r.downloads.acquire(ctx)
+			if err != nil {
+				return processArtifacts{}, err
+			}
+			err = r.discord.DownloadAttachment(ctx, target.AttachmentURL, origPath)
+			release()
+			if err != nil {
 				return processArtifacts{}, err
 			}
 			audioPath = origPath
+			if err := r.advanceStage(target, state.StageDownloaded, state.StageOutput{AudioPath: origPath}); err != nil {
+				return processArtifacts{}, err
+			}
 		}
 
-		baseDir := filepath.Dir(origPath)
-		baseName := strings.TrimSuffix(filepath.Base(origPath), filepath.Ext(origPath))
-		wavPath := filepath.Join(baseDir, baseName+"_16k.wav")
-		transcriptDir := filepath.Join(baseDir, "transcripts")
-
-		if err := transcribe.NormalizeToWav(ctx, r.cfg.FFmpegBin, origPath, wavPath); err != nil {
+		meta, err := r.probeAudio(ctx, target, origPath)
+		if err != nil {
 			return processArtifacts{}, err
 		}
+		audioMeta = meta
 
-		txCtx, cancel := transcribe.ContextWithTranscriptionTimeout(ctx)
-		defer cancel()
-
-		txRes, err := transcribe.RunWhisper(
-			txCtx,
-			transcribe.WhisperConfig{Bin: r.cfg.WhisperBin, Model: r.cfg.WhisperModel, Language: r.cfg.WhisperLanguage},
-			wavPath,
-			transcriptDir,
-		)
+		release, err := r.transcriptions.acquire(ctx)
 		if err != nil {
 			return processArtifacts{}, err
 		}
-		if err := os.Remove(wavPath); err != nil && !os.IsNotExist(err) {
-			log.Printf("cleanup normalized wav %s: %v", wavPath, err)
+		txRes, err := r.transcribeOriginal(ctx, journal.CaptureKey(target.Source, target.CaptureID), origPath)
+		release()
+		if err != nil {
+			return processArtifacts{}, err
 		}
 		transcriptText = txRes.Text
 		transcriptPath = txRes.TranscriptJSON
+		if err := r.advanceStage(target, state.StageTranscribed, state.StageOutput{
+			TranscriptText: transcriptText,
+			TranscriptPath: transcriptPath,
+		}); err != nil {
+			return processArtifacts{}, err
+		}
 	}
 
-	journalPath := journal.FilePath(r.cfg.VaultJournalDir, now)
-	exists, err := r.obsidian.FileExists(ctx, journalPath)
+	dest, err := r.destination(ctx, target, transcriptText, now)
 	if err != nil {
 		return processArtifacts{}, err
 	}
-	if !exists {
-		if err := r.obsidian.CreateFile(ctx, journalPath, journal.NewJournalContent(now)); err != nil {
-			return processArtifacts{}, err
-		}
+	if err := r.checkCircuit(dest); err != nil {
+		return processArtifacts{}, err
 	}
-
-	entry := journal.BuildEntry(journal.EntryInput{
+	journalPath := dest.Path
+	if body, tags := r.tagger.Apply(dest.Transcript); len(tags) > 0 {
+		dest.Transcript = body
+		dest.Tags = journal.MergeTags(dest.Tags, tags)
+		logging.FromContext(ctx).Info("entry tagged", "tags", strings.Join(tags, ","))
+	}
+	found := r.tasks.Extract(dest.Transcript, itemTime(target, now))
+	notePaths := r.notePaths(dest.Path, r.cfg.TasksMode == "note" && len(found) > 0)
+	target.Turn.claim(notePaths...)
+	embed, err := r.attachAudio(ctx, target, dest, audioPath, now)
+	if err != nil {
+		return processArtifacts{}, r.sinkErr(dest, err)
+	}
+	in := journal.EntryInput{
 		Now:        now,
-		Transcript: transcriptText,
+		Transcript: dest.Transcript,
 		Source:     target.Source,
 		CaptureID:  target.CaptureID,
 		DeviceID:   target.DeviceID,
-	})
-
-	alreadyLogged, err := r.journalContainsCapture(ctx, journalPath, target.Source, target.CaptureID)
-	if err != nil {
-		return processArtifacts{}, err
+		Duration:   time.Duration(audioMeta.DurationMS) * time.Millisecond,
+		Tags:       dest.Tags,
+		Audio:      embed,
 	}
-	if !alreadyLogged {
-		if err := r.obsidian.AppendFile(ctx, journalPath, entry); err != nil {
-			return processArtifacts{}, err
+	if kind != CandidateKindText {
+		post := r.postProcess(ctx, dest.Transcript)
+		in.Title, in.Summary, in.Cleaned = post.Title, post.Summary, post.Cleaned
+	}
+	if r.cfg.TasksMode == "inline" {
+		in.Tasks = found
+	}
+	entry := journal.NewEntry(in)
+	target.Turn.wait(notePaths...)
+	if err := r.appendJournal(ctx, target, dest, now, entry.Render()); err != nil {
+		return processArtifacts{}, r.sinkErr(dest, err)
+	}
+	if r.cfg.TasksMode == "note" && len(found) > 0 {
+		if err := r.appendTasks(ctx, target, dest, entry, found, now); err != nil {
+			return processArtifacts{}, r.sinkErr(dest, err)
 		}
 	}
+	if len(found) > 0 {
+		logging.FromContext(ctx).Info("tasks extracted", "count", len(found), "mode", r.cfg.TasksMode)
+	}
+	target.Turn.release()
+	if err := r.store.IndexTranscript(state.TranscriptDoc{
+		ItemID:      target.CaptureID,
+		Source:      target.Source,
+		DeviceID:    target.DeviceID,
+		CapturedAt:  itemTime(target, now),
+		JournalPath: journalPath,
+		Text:        transcriptText,
+	}); err != nil {
+		logging.FromContext(ctx).Warn("index transcript failed", "stage", state.StageTranscribed, "error", err)
+	}
+	if err := r.advanceStage(target, state.StageJournaled, state.StageOutput{JournalPath: journalPath, JournalSink: orPrimary(dest.Sink)}); err != nil {
+		return processArtifacts{}, err
+	}
+	if err := r.store.DeleteTranscriptChunks(journal.CaptureKey(target.Source, target.CaptureID)); err != nil {
+		logging.FromContext(ctx).Warn("clear transcription checkpoints failed", "stage", state.StageJournaled, "error", err)
+	}
 
 	return processArtifacts{
 		JournalPath:    journalPath,
@@ -790,20 +1084,199 @@ func (r *Runner) processTarget(ctx context.Context, target processTarget) (proce
 	}, nil
 }
 
-func (r *Runner) journalContainsCapture(ctx context.Context, journalPath, source, captureID string) (bool, error) {
-	content, err := r.obsidian.ReadFile(ctx, journalPath)
+type destination struct {
+	Path       string
+	Sink       string
+	Client     *obsidian.Client
+	Routed     bool
+	Transcript string
+	Tags       []string
+}
+
+func (r *Runner) destination(ctx context.Context, target processTarget, transcript string, now time.Time) (destination, error) {
+	decision, matched, err := r.router.Route(routing.Input{
+		Now:        now,
+		Transcript: transcript,
+		Source:     target.Source,
+		DeviceID:   target.DeviceID,
+		ChannelID:  target.ChannelID,
+	})
+	if err != nil {
+		return destination{}, permanent(err)
+	}
+	dest := destination{
+		Path:       journal.FilePath(r.cfg.VaultJournalDir, now),
+		Client:     r.obsidian,
+		Transcript: decision.Transcript,
+		Tags:       decision.Tags,
+	}
+	if !matched {
+		return dest, nil
+	}
+	if decision.Sink != "" && decision.Sink != "obsidian" {
+		client, ok := r.sinks[decision.Sink]
+		if !ok {
+			return destination{}, permanent(fmt.Errorf("route %s: unknown sink %q", decision.Route, decision.Sink))
+		}
+		dest.Client = client
+		dest.Sink = decision.Sink
+		for _, sink := range r.cfg.Sinks {
+			if sink.Name == decision.Sink {
+				dest.Path = journal.FilePath(sink.JournalDir, now)
+			}
+		}
+	}
+	if decision.Path != "" {
+		dest.Path = decision.Path
+		dest.Routed = true
+	}
+	logging.FromContext(ctx).Info("entry routed", "route", decision.Route, "sink", orPrimary(decision.Sink), "journal_path", dest.Path)
+	return dest, nil
+}
+
+func orPrimary(sink string) string {
+	if sink == "" {
+		return "obsidian"
+	}
+	return sink
+}
+
+func (r *Runner) notePaths(journalPath string, withTasks bool) []string {
+	if withTasks {
+		return []string{journalPath, r.tasksNotePath()}
+	}
+	return []string{journalPath}
+}
+
+func (r *Runner) appendJournal(ctx context.Context, target processTarget, dest destination, now time.Time, entry string) error {
+	release, err := r.appends.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	exists, err := dest.Client.FileExists(ctx, dest.Path)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		content := journal.NewJournalContent(now, dest.Tags...)
+		if dest.Routed {
+			content = journal.NewNoteContent(dest.Path, now, dest.Tags...)
+		}
+		if err := dest.Client.CreateFile(ctx, dest.Path, content); err != nil {
+			return err
+		}
+	}
+
+	content, err := dest.Client.ReadFile(ctx, dest.Path)
+	if err != nil {
+		return err
+	}
+	if !containsCapture(content, target.Source, target.CaptureID) {
+		if err := dest.Client.AppendFile(ctx, dest.Path, entry); err != nil {
+			return err
+		}
+	}
+	r.mergeFrontmatterTags(ctx, dest, content)
+	return nil
+}
+
+func (r *Runner) mergeFrontmatterTags(ctx context.Context, dest destination, content string) {
+	if len(dest.Tags) == 0 || !journal.HasFrontmatter(content) {
+		return
+	}
+	existing := journal.FrontmatterTags(content)
+	merged := journal.MergeTags(existing, dest.Tags)
+	if len(merged) == len(existing) {
+		return
+	}
+	if err := dest.Client.SetFrontmatter(ctx, dest.Path, "tags", merged); err != nil {
+		logging.FromContext(ctx).Warn("frontmatter tags not updated", "journal_path", dest.Path, "error", err)
+	}
+}
+
+func (r *Runner) appendTasks(ctx context.Context, target processTarget, dest destination, entry journal.Entry, found []journal.Task, now time.Time) error {
+	release, err := r.appends.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	notePath := r.tasksNotePath()
+	exists, err := dest.Client.FileExists(ctx, notePath)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		if err := dest.Client.CreateFile(ctx, notePath, journal.NewNoteContent(notePath, now)); err != nil {
+			return err
+		}
+	}
+	alreadyLogged, err := r.journalContainsCapture(ctx, dest.Client, notePath, target.Source, target.CaptureID)
+	if err != nil || alreadyLogged {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString("\n")
+	for _, t := range found {
+		t.Link = journal.EntryLink(dest.Path, entry.CaptureKey)
+		b.WriteString(t.Line() + "\n")
+	}
+	b.WriteString("<!-- vi:" + entry.CaptureKey + " -->\n")
+	return dest.Client.AppendFile(ctx, notePath, b.String())
+}
+
+func (r *Runner) advanceStage(target processTarget, stage string, out state.StageOutput) error {
+	var err error
+	if target.Source == "discord" {
+		err = r.store.AdvanceMessageStage(target.MessageID, stage, out)
+	} else {
+		err = r.store.AdvanceCaptureStage(target.CaptureID, stage, out)
+	}
+	if err != nil {
+		return fmt.Errorf("record stage %s: %w", stage, err)
+	}
+	stagesCompleted.Inc(sourceKind(target.Source), stage)
+	return nil
+}
+
+func itemTime(target processTarget, fallback time.Time) time.Time {
+	if target.CapturedAt != nil {
+		return *target.CapturedAt
+	}
+	if target.Source == "discord" {
+		if t, ok := snowflakeTime(target.MessageID); ok {
+			return t
+		}
+	}
+	return fallback
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func (r *Runner) journalContainsCapture(ctx context.Context, client *obsidian.Client, journalPath, source, captureID string) (bool, error) {
+	content, err := client.ReadFile(ctx, journalPath)
 	if err != nil {
 		return false, err
 	}
+	return containsCapture(content, source, captureID), nil
+}
+
+func containsCapture(content, source, captureID string) bool {
 	captureKey := journal.CaptureKey(source, captureID)
 	oldMarker := fmt.Sprintf("capture_key: \"%s\"", captureKey)
 	newMarker := fmt.Sprintf("<!-- vi:%s -->", captureKey)
-	return strings.Contains(content, oldMarker) || strings.Contains(content, newMarker), nil
+	return strings.Contains(content, oldMarker) || strings.Contains(content, newMarker)
 }
 
 func (r *Runner) scheduleFailure(messageID string, previousAttempts int, processErr error) bool {
 	attempts := previousAttempts + 1
-	if attempts >= r.cfg.MaxRetryAttempts {
+	if attempts >= r.cfg.MaxRetryAttempts || isPermanent(processErr) {
 		_ = r.store.MarkFailed(messageID, processErr.Error(), attempts, nil)
 		return false
 	}
@@ -814,7 +1287,7 @@ func (r *Runner) scheduleFailure(messageID string, previousAttempts int, process
 
 func (r *Runner) scheduleCaptureFailure(captureID string, previousAttempts int, processErr error) bool {
 	attempts := previousAttempts + 1
-	if attempts >= r.cfg.MaxRetryAttempts {
+	if attempts >= r.cfg.MaxRetryAttempts || isPermanent(processErr) {
 		_ = r.store.MarkCaptureFailed(captureID, processErr.Error(), attempts, nil)
 		return false
 	}
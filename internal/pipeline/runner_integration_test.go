@@ -2,7 +2,9 @@ package pipeline
 
 import (
 	"context"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,15 +12,19 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"voice-inbox-daemon/internal/config"
 	"voice-inbox-daemon/internal/discord"
 	"voice-inbox-daemon/internal/journal"
+	"voice-inbox-daemon/internal/metrics"
 	"voice-inbox-daemon/internal/obsidian"
+	"voice-inbox-daemon/internal/routing"
 	"voice-inbox-daemon/internal/state"
 )
 
@@ -28,6 +34,7 @@ type discordMock struct {
 	messages     []discord.Message
 	reactionFail bool
 	reactionHits int
+	downloadHits int
 	mu           sync.Mutex
 }
 
@@ -95,6 +102,9 @@ func (d *discordMock) handleAttachment(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusUnauthorized)
 		return
 	}
+	d.mu.Lock()
+	d.downloadHits++
+	d.mu.Unlock()
 	_, _ = w.Write([]byte("FAKE_AUDIO"))
 }
 
@@ -104,6 +114,7 @@ type obsidianMock struct {
 	files      map[string]string
 	appendFail bool
 	appendHits int
+	patchHits  int
 	mu         sync.Mutex
 }
 
@@ -113,6 +124,7 @@ func newObsidianMock(t *testing.T) *obsidianMock {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", om.handleRoot)
 	mux.HandleFunc("/vault/", om.handleVault)
+	mux.HandleFunc("/search/simple/", om.handleSearch)
 	om.server = httptest.NewTLSServer(mux)
 	return om
 }
@@ -129,6 +141,19 @@ func (o *obsidianMock) handleRoot(w http.ResponseWriter, r *http.Request) {
 	_ = json.NewEncoder(w).Encode(map[string]any{"authenticated": true})
 }
 
+func (o *obsidianMock) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("query")
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	results := []map[string]any{}
+	for name, content := range o.files {
+		if strings.HasSuffix(name, ".md") && strings.Contains(content, query) {
+			results = append(results, map[string]any{"filename": name, "score": 1})
+		}
+	}
+	_ = json.NewEncoder(w).Encode(results)
+}
+
 func (o *obsidianMock) handleVault(w http.ResponseWriter, r *http.Request) {
 	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
 		w.WriteHeader(http.StatusUnauthorized)
@@ -166,11 +191,76 @@ func (o *obsidianMock) handleVault(w http.ResponseWriter, r *http.Request) {
 		}
 		o.files[decoded] += string(body)
 		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		if _, ok := o.files[decoded]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(o.files, decoded)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodPatch:
+		content, ok := o.files[decoded]
+		if ok && r.Header.Get("Target-Type") == "heading" {
+			o.patchHits++
+			replaced, found := replaceHeadingSection(content, r.Header.Get("Target"), string(body))
+			if !found {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			o.files[decoded] = replaced
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if !ok || r.Header.Get("Target-Type") != "frontmatter" || r.Header.Get("Target") != "tags" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var tags []string
+		if err := json.Unmarshal(body, &tags); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		lines := strings.Split(content, "\n")
+		for i, line := range lines {
+			if strings.HasPrefix(line, "tags:") {
+				lines[i] = "tags: [" + strings.Join(tags, ", ") + "]"
+				break
+			}
+		}
+		o.files[decoded] = strings.Join(lines, "\n")
+		w.WriteHeader(http.StatusOK)
 	default:
 		w.WriteHeader(http.StatusMethodNotAllowed)
 	}
 }
 
+func replaceHeadingSection(content, rawTarget, body string) (string, bool) {
+	target, err := url.PathUnescape(rawTarget)
+	if err != nil {
+		return "", false
+	}
+	parts := strings.Split(target, "::")
+	title := parts[len(parts)-1]
+	lines := strings.SplitAfter(content, "\n")
+	offset, start, level := 0, -1, 0
+	for _, line := range lines {
+		trimmed := strings.TrimRight(line, "\n")
+		n := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+		isHeading := n > 0 && strings.HasPrefix(trimmed[n:], " ")
+		switch {
+		case start < 0 && isHeading && strings.TrimSpace(trimmed[n:]) == title:
+			start, level = offset+len(line), n
+		case start >= 0 && isHeading && n <= level:
+			return content[:start] + body + content[offset:], true
+		}
+		offset += len(line)
+	}
+	if start < 0 {
+		return "", false
+	}
+	return content[:start] + body, true
+}
+
 func decodeVaultPath(raw string) (string, error) {
 	parts := strings.Split(strings.TrimPrefix(raw, "/"), "/")
 	decoded := make([]string, 0, len(parts))
@@ -196,7 +286,7 @@ func writeFakeBinary(t *testing.T, path, body string) {
 	}
 }
 
-func setupRunner(t *testing.T, dm *discordMock, om *obsidianMock) (*Runner, *state.Store, config.Config, func()) {
+func setupRunner(t *testing.T, dm *discordMock, om *obsidianMock, opts ...func(*config.Config)) (*Runner, *state.Store, config.Config, func()) {
 	t.Helper()
 	tmp := t.TempDir()
 	binDir := filepath.Join(tmp, "bin")
@@ -273,18 +363,23 @@ printf '{"text":"テスト文字起こし"}' > "$out_dir/$base.json"
 		LogDir:                  filepath.Join(tmp, "logs"),
 		LockFilePath:            dbPath + ".lock",
 	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	cleanup := func() {
+		_ = st.Close()
+	}
+	return newTestRunner(cfg, st), st, cfg, cleanup
+}
 
-	runner := New(
+func newTestRunner(cfg config.Config, st *state.Store) *Runner {
+	return New(
 		cfg,
 		st,
 		discord.NewWithBaseURL(cfg.DiscordBotToken, cfg.DiscordAPIBaseURL),
 		obsidian.New(cfg.ObsidianBaseURL, cfg.ObsidianAuthHeader, cfg.ObsidianAPIKey, cfg.ObsidianVerifyTLS),
 	)
-
-	cleanup := func() {
-		_ = st.Close()
-	}
-	return runner, st, cfg, cleanup
 }
 
 func makeMessage(serverURL, messageID string) discord.Message {
@@ -357,6 +452,38 @@ func TestPollOnceSuccessAndNoDuplicate(t *testing.T) {
 	}
 }
 
+func TestPollOnceKeepsPollingOtherChannelsWhenOneFails(t *testing.T) {
+	dm := newDiscordMock(t)
+	defer dm.close()
+	om := newObsidianMock(t)
+	defer om.close()
+
+	dm.messages = []discord.Message{makeMessage(dm.server.URL, "2101")}
+	runner, st, _, cleanup := setupRunner(t, dm, om)
+	defer cleanup()
+	runner.cfg.Channels = []config.Channel{
+		{ID: "999", AllowedAuthorIDs: []string{"968754117885456425"}},
+		{ID: "1476388224124325909", AllowedAuthorIDs: []string{"968754117885456425"}},
+	}
+
+	res, err := runner.PollOnce(context.Background())
+	if err == nil {
+		t.Fatalf("expected the unreachable channel to be reported")
+	}
+	if res.Succeeded != 1 || res.Failed != 1 || res.Aborted() {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if rec, found, _ := st.GetMessage("2101"); !found || rec.Status != "done" {
+		t.Fatalf("expected message from the healthy channel to be done: %+v", rec)
+	}
+	if v, _, _ := st.GetKV("last_seen_message_id"); v != "2101" {
+		t.Fatalf("expected primary channel cursor to advance, got %q", v)
+	}
+	if _, found, _ := st.GetKV("last_seen_message_id:999"); found {
+		t.Fatalf("failed channel must not get a cursor")
+	}
+}
+
 func TestPollOnceObsidianAppendFailure(t *testing.T) {
 	dm := newDiscordMock(t)
 	defer dm.close()
@@ -389,6 +516,27 @@ func TestPollOnceObsidianAppendFailure(t *testing.T) {
 	if rec.NextRetryAt == nil {
 		t.Fatalf("expected next_retry_at to be set")
 	}
+
+	list, err := runner.RunList(context.Background(), state.RunFilter{Command: "poll", FailedOnly: true})
+	if err != nil {
+		t.Fatalf("list runs: %v", err)
+	}
+	runs, _ := list.Data["runs"].([]state.RunRecord)
+	if len(runs) != 1 || runs[0].RunID != res.RunID || runs[0].Failed != 1 || len(runs[0].Errors) != len(res.Errors) {
+		t.Fatalf("expected the failed poll with its errors, got %+v", runs)
+	}
+
+	show, err := runner.RunShow(context.Background(), res.RunID)
+	if err != nil {
+		t.Fatalf("show run: %v", err)
+	}
+	items, _ := show.Data["items"].([]RunItem)
+	if len(items) != 1 || items[0].ID != "2001" || items[0].LastEvent != "failed" || items[0].Error == "" {
+		t.Fatalf("expected run to list the failed message, got %+v", items)
+	}
+	if _, err := runner.RunShow(context.Background(), "no-such-run"); !errors.Is(err, ErrRunNotFound) {
+		t.Fatalf("expected ErrRunNotFound, got %v", err)
+	}
 }
 
 func TestPollOnceProcessesDueRetries(t *testing.T) {
@@ -440,6 +588,70 @@ func TestPollOnceProcessesDueRetries(t *testing.T) {
 	}
 }
 
+func TestPollOnceRetryResumesFromTranscribedStage(t *testing.T) {
+	dm := newDiscordMock(t)
+	defer dm.close()
+	om := newObsidianMock(t)
+	defer om.close()
+	om.appendFail = true
+
+	dm.messages = []discord.Message{makeMessage(dm.server.URL, "2601")}
+	runner, st, cfg, cleanup := setupRunner(t, dm, om)
+	defer cleanup()
+
+	if _, err := runner.PollOnce(context.Background()); err == nil {
+		t.Fatalf("expected initial poll failure")
+	}
+
+	rec, found, err := st.GetMessage("2601")
+	if err != nil || !found {
+		t.Fatalf("expected stored message: found=%v err=%v", found, err)
+	}
+	if rec.Stage != state.StageTranscribed {
+		t.Fatalf("expected transcribed stage after journal failure, got %q", rec.Stage)
+	}
+	if rec.TranscriptText != "テスト文字起こし" || rec.AudioPath == "" {
+		t.Fatalf("expected stage outputs to be stored, got %+v", rec)
+	}
+	summary, err := st.Summary(time.Now(), cfg.MaxRetryAttempts)
+	if err != nil {
+		t.Fatalf("summary: %v", err)
+	}
+	if summary.ByStage[state.StageTranscribed] != 1 {
+		t.Fatalf("expected one message at transcribed stage, got %+v", summary.ByStage)
+	}
+
+	writeFakeBinary(t, cfg.WhisperBin, `#!/bin/sh
+echo "whisper should not run" >&2
+exit 1
+`)
+	past := time.Now().Add(-time.Minute)
+	if err := st.MarkFailed(rec.MessageID, rec.LastError, rec.Attempts, &past); err != nil {
+		t.Fatalf("force retry due: %v", err)
+	}
+	om.appendFail = false
+	dm.messages = nil
+
+	res, err := runner.PollOnce(context.Background())
+	if err != nil {
+		t.Fatalf("retry should resume at journal stage: %v", err)
+	}
+	if res.Succeeded != 1 {
+		t.Fatalf("unexpected retry result: %+v", res)
+	}
+	if dm.downloadHits != 1 {
+		t.Fatalf("expected attachment to be downloaded once, got %d", dm.downloadHits)
+	}
+
+	rec, _, err = st.GetMessage("2601")
+	if err != nil {
+		t.Fatalf("get message: %v", err)
+	}
+	if rec.Status != "done" || rec.Stage != state.StageAcknowledged {
+		t.Fatalf("expected done/acknowledged, got %s/%s", rec.Status, rec.Stage)
+	}
+}
+
 func TestPollOnceReactionFailureGoesReactionPending(t *testing.T) {
 	dm := newDiscordMock(t)
 	defer dm.close()
@@ -509,212 +721,505 @@ func TestPollOnceTextMessage(t *testing.T) {
 	}
 }
 
-func TestProcessCapturesOnceAppendsJournalAndMarksDone(t *testing.T) {
+func TestPollOnceRoutesEntriesByRules(t *testing.T) {
 	dm := newDiscordMock(t)
 	defer dm.close()
 	om := newObsidianMock(t)
 	defer om.close()
 
-	runner, st, cfg, cleanup := setupRunner(t, dm, om)
-	defer cleanup()
-
-	audioPath := filepath.Join(cfg.AudioStoreDir, "ingest", "memo.m4a")
-	if err := os.MkdirAll(filepath.Dir(audioPath), 0o755); err != nil {
-		t.Fatalf("mkdir capture dir: %v", err)
-	}
-	if err := os.WriteFile(audioPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
-		t.Fatalf("write capture audio: %v", err)
-	}
-
-	capturedAt := time.Now().Add(-2 * time.Minute).UTC()
-	if err := st.CreateCapture(state.CaptureRecord{
-		CaptureID:       "cap-1001",
-		Source:          "android-voice-inbox",
-		SourceDedupeKey: "cap-1001",
-		DeviceID:        "pixel-8a",
-		CapturedAt:      &capturedAt,
-		ReceivedAt:      time.Now().UTC(),
-		RawAudioPath:    audioPath,
-		ContentType:     "audio/mp4",
-		Status:          "pending",
-	}); err != nil {
-		t.Fatalf("create capture: %v", err)
+	dm.messages = []discord.Message{
+		makeTextMessage("4101", "Idea: 音声でタスクを登録する"),
+		makeTextMessage("4102", "project:voice-inbox ルーティングを実装した"),
+		makeTextMessage("4103", "今日は晴れ"),
 	}
+	runner, st, _, cleanup := setupRunner(t, dm, om, func(cfg *config.Config) {
+		cfg.Routes = []routing.Rule{
+			{Name: "ideas", Prefixes: []string{"idea:"}, Path: "Inbox/Ideas.md", StripTrigger: true, Tags: []string{"idea"}},
+			{Name: "projects", Match: `^project:(?P<tag>\S+)`, Sources: []string{"discord"}, Path: "Projects/{{.Tag}}/log"},
+		}
+	})
+	defer cleanup()
 
-	res, err := runner.ProcessCapturesOnce(context.Background())
-	if err != nil {
-		t.Fatalf("process captures once failed: %v", err)
-	}
-	if res.Succeeded != 1 || res.Failed != 0 {
-		t.Fatalf("unexpected result: %+v", res)
+	res, err := runner.PollOnce(context.Background())
+	if err != nil || res.Succeeded != 3 {
+		t.Fatalf("poll once: res=%+v err=%v", res, err)
 	}
 
-	rec, found, err := st.GetCapture("cap-1001")
-	if err != nil || !found {
-		t.Fatalf("expected stored capture: found=%v err=%v", found, err)
+	ideas := om.files["Inbox/Ideas.md"]
+	for _, want := range []string{"type: voice-inbox", "# Ideas", "音声でタスクを登録する\n\n#idea", "<!-- vi:discord:4101 -->"} {
+		if !strings.Contains(ideas, want) {
+			t.Fatalf("ideas note should include %q:\n%s", want, ideas)
+		}
 	}
-	if rec.Status != "done" {
-		t.Fatalf("expected done status, got %s", rec.Status)
+	if strings.Contains(ideas, "Idea:") {
+		t.Fatalf("trigger word should be stripped:\n%s", ideas)
 	}
-	if rec.JournalPath == "" || rec.TranscriptPath == "" {
-		t.Fatalf("expected journal and transcript artifacts, got %+v", rec)
+	project := om.files["Projects/voice-inbox/log.md"]
+	if !strings.Contains(project, "project:voice-inbox ルーティングを実装した") {
+		t.Fatalf("project note should keep the transcript:\n%s", project)
 	}
-
 	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
-	content := om.files[journalPath]
-	if !strings.Contains(content, "<!-- vi:android-voice-inbox:cap-1001 -->") {
-		t.Fatalf("journal should include HTML capture marker")
+	daily := om.files[journalPath]
+	if !strings.Contains(daily, "今日は晴れ") || strings.Contains(daily, "vi:discord:4101") || strings.Contains(daily, "vi:discord:4102") {
+		t.Fatalf("only unmatched entries should reach the daily journal:\n%s", daily)
 	}
-	if !strings.Contains(content, "via pixel-8a") {
-		t.Fatalf("journal should include device label")
+	if rec, _, _ := st.GetMessage("4101"); rec.JournalPath != "Inbox/Ideas.md" {
+		t.Fatalf("expected routed journal path, got %q", rec.JournalPath)
 	}
 }
 
-func TestProcessCapturesOnceProcessesPendingCapture(t *testing.T) {
+func TestPollOnceTagsEntriesAndMergesFrontmatter(t *testing.T) {
 	dm := newDiscordMock(t)
 	defer dm.close()
 	om := newObsidianMock(t)
 	defer om.close()
 
-	runner, st, cfg, cleanup := setupRunner(t, dm, om)
+	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
+	om.files[journalPath] = journal.NewJournalContent(time.Now())
+	dm.messages = []discord.Message{
+		makeTextMessage("4201", "予算の見直しを始めた #Alpha"),
+		makeTextMessage("4202", "Shipped the importer. Hashtag project beta."),
+	}
+	runner, _, _, cleanup := setupRunner(t, dm, om, func(cfg *config.Config) {
+		cfg.TagHashtags = true
+		cfg.TagKeywords = []string{"予算=finance"}
+	})
 	defer cleanup()
 
-	rawPath := filepath.Join(cfg.AudioStoreDir, "ingest", "2026", "03", "19", "capture-5001.ogg")
-	if err := os.MkdirAll(filepath.Dir(rawPath), 0o755); err != nil {
-		t.Fatalf("mkdir raw dir: %v", err)
-	}
-	if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
-		t.Fatalf("write raw audio: %v", err)
-	}
-	capturedAt := time.Date(2026, 3, 19, 11, 0, 0, 0, time.UTC)
-	if err := st.CreateCapture(state.CaptureRecord{
-		CaptureID:    "capture-5001",
-		Source:       "android-voice-inbox",
-		DeviceID:     "pixel-8a",
-		CapturedAt:   &capturedAt,
-		ReceivedAt:   time.Now().UTC(),
-		RawAudioPath: rawPath,
-		ContentType:  "audio/ogg",
-		Status:       "pending",
-	}); err != nil {
-		t.Fatalf("create capture: %v", err)
-	}
-
-	res, err := runner.ProcessCapturesOnce(context.Background())
-	if err != nil {
-		t.Fatalf("process captures once failed: %v", err)
-	}
-	if res.Succeeded != 1 || res.Failed != 0 {
-		t.Fatalf("unexpected result: %+v", res)
-	}
-
-	rec, found, err := st.GetCapture("capture-5001")
-	if err != nil || !found {
-		t.Fatalf("expected stored capture: found=%v err=%v", found, err)
-	}
-	if rec.Status != "done" {
-		t.Fatalf("expected done status, got %s", rec.Status)
-	}
-
-	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
-	content := om.files[journalPath]
-	if !strings.Contains(content, "<!-- vi:android-voice-inbox:capture-5001 -->") {
-		t.Fatalf("journal should include capture marker")
+	res, err := runner.PollOnce(context.Background())
+	if err != nil || res.Succeeded != 2 {
+		t.Fatalf("poll once: res=%+v err=%v", res, err)
+	}
+
+	daily := om.files[journalPath]
+	for _, want := range []string{
+		"tags: [journal, alpha, finance, project-beta]",
+		"予算の見直しを始めた #Alpha\n\n#alpha #finance",
+		"Shipped the importer.\n\n#project-beta",
+	} {
+		if !strings.Contains(daily, want) {
+			t.Fatalf("daily note should include %q:\n%s", want, daily)
+		}
 	}
-	if !strings.Contains(content, "テスト文字起こし") {
-		t.Fatalf("journal should include transcript")
+	if strings.Contains(daily, "Hashtag project beta") {
+		t.Fatalf("spoken hashtag should be removed from the body:\n%s", daily)
 	}
 }
 
-func TestProcessCapturesOnceUsesPreTranscribedTextAndSkipsWhisper(t *testing.T) {
+func TestProcessCapturesOnceEmbedsAudioInVault(t *testing.T) {
 	dm := newDiscordMock(t)
 	defer dm.close()
 	om := newObsidianMock(t)
 	defer om.close()
 
-	runner, st, cfg, cleanup := setupRunner(t, dm, om)
+	runner, st, cfg, cleanup := setupRunner(t, dm, om, func(cfg *config.Config) {
+		cfg.AttachMode = "original"
+		cfg.AttachDir = "Attachments/Voice Inbox"
+	})
 	defer cleanup()
 
-	cfg.WhisperBin = filepath.Join(t.TempDir(), "missing-whisper")
-	runner = New(
-		cfg,
-		st,
-		discord.NewWithBaseURL(cfg.DiscordBotToken, cfg.DiscordAPIBaseURL),
-		obsidian.New(cfg.ObsidianBaseURL, cfg.ObsidianAuthHeader, cfg.ObsidianAPIKey, cfg.ObsidianVerifyTLS),
-	)
-
-	rawPath := filepath.Join(cfg.AudioStoreDir, "ingest", "2026", "03", "19", "capture-pretranscribed.ogg")
+	capturedAt := time.Date(2026, 3, 19, 9, 5, 0, 0, time.Local)
+	rawPath := filepath.Join(cfg.AudioStoreDir, "ingest", "capture-audio.bin")
 	if err := os.MkdirAll(filepath.Dir(rawPath), 0o755); err != nil {
-		t.Fatalf("mkdir raw dir: %v", err)
+		t.Fatal(err)
 	}
 	if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
-		t.Fatalf("write raw audio: %v", err)
+		t.Fatal(err)
 	}
 	if err := st.CreateCapture(state.CaptureRecord{
-		CaptureID:      "capture-pretranscribed",
+		CaptureID:      "capture-audio",
 		Source:         "android-voice-inbox",
-		DeviceID:       "pixel-8a",
+		CapturedAt:     &capturedAt,
 		ReceivedAt:     time.Now().UTC(),
 		RawAudioPath:   rawPath,
-		ContentType:    "audio/ogg",
-		TranscriptText: "Android already transcribed this",
+		ContentType:    "audio/ogg; codecs=opus",
+		TranscriptText: "録音を vault で聞けるようにする",
 		Status:         "pending",
 	}); err != nil {
-		t.Fatalf("create capture: %v", err)
+		t.Fatal(err)
 	}
 
-	res, err := runner.ProcessCapturesOnce(context.Background())
-	if err != nil {
-		t.Fatalf("process captures once failed: %v", err)
-	}
-	if res.Succeeded != 1 || res.Failed != 0 {
-		t.Fatalf("unexpected result: %+v", res)
+	if res, err := runner.ProcessCapturesOnce(context.Background()); err != nil || res.Succeeded != 1 {
+		t.Fatalf("process captures: res=%+v err=%v", res, err)
 	}
 
-	rec, found, err := st.GetCapture("capture-pretranscribed")
-	if err != nil || !found {
-		t.Fatalf("expected stored capture: found=%v err=%v", found, err)
-	}
-	if rec.Status != "done" {
-		t.Fatalf("expected done status, got %s", rec.Status)
-	}
-	if rec.TranscriptPath != "" {
-		t.Fatalf("expected no whisper transcript artifact for pre-transcribed capture, got %q", rec.TranscriptPath)
+	vaultPath := "Attachments/Voice Inbox/2026/2026-03-19_090500_android-voice-inbox-capture-audio.ogg"
+	if om.files[vaultPath] != "FAKE_AUDIO" {
+		t.Fatalf("expected uploaded attachment at %s, got %q", vaultPath, om.files[vaultPath])
 	}
-
 	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
-	content := om.files[journalPath]
-	if !strings.Contains(content, "Android already transcribed this") {
-		t.Fatalf("journal should include pre-transcribed text")
-	}
-	if !strings.Contains(content, "<!-- vi:android-voice-inbox:capture-pretranscribed -->") {
-		t.Fatalf("journal should include HTML marker")
+	if !strings.Contains(om.files[journalPath], "録音を vault で聞けるようにする\n\n![["+vaultPath+"]]") {
+		t.Fatalf("journal should embed the recording:\n%s", om.files[journalPath])
 	}
 }
 
-func TestProcessCapturesOnceTreatsStoredRawFileAsAudioDespiteTextMime(t *testing.T) {
+func TestCleanupKeepsVaultAttachmentsReferencedByAnyNote(t *testing.T) {
 	dm := newDiscordMock(t)
 	defer dm.close()
 	om := newObsidianMock(t)
 	defer om.close()
 
-	runner, st, cfg, cleanup := setupRunner(t, dm, om)
+	runner, st, _, cleanup := setupRunner(t, dm, om, func(cfg *config.Config) {
+		cfg.AttachRetentionDays = 30
+	})
 	defer cleanup()
 
-	rawPath := filepath.Join(cfg.AudioStoreDir, "ingest", "2026", "03", "19", "capture-plain-text.bin")
-	if err := os.MkdirAll(filepath.Dir(rawPath), 0o755); err != nil {
-		t.Fatalf("mkdir raw dir: %v", err)
+	old := time.Now().AddDate(0, 0, -60)
+	om.files["Journal/old.md"] = "![[Attachments/Voice Inbox/2026/kept.ogg]]\n![[Attachments/Voice Inbox/2026/own.ogg]]\n"
+	om.files["Notes/project.md"] = "![[Attachments/Voice Inbox/2026/kept.ogg]]\n"
+	for _, name := range []string{"kept.ogg", "own.ogg", "orphan.ogg", "recent.ogg"} {
+		vaultPath := "Attachments/Voice Inbox/2026/" + name
+		om.files[vaultPath] = "FAKE_AUDIO"
+		createdAt := old
+		if name == "recent.ogg" {
+			createdAt = time.Now()
+		}
+		if err := st.RecordAttachment(state.VaultAttachment{VaultPath: vaultPath, Source: "discord", ItemID: name, NotePath: "Journal/old.md", CreatedAt: createdAt}); err != nil {
+			t.Fatal(err)
+		}
 	}
-	if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
+
+	res, err := runner.Cleanup(context.Background())
+	if err != nil {
+		t.Fatalf("cleanup: %v (%v)", err, res.Errors)
+	}
+	if res.Data["vault_attachments_removed"] != 1 || res.Data["vault_attachments_referenced"] != 2 {
+		t.Fatalf("unexpected cleanup data: %+v", res.Data)
+	}
+	if _, ok := om.files["Attachments/Voice Inbox/2026/orphan.ogg"]; ok {
+		t.Fatalf("orphan.ogg is referenced by no note and should be removed past retention")
+	}
+	for _, name := range []string{"kept.ogg", "own.ogg", "recent.ogg"} {
+		if _, ok := om.files["Attachments/Voice Inbox/2026/"+name]; !ok {
+			t.Fatalf("%s should be kept", name)
+		}
+	}
+}
+
+func TestProcessCapturesOnceSummarizesWithLLMAndDegradesOnFailure(t *testing.T) {
+	dm := newDiscordMock(t)
+	defer dm.close()
+	om := newObsidianMock(t)
+	defer om.close()
+
+	var llmDown atomic.Bool
+	llmSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if llmDown.Load() {
+			http.Error(w, "model not loaded", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"{\"title\":\"買い出し\",\"summary\":\"週末に牛乳と卵を買う。\",\"cleaned\":\"週末に牛乳と卵を買いに行きます。\"}"}}]}`))
+	}))
+	defer llmSrv.Close()
+
+	runner, st, cfg, cleanup := setupRunner(t, dm, om, func(cfg *config.Config) {
+		cfg.LLMEnabled = true
+		cfg.LLMBaseURL = llmSrv.URL
+		cfg.LLMModel = "test"
+		cfg.LLMTimeoutSeconds = 5
+		cfg.LLMMinChars = 10
+	})
+	defer cleanup()
+
+	createCapture := func(id, text string) {
+		t.Helper()
+		rawPath := filepath.Join(cfg.AudioStoreDir, "ingest", id+".ogg")
+		if err := os.MkdirAll(filepath.Dir(rawPath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.CreateCapture(state.CaptureRecord{
+			CaptureID:      id,
+			Source:         "android-voice-inbox",
+			ReceivedAt:     time.Now().UTC(),
+			RawAudioPath:   rawPath,
+			ContentType:    "audio/ogg",
+			TranscriptText: text,
+			Status:         "pending",
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	createCapture("capture-llm", "えーと、あの、週末に、牛乳と、卵を、買いに行く、かな")
+	if res, err := runner.ProcessCapturesOnce(context.Background()); err != nil || res.Succeeded != 1 {
+		t.Fatalf("process captures: res=%+v err=%v", res, err)
+	}
+	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
+	daily := om.files[journalPath]
+	for _, want := range []string{
+		"### 🎤 買い出し\n\n週末に牛乳と卵を買う。\n",
+		"> [!note]- 整形版\n> 週末に牛乳と卵を買いに行きます。\n",
+		"> [!quote]- 文字起こし（原文）\n> えーと、あの、週末に、牛乳と、卵を、買いに行く、かな\n",
+	} {
+		if !strings.Contains(daily, want) {
+			t.Fatalf("journal should include %q:\n%s", want, daily)
+		}
+	}
+
+	llmDown.Store(true)
+	if res, err := runner.Doctor(context.Background()); err != nil || res.Failed != 0 {
+		t.Fatalf("an unreachable LLM should only warn in doctor: res=%+v err=%v", res, err)
+	}
+	createCapture("capture-raw", "サーバーが落ちていても原文はそのまま残る")
+	if res, err := runner.ProcessCapturesOnce(context.Background()); err != nil || res.Succeeded != 1 {
+		t.Fatalf("capture should not be blocked by the LLM: res=%+v err=%v", res, err)
+	}
+	daily = om.files[journalPath]
+	if !strings.Contains(daily, "### 🎤 Voice Inbox\n\nサーバーが落ちていても原文はそのまま残る\n") {
+		t.Fatalf("failed post-processing should fall back to the raw transcript:\n%s", daily)
+	}
+}
+
+func TestDigestSummarizesDayAndWeek(t *testing.T) {
+	dm := newDiscordMock(t)
+	defer dm.close()
+	om := newObsidianMock(t)
+	defer om.close()
+
+	runner, st, cfg, cleanup := setupRunner(t, dm, om, func(cfg *config.Config) {
+		cfg.TasksMode = "inline"
+		cfg.TasksTriggers = []string{"remind me to"}
+		cfg.DigestWeekDir = "01_Projects/Journal/Weekly"
+	})
+	defer cleanup()
+
+	for i, text := range []string{"朝の散歩メモ", "Remind me to send the invoice."} {
+		capturedAt := time.Date(2026, 3, 19, 9+i, 0, 0, 0, time.Local)
+		id := fmt.Sprintf("capture-digest-%d", i)
+		rawPath := filepath.Join(cfg.AudioStoreDir, "ingest", id+".ogg")
+		if err := os.MkdirAll(filepath.Dir(rawPath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.CreateCapture(state.CaptureRecord{
+			CaptureID:      id,
+			Source:         "android-voice-inbox",
+			DeviceID:       "Pixel 8a",
+			CapturedAt:     &capturedAt,
+			ReceivedAt:     time.Now().UTC(),
+			RawAudioPath:   rawPath,
+			ContentType:    "audio/ogg",
+			TranscriptText: text,
+			Status:         "pending",
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if res, err := runner.ProcessCapturesOnce(context.Background()); err != nil || res.Succeeded != 2 {
+		t.Fatalf("process captures: res=%+v err=%v", res, err)
+	}
+	backfilled := strconv.FormatInt(snowflakeAt(time.Date(2026, 3, 19, 11, 0, 0, 0, time.Local)), 10)
+	dm.messages = []discord.Message{makeMessage(dm.server.URL, backfilled)}
+	if res, err := runner.PollOnce(context.Background()); err != nil || res.Succeeded != 1 {
+		t.Fatalf("poll backfilled message: res=%+v err=%v", res, err)
+	}
+
+	day := DayPeriod(time.Date(2026, 3, 19, 12, 0, 0, 0, time.Local))
+	for pass := range 3 {
+		if pass == 1 {
+			om.mu.Lock()
+			om.files["01_Projects/Journal/2026-03-19.md"] = strings.Replace(om.files["01_Projects/Journal/2026-03-19.md"], "- エントリ: 3 件", "- エントリ: 0 件", 1) + "\n## 手書きメモ\n\nObsidian で追記\n"
+			om.mu.Unlock()
+		}
+		res, err := runner.Digest(context.Background(), day, false)
+		if err != nil || res.Data["entries"] != 3 {
+			t.Fatalf("digest: res=%+v err=%v", res, err)
+		}
+	}
+	if om.patchHits != 1 {
+		t.Fatalf("a stale digest should be patched under its heading once, got %d patches", om.patchHits)
+	}
+	note := om.files["01_Projects/Journal/2026-03-19.md"]
+	if !strings.HasSuffix(note, "\n## 手書きメモ\n\nObsidian で追記\n") {
+		t.Fatalf("notes after the digest should be kept:\n%s", note)
+	}
+	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02")
+	for _, want := range []string{
+		"## 📊 ダイジェスト 2026-03-19\n",
+		"- エントリ: 3 件\n",
+		"| Pixel 8a | 2 |",
+		"- send the invoice (" + "[[" + journalPath + "#^vi-android-voice-inbox-capture-digest-1]])",
+		"| Discord | 1 |",
+		"<!-- vi:digest:2026-03-19 -->",
+	} {
+		if !strings.Contains(note, want) {
+			t.Fatalf("day digest should include %q:\n%s", want, note)
+		}
+	}
+	if strings.Count(note, "ダイジェスト 2026-03-19") != 1 {
+		t.Fatalf("rerunning the digest should replace it:\n%s", note)
+	}
+
+	if res, err := runner.Digest(context.Background(), WeekPeriod(day.From), false); err != nil || res.Data["journal_path"] != "01_Projects/Journal/Weekly/2026-W12.md" {
+		t.Fatalf("weekly digest: res=%+v err=%v", res, err)
+	}
+	weekly := om.files["01_Projects/Journal/Weekly/2026-W12.md"]
+	if !strings.Contains(weekly, "tags: [voice-inbox, digest]") || !strings.Contains(weekly, "- 03-19 Thu 10:00 [[") {
+		t.Fatalf("unexpected weekly note:\n%s", weekly)
+	}
+
+	empty, err := runner.Digest(context.Background(), DayPeriod(time.Date(2026, 3, 20, 0, 0, 0, 0, time.Local)), false)
+	if err != nil || empty.Data["skipped"] != "no entries" {
+		t.Fatalf("empty day should be skipped: res=%+v err=%v", empty, err)
+	}
+}
+
+func TestProcessCapturesOnceWritesExtractedTasksToTasksNote(t *testing.T) {
+	dm := newDiscordMock(t)
+	defer dm.close()
+	om := newObsidianMock(t)
+	defer om.close()
+
+	runner, st, cfg, cleanup := setupRunner(t, dm, om, func(cfg *config.Config) {
+		cfg.TasksMode = "note"
+		cfg.TasksNotePath = "Tasks/Voice Inbox"
+		cfg.TasksTriggers = []string{"remind me to", "忘れずに"}
+	})
+	defer cleanup()
+
+	capturedAt := time.Date(2026, 3, 19, 9, 0, 0, 0, time.Local)
+	rawPath := filepath.Join(cfg.AudioStoreDir, "ingest", "capture-tasks.ogg")
+	if err := os.MkdirAll(filepath.Dir(rawPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.CreateCapture(state.CaptureRecord{
+		CaptureID:      "capture-tasks",
+		Source:         "android-voice-inbox",
+		CapturedAt:     &capturedAt,
+		ReceivedAt:     time.Now().UTC(),
+		RawAudioPath:   rawPath,
+		ContentType:    "audio/ogg",
+		TranscriptText: "会議は長かった。Remind me to send the invoice tomorrow. 金曜に資料を忘れずに送る",
+		Status:         "pending",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if res, err := runner.ProcessCapturesOnce(context.Background()); err != nil || res.Succeeded != 1 {
+		t.Fatalf("process captures: res=%+v err=%v", res, err)
+	}
+
+	note := om.files["Tasks/Voice Inbox.md"]
+	for _, want := range []string{
+		"- [ ] send the invoice tomorrow 📅 2026-03-20 ([[01_Projects/Journal/",
+		"- [ ] 金曜に資料を忘れずに送る 📅 2026-03-20 (",
+		"<!-- vi:android-voice-inbox:capture-tasks -->",
+	} {
+		if !strings.Contains(note, want) {
+			t.Fatalf("tasks note should include %q:\n%s", want, note)
+		}
+	}
+	if strings.Contains(note, "会議は長かった") {
+		t.Fatalf("only task sentences belong in the tasks note:\n%s", note)
+	}
+	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
+	if strings.Contains(om.files[journalPath], "- [ ]") {
+		t.Fatalf("note mode should keep checkboxes out of the journal entry")
+	}
+}
+
+func TestProcessCapturesOnceAppendsJournalAndMarksDone(t *testing.T) {
+	dm := newDiscordMock(t)
+	defer dm.close()
+	om := newObsidianMock(t)
+	defer om.close()
+
+	runner, st, cfg, cleanup := setupRunner(t, dm, om)
+	defer cleanup()
+
+	audioPath := filepath.Join(cfg.AudioStoreDir, "ingest", "memo.m4a")
+	if err := os.MkdirAll(filepath.Dir(audioPath), 0o755); err != nil {
+		t.Fatalf("mkdir capture dir: %v", err)
+	}
+	if err := os.WriteFile(audioPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
+		t.Fatalf("write capture audio: %v", err)
+	}
+
+	capturedAt := time.Now().Add(-2 * time.Minute).UTC()
+	if err := st.CreateCapture(state.CaptureRecord{
+		CaptureID:       "cap-1001",
+		Source:          "android-voice-inbox",
+		SourceDedupeKey: "cap-1001",
+		DeviceID:        "pixel-8a",
+		CapturedAt:      &capturedAt,
+		ReceivedAt:      time.Now().UTC(),
+		RawAudioPath:    audioPath,
+		ContentType:     "audio/mp4",
+		Status:          "pending",
+	}); err != nil {
+		t.Fatalf("create capture: %v", err)
+	}
+
+	res, err := runner.ProcessCapturesOnce(context.Background())
+	if err != nil {
+		t.Fatalf("process captures once failed: %v", err)
+	}
+	if res.Succeeded != 1 || res.Failed != 0 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	rec, found, err := st.GetCapture("cap-1001")
+	if err != nil || !found {
+		t.Fatalf("expected stored capture: found=%v err=%v", found, err)
+	}
+	if rec.Status != "done" {
+		t.Fatalf("expected done status, got %s", rec.Status)
+	}
+	if rec.JournalPath == "" || rec.TranscriptPath == "" {
+		t.Fatalf("expected journal and transcript artifacts, got %+v", rec)
+	}
+
+	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
+	content := om.files[journalPath]
+	if !strings.Contains(content, "<!-- vi:android-voice-inbox:cap-1001 -->") {
+		t.Fatalf("journal should include HTML capture marker")
+	}
+	if !strings.Contains(content, "via pixel-8a") {
+		t.Fatalf("journal should include device label")
+	}
+
+	hits, err := st.SearchTranscripts(state.SearchQuery{Text: "文字起こし"})
+	if err != nil {
+		t.Fatalf("search transcripts: %v", err)
+	}
+	if len(hits) != 1 || hits[0].ItemID != "cap-1001" || hits[0].JournalPath != journalPath || hits[0].DeviceID != "pixel-8a" {
+		t.Fatalf("expected processed capture to be searchable, got %+v", hits)
+	}
+}
+
+func TestProcessCapturesOnceProcessesPendingCapture(t *testing.T) {
+	dm := newDiscordMock(t)
+	defer dm.close()
+	om := newObsidianMock(t)
+	defer om.close()
+
+	runner, st, cfg, cleanup := setupRunner(t, dm, om)
+	defer cleanup()
+
+	rawPath := filepath.Join(cfg.AudioStoreDir, "ingest", "2026", "03", "19", "capture-5001.ogg")
+	if err := os.MkdirAll(filepath.Dir(rawPath), 0o755); err != nil {
+		t.Fatalf("mkdir raw dir: %v", err)
+	}
+	if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
 		t.Fatalf("write raw audio: %v", err)
 	}
+	capturedAt := time.Date(2026, 3, 19, 11, 0, 0, 0, time.UTC)
 	if err := st.CreateCapture(state.CaptureRecord{
-		CaptureID:    "capture-plain-text",
+		CaptureID:    "capture-5001",
 		Source:       "android-voice-inbox",
 		DeviceID:     "pixel-8a",
+		CapturedAt:   &capturedAt,
 		ReceivedAt:   time.Now().UTC(),
 		RawAudioPath: rawPath,
-		ContentType:  "text/plain",
+		ContentType:  "audio/ogg",
 		Status:       "pending",
 	}); err != nil {
 		t.Fatalf("create capture: %v", err)
@@ -722,31 +1227,627 @@ func TestProcessCapturesOnceTreatsStoredRawFileAsAudioDespiteTextMime(t *testing
 
 	res, err := runner.ProcessCapturesOnce(context.Background())
 	if err != nil {
-		t.Fatalf("process captures once failed: %v", err)
+		t.Fatalf("process captures once failed: %v", err)
+	}
+	if res.Succeeded != 1 || res.Failed != 0 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	rec, found, err := st.GetCapture("capture-5001")
+	if err != nil || !found {
+		t.Fatalf("expected stored capture: found=%v err=%v", found, err)
+	}
+	if rec.Status != "done" {
+		t.Fatalf("expected done status, got %s", rec.Status)
+	}
+
+	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
+	content := om.files[journalPath]
+	if !strings.Contains(content, "<!-- vi:android-voice-inbox:capture-5001 -->") {
+		t.Fatalf("journal should include capture marker")
+	}
+	if !strings.Contains(content, "テスト文字起こし") {
+		t.Fatalf("journal should include transcript")
+	}
+}
+
+func TestProcessCapturesOnceUsesPreTranscribedTextAndSkipsWhisper(t *testing.T) {
+	dm := newDiscordMock(t)
+	defer dm.close()
+	om := newObsidianMock(t)
+	defer om.close()
+
+	runner, st, cfg, cleanup := setupRunner(t, dm, om)
+	defer cleanup()
+
+	cfg.WhisperBin = filepath.Join(t.TempDir(), "missing-whisper")
+	runner = New(
+		cfg,
+		st,
+		discord.NewWithBaseURL(cfg.DiscordBotToken, cfg.DiscordAPIBaseURL),
+		obsidian.New(cfg.ObsidianBaseURL, cfg.ObsidianAuthHeader, cfg.ObsidianAPIKey, cfg.ObsidianVerifyTLS),
+	)
+
+	rawPath := filepath.Join(cfg.AudioStoreDir, "ingest", "2026", "03", "19", "capture-pretranscribed.ogg")
+	if err := os.MkdirAll(filepath.Dir(rawPath), 0o755); err != nil {
+		t.Fatalf("mkdir raw dir: %v", err)
+	}
+	if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
+		t.Fatalf("write raw audio: %v", err)
+	}
+	if err := st.CreateCapture(state.CaptureRecord{
+		CaptureID:      "capture-pretranscribed",
+		Source:         "android-voice-inbox",
+		DeviceID:       "pixel-8a",
+		ReceivedAt:     time.Now().UTC(),
+		RawAudioPath:   rawPath,
+		ContentType:    "audio/ogg",
+		TranscriptText: "Android already transcribed this",
+		Status:         "pending",
+	}); err != nil {
+		t.Fatalf("create capture: %v", err)
+	}
+
+	res, err := runner.ProcessCapturesOnce(context.Background())
+	if err != nil {
+		t.Fatalf("process captures once failed: %v", err)
+	}
+	if res.Succeeded != 1 || res.Failed != 0 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	rec, found, err := st.GetCapture("capture-pretranscribed")
+	if err != nil || !found {
+		t.Fatalf("expected stored capture: found=%v err=%v", found, err)
+	}
+	if rec.Status != "done" {
+		t.Fatalf("expected done status, got %s", rec.Status)
+	}
+	if rec.TranscriptPath != "" {
+		t.Fatalf("expected no whisper transcript artifact for pre-transcribed capture, got %q", rec.TranscriptPath)
+	}
+
+	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
+	content := om.files[journalPath]
+	if !strings.Contains(content, "Android already transcribed this") {
+		t.Fatalf("journal should include pre-transcribed text")
+	}
+	if !strings.Contains(content, "<!-- vi:android-voice-inbox:capture-pretranscribed -->") {
+		t.Fatalf("journal should include HTML marker")
+	}
+}
+
+func TestProcessCapturesOnceTreatsStoredRawFileAsAudioDespiteTextMime(t *testing.T) {
+	dm := newDiscordMock(t)
+	defer dm.close()
+	om := newObsidianMock(t)
+	defer om.close()
+
+	runner, st, cfg, cleanup := setupRunner(t, dm, om)
+	defer cleanup()
+
+	rawPath := filepath.Join(cfg.AudioStoreDir, "ingest", "2026", "03", "19", "capture-plain-text.bin")
+	if err := os.MkdirAll(filepath.Dir(rawPath), 0o755); err != nil {
+		t.Fatalf("mkdir raw dir: %v", err)
+	}
+	if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
+		t.Fatalf("write raw audio: %v", err)
+	}
+	if err := st.CreateCapture(state.CaptureRecord{
+		CaptureID:    "capture-plain-text",
+		Source:       "android-voice-inbox",
+		DeviceID:     "pixel-8a",
+		ReceivedAt:   time.Now().UTC(),
+		RawAudioPath: rawPath,
+		ContentType:  "text/plain",
+		Status:       "pending",
+	}); err != nil {
+		t.Fatalf("create capture: %v", err)
+	}
+
+	res, err := runner.ProcessCapturesOnce(context.Background())
+	if err != nil {
+		t.Fatalf("process captures once failed: %v", err)
+	}
+	if res.Succeeded != 1 || res.Failed != 0 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	rec, found, err := st.GetCapture("capture-plain-text")
+	if err != nil || !found {
+		t.Fatalf("expected stored capture: found=%v err=%v", found, err)
+	}
+	if rec.Status != "done" {
+		t.Fatalf("expected done status, got %s", rec.Status)
+	}
+
+	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
+	content := om.files[journalPath]
+	if !strings.Contains(content, "テスト文字起こし") {
+		t.Fatalf("journal should include transcript")
+	}
+	if !strings.Contains(content, "<!-- vi:android-voice-inbox:capture-plain-text -->") {
+		t.Fatalf("journal should include HTML marker, got %s", content)
+	}
+}
+
+func TestProcessCapturesOnceBlankTranscriptFallsBackToWhisper(t *testing.T) {
+	dm := newDiscordMock(t)
+	defer dm.close()
+	om := newObsidianMock(t)
+	defer om.close()
+
+	runner, st, cfg, cleanup := setupRunner(t, dm, om)
+	defer cleanup()
+
+	rawPath := filepath.Join(cfg.AudioStoreDir, "ingest", "2026", "03", "19", "capture-blank-transcript.ogg")
+	if err := os.MkdirAll(filepath.Dir(rawPath), 0o755); err != nil {
+		t.Fatalf("mkdir raw dir: %v", err)
+	}
+	if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
+		t.Fatalf("write raw audio: %v", err)
+	}
+	if err := st.CreateCapture(state.CaptureRecord{
+		CaptureID:      "capture-blank-transcript",
+		Source:         "android-voice-inbox",
+		DeviceID:       "pixel-8a",
+		ReceivedAt:     time.Now().UTC(),
+		RawAudioPath:   rawPath,
+		ContentType:    "audio/ogg",
+		TranscriptText: "   ",
+		Status:         "pending",
+	}); err != nil {
+		t.Fatalf("create capture: %v", err)
+	}
+
+	res, err := runner.ProcessCapturesOnce(context.Background())
+	if err != nil {
+		t.Fatalf("process captures once failed: %v", err)
+	}
+	if res.Succeeded != 1 || res.Failed != 0 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	rec, found, err := st.GetCapture("capture-blank-transcript")
+	if err != nil || !found {
+		t.Fatalf("expected stored capture: found=%v err=%v", found, err)
+	}
+	if rec.Status != "done" {
+		t.Fatalf("expected done status, got %s", rec.Status)
+	}
+	if rec.TranscriptPath == "" {
+		t.Fatalf("expected whisper transcript artifact for blank transcript fallback")
+	}
+
+	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
+	content := om.files[journalPath]
+	if !strings.Contains(content, "テスト文字起こし") {
+		t.Fatalf("journal should include whisper transcript")
+	}
+	if !strings.Contains(content, "<!-- vi:android-voice-inbox:capture-blank-transcript -->") {
+		t.Fatalf("journal should include HTML marker for fallback path")
+	}
+}
+
+func TestProcessTargetDefaultsUnknownMimeRawFileToAudio(t *testing.T) {
+	dm := newDiscordMock(t)
+	defer dm.close()
+	om := newObsidianMock(t)
+	defer om.close()
+
+	runner, _, cfg, cleanup := setupRunner(t, dm, om)
+	defer cleanup()
+
+	rawPath := filepath.Join(cfg.AudioStoreDir, "ingest", "2026", "03", "19", "capture-octet-stream.bin")
+	if err := os.MkdirAll(filepath.Dir(rawPath), 0o755); err != nil {
+		t.Fatalf("mkdir raw dir: %v", err)
+	}
+	if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
+		t.Fatalf("write raw audio: %v", err)
+	}
+
+	artifacts, err := runner.processTarget(context.Background(), processTarget{
+		Source:       "android-voice-inbox",
+		CaptureID:    "capture-octet-stream",
+		ContentType:  "application/octet-stream",
+		RawAudioPath: rawPath,
+	})
+	if err != nil {
+		t.Fatalf("process target failed: %v", err)
+	}
+	if artifacts.TranscriptPath == "" {
+		t.Fatalf("expected transcript artifact, got %+v", artifacts)
+	}
+
+	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
+	content := om.files[journalPath]
+	if !strings.Contains(content, "テスト文字起こし") {
+		t.Fatalf("journal should include transcript")
+	}
+	if !strings.Contains(content, "<!-- vi:android-voice-inbox:capture-octet-stream -->") {
+		t.Fatalf("journal should include capture marker")
+	}
+
+	normalizedPath := filepath.Join(filepath.Dir(rawPath), "capture-octet-stream_16k.wav")
+	if _, err := os.Stat(normalizedPath); !os.IsNotExist(err) {
+		t.Fatalf("expected normalized wav cleanup, stat err=%v", err)
+	}
+}
+
+func TestProcessTargetJournalDedupeIncludesSource(t *testing.T) {
+	dm := newDiscordMock(t)
+	defer dm.close()
+	om := newObsidianMock(t)
+	defer om.close()
+
+	runner, _, cfg, cleanup := setupRunner(t, dm, om)
+	defer cleanup()
+
+	rawDir := filepath.Join(cfg.AudioStoreDir, "ingest", "2026", "03", "19")
+	if err := os.MkdirAll(rawDir, 0o755); err != nil {
+		t.Fatalf("mkdir raw dir: %v", err)
+	}
+
+	firstPath := filepath.Join(rawDir, "shared-id-discord.ogg")
+	if err := os.WriteFile(firstPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
+		t.Fatalf("write first raw audio: %v", err)
+	}
+	if _, err := runner.processTarget(context.Background(), processTarget{
+		Source:       "discord",
+		CaptureID:    "shared-id",
+		ContentType:  "audio/ogg",
+		RawAudioPath: firstPath,
+	}); err != nil {
+		t.Fatalf("process first target failed: %v", err)
+	}
+
+	secondPath := filepath.Join(rawDir, "shared-id-android.ogg")
+	if err := os.WriteFile(secondPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
+		t.Fatalf("write second raw audio: %v", err)
+	}
+	if _, err := runner.processTarget(context.Background(), processTarget{
+		Source:       "android-voice-inbox",
+		CaptureID:    "shared-id",
+		ContentType:  "audio/ogg",
+		RawAudioPath: secondPath,
+	}); err != nil {
+		t.Fatalf("process second target failed: %v", err)
+	}
+
+	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
+	content := om.files[journalPath]
+	if strings.Count(content, "<!-- vi:") != 2 {
+		t.Fatalf("expected both entries to be appended, got %s", content)
+	}
+	if !strings.Contains(content, "<!-- vi:discord:shared-id -->") {
+		t.Fatalf("journal should include discord capture key")
+	}
+	if !strings.Contains(content, "<!-- vi:android-voice-inbox:shared-id -->") {
+		t.Fatalf("journal should include android capture key")
+	}
+	if strings.Contains(content, "```yaml") {
+		t.Fatalf("journal should not include YAML metadata block")
+	}
+}
+
+func TestProcessCapturesOnceRecoversProcessingCapture(t *testing.T) {
+	dm := newDiscordMock(t)
+	defer dm.close()
+	om := newObsidianMock(t)
+	defer om.close()
+
+	runner, st, cfg, cleanup := setupRunner(t, dm, om)
+	defer cleanup()
+
+	rawPath := filepath.Join(cfg.AudioStoreDir, "ingest", "2026", "03", "19", "capture-5002.ogg")
+	if err := os.MkdirAll(filepath.Dir(rawPath), 0o755); err != nil {
+		t.Fatalf("mkdir raw dir: %v", err)
+	}
+	if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
+		t.Fatalf("write raw audio: %v", err)
+	}
+	if err := st.CreateCapture(state.CaptureRecord{
+		CaptureID:    "capture-5002",
+		Source:       "android-voice-inbox",
+		DeviceID:     "pixel-8a",
+		ReceivedAt:   time.Now().Add(-10 * time.Minute).UTC(),
+		RawAudioPath: rawPath,
+		ContentType:  "audio/ogg",
+		Status:       "processing",
+		Attempts:     1,
+		UpdatedAt:    time.Now().Add(-10 * time.Minute).UTC(),
+	}); err != nil {
+		t.Fatalf("create processing capture: %v", err)
+	}
+
+	res, err := runner.ProcessCapturesOnce(context.Background())
+	if err != nil {
+		t.Fatalf("process captures once failed: %v", err)
+	}
+	if res.Succeeded != 0 || res.Failed != 0 || res.Processed != 0 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if recovered, ok := res.Data["recovered_captures"].(int); ok && recovered < 1 {
+		t.Fatalf("expected recovered captures > 0, got %+v", res.Data)
+	}
+
+	rec, found, err := st.GetCapture("capture-5002")
+	if err != nil || !found {
+		t.Fatalf("expected recovered capture: found=%v err=%v", found, err)
+	}
+	if rec.Status != "failed" {
+		t.Fatalf("expected recovered capture to be backoff-failed, got %s", rec.Status)
+	}
+	if rec.Attempts != 2 {
+		t.Fatalf("expected attempts to increment, got %d", rec.Attempts)
+	}
+	if rec.NextRetryAt == nil || !rec.NextRetryAt.After(time.Now()) {
+		t.Fatalf("expected next retry in the future, got %+v", rec.NextRetryAt)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	if err := st.MarkCaptureFailed(rec.CaptureID, rec.LastError, rec.Attempts, &past); err != nil {
+		t.Fatalf("force retry due: %v", err)
+	}
+
+	res, err = runner.ProcessCapturesOnce(context.Background())
+	if err != nil {
+		t.Fatalf("expected successful recovery retry pass: %v", err)
+	}
+	if res.Succeeded != 1 || res.Failed != 0 {
+		t.Fatalf("unexpected retry result: %+v", res)
+	}
+
+	rec, found, err = st.GetCapture("capture-5002")
+	if err != nil || !found {
+		t.Fatalf("expected recovered capture after retry: found=%v err=%v", found, err)
+	}
+	if rec.Status != "done" {
+		t.Fatalf("expected done after retry, got %s", rec.Status)
+	}
+}
+
+func TestProcessCapturesOnceLeavesFreshProcessingCaptureUntouched(t *testing.T) {
+	dm := newDiscordMock(t)
+	defer dm.close()
+	om := newObsidianMock(t)
+	defer om.close()
+
+	runner, st, cfg, cleanup := setupRunner(t, dm, om)
+	defer cleanup()
+
+	rawPath := filepath.Join(cfg.AudioStoreDir, "ingest", "2026", "03", "19", "capture-fresh-processing.ogg")
+	if err := os.MkdirAll(filepath.Dir(rawPath), 0o755); err != nil {
+		t.Fatalf("mkdir raw dir: %v", err)
+	}
+	if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
+		t.Fatalf("write raw audio: %v", err)
+	}
+	updatedAt := time.Now().Add(-2 * time.Minute).UTC().Truncate(time.Second)
+	if err := st.CreateCapture(state.CaptureRecord{
+		CaptureID:    "capture-fresh-processing",
+		Source:       "android-voice-inbox",
+		DeviceID:     "pixel-8a",
+		ReceivedAt:   time.Now().UTC(),
+		RawAudioPath: rawPath,
+		ContentType:  "audio/ogg",
+		Status:       "processing",
+		Attempts:     3,
+		UpdatedAt:    updatedAt,
+	}); err != nil {
+		t.Fatalf("create processing capture: %v", err)
+	}
+
+	res, err := runner.ProcessCapturesOnce(context.Background())
+	if err != nil {
+		t.Fatalf("process captures once failed: %v", err)
+	}
+	if recovered, ok := res.Data["recovered_captures"]; ok {
+		t.Fatalf("expected no recovered captures, got %+v", recovered)
+	}
+
+	rec, found, err := st.GetCapture("capture-fresh-processing")
+	if err != nil || !found {
+		t.Fatalf("expected fresh processing capture: found=%v err=%v", found, err)
+	}
+	if rec.Status != "processing" {
+		t.Fatalf("expected status to remain processing, got %s", rec.Status)
+	}
+	if rec.Attempts != 3 {
+		t.Fatalf("expected attempts unchanged, got %d", rec.Attempts)
+	}
+	if !rec.UpdatedAt.Equal(updatedAt) {
+		t.Fatalf("expected updated_at unchanged, got %s", rec.UpdatedAt)
+	}
+}
+
+func TestProcessCapturesOnceSuccessAndNoDuplicateJournalAppend(t *testing.T) {
+	dm := newDiscordMock(t)
+	defer dm.close()
+	om := newObsidianMock(t)
+	defer om.close()
+
+	runner, st, cfg, cleanup := setupRunner(t, dm, om)
+	defer cleanup()
+
+	rawDir := filepath.Join(cfg.AudioStoreDir, "http", "2026", "03", "19")
+	if err := os.MkdirAll(rawDir, 0o755); err != nil {
+		t.Fatalf("mkdir raw dir: %v", err)
+	}
+	rawPath := filepath.Join(rawDir, "cap-2001.ogg")
+	if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
+		t.Fatalf("write raw audio: %v", err)
+	}
+
+	capturedAt := time.Date(2026, 3, 19, 12, 0, 0, 0, time.UTC)
+	if err := st.CreateCapture(state.CaptureRecord{
+		CaptureID:       "cap-2001",
+		Source:          "android-voice-inbox",
+		SourceDedupeKey: "cap-2001",
+		DeviceID:        "pixel-8a",
+		CapturedAt:      &capturedAt,
+		ReceivedAt:      time.Now().UTC(),
+		RawAudioPath:    rawPath,
+		ContentType:     "audio/ogg",
+		Status:          "pending",
+	}); err != nil {
+		t.Fatalf("create capture: %v", err)
+	}
+
+	res, err := runner.ProcessCapturesOnce(context.Background())
+	if err != nil {
+		t.Fatalf("process captures failed: %v", err)
+	}
+	if res.Succeeded != 1 || res.Failed != 0 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	rec, found, err := st.GetCapture("cap-2001")
+	if err != nil || !found {
+		t.Fatalf("expected stored capture: found=%v err=%v", found, err)
+	}
+	if rec.Status != "done" {
+		t.Fatalf("expected capture done, got %s", rec.Status)
+	}
+
+	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
+	if !strings.Contains(om.files[journalPath], "<!-- vi:android-voice-inbox:cap-2001 -->") {
+		t.Fatalf("journal should include capture marker")
+	}
+	appendHits := om.appendHits
+
+	res, err = runner.ProcessCapturesOnce(context.Background())
+	if err != nil {
+		t.Fatalf("second capture processing pass should succeed: %v", err)
+	}
+	if res.Succeeded != 0 || om.appendHits != appendHits {
+		t.Fatalf("expected no duplicate append, result=%+v appendHits=%d", res, om.appendHits)
+	}
+}
+
+func TestProcessCapturesOnceRetriesFailedCapture(t *testing.T) {
+	dm := newDiscordMock(t)
+	defer dm.close()
+	om := newObsidianMock(t)
+	defer om.close()
+	om.appendFail = true
+
+	runner, st, cfg, cleanup := setupRunner(t, dm, om)
+	defer cleanup()
+
+	rawDir := filepath.Join(cfg.AudioStoreDir, "http", "2026", "03", "19")
+	if err := os.MkdirAll(rawDir, 0o755); err != nil {
+		t.Fatalf("mkdir raw dir: %v", err)
+	}
+	rawPath := filepath.Join(rawDir, "cap-3001.ogg")
+	if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
+		t.Fatalf("write raw audio: %v", err)
+	}
+
+	capturedAt := time.Date(2026, 3, 19, 12, 0, 0, 0, time.UTC)
+	if err := st.CreateCapture(state.CaptureRecord{
+		CaptureID:       "cap-3001",
+		Source:          "android-voice-inbox",
+		SourceDedupeKey: "cap-3001",
+		DeviceID:        "pixel-8a",
+		CapturedAt:      &capturedAt,
+		ReceivedAt:      time.Now().UTC(),
+		RawAudioPath:    rawPath,
+		ContentType:     "audio/ogg",
+		Status:          "pending",
+	}); err != nil {
+		t.Fatalf("create capture: %v", err)
+	}
+
+	res, err := runner.ProcessCapturesOnce(context.Background())
+	if err == nil {
+		t.Fatalf("expected failed processing pass")
+	}
+	if res.Failed != 1 || res.Requeued != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	rec, found, err := st.GetCapture("cap-3001")
+	if err != nil || !found {
+		t.Fatalf("expected stored capture after failure: found=%v err=%v", found, err)
+	}
+	if rec.Status != "failed" || rec.NextRetryAt == nil {
+		t.Fatalf("expected failed capture with retry, got %+v", rec)
+	}
+
+	past := time.Now().Add(-time.Minute)
+	if err := st.MarkCaptureFailed(rec.CaptureID, rec.LastError, rec.Attempts, &past); err != nil {
+		t.Fatalf("force retry due: %v", err)
+	}
+	om.appendFail = false
+
+	res, err = runner.ProcessCapturesOnce(context.Background())
+	if err != nil {
+		t.Fatalf("expected successful recovery pass: %v", err)
+	}
+	if res.Succeeded != 1 || res.Failed != 0 {
+		t.Fatalf("unexpected retry result: %+v", res)
+	}
+
+	rec, found, err = st.GetCapture("cap-3001")
+	if err != nil || !found {
+		t.Fatalf("expected stored capture after retry: found=%v err=%v", found, err)
+	}
+	if rec.Status != "done" {
+		t.Fatalf("expected done after retry, got %s", rec.Status)
+	}
+}
+
+func TestProcessCapturesOnceDedupsWithNewHTMLMarker(t *testing.T) {
+	dm := newDiscordMock(t)
+	defer dm.close()
+	om := newObsidianMock(t)
+	defer om.close()
+
+	runner, st, cfg, cleanup := setupRunner(t, dm, om)
+	defer cleanup()
+
+	rawDir := filepath.Join(cfg.AudioStoreDir, "http", "2026", "03", "19")
+	if err := os.MkdirAll(rawDir, 0o755); err != nil {
+		t.Fatalf("mkdir raw dir: %v", err)
+	}
+	rawPath := filepath.Join(rawDir, "cap-html.ogg")
+	if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
+		t.Fatalf("write raw audio: %v", err)
+	}
+
+	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
+	om.files[journalPath] = journal.NewJournalContent(time.Now()) + "\n## ログ - 12:00\n### 🎤 Voice Inbox\n\n既存エントリ\n\n_12:00 via Pixel 8a_\n<!-- vi:android-voice-inbox:cap-html -->\n"
+
+	if err := st.CreateCapture(state.CaptureRecord{
+		CaptureID:       "cap-html",
+		Source:          "android-voice-inbox",
+		SourceDedupeKey: "cap-html",
+		DeviceID:        "Pixel 8a",
+		ReceivedAt:      time.Now().UTC(),
+		RawAudioPath:    rawPath,
+		ContentType:     "audio/ogg",
+		Status:          "pending",
+	}); err != nil {
+		t.Fatalf("create capture: %v", err)
+	}
+
+	res, err := runner.ProcessCapturesOnce(context.Background())
+	if err != nil {
+		t.Fatalf("process captures failed: %v", err)
 	}
 	if res.Succeeded != 1 || res.Failed != 0 {
 		t.Fatalf("unexpected result: %+v", res)
 	}
-
-	rec, found, err := st.GetCapture("capture-plain-text")
-	if err != nil || !found {
-		t.Fatalf("expected stored capture: found=%v err=%v", found, err)
-	}
-	if rec.Status != "done" {
-		t.Fatalf("expected done status, got %s", rec.Status)
-	}
-
-	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
-	content := om.files[journalPath]
-	if !strings.Contains(content, "テスト文字起こし") {
-		t.Fatalf("journal should include transcript")
+	if om.appendHits != 0 {
+		t.Fatalf("expected dedup to skip append, appendHits=%d", om.appendHits)
 	}
-	if !strings.Contains(content, "<!-- vi:android-voice-inbox:capture-plain-text -->") {
-		t.Fatalf("journal should include HTML marker, got %s", content)
+	if strings.Count(om.files[journalPath], "<!-- vi:android-voice-inbox:cap-html -->") != 1 {
+		t.Fatalf("expected exactly one HTML marker, got %q", om.files[journalPath])
 	}
 }
 
-func TestProcessCapturesOnceBlankTranscriptFallsBackToWhisper(t *testing.T) {
+func TestProcessCapturesOnceDedupsWithOldYAMLMarker(t *testing.T) {
 	dm := newDiscordMock(t)
 	defer dm.close()
 	om := newObsidianMock(t)
@@ -755,518 +1856,745 @@ func TestProcessCapturesOnceBlankTranscriptFallsBackToWhisper(t *testing.T) {
 	runner, st, cfg, cleanup := setupRunner(t, dm, om)
 	defer cleanup()
 
-	rawPath := filepath.Join(cfg.AudioStoreDir, "ingest", "2026", "03", "19", "capture-blank-transcript.ogg")
-	if err := os.MkdirAll(filepath.Dir(rawPath), 0o755); err != nil {
+	rawDir := filepath.Join(cfg.AudioStoreDir, "http", "2026", "03", "19")
+	if err := os.MkdirAll(rawDir, 0o755); err != nil {
 		t.Fatalf("mkdir raw dir: %v", err)
 	}
+	rawPath := filepath.Join(rawDir, "cap-yaml.ogg")
 	if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
 		t.Fatalf("write raw audio: %v", err)
 	}
+
+	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
+	om.files[journalPath] = journal.NewJournalContent(time.Now()) + "\n## ログ - 12:00\n### 🎤 Voice Inbox\n\n既存エントリ\n\n```yaml\nvoice_inbox:\n  capture_key: \"android-voice-inbox:cap-yaml\"\n```\n"
+
 	if err := st.CreateCapture(state.CaptureRecord{
-		CaptureID:      "capture-blank-transcript",
-		Source:         "android-voice-inbox",
-		DeviceID:       "pixel-8a",
-		ReceivedAt:     time.Now().UTC(),
-		RawAudioPath:   rawPath,
-		ContentType:    "audio/ogg",
-		TranscriptText: "   ",
-		Status:         "pending",
+		CaptureID:       "cap-yaml",
+		Source:          "android-voice-inbox",
+		SourceDedupeKey: "cap-yaml",
+		DeviceID:        "Pixel 8a",
+		ReceivedAt:      time.Now().UTC(),
+		RawAudioPath:    rawPath,
+		ContentType:     "audio/ogg",
+		Status:          "pending",
 	}); err != nil {
 		t.Fatalf("create capture: %v", err)
 	}
 
 	res, err := runner.ProcessCapturesOnce(context.Background())
 	if err != nil {
-		t.Fatalf("process captures once failed: %v", err)
+		t.Fatalf("process captures failed: %v", err)
 	}
 	if res.Succeeded != 1 || res.Failed != 0 {
 		t.Fatalf("unexpected result: %+v", res)
 	}
-
-	rec, found, err := st.GetCapture("capture-blank-transcript")
-	if err != nil || !found {
-		t.Fatalf("expected stored capture: found=%v err=%v", found, err)
-	}
-	if rec.Status != "done" {
-		t.Fatalf("expected done status, got %s", rec.Status)
+	if om.appendHits != 0 {
+		t.Fatalf("expected dedup to skip append, appendHits=%d", om.appendHits)
 	}
-	if rec.TranscriptPath == "" {
-		t.Fatalf("expected whisper transcript artifact for blank transcript fallback")
+	if strings.Count(om.files[journalPath], `capture_key: "android-voice-inbox:cap-yaml"`) != 1 {
+		t.Fatalf("expected exactly one YAML marker, got %q", om.files[journalPath])
 	}
+}
 
-	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
-	content := om.files[journalPath]
-	if !strings.Contains(content, "テスト文字起こし") {
-		t.Fatalf("journal should include whisper transcript")
-	}
-	if !strings.Contains(content, "<!-- vi:android-voice-inbox:capture-blank-transcript -->") {
-		t.Fatalf("journal should include HTML marker for fallback path")
+func writeToneWav(t *testing.T, path string, seconds int) {
+	t.Helper()
+	const sampleRate = 8000
+	dataSize := seconds * sampleRate * 2
+	buf := make([]byte, 44+dataSize)
+	copy(buf[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(buf[4:8], uint32(36+dataSize))
+	copy(buf[8:12], "WAVE")
+	copy(buf[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(buf[16:20], 16)
+	binary.LittleEndian.PutUint16(buf[20:22], 1)
+	binary.LittleEndian.PutUint16(buf[22:24], 1)
+	binary.LittleEndian.PutUint32(buf[24:28], sampleRate)
+	binary.LittleEndian.PutUint32(buf[28:32], sampleRate*2)
+	binary.LittleEndian.PutUint16(buf[32:34], 2)
+	binary.LittleEndian.PutUint16(buf[34:36], 16)
+	copy(buf[36:40], "data")
+	binary.LittleEndian.PutUint32(buf[40:44], uint32(dataSize))
+	for i := 44; i+1 < len(buf); i += 2 {
+		binary.LittleEndian.PutUint16(buf[i:i+2], uint16(int16(4000)))
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatalf("mkdir wav dir: %v", err)
+	}
+	if err := os.WriteFile(path, buf, 0o644); err != nil {
+		t.Fatalf("write wav: %v", err)
 	}
 }
 
-func TestProcessTargetDefaultsUnknownMimeRawFileToAudio(t *testing.T) {
+func TestProcessCapturesOnceResumesChunkedTranscription(t *testing.T) {
 	dm := newDiscordMock(t)
 	defer dm.close()
 	om := newObsidianMock(t)
 	defer om.close()
 
-	runner, _, cfg, cleanup := setupRunner(t, dm, om)
+	binDir := t.TempDir()
+	invocations := filepath.Join(binDir, "invocations.log")
+	failFlag := filepath.Join(binDir, "fail-chunk001")
+	if err := os.WriteFile(failFlag, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	runner, st, cfg, cleanup := setupRunner(t, dm, om, func(cfg *config.Config) {
+		cfg.WhisperBin = filepath.Join(binDir, "whisper")
+		writeFakeBinary(t, cfg.WhisperBin, fmt.Sprintf(`#!/bin/sh
+set -eu
+in="$1"
+out_dir="."
+shift
+while [ "$#" -gt 0 ]; do
+  case "$1" in
+    --output_dir)
+      shift
+      out_dir="$1"
+      ;;
+  esac
+  shift
+done
+base="$(basename "$in")"
+base="${base%%.*}"
+echo "$base" >> %q
+case "$base" in
+  *_chunk001)
+    if [ -f %q ]; then
+      echo "simulated timeout" >&2
+      exit 1
+    fi
+    ;;
+esac
+mkdir -p "$out_dir"
+printf '{"text":"%%s"}' "${base##*_}" > "$out_dir/$base.json"
+`, invocations, failFlag))
+		cfg.WhisperChunkSeconds = 4
+		cfg.WhisperChunkOverlapSec = 1
+		cfg.WhisperTimeoutFactor = 3
+	})
 	defer cleanup()
 
-	rawPath := filepath.Join(cfg.AudioStoreDir, "ingest", "2026", "03", "19", "capture-octet-stream.bin")
-	if err := os.MkdirAll(filepath.Dir(rawPath), 0o755); err != nil {
-		t.Fatalf("mkdir raw dir: %v", err)
+	rawPath := filepath.Join(cfg.AudioStoreDir, "ingest", "2026", "03", "19", "cap-long.wav")
+	writeToneWav(t, rawPath, 10)
+	if err := st.CreateCapture(state.CaptureRecord{
+		CaptureID:    "cap-long",
+		Source:       "android-voice-inbox",
+		ReceivedAt:   time.Now().UTC(),
+		RawAudioPath: rawPath,
+		ContentType:  "audio/wav",
+		Status:       "pending",
+	}); err != nil {
+		t.Fatalf("create capture: %v", err)
 	}
-	if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
-		t.Fatalf("write raw audio: %v", err)
+
+	if _, err := runner.ProcessCapturesOnce(context.Background()); err == nil {
+		t.Fatalf("expected first pass to fail on chunk 2")
+	}
+	checkpoints, err := st.ListTranscriptChunks("android-voice-inbox:cap-long")
+	if err != nil {
+		t.Fatalf("list checkpoints: %v", err)
+	}
+	if len(checkpoints) != 1 || checkpoints[0].ChunkIndex != 0 || checkpoints[0].ChunkCount < 3 {
+		t.Fatalf("expected chunk 0 checkpoint, got %+v", checkpoints)
 	}
 
-	artifacts, err := runner.processTarget(context.Background(), processTarget{
-		Source:       "android-voice-inbox",
-		CaptureID:    "capture-octet-stream",
-		ContentType:  "application/octet-stream",
-		RawAudioPath: rawPath,
-	})
+	if err := os.Remove(failFlag); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(invocations); err != nil {
+		t.Fatal(err)
+	}
+	rec, _, _ := st.GetCapture("cap-long")
+	past := time.Now().Add(-time.Minute)
+	if err := st.MarkCaptureFailed(rec.CaptureID, rec.LastError, rec.Attempts, &past); err != nil {
+		t.Fatalf("force retry due: %v", err)
+	}
+
+	res, err := runner.ProcessCapturesOnce(context.Background())
 	if err != nil {
-		t.Fatalf("process target failed: %v", err)
+		t.Fatalf("expected resumed pass to succeed: %v (%+v)", err, res)
 	}
-	if artifacts.TranscriptPath == "" {
-		t.Fatalf("expected transcript artifact, got %+v", artifacts)
+	calls, err := os.ReadFile(invocations)
+	if err != nil {
+		t.Fatalf("read invocations: %v", err)
+	}
+	if strings.Contains(string(calls), "_chunk000") {
+		t.Fatalf("expected completed chunk to be skipped, whisper saw %q", calls)
 	}
 
 	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
 	content := om.files[journalPath]
-	if !strings.Contains(content, "テスト文字起こし") {
-		t.Fatalf("journal should include transcript")
-	}
-	if !strings.Contains(content, "<!-- vi:android-voice-inbox:capture-octet-stream -->") {
-		t.Fatalf("journal should include capture marker")
+	if !strings.Contains(content, "chunk000 chunk001 chunk002") {
+		t.Fatalf("expected stitched transcript in journal, got %q", content)
 	}
-
-	normalizedPath := filepath.Join(filepath.Dir(rawPath), "capture-octet-stream_16k.wav")
-	if _, err := os.Stat(normalizedPath); !os.IsNotExist(err) {
-		t.Fatalf("expected normalized wav cleanup, stat err=%v", err)
+	if checkpoints, _ := st.ListTranscriptChunks("android-voice-inbox:cap-long"); len(checkpoints) != 0 {
+		t.Fatalf("expected checkpoints cleared after success, got %+v", checkpoints)
 	}
 }
 
-func TestProcessTargetJournalDedupeIncludesSource(t *testing.T) {
+func TestProcessCapturesOnceStoresProbedAudioMetadata(t *testing.T) {
 	dm := newDiscordMock(t)
 	defer dm.close()
 	om := newObsidianMock(t)
 	defer om.close()
 
-	runner, _, cfg, cleanup := setupRunner(t, dm, om)
+	runner, st, cfg, cleanup := setupRunner(t, dm, om, func(cfg *config.Config) {
+		cfg.FFprobeBin = filepath.Join(t.TempDir(), "ffprobe")
+		writeFakeBinary(t, cfg.FFprobeBin, `#!/bin/sh
+printf '{"streams":[{"codec_type":"audio","codec_name":"opus","channels":1,"sample_rate":"48000"}],"format":{"duration":"134.2","bit_rate":"24000"}}'
+`)
+	})
 	defer cleanup()
 
-	rawDir := filepath.Join(cfg.AudioStoreDir, "ingest", "2026", "03", "19")
-	if err := os.MkdirAll(rawDir, 0o755); err != nil {
-		t.Fatalf("mkdir raw dir: %v", err)
-	}
-
-	firstPath := filepath.Join(rawDir, "shared-id-discord.ogg")
-	if err := os.WriteFile(firstPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
-		t.Fatalf("write first raw audio: %v", err)
-	}
-	if _, err := runner.processTarget(context.Background(), processTarget{
-		Source:       "discord",
-		CaptureID:    "shared-id",
-		ContentType:  "audio/ogg",
-		RawAudioPath: firstPath,
-	}); err != nil {
-		t.Fatalf("process first target failed: %v", err)
+	rawPath := filepath.Join(cfg.AudioStoreDir, "ingest", "cap-probe.ogg")
+	if err := os.MkdirAll(filepath.Dir(rawPath), 0o755); err != nil {
+		t.Fatal(err)
 	}
-
-	secondPath := filepath.Join(rawDir, "shared-id-android.ogg")
-	if err := os.WriteFile(secondPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
-		t.Fatalf("write second raw audio: %v", err)
+	if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
+		t.Fatal(err)
 	}
-	if _, err := runner.processTarget(context.Background(), processTarget{
+	if err := st.CreateCapture(state.CaptureRecord{
+		CaptureID:    "cap-probe",
 		Source:       "android-voice-inbox",
-		CaptureID:    "shared-id",
+		DeviceID:     "pixel-8a",
+		ReceivedAt:   time.Now().UTC(),
+		RawAudioPath: rawPath,
 		ContentType:  "audio/ogg",
-		RawAudioPath: secondPath,
+		Status:       "pending",
 	}); err != nil {
-		t.Fatalf("process second target failed: %v", err)
+		t.Fatalf("create capture: %v", err)
 	}
 
-	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
-	content := om.files[journalPath]
-	if strings.Count(content, "<!-- vi:") != 2 {
-		t.Fatalf("expected both entries to be appended, got %s", content)
+	if res, err := runner.ProcessCapturesOnce(context.Background()); err != nil {
+		t.Fatalf("process captures failed: %v (%+v)", err, res)
 	}
-	if !strings.Contains(content, "<!-- vi:discord:shared-id -->") {
-		t.Fatalf("journal should include discord capture key")
+
+	rec, _, err := st.GetCapture("cap-probe")
+	if err != nil {
+		t.Fatalf("get capture: %v", err)
 	}
-	if !strings.Contains(content, "<!-- vi:android-voice-inbox:shared-id -->") {
-		t.Fatalf("journal should include android capture key")
+	want := state.AudioMetadata{DurationMS: 134200, Codec: "opus", Channels: 1, SampleRate: 48000, Bitrate: 24000}
+	if rec.Audio != want {
+		t.Fatalf("expected audio metadata %+v, got %+v", want, rec.Audio)
 	}
-	if strings.Contains(content, "```yaml") {
-		t.Fatalf("journal should not include YAML metadata block")
+
+	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
+	if content := om.files[journalPath]; !strings.Contains(content, "via pixel-8a (2m14s)_") {
+		t.Fatalf("expected duration in entry footer, got %q", content)
 	}
 }
 
-func TestProcessCapturesOnceRecoversProcessingCapture(t *testing.T) {
+func TestProcessCapturesOnceRejectsCorruptAudioPermanently(t *testing.T) {
 	dm := newDiscordMock(t)
 	defer dm.close()
 	om := newObsidianMock(t)
 	defer om.close()
 
-	runner, st, cfg, cleanup := setupRunner(t, dm, om)
+	runner, st, cfg, cleanup := setupRunner(t, dm, om, func(cfg *config.Config) {
+		cfg.FFprobeBin = filepath.Join(t.TempDir(), "ffprobe")
+		writeFakeBinary(t, cfg.FFprobeBin, `#!/bin/sh
+echo "Invalid data found when processing input" >&2
+exit 1
+`)
+	})
 	defer cleanup()
 
-	rawPath := filepath.Join(cfg.AudioStoreDir, "ingest", "2026", "03", "19", "capture-5002.ogg")
+	rawPath := filepath.Join(cfg.AudioStoreDir, "ingest", "cap-corrupt.ogg")
 	if err := os.MkdirAll(filepath.Dir(rawPath), 0o755); err != nil {
-		t.Fatalf("mkdir raw dir: %v", err)
+		t.Fatal(err)
 	}
-	if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
-		t.Fatalf("write raw audio: %v", err)
+	if err := os.WriteFile(rawPath, []byte("<html>not audio</html>"), 0o644); err != nil {
+		t.Fatal(err)
 	}
 	if err := st.CreateCapture(state.CaptureRecord{
-		CaptureID:    "capture-5002",
+		CaptureID:    "cap-corrupt",
 		Source:       "android-voice-inbox",
-		DeviceID:     "pixel-8a",
-		ReceivedAt:   time.Now().Add(-10 * time.Minute).UTC(),
+		ReceivedAt:   time.Now().UTC(),
 		RawAudioPath: rawPath,
 		ContentType:  "audio/ogg",
-		Status:       "processing",
-		Attempts:     1,
-		UpdatedAt:    time.Now().Add(-10 * time.Minute).UTC(),
+		Status:       "pending",
 	}); err != nil {
-		t.Fatalf("create processing capture: %v", err)
+		t.Fatalf("create capture: %v", err)
 	}
 
 	res, err := runner.ProcessCapturesOnce(context.Background())
+	if err == nil {
+		t.Fatalf("expected corrupt capture to fail")
+	}
+	if res.Failed != 1 || res.Requeued != 0 {
+		t.Fatalf("expected permanent failure without requeue, got %+v", res)
+	}
+
+	rec, _, err := st.GetCapture("cap-corrupt")
 	if err != nil {
-		t.Fatalf("process captures once failed: %v", err)
+		t.Fatalf("get capture: %v", err)
 	}
-	if res.Succeeded != 0 || res.Failed != 0 || res.Processed != 0 {
-		t.Fatalf("unexpected result: %+v", res)
+	if rec.Status != "failed" || rec.NextRetryAt != nil || rec.Attempts != 1 {
+		t.Fatalf("expected permanently failed capture, got %+v", rec)
 	}
-	if recovered, ok := res.Data["recovered_captures"].(int); ok && recovered < 1 {
-		t.Fatalf("expected recovered captures > 0, got %+v", res.Data)
+	if !strings.Contains(rec.LastError, "Invalid data found") {
+		t.Fatalf("expected ffprobe error in last_error, got %q", rec.LastError)
 	}
+}
 
-	rec, found, err := st.GetCapture("capture-5002")
-	if err != nil || !found {
-		t.Fatalf("expected recovered capture: found=%v err=%v", found, err)
-	}
-	if rec.Status != "failed" {
-		t.Fatalf("expected recovered capture to be backoff-failed, got %s", rec.Status)
+func TestProcessCapturesOnceReusesCachedTranscript(t *testing.T) {
+	dm := newDiscordMock(t)
+	defer dm.close()
+	om := newObsidianMock(t)
+	defer om.close()
+	om.appendFail = true
+
+	binDir := t.TempDir()
+	invocations := filepath.Join(binDir, "invocations.log")
+	runner, st, cfg, cleanup := setupRunner(t, dm, om, func(cfg *config.Config) {
+		realWhisper := cfg.WhisperBin
+		cfg.WhisperBin = filepath.Join(binDir, "whisper")
+		writeFakeBinary(t, cfg.WhisperBin, fmt.Sprintf(`#!/bin/sh
+echo "$1" >> %q
+exec %q "$@"
+`, invocations, realWhisper))
+	})
+	defer cleanup()
+
+	rawDir := filepath.Join(cfg.AudioStoreDir, "ingest", "2026", "03", "19")
+	if err := os.MkdirAll(rawDir, 0o755); err != nil {
+		t.Fatal(err)
 	}
-	if rec.Attempts != 2 {
-		t.Fatalf("expected attempts to increment, got %d", rec.Attempts)
+	for _, id := range []string{"cap-cache-a", "cap-cache-b"} {
+		rawPath := filepath.Join(rawDir, id+".ogg")
+		if err := os.WriteFile(rawPath, []byte("SAME_AUDIO_BYTES"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.CreateCapture(state.CaptureRecord{
+			CaptureID:    id,
+			Source:       "android-voice-inbox",
+			ReceivedAt:   time.Now().UTC(),
+			RawAudioPath: rawPath,
+			ContentType:  "audio/ogg",
+			Status:       "pending",
+		}); err != nil {
+			t.Fatalf("create capture: %v", err)
+		}
 	}
-	if rec.NextRetryAt == nil || !rec.NextRetryAt.After(time.Now()) {
-		t.Fatalf("expected next retry in the future, got %+v", rec.NextRetryAt)
+
+	if _, err := runner.ProcessCapturesOnce(context.Background()); err == nil {
+		t.Fatalf("expected append failure on first pass")
 	}
 
+	om.appendFail = false
 	past := time.Now().Add(-time.Minute)
-	if err := st.MarkCaptureFailed(rec.CaptureID, rec.LastError, rec.Attempts, &past); err != nil {
-		t.Fatalf("force retry due: %v", err)
+	for _, id := range []string{"cap-cache-a", "cap-cache-b"} {
+		rec, _, _ := st.GetCapture(id)
+		if err := st.MarkCaptureFailed(id, rec.LastError, rec.Attempts, &past); err != nil {
+			t.Fatalf("force retry due: %v", err)
+		}
 	}
-
-	res, err = runner.ProcessCapturesOnce(context.Background())
+	res, err := runner.ProcessCapturesOnce(context.Background())
 	if err != nil {
-		t.Fatalf("expected successful recovery retry pass: %v", err)
+		t.Fatalf("expected retry pass to succeed: %v (%+v)", err, res)
 	}
-	if res.Succeeded != 1 || res.Failed != 0 {
-		t.Fatalf("unexpected retry result: %+v", res)
+	if res.Succeeded != 2 {
+		t.Fatalf("expected both captures done, got %+v", res)
 	}
 
-	rec, found, err = st.GetCapture("capture-5002")
-	if err != nil || !found {
-		t.Fatalf("expected recovered capture after retry: found=%v err=%v", found, err)
+	calls, err := os.ReadFile(invocations)
+	if err != nil {
+		t.Fatalf("read invocations: %v", err)
 	}
-	if rec.Status != "done" {
-		t.Fatalf("expected done after retry, got %s", rec.Status)
+	if n := strings.Count(string(calls), "\n"); n != 1 {
+		t.Fatalf("expected whisper to run once for identical audio across retries, ran %d times: %q", n, calls)
 	}
 }
 
-func TestProcessCapturesOnceLeavesFreshProcessingCaptureUntouched(t *testing.T) {
+func TestProcessCapturesOnceKeepsJournalOrderWithConcurrentWorkers(t *testing.T) {
 	dm := newDiscordMock(t)
 	defer dm.close()
 	om := newObsidianMock(t)
 	defer om.close()
 
-	runner, st, cfg, cleanup := setupRunner(t, dm, om)
+	runner, st, cfg, cleanup := setupRunner(t, dm, om, func(cfg *config.Config) {
+		realWhisper := cfg.WhisperBin
+		cfg.WhisperBin = filepath.Join(t.TempDir(), "whisper")
+		writeFakeBinary(t, cfg.WhisperBin, fmt.Sprintf(`#!/bin/sh
+sleep 1
+exec %q "$@"
+`, realWhisper))
+		cfg.WorkerConcurrency = 3
+		cfg.TranscribeConcurrency = 1
+		cfg.JournalConcurrency = 2
+	})
 	defer cleanup()
 
-	rawPath := filepath.Join(cfg.AudioStoreDir, "ingest", "2026", "03", "19", "capture-fresh-processing.ogg")
-	if err := os.MkdirAll(filepath.Dir(rawPath), 0o755); err != nil {
-		t.Fatalf("mkdir raw dir: %v", err)
-	}
-	if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
-		t.Fatalf("write raw audio: %v", err)
+	rawDir := filepath.Join(cfg.AudioStoreDir, "ingest")
+	if err := os.MkdirAll(rawDir, 0o755); err != nil {
+		t.Fatal(err)
 	}
-	updatedAt := time.Now().Add(-2 * time.Minute).UTC().Truncate(time.Second)
-	if err := st.CreateCapture(state.CaptureRecord{
-		CaptureID:    "capture-fresh-processing",
-		Source:       "android-voice-inbox",
-		DeviceID:     "pixel-8a",
-		ReceivedAt:   time.Now().UTC(),
-		RawAudioPath: rawPath,
-		ContentType:  "audio/ogg",
-		Status:       "processing",
-		Attempts:     3,
-		UpdatedAt:    updatedAt,
-	}); err != nil {
-		t.Fatalf("create processing capture: %v", err)
+	base := time.Now().UTC().Add(-time.Hour)
+	captures := []struct {
+		id   string
+		text string
+		at   time.Time
+	}{
+		{id: "cap-order-2", text: "second memo", at: base.Add(2 * time.Minute)},
+		{id: "cap-order-1", at: base.Add(time.Minute)},
+		{id: "cap-order-3", text: "third memo", at: base.Add(3 * time.Minute)},
+	}
+	for _, c := range captures {
+		rawPath := filepath.Join(rawDir, c.id+".ogg")
+		if err := os.WriteFile(rawPath, []byte("AUDIO_"+c.id), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		capturedAt := c.at
+		if err := st.CreateCapture(state.CaptureRecord{
+			CaptureID:      c.id,
+			Source:         "android-voice-inbox",
+			CapturedAt:     &capturedAt,
+			ReceivedAt:     time.Now().UTC(),
+			RawAudioPath:   rawPath,
+			ContentType:    "audio/ogg",
+			TranscriptText: c.text,
+			Status:         "pending",
+		}); err != nil {
+			t.Fatalf("create capture %s: %v", c.id, err)
+		}
 	}
 
 	res, err := runner.ProcessCapturesOnce(context.Background())
 	if err != nil {
-		t.Fatalf("process captures once failed: %v", err)
+		t.Fatalf("process captures once failed: %v (%+v)", err, res)
 	}
-	if recovered, ok := res.Data["recovered_captures"]; ok {
-		t.Fatalf("expected no recovered captures, got %+v", recovered)
+	if res.Succeeded != 3 {
+		t.Fatalf("unexpected result: %+v", res)
 	}
 
-	rec, found, err := st.GetCapture("capture-fresh-processing")
-	if err != nil || !found {
-		t.Fatalf("expected fresh processing capture: found=%v err=%v", found, err)
-	}
-	if rec.Status != "processing" {
-		t.Fatalf("expected status to remain processing, got %s", rec.Status)
-	}
-	if rec.Attempts != 3 {
-		t.Fatalf("expected attempts unchanged, got %d", rec.Attempts)
+	content := om.files["01_Projects/Journal/"+time.Now().Format("2006-01-02")+".md"]
+	first := strings.Index(content, "cap-order-1 -->")
+	second := strings.Index(content, "cap-order-2 -->")
+	third := strings.Index(content, "cap-order-3 -->")
+	if first < 0 || second < 0 || third < 0 {
+		t.Fatalf("expected all captures in journal, got:\n%s", content)
 	}
-	if !rec.UpdatedAt.Equal(updatedAt) {
-		t.Fatalf("expected updated_at unchanged, got %s", rec.UpdatedAt)
+	if !(first < second && second < third) {
+		t.Fatalf("expected entries in capture-time order, got positions %d %d %d", first, second, third)
 	}
 }
 
-func TestProcessCapturesOnceSuccessAndNoDuplicateJournalAppend(t *testing.T) {
+func TestInspectShowsHistoryArtifactsAndJournalMarker(t *testing.T) {
 	dm := newDiscordMock(t)
 	defer dm.close()
 	om := newObsidianMock(t)
 	defer om.close()
+	om.appendFail = true
 
-	runner, st, cfg, cleanup := setupRunner(t, dm, om)
+	dm.messages = []discord.Message{makeMessage(dm.server.URL, "2701")}
+	runner, st, _, cleanup := setupRunner(t, dm, om)
 	defer cleanup()
 
-	rawDir := filepath.Join(cfg.AudioStoreDir, "http", "2026", "03", "19")
-	if err := os.MkdirAll(rawDir, 0o755); err != nil {
-		t.Fatalf("mkdir raw dir: %v", err)
+	if _, err := runner.PollOnce(context.Background()); err == nil {
+		t.Fatalf("expected initial poll failure")
 	}
-	rawPath := filepath.Join(rawDir, "cap-2001.ogg")
-	if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
-		t.Fatalf("write raw audio: %v", err)
+	rec, _, err := st.GetMessage("2701")
+	if err != nil {
+		t.Fatalf("get message: %v", err)
 	}
-
-	capturedAt := time.Date(2026, 3, 19, 12, 0, 0, 0, time.UTC)
-	if err := st.CreateCapture(state.CaptureRecord{
-		CaptureID:       "cap-2001",
-		Source:          "android-voice-inbox",
-		SourceDedupeKey: "cap-2001",
-		DeviceID:        "pixel-8a",
-		CapturedAt:      &capturedAt,
-		ReceivedAt:      time.Now().UTC(),
-		RawAudioPath:    rawPath,
-		ContentType:     "audio/ogg",
-		Status:          "pending",
-	}); err != nil {
-		t.Fatalf("create capture: %v", err)
+	past := time.Now().Add(-time.Minute)
+	if err := st.MarkFailed(rec.MessageID, rec.LastError, rec.Attempts, &past); err != nil {
+		t.Fatalf("force retry due: %v", err)
+	}
+	om.appendFail = false
+	dm.messages = nil
+	if _, err := runner.PollOnce(context.Background()); err != nil {
+		t.Fatalf("retry poll: %v", err)
 	}
 
-	res, err := runner.ProcessCapturesOnce(context.Background())
+	res, err := runner.Inspect(context.Background(), "2701")
 	if err != nil {
-		t.Fatalf("process captures failed: %v", err)
+		t.Fatalf("inspect: %v", err)
 	}
-	if res.Succeeded != 1 || res.Failed != 0 {
-		t.Fatalf("unexpected result: %+v", res)
+	report, ok := res.Data["item"].(InspectReport)
+	if !ok || report.Message == nil || report.Source != state.SourceDiscord {
+		t.Fatalf("unexpected inspect report: %+v", res.Data)
 	}
-
-	rec, found, err := st.GetCapture("cap-2001")
-	if err != nil || !found {
-		t.Fatalf("expected stored capture: found=%v err=%v", found, err)
+	if report.Journal == nil || !report.Journal.MarkerFound || report.Journal.Sink != "obsidian" {
+		t.Fatalf("expected journal marker to be found in the stored sink, got %+v", report.Journal)
 	}
-	if rec.Status != "done" {
-		t.Fatalf("expected capture done, got %s", rec.Status)
+	audioFound := false
+	for _, a := range report.Artifacts {
+		if a.Kind == "audio" && a.Exists {
+			audioFound = true
+		}
 	}
-
-	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
-	if !strings.Contains(om.files[journalPath], "<!-- vi:android-voice-inbox:cap-2001 -->") {
-		t.Fatalf("journal should include capture marker")
+	if !audioFound {
+		t.Fatalf("expected stored audio artifact, got %+v", report.Artifacts)
 	}
-	appendHits := om.appendHits
 
-	res, err = runner.ProcessCapturesOnce(context.Background())
-	if err != nil {
-		t.Fatalf("second capture processing pass should succeed: %v", err)
+	var events []string
+	failedWithError := false
+	for _, ev := range report.Events {
+		events = append(events, ev.Event)
+		if ev.Event == "failed" && strings.Contains(ev.Error, "append") {
+			failedWithError = true
+		}
 	}
-	if res.Succeeded != 0 || om.appendHits != appendHits {
-		t.Fatalf("expected no duplicate append, result=%+v appendHits=%d", res, om.appendHits)
+	if !failedWithError {
+		t.Fatalf("expected failed attempt with its error in history, got %+v", report.Events)
+	}
+	if events[0] != "queued" || events[len(events)-1] != "done" {
+		t.Fatalf("expected history from queued to done, got %v", events)
+	}
+
+	if _, err := runner.Inspect(context.Background(), "does-not-exist"); err == nil {
+		t.Fatalf("expected unknown id to fail")
 	}
 }
 
-func TestProcessCapturesOnceRetriesFailedCapture(t *testing.T) {
+func TestCleanupWritesRotatedBackupAndExportCoversTables(t *testing.T) {
 	dm := newDiscordMock(t)
 	defer dm.close()
 	om := newObsidianMock(t)
 	defer om.close()
-	om.appendFail = true
 
-	runner, st, cfg, cleanup := setupRunner(t, dm, om)
+	dm.messages = []discord.Message{makeMessage(dm.server.URL, "1001")}
+	runner, _, cfg, cleanup := setupRunner(t, dm, om)
 	defer cleanup()
+	runner.cfg.BackupDir = filepath.Join(filepath.Dir(cfg.StateDBPath), "backups")
+	runner.cfg.BackupKeep = 1
 
-	rawDir := filepath.Join(cfg.AudioStoreDir, "http", "2026", "03", "19")
-	if err := os.MkdirAll(rawDir, 0o755); err != nil {
-		t.Fatalf("mkdir raw dir: %v", err)
+	if _, err := runner.PollOnce(context.Background()); err != nil {
+		t.Fatalf("poll once failed: %v", err)
 	}
-	rawPath := filepath.Join(rawDir, "cap-3001.ogg")
-	if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
-		t.Fatalf("write raw audio: %v", err)
+	stale := filepath.Join(runner.cfg.BackupDir, "state-20200101-000000.db")
+	if err := os.MkdirAll(runner.cfg.BackupDir, 0o755); err != nil {
+		t.Fatalf("mkdir backups: %v", err)
 	}
-
-	capturedAt := time.Date(2026, 3, 19, 12, 0, 0, 0, time.UTC)
-	if err := st.CreateCapture(state.CaptureRecord{
-		CaptureID:       "cap-3001",
-		Source:          "android-voice-inbox",
-		SourceDedupeKey: "cap-3001",
-		DeviceID:        "pixel-8a",
-		CapturedAt:      &capturedAt,
-		ReceivedAt:      time.Now().UTC(),
-		RawAudioPath:    rawPath,
-		ContentType:     "audio/ogg",
-		Status:          "pending",
-	}); err != nil {
-		t.Fatalf("create capture: %v", err)
+	if err := os.WriteFile(stale, []byte("old"), 0o600); err != nil {
+		t.Fatalf("write stale backup: %v", err)
 	}
 
-	res, err := runner.ProcessCapturesOnce(context.Background())
-	if err == nil {
-		t.Fatalf("expected failed processing pass")
+	res, err := runner.Cleanup(context.Background())
+	if err != nil {
+		t.Fatalf("cleanup failed: %v (%+v)", err, res)
 	}
-	if res.Failed != 1 || res.Requeued != 1 {
-		t.Fatalf("unexpected result: %+v", res)
+	backupPath, _ := res.Data["backup_path"].(string)
+	if backupPath == "" || res.Data["backups_pruned"] != 1 {
+		t.Fatalf("expected a backup and one pruned file, got %+v", res.Data)
 	}
-
-	rec, found, err := st.GetCapture("cap-3001")
-	if err != nil || !found {
-		t.Fatalf("expected stored capture after failure: found=%v err=%v", found, err)
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Fatalf("expected stale backup to be rotated out, err=%v", err)
 	}
-	if rec.Status != "failed" || rec.NextRetryAt == nil {
-		t.Fatalf("expected failed capture with retry, got %+v", rec)
+	if _, err := state.CheckIntegrity(backupPath); err != nil {
+		t.Fatalf("backup should pass integrity check: %v", err)
 	}
 
-	past := time.Now().Add(-time.Minute)
-	if err := st.MarkCaptureFailed(rec.CaptureID, rec.LastError, rec.Attempts, &past); err != nil {
-		t.Fatalf("force retry due: %v", err)
+	var jsonl strings.Builder
+	for _, table := range state.ExportTables {
+		if _, err := runner.ExportTable(&jsonl, ExportJSONL, table); err != nil {
+			t.Fatalf("export %s: %v", table, err)
+		}
 	}
-	om.appendFail = false
-
-	res, err = runner.ProcessCapturesOnce(context.Background())
-	if err != nil {
-		t.Fatalf("expected successful recovery pass: %v", err)
+	var sawMessage, sawRun bool
+	for _, line := range strings.Split(strings.TrimSpace(jsonl.String()), "\n") {
+		var row map[string]any
+		if err := json.Unmarshal([]byte(line), &row); err != nil {
+			t.Fatalf("decode export line %q: %v", line, err)
+		}
+		switch row["table"] {
+		case "messages":
+			sawMessage = row["message_id"] == "1001" && row["status"] == "done" && row["attempts"] == float64(0)
+		case "runs":
+			sawRun = sawRun || row["command"] == "poll"
+		}
 	}
-	if res.Succeeded != 1 || res.Failed != 0 {
-		t.Fatalf("unexpected retry result: %+v", res)
+	if !sawMessage || !sawRun {
+		t.Fatalf("expected message and run rows in export:\n%s", jsonl.String())
 	}
 
-	rec, found, err = st.GetCapture("cap-3001")
-	if err != nil || !found {
-		t.Fatalf("expected stored capture after retry: found=%v err=%v", found, err)
+	var csvOut strings.Builder
+	n, err := runner.ExportTable(&csvOut, ExportCSV, "messages")
+	if err != nil || n != 1 {
+		t.Fatalf("csv export: n=%d err=%v", n, err)
 	}
-	if rec.Status != "done" {
-		t.Fatalf("expected done after retry, got %s", rec.Status)
+	lines := strings.Split(strings.TrimSpace(csvOut.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "message_id,") || !strings.HasPrefix(lines[1], "1001,") {
+		t.Fatalf("unexpected csv export:\n%s", csvOut.String())
 	}
 }
 
-func TestProcessCapturesOnceDedupsWithNewHTMLMarker(t *testing.T) {
+func TestMetricsCountOutcomesAndExposeQueueGauges(t *testing.T) {
 	dm := newDiscordMock(t)
 	defer dm.close()
 	om := newObsidianMock(t)
 	defer om.close()
 
-	runner, st, cfg, cleanup := setupRunner(t, dm, om)
+	dm.messages = []discord.Message{makeMessage(dm.server.URL, "3001")}
+	runner, st, _, cleanup := setupRunner(t, dm, om)
 	defer cleanup()
 
-	rawDir := filepath.Join(cfg.AudioStoreDir, "http", "2026", "03", "19")
-	if err := os.MkdirAll(rawDir, 0o755); err != nil {
-		t.Fatalf("mkdir raw dir: %v", err)
+	succeededBefore := itemsSucceeded.Value(state.SourceDiscord)
+	journaledBefore := stagesCompleted.Value(state.SourceDiscord, state.StageJournaled)
+	whisperBefore := metrics.ExternalCallDuration.Count("whisper", "transcribe", "ok")
+	fetchBefore := metrics.ExternalCallDuration.Count("discord", "fetch_messages", "2xx")
+
+	if _, err := runner.PollOnce(context.Background()); err != nil {
+		t.Fatalf("poll once failed: %v", err)
 	}
-	rawPath := filepath.Join(rawDir, "cap-html.ogg")
-	if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
-		t.Fatalf("write raw audio: %v", err)
+	if got := itemsSucceeded.Value(state.SourceDiscord) - succeededBefore; got != 1 {
+		t.Fatalf("expected 1 succeeded discord item, got %v", got)
+	}
+	if got := stagesCompleted.Value(state.SourceDiscord, state.StageJournaled) - journaledBefore; got != 1 {
+		t.Fatalf("expected journaled stage to be counted once, got %v", got)
+	}
+	if metrics.ExternalCallDuration.Count("whisper", "transcribe", "ok") <= whisperBefore {
+		t.Fatalf("expected whisper duration to be observed")
+	}
+	if metrics.ExternalCallDuration.Count("discord", "fetch_messages", "2xx") <= fetchBefore {
+		t.Fatalf("expected discord fetch duration to be observed")
 	}
-
-	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
-	om.files[journalPath] = journal.NewJournalContent(time.Now()) + "\n## ログ - 12:00\n### 🎤 Voice Inbox\n\n既存エントリ\n\n_12:00 via Pixel 8a_\n<!-- vi:android-voice-inbox:cap-html -->\n"
 
 	if err := st.CreateCapture(state.CaptureRecord{
-		CaptureID:       "cap-html",
-		Source:          "android-voice-inbox",
-		SourceDedupeKey: "cap-html",
-		DeviceID:        "Pixel 8a",
-		ReceivedAt:      time.Now().UTC(),
-		RawAudioPath:    rawPath,
-		ContentType:     "audio/ogg",
-		Status:          "pending",
+		CaptureID:    "cap-queued",
+		Source:       "android-voice-inbox",
+		ReceivedAt:   time.Now().Add(-10 * time.Minute).UTC(),
+		RawAudioPath: "/tmp/cap-queued.ogg",
+		Status:       "pending",
 	}); err != nil {
 		t.Fatalf("create capture: %v", err)
 	}
 
-	res, err := runner.ProcessCapturesOnce(context.Background())
-	if err != nil {
-		t.Fatalf("process captures failed: %v", err)
-	}
-	if res.Succeeded != 1 || res.Failed != 0 {
-		t.Fatalf("unexpected result: %+v", res)
-	}
-	if om.appendHits != 0 {
-		t.Fatalf("expected dedup to skip append, appendHits=%d", om.appendHits)
+	reg := metrics.NewRegistry()
+	runner.RegisterMetrics(reg)
+	var out strings.Builder
+	if err := reg.WriteText(&out); err != nil {
+		t.Fatalf("write metrics: %v", err)
+	}
+	text := out.String()
+	for _, want := range []string{
+		`voice_inbox_queue_items{source="discord",status="done"} 1`,
+		`voice_inbox_queue_items{source="capture",status="pending"} 1`,
+		`voice_inbox_retry_due_items{source="capture"} 1`,
+		`voice_inbox_oldest_pending_age_seconds{source="discord"} 0`,
+		"voice_inbox_last_successful_poll_timestamp_seconds ",
+		"voice_inbox_audio_store_bytes ",
+	} {
+		if !strings.Contains(text, want) {
+			t.Fatalf("missing %q in:\n%s", want, text)
+		}
 	}
-	if strings.Count(om.files[journalPath], "<!-- vi:android-voice-inbox:cap-html -->") != 1 {
-		t.Fatalf("expected exactly one HTML marker, got %q", om.files[journalPath])
+	if strings.Contains(text, `voice_inbox_oldest_pending_age_seconds{source="capture"} 0`+"\n") {
+		t.Fatalf("expected a non-zero pending age for the queued capture:\n%s", text)
 	}
 }
 
-func TestProcessCapturesOnceDedupsWithOldYAMLMarker(t *testing.T) {
+func TestJournalQueueParksWhileObsidianIsDownAndDrainsInCaptureOrder(t *testing.T) {
 	dm := newDiscordMock(t)
 	defer dm.close()
 	om := newObsidianMock(t)
 	defer om.close()
 
-	runner, st, cfg, cleanup := setupRunner(t, dm, om)
+	_, st, cfg, cleanup := setupRunner(t, dm, om)
 	defer cleanup()
 
-	rawDir := filepath.Join(cfg.AudioStoreDir, "http", "2026", "03", "19")
-	if err := os.MkdirAll(rawDir, 0o755); err != nil {
-		t.Fatalf("mkdir raw dir: %v", err)
+	closed := httptest.NewTLSServer(http.NotFoundHandler())
+	downCfg := cfg
+	downCfg.ObsidianBaseURL = closed.URL
+	closed.Close()
+	runner := newTestRunner(downCfg, st)
+
+	base := time.Date(2026, 3, 19, 8, 0, 0, 0, time.UTC)
+	for i, id := range []string{"cap-late", "cap-early", "cap-middle"} {
+		capturedAt := base.Add(map[string]time.Duration{"cap-early": 0, "cap-middle": time.Hour, "cap-late": 2 * time.Hour}[id])
+		if err := st.CreateCapture(state.CaptureRecord{
+			CaptureID:       id,
+			Source:          "android-voice-inbox",
+			SourceDedupeKey: id,
+			CapturedAt:      &capturedAt,
+			ReceivedAt:      time.Now().Add(time.Duration(i) * time.Second).UTC(),
+			ContentType:     "audio/ogg",
+			TranscriptText:  "memo " + id,
+			Status:          "pending",
+		}); err != nil {
+			t.Fatalf("create capture: %v", err)
+		}
 	}
-	rawPath := filepath.Join(rawDir, "cap-yaml.ogg")
-	if err := os.WriteFile(rawPath, []byte("FAKE_AUDIO"), 0o644); err != nil {
-		t.Fatalf("write raw audio: %v", err)
+
+	for pass := 0; pass < 2; pass++ {
+		res, err := runner.ProcessCapturesOnce(context.Background())
+		if err != nil || res.Failed != 0 {
+			t.Fatalf("parking must not count as failure: %+v err=%v", res, err)
+		}
+	}
+	if res, err := runner.ProbeSinks(context.Background()); err != nil || res.Succeeded != 0 {
+		t.Fatalf("probe against a closed sink should drain nothing: %+v err=%v", res, err)
+	}
+	for _, id := range []string{"cap-early", "cap-middle", "cap-late"} {
+		rec, _, err := st.GetCapture(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if rec.Status != "journal_pending" || rec.Attempts != 0 || rec.NextRetryAt != nil {
+			t.Fatalf("expected %s parked without consuming attempts, got status=%s attempts=%d next=%v", id, rec.Status, rec.Attempts, rec.NextRetryAt)
+		}
 	}
 
-	journalPath := "01_Projects/Journal/" + time.Now().Format("2006-01-02") + ".md"
-	om.files[journalPath] = journal.NewJournalContent(time.Now()) + "\n## ログ - 12:00\n### 🎤 Voice Inbox\n\n既存エントリ\n\n```yaml\nvoice_inbox:\n  capture_key: \"android-voice-inbox:cap-yaml\"\n```\n"
+	runner = newTestRunner(cfg, st)
+	res, err := runner.ProbeSinks(context.Background())
+	if err != nil || res.Succeeded != 3 {
+		t.Fatalf("expected the probe to drain 3 parked captures: %+v err=%v", res, err)
+	}
+	var content string
+	for name, body := range om.files {
+		if strings.HasPrefix(name, cfg.VaultJournalDir+"/") {
+			content = body
+		}
+	}
+	early, middle, late := strings.Index(content, "memo cap-early"), strings.Index(content, "memo cap-middle"), strings.Index(content, "memo cap-late")
+	if early < 0 || !(early < middle && middle < late) {
+		t.Fatalf("expected entries in capture-time order, got:\n%s", content)
+	}
+
+	for _, parked := range []struct{ id, sink string }{{"cap-reparked", "obsidian"}, {"cap-other-sink", "archive"}} {
+		if err := st.CreateCapture(state.CaptureRecord{
+			CaptureID:       parked.id,
+			Source:          "android-voice-inbox",
+			SourceDedupeKey: parked.id,
+			ReceivedAt:      time.Now().UTC(),
+			ContentType:     "audio/ogg",
+			TranscriptText:  "memo " + parked.id,
+			Status:          "pending",
+		}); err != nil {
+			t.Fatalf("create capture: %v", err)
+		}
+		if err := st.MarkCaptureJournalPending(parked.id, parked.sink, "connection refused"); err != nil {
+			t.Fatalf("park capture: %v", err)
+		}
+	}
+	res, err = runner.ProbeSinks(context.Background())
+	if err != nil || res.Succeeded != 1 {
+		t.Fatalf("expected items parked for a healthy sink to drain without a circuit change: %+v err=%v", res, err)
+	}
+	if rec, _, _ := st.GetCapture("cap-other-sink"); rec.Status != "journal_pending" {
+		t.Fatalf("items for a sink that is not healthy should stay parked, got %s", rec.Status)
+	}
 
+	om.appendFail = true
 	if err := st.CreateCapture(state.CaptureRecord{
-		CaptureID:       "cap-yaml",
+		CaptureID:       "cap-broken",
 		Source:          "android-voice-inbox",
-		SourceDedupeKey: "cap-yaml",
-		DeviceID:        "Pixel 8a",
+		SourceDedupeKey: "cap-broken",
 		ReceivedAt:      time.Now().UTC(),
-		RawAudioPath:    rawPath,
 		ContentType:     "audio/ogg",
+		TranscriptText:  "memo cap-broken",
 		Status:          "pending",
 	}); err != nil {
 		t.Fatalf("create capture: %v", err)
 	}
-
-	res, err := runner.ProcessCapturesOnce(context.Background())
-	if err != nil {
-		t.Fatalf("process captures failed: %v", err)
-	}
-	if res.Succeeded != 1 || res.Failed != 0 {
-		t.Fatalf("unexpected result: %+v", res)
+	if _, err := runner.ProcessCapturesOnce(context.Background()); err == nil {
+		t.Fatalf("expected a content error to fail the pass")
 	}
-	if om.appendHits != 0 {
-		t.Fatalf("expected dedup to skip append, appendHits=%d", om.appendHits)
+	rec, _, err := st.GetCapture("cap-broken")
+	if err != nil {
+		t.Fatal(err)
 	}
-	if strings.Count(om.files[journalPath], `capture_key: "android-voice-inbox:cap-yaml"`) != 1 {
-		t.Fatalf("expected exactly one YAML marker, got %q", om.files[journalPath])
+	if rec.Status != "failed" || rec.Attempts != 1 || rec.NextRetryAt == nil {
+		t.Fatalf("expected normal retry accounting for content errors, got status=%s attempts=%d", rec.Status, rec.Attempts)
 	}
 }
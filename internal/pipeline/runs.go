@@ -0,0 +1,96 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"voice-inbox-daemon/internal/state"
+)
+
+var ErrRunNotFound = errors.New("no run with that id")
+
+type RunItem struct {
+	Source    string `json:"source"`
+	ID        string `json:"id"`
+	Events    int    `json:"events"`
+	LastEvent string `json:"last_event"`
+	Status    string `json:"status,omitempty"`
+	Stage     string `json:"stage,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+func (r *Runner) RunList(_ context.Context, f state.RunFilter) (Result, error) {
+	started := time.Now()
+	res := Result{Command: "runs", Data: map[string]any{}}
+
+	runs, err := r.store.ListRuns(f)
+	if err != nil {
+		res.Failed = 1
+		res.Errors = append(res.Errors, err.Error())
+		finalizeResult(&res, started)
+		return res, err
+	}
+	if runs == nil {
+		runs = []state.RunRecord{}
+	}
+	res.Processed = len(runs)
+	res.Data["runs"] = runs
+	finalizeResult(&res, started)
+	return res, nil
+}
+
+func (r *Runner) RunShow(_ context.Context, runID string) (Result, error) {
+	started := time.Now()
+	res := Result{Command: "runs show", Data: map[string]any{}}
+
+	run, found, err := r.store.GetRun(runID)
+	if err == nil && !found {
+		err = fmt.Errorf("%w: %s", ErrRunNotFound, runID)
+	}
+	var events []state.ItemEvent
+	if err == nil {
+		events, err = r.store.ListRunEvents(runID)
+	}
+	if err != nil {
+		res.Failed = 1
+		res.Errors = append(res.Errors, err.Error())
+		finalizeResult(&res, started)
+		return res, err
+	}
+
+	items := summarizeRunItems(events)
+	res.Processed = len(items)
+	res.Data["run"] = run
+	res.Data["items"] = items
+	res.Data["events"] = events
+	finalizeResult(&res, started)
+	return res, nil
+}
+
+func summarizeRunItems(events []state.ItemEvent) []RunItem {
+	items := []RunItem{}
+	index := map[string]int{}
+	for _, ev := range events {
+		i, ok := index[ev.ItemID]
+		if !ok {
+			i = len(items)
+			index[ev.ItemID] = i
+			items = append(items, RunItem{Source: ev.Source, ID: ev.ItemID})
+		}
+		item := &items[i]
+		item.Events++
+		item.LastEvent = ev.Event
+		if ev.Status != "" {
+			item.Status = ev.Status
+		}
+		if ev.Stage != "" {
+			item.Stage = ev.Stage
+		}
+		if ev.Error != "" {
+			item.Error = ev.Error
+		}
+	}
+	return items
+}
@@ -0,0 +1,47 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"voice-inbox-daemon/internal/state"
+)
+
+func (r *Runner) Search(_ context.Context, q state.SearchQuery) (Result, error) {
+	started := time.Now()
+	res := Result{Command: "search", Data: map[string]any{}}
+
+	if strings.TrimSpace(q.Text) == "" {
+		err := errors.New("search query must not be empty")
+		res.Failed = 1
+		res.Errors = append(res.Errors, err.Error())
+		finalizeResult(&res, started)
+		return res, err
+	}
+	switch q.Source {
+	case "", state.SourceDiscord, state.SourceCapture:
+	default:
+		err := fmt.Errorf("unknown source %q (want discord or capture)", q.Source)
+		res.Failed = 1
+		res.Errors = append(res.Errors, err.Error())
+		finalizeResult(&res, started)
+		return res, err
+	}
+	hits, err := r.store.SearchTranscripts(q)
+	if err != nil {
+		res.Failed = 1
+		res.Errors = append(res.Errors, err.Error())
+		finalizeResult(&res, started)
+		return res, err
+	}
+	if hits == nil {
+		hits = []state.SearchHit{}
+	}
+	res.Processed = len(hits)
+	res.Data["results"] = hits
+	finalizeResult(&res, started)
+	return res, nil
+}
@@ -0,0 +1,233 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"voice-inbox-daemon/internal/logging"
+	"voice-inbox-daemon/internal/state"
+	"voice-inbox-daemon/internal/transcribe"
+)
+
+type chunkTranscript struct {
+	Index   int    `json:"index"`
+	StartMS int64  `json:"start_ms"`
+	EndMS   int64  `json:"end_ms"`
+	Text    string `json:"text"`
+}
+
+func (r *Runner) probeAudio(ctx context.Context, target processTarget, path string) (state.AudioMetadata, error) {
+	if target.Audio.Probed() || strings.TrimSpace(r.cfg.FFprobeBin) == "" {
+		return target.Audio, nil
+	}
+	info, err := transcribe.ProbeAudio(ctx, r.cfg.FFprobeBin, path)
+	if err != nil {
+		if errors.Is(err, transcribe.ErrNoAudioStream) || errors.Is(err, transcribe.ErrUnreadableFile) {
+			return state.AudioMetadata{}, permanent(fmt.Errorf("rejected %s: %w", filepath.Base(path), err))
+		}
+		if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+			logging.FromContext(ctx).Warn("skip audio probe", "stage", state.StageDownloaded, "error", err)
+			return target.Audio, nil
+		}
+		return state.AudioMetadata{}, err
+	}
+
+	meta := state.AudioMetadata{
+		DurationMS: info.DurationMS,
+		Codec:      info.Codec,
+		Channels:   info.Channels,
+		SampleRate: info.SampleRate,
+		Bitrate:    info.Bitrate,
+	}
+	var saveErr error
+	if target.Source == "discord" {
+		saveErr = r.store.SetMessageAudioMetadata(target.MessageID, meta)
+	} else {
+		saveErr = r.store.SetCaptureAudioMetadata(target.CaptureID, meta)
+	}
+	if saveErr != nil {
+		logging.FromContext(ctx).Warn("store audio metadata failed", "stage", state.StageDownloaded, "error", saveErr)
+	}
+	return meta, nil
+}
+
+func (r *Runner) transcribeOriginal(ctx context.Context, captureKey, origPath string) (transcribe.Result, error) {
+	audioHash, err := fileSHA256(origPath)
+	if err != nil {
+		return transcribe.Result{}, fmt.Errorf("hash audio: %w", err)
+	}
+	cached, found, err := r.store.GetCachedTranscript(audioHash, r.cfg.WhisperModel, r.cfg.WhisperLanguage)
+	if err != nil {
+		logging.FromContext(ctx).Warn("read transcript cache failed", "stage", state.StageDownloaded, "error", err)
+	} else if found {
+		res := transcribe.Result{Text: cached.Text}
+		if cached.TranscriptPath != "" {
+			if _, err := os.Stat(cached.TranscriptPath); err == nil {
+				res.TranscriptJSON = cached.TranscriptPath
+			}
+		}
+		return res, nil
+	}
+
+	baseDir := filepath.Dir(origPath)
+	baseName := strings.TrimSuffix(filepath.Base(origPath), filepath.Ext(origPath))
+	wavPath := filepath.Join(baseDir, baseName+"_16k.wav")
+	transcriptDir := filepath.Join(baseDir, "transcripts")
+
+	if err := transcribe.NormalizeToWav(ctx, r.cfg.FFmpegBin, origPath, wavPath); err != nil {
+		return transcribe.Result{}, err
+	}
+
+	txRes, err := r.transcribeAudio(ctx, captureKey, wavPath, transcriptDir)
+	if err != nil {
+		return transcribe.Result{}, err
+	}
+	if err := os.Remove(wavPath); err != nil && !os.IsNotExist(err) {
+		logging.FromContext(ctx).Warn("cleanup normalized wav failed", "stage", state.StageDownloaded, "path", wavPath, "error", err)
+	}
+
+	if err := r.store.PutCachedTranscript(state.CachedTranscript{
+		AudioSHA256:    audioHash,
+		Model:          r.cfg.WhisperModel,
+		Language:       r.cfg.WhisperLanguage,
+		Text:           txRes.Text,
+		TranscriptPath: txRes.TranscriptJSON,
+	}); err != nil {
+		logging.FromContext(ctx).Warn("write transcript cache failed", "stage", state.StageDownloaded, "error", err)
+	}
+	return txRes, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func (r *Runner) whisperConfig() transcribe.WhisperConfig {
+	return transcribe.WhisperConfig{Bin: r.cfg.WhisperBin, Model: r.cfg.WhisperModel, Language: r.cfg.WhisperLanguage}
+}
+
+func (r *Runner) transcriptionContext(ctx context.Context, audio time.Duration) (context.Context, context.CancelFunc) {
+	if audio <= 0 || r.cfg.WhisperTimeoutFactor <= 0 {
+		return transcribe.ContextWithTranscriptionTimeout(ctx)
+	}
+	return transcribe.ContextWithTranscriptionTimeoutFor(ctx, audio, float64(r.cfg.WhisperTimeoutFactor))
+}
+
+func (r *Runner) transcribeAudio(ctx context.Context, captureKey, wavPath, transcriptDir string) (transcribe.Result, error) {
+	info, err := transcribe.ProbeWav(wavPath)
+	if err != nil {
+		txCtx, cancel := r.transcriptionContext(ctx, 0)
+		defer cancel()
+		return transcribe.RunWhisper(txCtx, r.whisperConfig(), wavPath, transcriptDir)
+	}
+
+	chunkLength := time.Duration(r.cfg.WhisperChunkSeconds) * time.Second
+	if chunkLength <= 0 || info.Duration <= chunkLength {
+		txCtx, cancel := r.transcriptionContext(ctx, info.Duration)
+		defer cancel()
+		return transcribe.RunWhisper(txCtx, r.whisperConfig(), wavPath, transcriptDir)
+	}
+
+	chunks, err := transcribe.PlanChunks(wavPath, info, transcribe.ChunkOptions{
+		Length:  chunkLength,
+		Overlap: time.Duration(r.cfg.WhisperChunkOverlapSec) * time.Second,
+	})
+	if err != nil {
+		return transcribe.Result{}, fmt.Errorf("plan transcription chunks: %w", err)
+	}
+
+	checkpoints, err := r.store.ListTranscriptChunks(captureKey)
+	if err != nil {
+		return transcribe.Result{}, fmt.Errorf("load transcription checkpoints: %w", err)
+	}
+	completed := make(map[int]state.TranscriptChunkRecord, len(checkpoints))
+	for _, cp := range checkpoints {
+		completed[cp.ChunkIndex] = cp
+	}
+
+	baseName := strings.TrimSuffix(filepath.Base(wavPath), filepath.Ext(wavPath))
+	chunkDir := filepath.Join(filepath.Dir(wavPath), baseName+"_chunks")
+	defer func() {
+		if err := os.RemoveAll(chunkDir); err != nil {
+			logging.FromContext(ctx).Warn("cleanup chunk dir failed", "stage", state.StageDownloaded, "path", chunkDir, "error", err)
+		}
+	}()
+
+	parts := make([]chunkTranscript, 0, len(chunks))
+	for _, chunk := range chunks {
+		startMS := chunk.Start.Milliseconds()
+		endMS := chunk.End.Milliseconds()
+		if cp, ok := completed[chunk.Index]; ok && cp.ChunkCount == len(chunks) && cp.StartMS == startMS && cp.EndMS == endMS {
+			parts = append(parts, chunkTranscript{Index: chunk.Index, StartMS: startMS, EndMS: endMS, Text: cp.Text})
+			continue
+		}
+
+		chunkPath := filepath.Join(chunkDir, fmt.Sprintf("%s_chunk%03d.wav", baseName, chunk.Index))
+		if err := transcribe.WriteChunk(wavPath, info, chunk, chunkPath); err != nil {
+			return transcribe.Result{}, fmt.Errorf("write chunk %d/%d: %w", chunk.Index+1, len(chunks), err)
+		}
+
+		txCtx, cancel := r.transcriptionContext(ctx, chunk.Duration())
+		txRes, err := transcribe.RunWhisper(txCtx, r.whisperConfig(), chunkPath, chunkDir)
+		cancel()
+		if err != nil {
+			return transcribe.Result{}, fmt.Errorf("chunk %d/%d: %w", chunk.Index+1, len(chunks), err)
+		}
+
+		if err := r.store.SaveTranscriptChunk(state.TranscriptChunkRecord{
+			CaptureKey: captureKey,
+			ChunkIndex: chunk.Index,
+			ChunkCount: len(chunks),
+			StartMS:    startMS,
+			EndMS:      endMS,
+			Text:       txRes.Text,
+		}); err != nil {
+			return transcribe.Result{}, fmt.Errorf("checkpoint chunk %d/%d: %w", chunk.Index+1, len(chunks), err)
+		}
+		parts = append(parts, chunkTranscript{Index: chunk.Index, StartMS: startMS, EndMS: endMS, Text: txRes.Text})
+	}
+
+	texts := make([]string, 0, len(parts))
+	for _, p := range parts {
+		texts = append(texts, p.Text)
+	}
+	text := transcribe.StitchTranscripts(texts)
+
+	if err := os.MkdirAll(transcriptDir, 0o755); err != nil {
+		return transcribe.Result{}, err
+	}
+	jsonPath := filepath.Join(transcriptDir, baseName+".json")
+	payload, err := json.Marshal(map[string]any{"text": text, "chunks": parts})
+	if err != nil {
+		return transcribe.Result{}, err
+	}
+	if err := os.WriteFile(jsonPath, payload, 0o644); err != nil {
+		return transcribe.Result{}, fmt.Errorf("write stitched transcript: %w", err)
+	}
+
+	return transcribe.Result{
+		Text:           text,
+		TranscriptJSON: jsonPath,
+		WavPath:        wavPath,
+	}, nil
+}
@@ -0,0 +1,250 @@
+package routing
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const triggerSeparators = ":：、,，.。!！ 　\t\n"
+
+type Rule struct {
+	Name         string   `json:"name"`
+	Prefixes     []string `json:"prefixes,omitempty"`
+	Match        string   `json:"match,omitempty"`
+	Sources      []string `json:"sources,omitempty"`
+	DeviceIDs    []string `json:"device_ids,omitempty"`
+	ChannelIDs   []string `json:"channel_ids,omitempty"`
+	Path         string   `json:"path,omitempty"`
+	Sink         string   `json:"sink,omitempty"`
+	StripTrigger bool     `json:"strip_trigger,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+}
+
+type Input struct {
+	Now        time.Time
+	Transcript string
+	Source     string
+	DeviceID   string
+	ChannelID  string
+}
+
+type Decision struct {
+	Route      string
+	Sink       string
+	Path       string
+	Transcript string
+	Tags       []string
+}
+
+type Router struct {
+	rules []compiled
+}
+
+type compiled struct {
+	Rule
+	match *regexp.Regexp
+	path  *template.Template
+}
+
+type pathData struct {
+	Date   string
+	Year   string
+	Month  string
+	Day    string
+	Week   string
+	Tag    string
+	Route  string
+	Source string
+	Device string
+	Match  map[string]string
+}
+
+func New(rules []Rule) (*Router, error) {
+	r := &Router{}
+	for i, rule := range rules {
+		if strings.TrimSpace(rule.Name) == "" {
+			rule.Name = fmt.Sprintf("routes[%d]", i)
+		}
+		c := compiled{Rule: rule}
+		if rule.Match != "" {
+			re, err := regexp.Compile(rule.Match)
+			if err != nil {
+				return nil, fmt.Errorf("route %s: match: %w", rule.Name, err)
+			}
+			c.match = re
+		}
+		if rule.Path != "" {
+			tmpl, err := template.New(rule.Name).Option("missingkey=zero").Parse(rule.Path)
+			if err != nil {
+				return nil, fmt.Errorf("route %s: path: %w", rule.Name, err)
+			}
+			c.path = tmpl
+		}
+		r.rules = append(r.rules, c)
+	}
+	return r, nil
+}
+
+func (r *Router) Route(in Input) (Decision, bool, error) {
+	if r == nil {
+		return Decision{Transcript: in.Transcript}, false, nil
+	}
+	for _, rule := range r.rules {
+		d, ok, err := rule.apply(in)
+		if err != nil || ok {
+			return d, ok, err
+		}
+	}
+	return Decision{Transcript: in.Transcript}, false, nil
+}
+
+func (c compiled) apply(in Input) (Decision, bool, error) {
+	if len(c.Sources) > 0 && !containsFold(c.Sources, in.Source) {
+		return Decision{}, false, nil
+	}
+	if len(c.DeviceIDs) > 0 && !containsFold(c.DeviceIDs, in.DeviceID) {
+		return Decision{}, false, nil
+	}
+	if len(c.ChannelIDs) > 0 && !containsFold(c.ChannelIDs, in.ChannelID) {
+		return Decision{}, false, nil
+	}
+
+	transcript := strings.TrimSpace(in.Transcript)
+	body := transcript
+	if len(c.Prefixes) > 0 {
+		rest, ok := stripTrigger(transcript, c.Prefixes)
+		if !ok {
+			return Decision{}, false, nil
+		}
+		if c.StripTrigger {
+			body = rest
+		}
+	}
+
+	groups := map[string]string{}
+	if c.match != nil {
+		m := c.match.FindStringSubmatch(transcript)
+		if m == nil {
+			return Decision{}, false, nil
+		}
+		for i, name := range c.match.SubexpNames() {
+			if name != "" {
+				groups[name] = m[i]
+			}
+		}
+	}
+
+	d := Decision{Route: c.Name, Sink: c.Sink, Transcript: body, Tags: append([]string(nil), c.Tags...)}
+	if c.path == nil {
+		return d, true, nil
+	}
+
+	tag := groups["tag"]
+	if tag == "" && len(c.Tags) > 0 {
+		tag = c.Tags[0]
+	}
+	year, week := in.Now.ISOWeek()
+	data := pathData{
+		Date:   in.Now.Format("2006-01-02"),
+		Year:   in.Now.Format("2006"),
+		Month:  in.Now.Format("01"),
+		Day:    in.Now.Format("02"),
+		Week:   fmt.Sprintf("%04d-W%02d", year, week),
+		Tag:    pathSegment(tag),
+		Route:  pathSegment(c.Name),
+		Source: pathSegment(in.Source),
+		Device: pathSegment(in.DeviceID),
+		Match:  map[string]string{},
+	}
+	for k, v := range groups {
+		data.Match[k] = pathSegment(v)
+	}
+	var buf bytes.Buffer
+	if err := c.path.Execute(&buf, data); err != nil {
+		return Decision{}, false, fmt.Errorf("route %s: render path: %w", c.Name, err)
+	}
+	p, err := cleanNotePath(buf.String())
+	if err != nil {
+		return Decision{}, false, fmt.Errorf("route %s: %w", c.Name, err)
+	}
+	d.Path = p
+	return d, true, nil
+}
+
+func stripTrigger(transcript string, prefixes []string) (string, bool) {
+	for _, prefix := range prefixes {
+		word := strings.TrimRight(strings.TrimSpace(prefix), triggerSeparators)
+		if word == "" {
+			continue
+		}
+		n := utf8.RuneCountInString(word)
+		runes := []rune(transcript)
+		if len(runes) < n || !strings.EqualFold(string(runes[:n]), word) {
+			continue
+		}
+		rest := string(runes[n:])
+		if rest != "" {
+			next, _ := utf8.DecodeRuneInString(rest)
+			if !strings.ContainsRune(triggerSeparators, next) && (unicode.IsLetter(next) || unicode.IsDigit(next)) && isWordRune(word) {
+				continue
+			}
+		}
+		return strings.TrimLeft(rest, triggerSeparators), true
+	}
+	return "", false
+}
+
+func isWordRune(word string) bool {
+	last, _ := utf8.DecodeLastRuneInString(word)
+	return last < utf8.RuneSelf && (unicode.IsLetter(last) || unicode.IsDigit(last))
+}
+
+func containsFold(values []string, v string) bool {
+	for _, candidate := range values {
+		if strings.EqualFold(strings.TrimSpace(candidate), strings.TrimSpace(v)) {
+			return true
+		}
+	}
+	return false
+}
+
+func pathSegment(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '/' || r == '\\' || r == ':' || unicode.IsControl(r):
+			return '-'
+		}
+		return r
+	}, s)
+	return strings.Trim(s, ". ")
+}
+
+func cleanNotePath(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", errors.New("path rendered empty")
+	}
+	p := path.Clean("/" + strings.ReplaceAll(raw, "\\", "/"))
+	p = strings.TrimPrefix(p, "/")
+	if p == "" || p == "." {
+		return "", errors.New("path rendered empty")
+	}
+	for _, seg := range strings.Split(p, "/") {
+		if seg == "" || seg == ".." {
+			return "", fmt.Errorf("invalid path %q", raw)
+		}
+	}
+	if path.Ext(p) != ".md" {
+		p += ".md"
+	}
+	return p, nil
+}
@@ -0,0 +1,88 @@
+package routing
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRouteFirstMatchingRuleWins(t *testing.T) {
+	router, err := New([]Rule{
+		{Name: "todo", Prefixes: []string{"todo:", "タスク"}, Path: "Inbox/Todo.md", StripTrigger: true, Tags: []string{"todo"}},
+		{Name: "pixel", DeviceIDs: []string{"Pixel 8a"}, Sink: "work"},
+		{Name: "catch-all", Path: "Inbox/Other.md"},
+	})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+	now := time.Date(2026, 3, 19, 9, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		in         Input
+		route      string
+		path       string
+		transcript string
+	}{
+		{Input{Transcript: "TODO: 牛乳を買う", DeviceID: "Pixel 8a"}, "todo", "Inbox/Todo.md", "牛乳を買う"},
+		{Input{Transcript: "タスク、請求書を送る"}, "todo", "Inbox/Todo.md", "請求書を送る"},
+		{Input{Transcript: "todos are done", DeviceID: "pixel 8a"}, "pixel", "", "todos are done"},
+		{Input{Transcript: "雑談"}, "catch-all", "Inbox/Other.md", "雑談"},
+	}
+	for _, c := range cases {
+		c.in.Now = now
+		d, ok, err := router.Route(c.in)
+		if err != nil || !ok {
+			t.Fatalf("%q: ok=%v err=%v", c.in.Transcript, ok, err)
+		}
+		if d.Route != c.route || d.Path != c.path || d.Transcript != c.transcript {
+			t.Fatalf("%q: unexpected decision %+v", c.in.Transcript, d)
+		}
+	}
+}
+
+func TestRouteUnmatchedFallsThrough(t *testing.T) {
+	router, err := New([]Rule{{Name: "shopping", Prefixes: []string{"shopping:"}, Sources: []string{"discord"}, Path: "Lists/Shopping.md"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, ok, err := router.Route(Input{Transcript: "shopping: eggs", Source: "android-voice-inbox"})
+	if err != nil || ok || d.Transcript != "shopping: eggs" {
+		t.Fatalf("expected fall through, got %+v ok=%v err=%v", d, ok, err)
+	}
+
+	var none *Router
+	if _, ok, _ := none.Route(Input{Transcript: "x"}); ok {
+		t.Fatal("nil router should never match")
+	}
+}
+
+func TestRoutePathTemplateIsSanitized(t *testing.T) {
+	router, err := New([]Rule{{
+		Name:  "project",
+		Match: `^project (?P<tag>\S+)`,
+		Path:  "Projects/{{.Tag}}/{{.Week}}",
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	d, ok, err := router.Route(Input{Now: now, Transcript: "project ../../etc/passwd done"})
+	if err != nil || !ok {
+		t.Fatalf("ok=%v err=%v", ok, err)
+	}
+	if d.Path != "Projects/-..-etc-passwd/2026-W01.md" {
+		t.Fatalf("unexpected path %q", d.Path)
+	}
+	if strings.Contains(d.Path, "../") {
+		t.Fatalf("path escapes vault: %q", d.Path)
+	}
+}
+
+func TestNewRejectsInvalidRules(t *testing.T) {
+	if _, err := New([]Rule{{Match: "("}}); err == nil || !strings.Contains(err.Error(), "routes[0]: match") {
+		t.Fatalf("expected regex error, got %v", err)
+	}
+	if _, err := New([]Rule{{Name: "bad", Path: "{{.Tag"}}); err == nil || !strings.Contains(err.Error(), "route bad: path") {
+		t.Fatalf("expected template error, got %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	Service        = "voice-inbox"
+	commandTimeout = 10 * time.Second
+)
+
+var ErrNoKeyring = errors.New("no keyring backend available (install secret-tool on Linux)")
+
+type Keyring interface {
+	Name() string
+	Get(key string) (string, bool, error)
+	Set(key, value string) error
+}
+
+func ReadFile(path string) (string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(raw), "\r\n"), nil
+}
+
+func RunCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", command)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("%w: %s", err, firstLine(msg))
+		}
+		return "", err
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+func DefaultKeyring() (Keyring, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		if path, err := exec.LookPath("security"); err == nil {
+			return keychain{bin: path}, nil
+		}
+	default:
+		if path, err := exec.LookPath("secret-tool"); err == nil {
+			return secretService{bin: path}, nil
+		}
+	}
+	return nil, ErrNoKeyring
+}
+
+type secretService struct {
+	bin string
+}
+
+func (s secretService) Name() string { return "secret-service" }
+
+func (s secretService) Get(key string) (string, bool, error) {
+	out, err := run(nil, s.bin, "lookup", "service", Service, "key", key)
+	if code, stderr, ok := exitStatus(err); ok && code == 1 && stderr == "" {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return out, out != "", nil
+}
+
+func (s secretService) Set(key, value string) error {
+	_, err := run(strings.NewReader(value), s.bin, "store", "--label", Service+" "+key, "service", Service, "key", key)
+	return err
+}
+
+const errSecItemNotFound = 44
+
+type keychain struct {
+	bin string
+}
+
+func (k keychain) Name() string { return "keychain" }
+
+func (k keychain) Get(key string) (string, bool, error) {
+	out, err := run(nil, k.bin, "find-generic-password", "-s", Service, "-a", key, "-w")
+	if code, _, ok := exitStatus(err); ok && code == errSecItemNotFound {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return out, out != "", nil
+}
+
+func (k keychain) Set(key, value string) error {
+	script := fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", quote(Service), quote(key), quote(value))
+	_, err := run(strings.NewReader(script), k.bin, "-i")
+	return err
+}
+
+func run(stdin *strings.Reader, bin string, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, bin, args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", &commandError{bin: bin, err: err, stderr: strings.TrimSpace(stderr.String())}
+	}
+	return strings.TrimRight(stdout.String(), "\r\n"), nil
+}
+
+type commandError struct {
+	bin    string
+	err    error
+	stderr string
+}
+
+func (e *commandError) Error() string {
+	if e.stderr != "" {
+		return fmt.Sprintf("%s: %v: %s", e.bin, e.err, firstLine(e.stderr))
+	}
+	return fmt.Sprintf("%s: %v", e.bin, e.err)
+}
+
+func (e *commandError) Unwrap() error {
+	return e.err
+}
+
+func exitStatus(err error) (int, string, bool) {
+	var cmdErr *commandError
+	var exitErr *exec.ExitError
+	if !errors.As(err, &cmdErr) || !errors.As(cmdErr.err, &exitErr) {
+		return 0, "", false
+	}
+	return exitErr.ExitCode(), cmdErr.stderr, true
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
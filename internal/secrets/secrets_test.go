@@ -0,0 +1,61 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func fakeBin(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fake")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestQuoteEscapesBackslashesAndQuotes(t *testing.T) {
+	cases := map[string]string{
+		"plain":         `"plain"`,
+		`say "hi"`:      `"say \"hi\""`,
+		`C:\path`:       `"C:\\path"`,
+		`\"`:            `"\\\""`,
+		"with space  x": `"with space  x"`,
+	}
+	for in, want := range cases {
+		if got := quote(in); got != want {
+			t.Fatalf("quote(%q) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestSecretServiceGetSeparatesNotFoundFromFailure(t *testing.T) {
+	found := secretService{bin: fakeBin(t, "printf 'token\\n'")}
+	if v, ok, err := found.Get("DISCORD_BOT_TOKEN"); err != nil || !ok || v != "token" {
+		t.Fatalf("found: v=%q ok=%v err=%v", v, ok, err)
+	}
+
+	missing := secretService{bin: fakeBin(t, "exit 1")}
+	if v, ok, err := missing.Get("DISCORD_BOT_TOKEN"); err != nil || ok || v != "" {
+		t.Fatalf("missing: v=%q ok=%v err=%v", v, ok, err)
+	}
+
+	broken := secretService{bin: fakeBin(t, "echo 'Cannot autolaunch D-Bus without X11 $DISPLAY' >&2; exit 1")}
+	if _, ok, err := broken.Get("DISCORD_BOT_TOKEN"); err == nil || ok || !strings.Contains(err.Error(), "Cannot autolaunch D-Bus") {
+		t.Fatalf("broken: expected a failure with the stderr line, ok=%v err=%v", ok, err)
+	}
+}
+
+func TestKeychainGetSeparatesNotFoundFromFailure(t *testing.T) {
+	missing := keychain{bin: fakeBin(t, "echo 'The specified item could not be found in the keychain.' >&2; exit 44")}
+	if _, ok, err := missing.Get("DISCORD_BOT_TOKEN"); err != nil || ok {
+		t.Fatalf("missing: ok=%v err=%v", ok, err)
+	}
+
+	locked := keychain{bin: fakeBin(t, "echo 'User interaction is not allowed.' >&2; exit 36")}
+	if _, ok, err := locked.Get("DISCORD_BOT_TOKEN"); err == nil || ok {
+		t.Fatalf("locked: expected a failure, ok=%v err=%v", ok, err)
+	}
+}
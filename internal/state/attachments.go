@@ -0,0 +1,69 @@
+package state
+
+import (
+	"time"
+)
+
+type VaultAttachment struct {
+	Sink      string
+	VaultPath string
+	Source    string
+	ItemID    string
+	NotePath  string
+	SizeBytes int64
+	CreatedAt time.Time
+}
+
+func (s *Store) RecordAttachment(rec VaultAttachment) error {
+	createdAt := rec.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO vault_attachments (sink, vault_path, source, item_id, note_path, size_bytes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(sink, vault_path) DO UPDATE SET
+			source = excluded.source,
+			item_id = excluded.item_id,
+			note_path = excluded.note_path,
+			size_bytes = excluded.size_bytes
+	`, rec.Sink, rec.VaultPath, rec.Source, rec.ItemID, rec.NotePath, rec.SizeBytes, createdAt.UTC().Format(time.RFC3339))
+	return err
+}
+
+func (s *Store) ListAttachmentsBefore(cutoff time.Time, after VaultAttachment, limit int) ([]VaultAttachment, error) {
+	afterCreatedAt := ""
+	if !after.CreatedAt.IsZero() {
+		afterCreatedAt = after.CreatedAt.UTC().Format(time.RFC3339)
+	}
+	rows, err := s.db.Query(`
+		SELECT sink, vault_path, source, item_id, note_path, size_bytes, created_at
+		FROM vault_attachments
+		WHERE created_at < ? AND (created_at, sink, vault_path) > (?, ?, ?)
+		ORDER BY created_at, sink, vault_path
+		LIMIT ?
+	`, cutoff.UTC().Format(time.RFC3339), afterCreatedAt, after.Sink, after.VaultPath, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []VaultAttachment
+	for rows.Next() {
+		var rec VaultAttachment
+		var createdAtRaw string
+		if err := rows.Scan(&rec.Sink, &rec.VaultPath, &rec.Source, &rec.ItemID, &rec.NotePath, &rec.SizeBytes, &createdAtRaw); err != nil {
+			return nil, err
+		}
+		if t, err := time.Parse(time.RFC3339, createdAtRaw); err == nil {
+			rec.CreatedAt = t
+		}
+		out = append(out, rec)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) DeleteAttachment(sink, vaultPath string) error {
+	_, err := s.db.Exec(`DELETE FROM vault_attachments WHERE sink = ? AND vault_path = ?`, sink, vaultPath)
+	return err
+}
@@ -0,0 +1,184 @@
+package state
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const backupPrefix = "state-"
+
+type BackupInfo struct {
+	Path          string `json:"path"`
+	SizeBytes     int64  `json:"size_bytes"`
+	SchemaVersion int    `json:"schema_version"`
+}
+
+func (s *Store) BackupTo(path string) (BackupInfo, error) {
+	if _, err := os.Stat(path); err == nil {
+		return BackupInfo{}, fmt.Errorf("backup target already exists: %s", path)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return BackupInfo{}, err
+	}
+	partial := path + ".partial"
+	_ = os.Remove(partial)
+	if _, err := s.db.Exec(`VACUUM INTO ?`, partial); err != nil {
+		_ = os.Remove(partial)
+		return BackupInfo{}, fmt.Errorf("vacuum into %s: %w", partial, err)
+	}
+	info, err := CheckIntegrity(partial)
+	if err != nil {
+		_ = os.Remove(partial)
+		return BackupInfo{}, err
+	}
+	if err := os.Rename(partial, path); err != nil {
+		_ = os.Remove(partial)
+		return BackupInfo{}, err
+	}
+	info.Path = path
+	return info, nil
+}
+
+func CheckIntegrity(path string) (BackupInfo, error) {
+	st, err := os.Stat(path)
+	if err != nil {
+		return BackupInfo{}, err
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return BackupInfo{}, err
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRow(`PRAGMA integrity_check`).Scan(&result); err != nil {
+		return BackupInfo{}, fmt.Errorf("integrity check %s: %w", path, err)
+	}
+	if result != "ok" {
+		return BackupInfo{}, fmt.Errorf("integrity check %s failed: %s", path, result)
+	}
+	for _, table := range []string{"messages", "captures", "runs", "kv"} {
+		exists, err := tableExists(db, table)
+		if err != nil {
+			return BackupInfo{}, err
+		}
+		if !exists {
+			return BackupInfo{}, fmt.Errorf("%s is not a voice-inbox state db: missing table %s", path, table)
+		}
+	}
+	probe := &Store{db: db}
+	version, err := probe.checkSchemaVersion()
+	if err != nil {
+		return BackupInfo{}, err
+	}
+	return BackupInfo{Path: path, SizeBytes: st.Size(), SchemaVersion: version}, nil
+}
+
+func Restore(src, dst string, now time.Time) (BackupInfo, string, error) {
+	info, err := CheckIntegrity(src)
+	if err != nil {
+		return BackupInfo{}, "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return BackupInfo{}, "", err
+	}
+	inUse, err := AcquireFileLock(inUseLockPath(dst))
+	if err != nil {
+		return BackupInfo{}, "", fmt.Errorf("state db is open in another process (stop daemon / serve first): %w", err)
+	}
+	defer inUse.Release()
+
+	staged := dst + ".restore"
+	if err := copyFile(src, staged); err != nil {
+		_ = os.Remove(staged)
+		return BackupInfo{}, "", err
+	}
+
+	previous := ""
+	if _, err := os.Stat(dst); err == nil {
+		previous = dst + ".pre-restore-" + now.UTC().Format("20060102-150405")
+	}
+	for _, suffix := range []string{"", "-journal", "-wal", "-shm"} {
+		if _, err := os.Stat(dst + suffix); err != nil {
+			continue
+		}
+		if previous == "" {
+			err = os.Remove(dst + suffix)
+		} else {
+			err = os.Rename(dst+suffix, previous+suffix)
+		}
+		if err != nil {
+			_ = os.Remove(staged)
+			return BackupInfo{}, previous, err
+		}
+	}
+	if err := os.Rename(staged, dst); err != nil {
+		_ = os.Remove(staged)
+		return BackupInfo{}, previous, err
+	}
+	info.Path = dst
+	return info, previous, nil
+}
+
+func BackupFileName(now time.Time) string {
+	return backupPrefix + now.UTC().Format("20060102-150405") + ".db"
+}
+
+func RotateBackups(dir string, keep int) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.Type().IsRegular() && strings.HasPrefix(name, backupPrefix) && strings.HasSuffix(name, ".db") {
+			names = append(names, name)
+		}
+	}
+	if len(names) <= keep {
+		return nil, nil
+	}
+	sort.Strings(names)
+
+	var removed []string
+	for _, name := range names[:len(names)-keep] {
+		path := filepath.Join(dir, name)
+		if err := os.Remove(path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,107 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackupRestoreAndRotate(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "state.db")
+	st, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	if err := st.UpsertPending(MessageRecord{
+		MessageID:     "m-keep",
+		ChannelID:     "c1",
+		AuthorID:      "a1",
+		AttachmentID:  "att1",
+		AttachmentURL: "https://example.invalid/a.ogg",
+	}); err != nil {
+		t.Fatalf("upsert message: %v", err)
+	}
+
+	backupPath := filepath.Join(dir, "backups", BackupFileName(time.Date(2026, 3, 19, 9, 0, 0, 0, time.UTC)))
+	info, err := st.BackupTo(backupPath)
+	if err != nil {
+		t.Fatalf("backup: %v", err)
+	}
+	if info.SchemaVersion != LatestSchemaVersion() || info.SizeBytes == 0 {
+		t.Fatalf("unexpected backup info: %+v", info)
+	}
+	if _, err := st.BackupTo(backupPath); err == nil {
+		t.Fatalf("expected backup to refuse overwriting an existing file")
+	}
+
+	if err := st.MarkDone("m-keep", "Journal/x.md", "", "", ""); err != nil {
+		t.Fatalf("mark done: %v", err)
+	}
+	_ = st.Close()
+
+	corrupt := filepath.Join(dir, "corrupt.db")
+	if err := os.WriteFile(corrupt, []byte("definitely not sqlite"), 0o600); err != nil {
+		t.Fatalf("write corrupt file: %v", err)
+	}
+	if _, _, err := Restore(corrupt, dbPath, time.Now()); err == nil {
+		t.Fatalf("expected restore of a corrupt file to fail")
+	}
+
+	if err := os.WriteFile(dbPath+"-wal", []byte("uncheckpointed"), 0o600); err != nil {
+		t.Fatalf("write wal: %v", err)
+	}
+	now := time.Date(2026, 3, 20, 8, 0, 0, 0, time.UTC)
+	restored, previous, err := Restore(backupPath, dbPath, now)
+	if err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	if restored.Path != dbPath || !strings.HasSuffix(previous, ".pre-restore-20260320-080000") {
+		t.Fatalf("unexpected restore result: %+v previous=%s", restored, previous)
+	}
+	if _, err := os.Stat(previous); err != nil {
+		t.Fatalf("expected previous db to be kept: %v", err)
+	}
+	if wal, err := os.ReadFile(previous + "-wal"); err != nil || string(wal) != "uncheckpointed" {
+		t.Fatalf("expected the wal to move with the previous db: %q %v", wal, err)
+	}
+	if _, err := os.Stat(dbPath + "-wal"); !os.IsNotExist(err) {
+		t.Fatalf("expected no wal next to the restored db, got %v", err)
+	}
+
+	st, err = Open(dbPath)
+	if err != nil {
+		t.Fatalf("reopen store: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+	if _, _, err := Restore(backupPath, dbPath, now.Add(time.Minute)); err == nil {
+		t.Fatalf("expected restore to refuse while the state db is open")
+	}
+	rec, found, err := st.GetMessage("m-keep")
+	if err != nil || !found {
+		t.Fatalf("expected restored message: found=%v err=%v", found, err)
+	}
+	if rec.Status != "pending" {
+		t.Fatalf("expected state from the backup (pending), got %s", rec.Status)
+	}
+
+	backupDir := filepath.Dir(backupPath)
+	for i := 1; i <= 3; i++ {
+		if _, err := st.BackupTo(filepath.Join(backupDir, BackupFileName(now.Add(time.Duration(i)*time.Hour)))); err != nil {
+			t.Fatalf("backup %d: %v", i, err)
+		}
+	}
+	removed, err := RotateBackups(backupDir, 2)
+	if err != nil {
+		t.Fatalf("rotate: %v", err)
+	}
+	if len(removed) != 2 || filepath.Base(removed[0]) != filepath.Base(backupPath) {
+		t.Fatalf("expected the two oldest backups to be removed, got %v", removed)
+	}
+	entries, _ := os.ReadDir(backupDir)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 backups to remain, got %d", len(entries))
+	}
+}
@@ -0,0 +1,94 @@
+package state
+
+import (
+	"time"
+)
+
+type TranscriptChunkRecord struct {
+	CaptureKey  string
+	ChunkIndex  int
+	ChunkCount  int
+	StartMS     int64
+	EndMS       int64
+	Text        string
+	CompletedAt time.Time
+}
+
+type TranscriptProgress struct {
+	CaptureKey      string `json:"capture_key"`
+	CompletedChunks int    `json:"completed_chunks"`
+	TotalChunks     int    `json:"total_chunks"`
+}
+
+func (s *Store) SaveTranscriptChunk(rec TranscriptChunkRecord) error {
+	completedAt := rec.CompletedAt
+	if completedAt.IsZero() {
+		completedAt = time.Now()
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO transcript_chunks (capture_key, chunk_index, chunk_count, start_ms, end_ms, text, completed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(capture_key, chunk_index) DO UPDATE SET
+			chunk_count = excluded.chunk_count,
+			start_ms = excluded.start_ms,
+			end_ms = excluded.end_ms,
+			text = excluded.text,
+			completed_at = excluded.completed_at
+	`, rec.CaptureKey, rec.ChunkIndex, rec.ChunkCount, rec.StartMS, rec.EndMS, rec.Text, completedAt.UTC().Format(time.RFC3339))
+	return err
+}
+
+func (s *Store) ListTranscriptChunks(captureKey string) ([]TranscriptChunkRecord, error) {
+	rows, err := s.db.Query(`
+		SELECT capture_key, chunk_index, chunk_count, start_ms, end_ms, text, completed_at
+		FROM transcript_chunks
+		WHERE capture_key = ?
+		ORDER BY chunk_index ASC
+	`, captureKey)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []TranscriptChunkRecord
+	for rows.Next() {
+		var rec TranscriptChunkRecord
+		var completedAtRaw string
+		if err := rows.Scan(&rec.CaptureKey, &rec.ChunkIndex, &rec.ChunkCount, &rec.StartMS, &rec.EndMS, &rec.Text, &completedAtRaw); err != nil {
+			return nil, err
+		}
+		if t, err := time.Parse(time.RFC3339, completedAtRaw); err == nil {
+			rec.CompletedAt = t
+		}
+		out = append(out, rec)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) DeleteTranscriptChunks(captureKey string) error {
+	_, err := s.db.Exec(`DELETE FROM transcript_chunks WHERE capture_key = ?`, captureKey)
+	return err
+}
+
+func (s *Store) ListTranscriptProgress() ([]TranscriptProgress, error) {
+	rows, err := s.db.Query(`
+		SELECT capture_key, COUNT(*), MAX(chunk_count)
+		FROM transcript_chunks
+		GROUP BY capture_key
+		ORDER BY MIN(completed_at) ASC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []TranscriptProgress
+	for rows.Next() {
+		var p TranscriptProgress
+		if err := rows.Scan(&p.CaptureKey, &p.CompletedChunks, &p.TotalChunks); err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
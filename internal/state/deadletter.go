@@ -0,0 +1,170 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const (
+	SourceDiscord = "discord"
+	SourceCapture = "capture"
+)
+
+var itemTables = []struct {
+	source, table, idColumn string
+}{
+	{SourceDiscord, "messages", "message_id"},
+	{SourceCapture, "captures", "capture_id"},
+}
+
+type FailedItem struct {
+	Source      string     `json:"source"`
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Stage       string     `json:"stage,omitempty"`
+	Attempts    int        `json:"attempts"`
+	NextRetryAt *time.Time `json:"next_retry_at,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (f FailedItem) DeadLettered() bool {
+	return f.Status == "failed" && f.NextRetryAt == nil
+}
+
+func (s *Store) ListFailed(source string) ([]FailedItem, error) {
+	var out []FailedItem
+	for _, t := range itemTables {
+		if source != "" && source != t.source {
+			continue
+		}
+		items, err := s.listFailedFrom(t.source, t.table, t.idColumn)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, items...)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].UpdatedAt.Before(out[j].UpdatedAt) })
+	return out, nil
+}
+
+func (s *Store) listFailedFrom(source, table, idColumn string) ([]FailedItem, error) {
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT %s, status, stage, attempts, next_retry_at, last_error, updated_at
+		FROM %s
+		WHERE status = 'failed'
+		ORDER BY updated_at ASC
+	`, idColumn, table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []FailedItem
+	for rows.Next() {
+		item := FailedItem{Source: source}
+		var stage, nextRetry, lastError sql.NullString
+		var updatedAtRaw string
+		if err := rows.Scan(&item.ID, &item.Status, &stage, &item.Attempts, &nextRetry, &lastError, &updatedAtRaw); err != nil {
+			return nil, err
+		}
+		item.Stage = stage.String
+		item.LastError = lastError.String
+		if nextRetry.Valid {
+			t, err := time.Parse(time.RFC3339, nextRetry.String)
+			if err == nil {
+				item.NextRetryAt = &t
+			}
+		}
+		if item.UpdatedAt, err = time.Parse(time.RFC3339, updatedAtRaw); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) Requeue(id string, now time.Time) (string, bool, error) {
+	return s.updateByID(id, ItemEvent{Event: "requeued", Status: "failed", CreatedAt: now}, `
+		SET status = 'failed', attempts = 0, next_retry_at = ?, updated_at = ?
+		WHERE %s = ? AND status IN ('failed', 'abandoned')
+	`, now.UTC().Format(time.RFC3339), now.UTC().Format(time.RFC3339))
+}
+
+func (s *Store) RequeueAllFailed(now time.Time, since *time.Time) (int, error) {
+	stamp := now.UTC().Format(time.RFC3339)
+	sinceRaw := ""
+	if since != nil {
+		sinceRaw = since.UTC().Format(time.RFC3339)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	total := 0
+	for _, t := range itemTables {
+		if _, err := tx.Exec(fmt.Sprintf(`
+			INSERT INTO item_events (source, item_id, event, status, stage, attempt, created_at)
+			SELECT ?, %s, 'requeued', 'failed', stage, 0, ?
+			FROM %s
+			WHERE status = 'failed' AND next_retry_at IS NULL AND updated_at >= ?
+		`, t.idColumn, t.table), t.source, stamp, sinceRaw); err != nil {
+			return 0, err
+		}
+		res, err := tx.Exec(fmt.Sprintf(`
+			UPDATE %s
+			SET attempts = 0, next_retry_at = ?, updated_at = ?
+			WHERE status = 'failed' AND next_retry_at IS NULL AND updated_at >= ?
+		`, t.table), stamp, stamp, sinceRaw)
+		if err != nil {
+			return 0, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return 0, err
+		}
+		total += int(n)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+func (s *Store) Abandon(id string, now time.Time) (string, bool, error) {
+	return s.updateByID(id, ItemEvent{Event: "abandoned", Status: "abandoned", CreatedAt: now}, `
+		SET status = 'abandoned', next_retry_at = NULL, updated_at = ?
+		WHERE %s = ? AND status IN ('pending', 'failed', 'journal_pending')
+	`, now.UTC().Format(time.RFC3339))
+}
+
+func (s *Store) updateByID(id string, ev ItemEvent, setAndWhere string, args ...any) (string, bool, error) {
+	source := ""
+	err := s.inTx(func(tx *sql.Tx) error {
+		for _, t := range itemTables {
+			res, err := tx.Exec(fmt.Sprintf("UPDATE "+t.table+" "+setAndWhere, t.idColumn), append(args, id)...)
+			if err != nil {
+				return err
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				source = t.source
+				ev.Source = t.source
+				ev.ItemID = id
+				return s.recordEvent(tx, ev)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return source, source != "", nil
+}
@@ -0,0 +1,100 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRequeueAndAbandonAcrossTables(t *testing.T) {
+	st, err := Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+
+	if err := st.UpsertPending(MessageRecord{
+		MessageID:     "m-dead",
+		ChannelID:     "c1",
+		AuthorID:      "a1",
+		AttachmentID:  "att1",
+		AttachmentURL: "https://example.invalid/a.ogg",
+	}); err != nil {
+		t.Fatalf("upsert message: %v", err)
+	}
+	if err := st.MarkFailed("m-dead", "whisper exploded", 8, nil); err != nil {
+		t.Fatalf("mark message failed: %v", err)
+	}
+	for _, id := range []string{"c-dead", "c-skip"} {
+		if err := st.CreateCapture(CaptureRecord{
+			CaptureID:    id,
+			Source:       "android-voice-inbox",
+			ReceivedAt:   time.Now().UTC(),
+			RawAudioPath: "/tmp/" + id + ".ogg",
+			Status:       "pending",
+		}); err != nil {
+			t.Fatalf("create capture: %v", err)
+		}
+		if err := st.MarkCaptureFailed(id, "obsidian down", 8, nil); err != nil {
+			t.Fatalf("mark capture failed: %v", err)
+		}
+	}
+
+	items, err := st.ListFailed("")
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(items) != 3 || !items[0].DeadLettered() {
+		t.Fatalf("expected 3 dead-lettered items, got %+v", items)
+	}
+	captureItems, err := st.ListFailed(SourceCapture)
+	if err != nil || len(captureItems) != 2 {
+		t.Fatalf("expected 2 capture items, got %d err=%v", len(captureItems), err)
+	}
+
+	source, found, err := st.Abandon("c-skip", time.Now())
+	if err != nil || !found || source != SourceCapture {
+		t.Fatalf("abandon capture: source=%q found=%v err=%v", source, found, err)
+	}
+	if err := st.MarkCaptureProcessing("c-dead"); err != nil {
+		t.Fatalf("mark capture processing: %v", err)
+	}
+	if _, found, err := st.Abandon("c-dead", time.Now()); err != nil || found {
+		t.Fatalf("expected an in-flight capture not to be abandoned: found=%v err=%v", found, err)
+	}
+	if rec, _, _ := st.GetCapture("c-dead"); rec.Status != "processing" {
+		t.Fatalf("expected capture to stay processing, got %s", rec.Status)
+	}
+	if err := st.MarkCaptureFailed("c-dead", "obsidian down", 8, nil); err != nil {
+		t.Fatalf("mark capture failed: %v", err)
+	}
+
+	now := time.Now()
+	source, found, err = st.Requeue("m-dead", now)
+	if err != nil || !found || source != SourceDiscord {
+		t.Fatalf("requeue message: source=%q found=%v err=%v", source, found, err)
+	}
+	candidates, err := st.ListRetryCandidates(now.Add(time.Second), 10)
+	if err != nil {
+		t.Fatalf("list retry candidates: %v", err)
+	}
+	if len(candidates) != 1 || candidates[0].MessageID != "m-dead" || candidates[0].Attempts != 0 {
+		t.Fatalf("expected requeued message to be due with attempts reset, got %+v", candidates)
+	}
+
+	n, err := st.RequeueAllFailed(now, nil)
+	if err != nil {
+		t.Fatalf("requeue all failed: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected only the dead capture to be requeued, got %d", n)
+	}
+	rec, _, err := st.GetCapture("c-skip")
+	if err != nil || rec.Status != "abandoned" {
+		t.Fatalf("expected abandoned capture to stay abandoned, got %q err=%v", rec.Status, err)
+	}
+
+	if _, found, err := st.Requeue("missing", now); err != nil || found {
+		t.Fatalf("expected unknown id to be reported as not found, found=%v err=%v", found, err)
+	}
+}
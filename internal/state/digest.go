@@ -0,0 +1,48 @@
+package state
+
+import (
+	"time"
+)
+
+type JournaledItem struct {
+	ItemID      string
+	Source      string
+	DeviceID    string
+	CapturedAt  time.Time
+	JournalPath string
+	DurationMS  int64
+}
+
+func (s *Store) ListJournaledBetween(from, to time.Time, fromMessageID, toMessageID int64) ([]JournaledItem, error) {
+	fromRaw, toRaw := from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339)
+	rows, err := s.db.Query(`
+		SELECT capture_id, source, COALESCE(device_id, ''), COALESCE(captured_at, received_at), journal_path, COALESCE(duration_ms, 0)
+		FROM captures
+		WHERE status = 'done' AND COALESCE(journal_path, '') != ''
+		  AND COALESCE(captured_at, received_at) >= ? AND COALESCE(captured_at, received_at) < ?
+		UNION ALL
+		SELECT message_id, 'discord', '', created_at, journal_path, COALESCE(duration_ms, 0)
+		FROM messages
+		WHERE status IN ('done', 'reaction_pending') AND COALESCE(journal_path, '') != ''
+		  AND CAST(message_id AS INTEGER) >= ? AND CAST(message_id AS INTEGER) < ?
+		ORDER BY 4, 1
+	`, fromRaw, toRaw, fromMessageID, toMessageID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []JournaledItem
+	for rows.Next() {
+		var item JournaledItem
+		var capturedAtRaw string
+		if err := rows.Scan(&item.ItemID, &item.Source, &item.DeviceID, &capturedAtRaw, &item.JournalPath, &item.DurationMS); err != nil {
+			return nil, err
+		}
+		if t, err := time.Parse(time.RFC3339, capturedAtRaw); err == nil {
+			item.CapturedAt = t
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
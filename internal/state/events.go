@@ -0,0 +1,97 @@
+package state
+
+import (
+	"database/sql"
+	"time"
+)
+
+type ItemEvent struct {
+	ID        int64     `json:"id"`
+	Source    string    `json:"source"`
+	ItemID    string    `json:"item_id"`
+	Event     string    `json:"event"`
+	Status    string    `json:"status,omitempty"`
+	Stage     string    `json:"stage,omitempty"`
+	Attempt   int       `json:"attempt,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	RunID     string    `json:"run_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func (s *Store) inTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) recordEvent(e execer, ev ItemEvent) error {
+	createdAt := ev.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	_, err := e.Exec(`
+		INSERT INTO item_events (source, item_id, event, status, stage, attempt, error, run_id, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`,
+		ev.Source,
+		ev.ItemID,
+		ev.Event,
+		nullable(ev.Status),
+		nullable(ev.Stage),
+		ev.Attempt,
+		nullable(trimError(ev.Error)),
+		nullable(s.runID),
+		createdAt.UTC().Format(time.RFC3339),
+	)
+	return err
+}
+
+func (s *Store) ListItemEvents(itemID string) ([]ItemEvent, error) {
+	return s.listEvents(`item_id = ?`, itemID)
+}
+
+func (s *Store) ListRunEvents(runID string) ([]ItemEvent, error) {
+	return s.listEvents(`run_id = ?`, runID)
+}
+
+func (s *Store) listEvents(where string, arg any) ([]ItemEvent, error) {
+	rows, err := s.db.Query(`
+		SELECT id, source, item_id, event, status, stage, attempt, error, run_id, created_at
+		FROM item_events
+		WHERE `+where+`
+		ORDER BY id ASC
+	`, arg)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ItemEvent
+	for rows.Next() {
+		var ev ItemEvent
+		var status, stage, errText, runID sql.NullString
+		var createdAtRaw string
+		if err := rows.Scan(&ev.ID, &ev.Source, &ev.ItemID, &ev.Event, &status, &stage, &ev.Attempt, &errText, &runID, &createdAtRaw); err != nil {
+			return nil, err
+		}
+		ev.Status = status.String
+		ev.Stage = stage.String
+		ev.Error = errText.String
+		ev.RunID = runID.String
+		if ev.CreatedAt, err = time.Parse(time.RFC3339, createdAtRaw); err != nil {
+			return nil, err
+		}
+		out = append(out, ev)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,56 @@
+package state
+
+import (
+	"fmt"
+	"slices"
+)
+
+var ExportTables = []string{"messages", "captures", "runs"}
+
+var exportOrder = map[string]string{
+	"messages": "created_at, message_id",
+	"captures": "received_at, capture_id",
+	"runs":     "started_at, run_id",
+}
+
+func (s *Store) ExportRows(table string, fn func(columns []string, values []any) error) (int, error) {
+	order, ok := exportOrder[table]
+	if !ok {
+		return 0, fmt.Errorf("unknown export table %q (want one of %v)", table, ExportTables)
+	}
+	rows, err := s.db.Query(fmt.Sprintf(`SELECT * FROM %s ORDER BY %s`, table, order))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for rows.Next() {
+		values := make([]any, len(columns))
+		dest := make([]any, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return n, err
+		}
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		if err := fn(columns, values); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
+func ValidExportTable(table string) bool {
+	return slices.Contains(ExportTables, table)
+}
@@ -0,0 +1,132 @@
+package state
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+)
+
+func (s *Store) MarkJournalPending(messageID, sink, errText string) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`
+			UPDATE messages SET
+				status = 'journal_pending',
+				journal_sink = ?,
+				last_error = ?,
+				next_retry_at = NULL,
+				updated_at = ?
+			WHERE message_id = ?
+		`, sink, trimError(errText), time.Now().UTC().Format(time.RFC3339), messageID); err != nil {
+			return err
+		}
+		return s.recordEvent(tx, ItemEvent{Source: SourceDiscord, ItemID: messageID, Event: "parked", Status: "journal_pending", Error: errText})
+	})
+}
+
+func (s *Store) MarkCaptureJournalPending(captureID, sink, errText string) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`
+			UPDATE captures SET
+				status = 'journal_pending',
+				journal_sink = ?,
+				last_error = ?,
+				next_retry_at = NULL,
+				updated_at = ?
+			WHERE capture_id = ?
+		`, sink, trimError(errText), time.Now().UTC().Format(time.RFC3339), captureID); err != nil {
+			return err
+		}
+		return s.recordEvent(tx, ItemEvent{Source: SourceCapture, ItemID: captureID, Event: "parked", Status: "journal_pending", Error: errText})
+	})
+}
+
+func (s *Store) CountJournalPending() (int, error) {
+	var n int
+	err := s.db.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM messages WHERE status = 'journal_pending')
+		     + (SELECT COUNT(*) FROM captures WHERE status = 'journal_pending')
+	`).Scan(&n)
+	return n, err
+}
+
+func (s *Store) ListJournalPendingMessages(sinks []string, limit int) ([]MessageRecord, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+	rows, err := s.db.Query(`
+		SELECT message_id, channel_id, author_id, attachment_id, attachment_url, attachment_filename,
+			content_type, message_content, audio_path, transcript_path, status, attempts, next_retry_at,
+			last_error, journal_path, discord_jump_url, created_at, updated_at,
+			duration_ms, audio_codec, audio_channels, audio_sample_rate, audio_bitrate, stage, transcript_text
+		FROM messages
+		WHERE status = 'journal_pending' AND COALESCE(journal_sink, 'obsidian') IN (`+placeholders(len(sinks))+`)
+		ORDER BY created_at ASC
+		LIMIT ?
+	`, sinkArgs(sinks, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []MessageRecord
+	for rows.Next() {
+		rec, found, err := scanMessageRows(rows)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			out = append(out, rec)
+		}
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) ListJournalPendingCaptures(sinks []string, limit int) ([]CaptureRecord, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+	rows, err := s.db.Query(`
+		SELECT capture_id, source, source_dedupe_key, device_id, captured_at, received_at,
+			raw_audio_path, content_type, transcript_text, status, attempts, next_retry_at,
+			journal_path, transcript_path, last_error, created_at, updated_at,
+			duration_ms, audio_codec, audio_channels, audio_sample_rate, audio_bitrate, stage
+		FROM captures
+		WHERE status = 'journal_pending' AND COALESCE(journal_sink, 'obsidian') IN (`+placeholders(len(sinks))+`)
+		ORDER BY COALESCE(captured_at, received_at) ASC
+		LIMIT ?
+	`, sinkArgs(sinks, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []CaptureRecord
+	for rows.Next() {
+		rec, found, err := scanCaptureRows(rows)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			out = append(out, rec)
+		}
+	}
+	return out, rows.Err()
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func sinkArgs(sinks []string, limit int) []any {
+	args := make([]any, 0, len(sinks)+1)
+	for _, sink := range sinks {
+		args = append(args, sink)
+	}
+	return append(args, limit)
+}
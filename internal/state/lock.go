@@ -12,6 +12,14 @@ type FileLock struct {
 }
 
 func AcquireFileLock(path string) (*FileLock, error) {
+	return acquireFileLock(path, syscall.LOCK_EX)
+}
+
+func acquireSharedFileLock(path string) (*FileLock, error) {
+	return acquireFileLock(path, syscall.LOCK_SH)
+}
+
+func acquireFileLock(path string, how int) (*FileLock, error) {
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return nil, err
 	}
@@ -19,7 +27,7 @@ func AcquireFileLock(path string) (*FileLock, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+	if err := syscall.Flock(int(f.Fd()), how|syscall.LOCK_NB); err != nil {
 		_ = f.Close()
 		return nil, fmt.Errorf("lock already held: %w", err)
 	}
@@ -36,3 +44,7 @@ func (l *FileLock) Release() error {
 	}
 	return l.file.Close()
 }
+
+func inUseLockPath(dbPath string) string {
+	return dbPath + ".inuse"
+}
@@ -0,0 +1,448 @@
+package state
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrSchemaTooNew = errors.New("state db schema is newer than this binary")
+
+type migration struct {
+	Version int
+	Name    string
+	Stmts   []string
+}
+
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Stmts     []string   `json:"statements,omitempty"`
+}
+
+var migrations = []migration{
+	{
+		Version: 1,
+		Name:    "messages, runs and kv",
+		Stmts: []string{
+			`CREATE TABLE IF NOT EXISTS messages (
+			  message_id TEXT PRIMARY KEY,
+			  channel_id TEXT NOT NULL,
+			  author_id TEXT NOT NULL,
+			  attachment_id TEXT NOT NULL,
+			  attachment_url TEXT NOT NULL,
+			  attachment_filename TEXT,
+			  content_type TEXT,
+			  audio_path TEXT,
+			  transcript_path TEXT,
+			  status TEXT NOT NULL,
+			  attempts INTEGER NOT NULL DEFAULT 0,
+			  next_retry_at TEXT,
+			  last_error TEXT,
+			  journal_path TEXT,
+			  discord_jump_url TEXT,
+			  created_at TEXT NOT NULL,
+			  updated_at TEXT NOT NULL
+			)`,
+			`ALTER TABLE messages ADD COLUMN message_content TEXT`,
+			`CREATE TABLE IF NOT EXISTS runs (
+			  run_id TEXT PRIMARY KEY,
+			  command TEXT NOT NULL,
+			  started_at TEXT NOT NULL,
+			  finished_at TEXT,
+			  processed_count INTEGER NOT NULL DEFAULT 0,
+			  success_count INTEGER NOT NULL DEFAULT 0,
+			  failed_count INTEGER NOT NULL DEFAULT 0
+			)`,
+			`CREATE TABLE IF NOT EXISTS kv (
+			  key TEXT PRIMARY KEY,
+			  value TEXT NOT NULL,
+			  updated_at TEXT NOT NULL
+			)`,
+		},
+	},
+	{
+		Version: 2,
+		Name:    "captures",
+		Stmts: []string{
+			`CREATE TABLE IF NOT EXISTS captures (
+			  capture_id TEXT PRIMARY KEY,
+			  source TEXT NOT NULL,
+			  source_dedupe_key TEXT,
+			  device_id TEXT,
+			  captured_at TEXT,
+			  received_at TEXT NOT NULL,
+			  raw_audio_path TEXT NOT NULL,
+			  content_type TEXT,
+			  status TEXT NOT NULL,
+			  attempts INTEGER NOT NULL DEFAULT 0,
+			  next_retry_at TEXT,
+			  journal_path TEXT,
+			  transcript_path TEXT,
+			  last_error TEXT,
+			  created_at TEXT NOT NULL,
+			  updated_at TEXT NOT NULL
+			)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_captures_source_dedupe_key ON captures(source_dedupe_key)`,
+		},
+	},
+	{
+		Version: 3,
+		Name:    "captures.transcript_text",
+		Stmts: []string{
+			`ALTER TABLE captures ADD COLUMN transcript_text TEXT`,
+		},
+	},
+	{
+		Version: 4,
+		Name:    "transcript_chunks",
+		Stmts: []string{
+			`CREATE TABLE IF NOT EXISTS transcript_chunks (
+			  capture_key TEXT NOT NULL,
+			  chunk_index INTEGER NOT NULL,
+			  chunk_count INTEGER NOT NULL,
+			  start_ms INTEGER NOT NULL,
+			  end_ms INTEGER NOT NULL,
+			  text TEXT NOT NULL,
+			  completed_at TEXT NOT NULL,
+			  PRIMARY KEY (capture_key, chunk_index)
+			)`,
+		},
+	},
+	{
+		Version: 5,
+		Name:    "audio metadata",
+		Stmts: []string{
+			`ALTER TABLE messages ADD COLUMN duration_ms INTEGER`,
+			`ALTER TABLE messages ADD COLUMN audio_codec TEXT`,
+			`ALTER TABLE messages ADD COLUMN audio_channels INTEGER`,
+			`ALTER TABLE messages ADD COLUMN audio_sample_rate INTEGER`,
+			`ALTER TABLE messages ADD COLUMN audio_bitrate INTEGER`,
+			`ALTER TABLE captures ADD COLUMN duration_ms INTEGER`,
+			`ALTER TABLE captures ADD COLUMN audio_codec TEXT`,
+			`ALTER TABLE captures ADD COLUMN audio_channels INTEGER`,
+			`ALTER TABLE captures ADD COLUMN audio_sample_rate INTEGER`,
+			`ALTER TABLE captures ADD COLUMN audio_bitrate INTEGER`,
+		},
+	},
+	{
+		Version: 6,
+		Name:    "transcript_cache",
+		Stmts: []string{
+			`CREATE TABLE IF NOT EXISTS transcript_cache (
+			  audio_sha256 TEXT NOT NULL,
+			  model TEXT NOT NULL,
+			  language TEXT NOT NULL,
+			  text TEXT NOT NULL,
+			  transcript_path TEXT,
+			  created_at TEXT NOT NULL,
+			  PRIMARY KEY (audio_sha256, model, language)
+			)`,
+		},
+	},
+	{
+		Version: 7,
+		Name:    "processing stages",
+		Stmts: []string{
+			`ALTER TABLE messages ADD COLUMN transcript_text TEXT`,
+			`ALTER TABLE messages ADD COLUMN stage TEXT`,
+			`ALTER TABLE captures ADD COLUMN stage TEXT`,
+		},
+	},
+	{
+		Version: 8,
+		Name:    "item_events",
+		Stmts: []string{
+			`CREATE TABLE IF NOT EXISTS item_events (
+			  id INTEGER PRIMARY KEY AUTOINCREMENT,
+			  source TEXT NOT NULL,
+			  item_id TEXT NOT NULL,
+			  event TEXT NOT NULL,
+			  status TEXT,
+			  stage TEXT,
+			  attempt INTEGER NOT NULL DEFAULT 0,
+			  error TEXT,
+			  created_at TEXT NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_item_events_item_id ON item_events(item_id, id)`,
+		},
+	},
+	{
+		Version: 9,
+		Name:    "transcripts_fts",
+		Stmts: []string{
+			`CREATE VIRTUAL TABLE IF NOT EXISTS transcripts_fts USING fts5(
+			  text,
+			  item_id UNINDEXED,
+			  source UNINDEXED,
+			  device_id UNINDEXED,
+			  captured_at UNINDEXED,
+			  journal_path UNINDEXED,
+			  tokenize = 'trigram'
+			)`,
+			`INSERT INTO transcripts_fts (text, item_id, source, device_id, captured_at, journal_path)
+			SELECT transcript_text, capture_id, source, device_id, COALESCE(captured_at, received_at), journal_path
+			FROM captures
+			WHERE status = 'done' AND TRIM(COALESCE(transcript_text, '')) != ''
+			  AND capture_id NOT IN (SELECT item_id FROM transcripts_fts)`,
+			`INSERT INTO transcripts_fts (text, item_id, source, device_id, captured_at, journal_path)
+			SELECT transcript_text, message_id, 'discord', NULL, created_at, journal_path
+			FROM messages
+			WHERE status = 'done' AND TRIM(COALESCE(transcript_text, '')) != ''
+			  AND message_id NOT IN (SELECT item_id FROM transcripts_fts)`,
+		},
+	},
+	{
+		Version: 10,
+		Name:    "run errors and item event run ids",
+		Stmts: []string{
+			`ALTER TABLE runs ADD COLUMN errors_json TEXT`,
+			`ALTER TABLE item_events ADD COLUMN run_id TEXT`,
+			`CREATE INDEX IF NOT EXISTS idx_item_events_run_id ON item_events(run_id, id)`,
+			`CREATE INDEX IF NOT EXISTS idx_runs_started_at ON runs(started_at)`,
+		},
+	},
+	{
+		Version: 11,
+		Name:    "vault_attachments",
+		Stmts: []string{
+			`CREATE TABLE IF NOT EXISTS vault_attachments (
+			  sink TEXT NOT NULL,
+			  vault_path TEXT NOT NULL,
+			  source TEXT NOT NULL,
+			  item_id TEXT NOT NULL,
+			  note_path TEXT NOT NULL,
+			  size_bytes INTEGER NOT NULL DEFAULT 0,
+			  created_at TEXT NOT NULL,
+			  PRIMARY KEY (sink, vault_path)
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_vault_attachments_created_at ON vault_attachments(created_at)`,
+		},
+	},
+	{
+		Version: 12,
+		Name:    "journal_pending sink",
+		Stmts: []string{
+			`ALTER TABLE messages ADD COLUMN journal_sink TEXT`,
+			`ALTER TABLE captures ADD COLUMN journal_sink TEXT`,
+		},
+	},
+}
+
+var addColumnPattern = regexp.MustCompile(`(?i)^\s*ALTER TABLE\s+(\w+)\s+ADD COLUMN\s+(\w+)`)
+
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+func OpenUnmigrated(path string) (*Store, error) {
+	return open(path, false)
+}
+
+func (s *Store) SchemaVersion() (int, error) {
+	exists, err := tableExists(s.db, "schema_migrations")
+	if err != nil {
+		return 0, err
+	}
+	if exists {
+		var version sql.NullInt64
+		if err := s.db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+			return 0, err
+		}
+		if version.Valid {
+			return int(version.Int64), nil
+		}
+	}
+	return s.legacySchemaVersion()
+}
+
+func (s *Store) legacySchemaVersion() (int, error) {
+	exists, err := tableExists(s.db, "kv")
+	if err != nil || !exists {
+		return 0, err
+	}
+	raw, ok, err := s.GetKV("schema_version")
+	if err != nil || !ok {
+		return 0, err
+	}
+	version, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil {
+		return 0, fmt.Errorf("invalid legacy schema_version %q", raw)
+	}
+	return version, nil
+}
+
+func (s *Store) MigrationStatus() ([]MigrationStatus, error) {
+	if _, err := s.checkSchemaVersion(); err != nil {
+		return nil, err
+	}
+	applied := map[int]time.Time{}
+	exists, err := tableExists(s.db, "schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		rows, err := s.db.Query(`SELECT version, applied_at FROM schema_migrations`)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var version int
+			var appliedAtRaw string
+			if err := rows.Scan(&version, &appliedAtRaw); err != nil {
+				return nil, err
+			}
+			appliedAt, err := time.Parse(time.RFC3339, appliedAtRaw)
+			if err != nil {
+				return nil, err
+			}
+			applied[version] = appliedAt
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	out := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		st := MigrationStatus{Version: m.Version, Name: m.Name}
+		if at, ok := applied[m.Version]; ok {
+			st.AppliedAt = &at
+		} else {
+			st.Stmts = m.Stmts
+		}
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+func (s *Store) PendingMigrations() ([]MigrationStatus, error) {
+	all, err := s.MigrationStatus()
+	if err != nil {
+		return nil, err
+	}
+	var pending []MigrationStatus
+	for _, m := range all {
+		if m.AppliedAt == nil {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+func (s *Store) Migrate() ([]MigrationStatus, error) {
+	if _, err := s.checkSchemaVersion(); err != nil {
+		return nil, err
+	}
+	if _, err := s.db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		  version INTEGER PRIMARY KEY,
+		  name TEXT NOT NULL,
+		  applied_at TEXT NOT NULL
+		)`); err != nil {
+		return nil, err
+	}
+	pending, err := s.PendingMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []MigrationStatus
+	for _, p := range pending {
+		m := migrations[p.Version-1]
+		appliedAt := time.Now().UTC()
+		if err := s.applyMigration(m, appliedAt); err != nil {
+			return applied, fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+		}
+		applied = append(applied, MigrationStatus{Version: m.Version, Name: m.Name, AppliedAt: &appliedAt})
+	}
+	return applied, nil
+}
+
+func (s *Store) checkSchemaVersion() (int, error) {
+	version, err := s.SchemaVersion()
+	if err != nil {
+		return 0, err
+	}
+	if version > LatestSchemaVersion() {
+		return version, fmt.Errorf("%w: db is at version %d, binary supports up to %d", ErrSchemaTooNew, version, LatestSchemaVersion())
+	}
+	return version, nil
+}
+
+func (s *Store) applyMigration(m migration, appliedAt time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	for _, stmt := range m.Stmts {
+		if match := addColumnPattern.FindStringSubmatch(stmt); match != nil {
+			exists, err := columnExists(tx, match[1], match[2])
+			if err != nil {
+				return err
+			}
+			if exists {
+				continue
+			}
+		}
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	stamp := appliedAt.Format(time.RFC3339)
+	if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`, m.Version, m.Name, stamp); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO kv (key, value, updated_at) VALUES ('schema_version', ?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value, updated_at = excluded.updated_at
+	`, strconv.Itoa(m.Version), stamp); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type queryer interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func tableExists(q queryer, table string) (bool, error) {
+	var name string
+	err := q.QueryRow(`SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&name)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func columnExists(q queryer, table, column string) (bool, error) {
+	rows, err := q.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, dataType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &dataType, &notNull, &defaultValue, &pk); err != nil {
+			return false, err
+		}
+		if strings.EqualFold(name, column) {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
@@ -0,0 +1,148 @@
+package state
+
+import (
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+const maxRunErrors = 100
+
+type RunRecord struct {
+	RunID      string     `json:"run_id"`
+	Command    string     `json:"command"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Processed  int        `json:"processed"`
+	Succeeded  int        `json:"succeeded"`
+	Failed     int        `json:"failed"`
+	Errors     []string   `json:"errors,omitempty"`
+}
+
+func (r RunRecord) HasFailures() bool {
+	return r.Failed > 0 || len(r.Errors) > 0
+}
+
+type RunFilter struct {
+	Command    string
+	FailedOnly bool
+	Limit      int
+}
+
+func (s *Store) ForRun(runID string) *Store {
+	scoped := *s
+	scoped.runID = runID
+	return &scoped
+}
+
+func encodeRunErrors(errs []string) (any, error) {
+	if len(errs) == 0 {
+		return nil, nil
+	}
+	trimmed := make([]string, 0, min(len(errs), maxRunErrors))
+	for _, e := range errs[:min(len(errs), maxRunErrors)] {
+		trimmed = append(trimmed, trimError(e))
+	}
+	raw, err := json.Marshal(trimmed)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+func (s *Store) ListRuns(f RunFilter) ([]RunRecord, error) {
+	limit := f.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+	var where []string
+	var args []any
+	if f.Command != "" {
+		where = append(where, `command = ?`)
+		args = append(args, f.Command)
+	}
+	if f.FailedOnly {
+		where = append(where, `(failed_count > 0 OR errors_json IS NOT NULL)`)
+	}
+	query := `SELECT run_id, command, started_at, finished_at, processed_count, success_count, failed_count, errors_json FROM runs`
+	if len(where) > 0 {
+		query += ` WHERE ` + strings.Join(where, " AND ")
+	}
+	query += ` ORDER BY started_at DESC, rowid DESC LIMIT ?`
+	args = append(args, limit)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []RunRecord
+	for rows.Next() {
+		run, err := scanRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, run)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) GetRun(runID string) (RunRecord, bool, error) {
+	row := s.db.QueryRow(`
+		SELECT run_id, command, started_at, finished_at, processed_count, success_count, failed_count, errors_json
+		FROM runs
+		WHERE run_id = ?
+	`, runID)
+	run, err := scanRun(row)
+	if err == sql.ErrNoRows {
+		return RunRecord{}, false, nil
+	}
+	if err != nil {
+		return RunRecord{}, false, err
+	}
+	return run, true, nil
+}
+
+func scanRun(row rowScanner) (RunRecord, error) {
+	var run RunRecord
+	var startedAtRaw string
+	var finishedAtRaw, errorsJSON sql.NullString
+	if err := row.Scan(&run.RunID, &run.Command, &startedAtRaw, &finishedAtRaw, &run.Processed, &run.Succeeded, &run.Failed, &errorsJSON); err != nil {
+		return RunRecord{}, err
+	}
+	var err error
+	if run.StartedAt, err = time.Parse(time.RFC3339, startedAtRaw); err != nil {
+		return RunRecord{}, err
+	}
+	if finishedAtRaw.Valid {
+		t, err := time.Parse(time.RFC3339, finishedAtRaw.String)
+		if err == nil {
+			run.FinishedAt = &t
+		}
+	}
+	if errorsJSON.Valid {
+		if err := json.Unmarshal([]byte(errorsJSON.String), &run.Errors); err != nil {
+			return RunRecord{}, err
+		}
+	}
+	return run, nil
+}
+
+func (s *Store) LastSuccessfulRun(command string) (time.Time, bool, error) {
+	var raw sql.NullString
+	err := s.db.QueryRow(`
+		SELECT MAX(finished_at)
+		FROM runs
+		WHERE command = ? AND finished_at IS NOT NULL AND failed_count = 0
+	`, command).Scan(&raw)
+	if err != nil || !raw.Valid {
+		return time.Time{}, false, err
+	}
+	t, err := time.Parse(time.RFC3339, raw.String)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return t, true, nil
+}
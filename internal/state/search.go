@@ -0,0 +1,176 @@
+package state
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+const minTrigramRunes = 3
+
+type TranscriptDoc struct {
+	ItemID      string
+	Source      string
+	DeviceID    string
+	CapturedAt  time.Time
+	JournalPath string
+	Text        string
+}
+
+type SearchQuery struct {
+	Text   string
+	Since  *time.Time
+	Until  *time.Time
+	Source string
+	Limit  int
+}
+
+type SearchHit struct {
+	ItemID      string    `json:"id"`
+	Source      string    `json:"source"`
+	DeviceID    string    `json:"device_id,omitempty"`
+	CapturedAt  time.Time `json:"captured_at"`
+	JournalPath string    `json:"journal_path,omitempty"`
+	Snippet     string    `json:"snippet"`
+	Rank        float64   `json:"rank"`
+}
+
+func (s *Store) IndexTranscript(doc TranscriptDoc) error {
+	if strings.TrimSpace(doc.Text) == "" {
+		return nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if _, err := tx.Exec(`DELETE FROM transcripts_fts WHERE item_id = ?`, doc.ItemID); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`
+		INSERT INTO transcripts_fts (text, item_id, source, device_id, captured_at, journal_path)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, doc.Text, doc.ItemID, doc.Source, doc.DeviceID, doc.CapturedAt.UTC().Format(time.RFC3339), doc.JournalPath); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) SearchTranscripts(q SearchQuery) ([]SearchHit, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 20
+	}
+
+	var long, short []string
+	for _, term := range strings.Fields(q.Text) {
+		if utf8.RuneCountInString(term) >= minTrigramRunes {
+			long = append(long, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+		} else {
+			short = append(short, term)
+		}
+	}
+	if len(long) == 0 && len(short) == 0 {
+		return nil, nil
+	}
+
+	var where []string
+	var args []any
+	selectCols := `item_id, source, device_id, captured_at, journal_path, text, '' AS snippet, 0.0 AS score`
+	order := `captured_at DESC`
+	if len(long) > 0 {
+		selectCols = `item_id, source, device_id, captured_at, journal_path, text,
+			snippet(transcripts_fts, 0, '[', ']', '…', 16) AS snippet, bm25(transcripts_fts) AS score`
+		where = append(where, `transcripts_fts MATCH ?`)
+		args = append(args, strings.Join(long, " "))
+		order = `score ASC`
+	}
+	for _, term := range short {
+		where = append(where, `text LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(term)+"%")
+	}
+	if q.Since != nil {
+		where = append(where, `captured_at >= ?`)
+		args = append(args, q.Since.UTC().Format(time.RFC3339))
+	}
+	if q.Until != nil {
+		where = append(where, `captured_at < ?`)
+		args = append(args, q.Until.UTC().Format(time.RFC3339))
+	}
+	switch q.Source {
+	case "":
+	case SourceCapture:
+		where = append(where, `source <> ?`)
+		args = append(args, SourceDiscord)
+	default:
+		where = append(where, `source = ?`)
+		args = append(args, q.Source)
+	}
+	args = append(args, limit)
+
+	rows, err := s.db.Query(`
+		SELECT `+selectCols+`
+		FROM transcripts_fts
+		WHERE `+strings.Join(where, " AND ")+`
+		ORDER BY `+order+`
+		LIMIT ?
+	`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []SearchHit
+	for rows.Next() {
+		var hit SearchHit
+		var deviceID, journalPath sql.NullString
+		var capturedAtRaw, text string
+		if err := rows.Scan(&hit.ItemID, &hit.Source, &deviceID, &capturedAtRaw, &journalPath, &text, &hit.Snippet, &hit.Rank); err != nil {
+			return nil, err
+		}
+		hit.DeviceID = deviceID.String
+		hit.JournalPath = journalPath.String
+		hit.CapturedAt, _ = time.Parse(time.RFC3339, capturedAtRaw)
+		if hit.Snippet == "" {
+			term := ""
+			if len(short) > 0 {
+				term = short[0]
+			}
+			hit.Snippet = snippetAround(text, term, 32)
+		}
+		out = append(out, hit)
+	}
+	return out, rows.Err()
+}
+
+func escapeLike(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return r.Replace(s)
+}
+
+func snippetAround(text, term string, radius int) string {
+	runes := []rune(text)
+	lower := strings.Map(unicode.ToLower, text)
+	idx := strings.Index(lower, strings.Map(unicode.ToLower, term))
+	if idx < 0 || term == "" {
+		if len(runes) > radius*2 {
+			return string(runes[:radius*2]) + "…"
+		}
+		return text
+	}
+	start := utf8.RuneCountInString(lower[:idx])
+	end := start + utf8.RuneCountInString(term)
+	from := max(start-radius, 0)
+	to := min(end+radius, len(runes))
+	out := string(runes[from:start]) + "[" + string(runes[start:end]) + "]" + string(runes[end:to])
+	if from > 0 {
+		out = "…" + out
+	}
+	if to < len(runes) {
+		out += "…"
+	}
+	return out
+}
@@ -0,0 +1,81 @@
+package state
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchTranscriptsRanksAndFilters(t *testing.T) {
+	st, err := Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+
+	base := time.Date(2026, 3, 19, 9, 0, 0, 0, time.UTC)
+	docs := []TranscriptDoc{
+		{ItemID: "m-1", Source: SourceDiscord, CapturedAt: base, JournalPath: "Journal/2026-03-19.md", Text: "明日の会議で予算の件を確認する"},
+		{ItemID: "c-1", Source: "android-voice-inbox", DeviceID: "pixel", CapturedAt: base.Add(24 * time.Hour), JournalPath: "Journal/2026-03-20.md", Text: "予算の見直しを来週までに"},
+		{ItemID: "c-2", Source: "android-voice-inbox", CapturedAt: base.Add(48 * time.Hour), Text: "buy milk and eggs"},
+	}
+	for _, doc := range docs {
+		if err := st.IndexTranscript(doc); err != nil {
+			t.Fatalf("index %s: %v", doc.ItemID, err)
+		}
+	}
+	if err := st.IndexTranscript(TranscriptDoc{ItemID: "c-2", Source: "android-voice-inbox", CapturedAt: base.Add(48 * time.Hour), Text: "buy oat milk"}); err != nil {
+		t.Fatalf("reindex: %v", err)
+	}
+
+	hits, err := st.SearchTranscripts(SearchQuery{Text: "予算の"})
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	if len(hits) != 2 {
+		t.Fatalf("expected 2 hits, got %+v", hits)
+	}
+	if !strings.Contains(hits[0].Snippet, "[予算の]") {
+		t.Fatalf("expected highlighted snippet, got %q", hits[0].Snippet)
+	}
+
+	since := base.Add(12 * time.Hour)
+	hits, err = st.SearchTranscripts(SearchQuery{Text: "予算の", Since: &since})
+	if err != nil || len(hits) != 1 || hits[0].ItemID != "c-1" || hits[0].DeviceID != "pixel" {
+		t.Fatalf("expected since filter to keep c-1 only, got %+v err=%v", hits, err)
+	}
+	hits, err = st.SearchTranscripts(SearchQuery{Text: "予算の", Source: SourceDiscord})
+	if err != nil || len(hits) != 1 || hits[0].ItemID != "m-1" || hits[0].JournalPath != "Journal/2026-03-19.md" {
+		t.Fatalf("expected source filter to keep m-1 only, got %+v err=%v", hits, err)
+	}
+	hits, err = st.SearchTranscripts(SearchQuery{Text: "予算の", Source: SourceCapture})
+	if err != nil || len(hits) != 1 || hits[0].ItemID != "c-1" {
+		t.Fatalf("expected capture filter to keep c-1 only, got %+v err=%v", hits, err)
+	}
+
+	hits, err = st.SearchTranscripts(SearchQuery{Text: "予算"})
+	if err != nil || len(hits) != 2 {
+		t.Fatalf("expected short-term fallback to match 2, got %+v err=%v", hits, err)
+	}
+	if !strings.Contains(hits[0].Snippet, "[予算]") || hits[0].ItemID != "c-1" {
+		t.Fatalf("expected newest first with highlighted snippet, got %+v", hits[0])
+	}
+
+	if err := st.IndexTranscript(TranscriptDoc{ItemID: "c-3", Source: "android-voice-inbox", CapturedAt: base.Add(72 * time.Hour), Text: "見積もりの件は OK と返す"}); err != nil {
+		t.Fatalf("index c-3: %v", err)
+	}
+	hits, err = st.SearchTranscripts(SearchQuery{Text: "ok"})
+	if err != nil || len(hits) != 1 || !strings.Contains(hits[0].Snippet, "[OK]") {
+		t.Fatalf("expected a case-insensitive short match with its snippet, got %+v err=%v", hits, err)
+	}
+
+	hits, err = st.SearchTranscripts(SearchQuery{Text: "eggs"})
+	if err != nil || len(hits) != 0 {
+		t.Fatalf("expected reindexed text to replace the old one, got %+v err=%v", hits, err)
+	}
+	hits, err = st.SearchTranscripts(SearchQuery{Text: "oat milk"})
+	if err != nil || len(hits) != 1 || hits[0].ItemID != "c-2" {
+		t.Fatalf("expected mixed short and long terms to match c-2, got %+v err=%v", hits, err)
+	}
+}
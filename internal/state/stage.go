@@ -0,0 +1,133 @@
+package state
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const (
+	StageDownloaded   = "downloaded"
+	StageTranscribed  = "transcribed"
+	StageJournaled    = "journaled"
+	StageAcknowledged = "acknowledged"
+)
+
+var stageOrder = map[string]int{
+	"":                0,
+	StageDownloaded:   1,
+	StageTranscribed:  2,
+	StageJournaled:    3,
+	StageAcknowledged: 4,
+}
+
+type StageOutput struct {
+	AudioPath      string
+	TranscriptText string
+	TranscriptPath string
+	JournalPath    string
+	JournalSink    string
+}
+
+func StageReached(current, want string) bool {
+	return stageOrder[current] >= stageOrder[want]
+}
+
+func (s *Store) AdvanceMessageStage(messageID, stage string, out StageOutput) error {
+	if _, ok := stageOrder[stage]; !ok || stage == "" {
+		return fmt.Errorf("unknown stage %q", stage)
+	}
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`
+			UPDATE messages SET
+				stage = ?,
+				audio_path = COALESCE(?, audio_path),
+				transcript_text = COALESCE(?, transcript_text),
+				transcript_path = COALESCE(?, transcript_path),
+				journal_path = COALESCE(?, journal_path),
+				journal_sink = COALESCE(?, journal_sink),
+				updated_at = ?
+			WHERE message_id = ?
+		`,
+			stage,
+			nullable(out.AudioPath),
+			nullable(out.TranscriptText),
+			nullable(out.TranscriptPath),
+			nullable(out.JournalPath),
+			nullable(out.JournalSink),
+			time.Now().UTC().Format(time.RFC3339),
+			messageID,
+		); err != nil {
+			return err
+		}
+		return s.recordEvent(tx, ItemEvent{Source: SourceDiscord, ItemID: messageID, Event: "stage", Stage: stage})
+	})
+}
+
+func (s *Store) AdvanceCaptureStage(captureID, stage string, out StageOutput) error {
+	if _, ok := stageOrder[stage]; !ok || stage == "" {
+		return fmt.Errorf("unknown stage %q", stage)
+	}
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`
+			UPDATE captures SET
+				stage = ?,
+				raw_audio_path = COALESCE(?, raw_audio_path),
+				transcript_text = COALESCE(?, transcript_text),
+				transcript_path = COALESCE(?, transcript_path),
+				journal_path = COALESCE(?, journal_path),
+				journal_sink = COALESCE(?, journal_sink),
+				updated_at = ?
+			WHERE capture_id = ?
+		`,
+			stage,
+			nullable(out.AudioPath),
+			nullable(out.TranscriptText),
+			nullable(out.TranscriptPath),
+			nullable(out.JournalPath),
+			nullable(out.JournalSink),
+			time.Now().UTC().Format(time.RFC3339),
+			captureID,
+		); err != nil {
+			return err
+		}
+		return s.recordEvent(tx, ItemEvent{Source: SourceCapture, ItemID: captureID, Event: "stage", Stage: stage})
+	})
+}
+
+func (s *Store) JournalSink(source, id string) (string, error) {
+	table, idColumn := "captures", "capture_id"
+	if source == SourceDiscord {
+		table, idColumn = "messages", "message_id"
+	}
+	var sink sql.NullString
+	err := s.db.QueryRow(`SELECT journal_sink FROM `+table+` WHERE `+idColumn+` = ?`, id).Scan(&sink)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return sink.String, err
+}
+
+func (s *Store) countOpenByStage(table string) (map[string]int, error) {
+	rows, err := s.db.Query(fmt.Sprintf(`
+		SELECT COALESCE(NULLIF(stage, ''), 'new'), COUNT(*)
+		FROM %s
+		WHERE status NOT IN ('done', 'abandoned')
+		GROUP BY 1
+	`, table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]int{}
+	for rows.Next() {
+		var stage string
+		var count int
+		if err := rows.Scan(&stage, &count); err != nil {
+			return nil, err
+		}
+		out[stage] = count
+	}
+	return out, rows.Err()
+}
@@ -14,7 +14,9 @@ import (
 )
 
 type Store struct {
-	db *sql.DB
+	db    *sql.DB
+	inUse *FileLock
+	runID string
 }
 
 type MessageRecord struct {
@@ -34,6 +36,9 @@ type MessageRecord struct {
 	LastError          string
 	JournalPath        string
 	DiscordJumpURL     string
+	TranscriptText     string
+	Stage              string
+	Audio              AudioMetadata
 	CreatedAt          time.Time
 	UpdatedAt          time.Time
 }
@@ -54,10 +59,24 @@ type CaptureRecord struct {
 	JournalPath     string
 	TranscriptPath  string
 	LastError       string
+	Stage           string
+	Audio           AudioMetadata
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
 }
 
+type AudioMetadata struct {
+	DurationMS int64  `json:"duration_ms,omitempty"`
+	Codec      string `json:"codec,omitempty"`
+	Channels   int    `json:"channels,omitempty"`
+	SampleRate int    `json:"sample_rate,omitempty"`
+	Bitrate    int64  `json:"bitrate,omitempty"`
+}
+
+func (m AudioMetadata) Probed() bool {
+	return m.DurationMS > 0 || m.Codec != ""
+}
+
 type StatusSummary struct {
 	Total              int            `json:"total"`
 	ByStatus           map[string]int `json:"by_status"`
@@ -67,21 +86,36 @@ type StatusSummary struct {
 	CaptureTotal       int            `json:"capture_total"`
 	CaptureByStatus    map[string]int `json:"capture_by_status,omitempty"`
 	CaptureRetryDue    int            `json:"capture_retry_due"`
+
+	ByStage                  map[string]int       `json:"by_stage,omitempty"`
+	CaptureByStage           map[string]int       `json:"capture_by_stage,omitempty"`
+	TranscriptionsInProgress []TranscriptProgress `json:"transcriptions_in_progress,omitempty"`
 }
 
 func Open(path string) (*Store, error) {
+	return open(path, true)
+}
+
+func open(path string, migrate bool) (*Store, error) {
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return nil, err
 	}
+	inUse, err := acquireSharedFileLock(inUseLockPath(path))
+	if err != nil {
+		return nil, fmt.Errorf("state db is being restored: %w", err)
+	}
 	db, err := sql.Open("sqlite", path)
 	if err != nil {
+		_ = inUse.Release()
 		return nil, err
 	}
 	db.SetMaxOpenConns(1)
-	s := &Store{db: db}
-	if err := s.initSchema(); err != nil {
-		_ = db.Close()
-		return nil, err
+	s := &Store{db: db, inUse: inUse}
+	if migrate {
+		if _, err := s.Migrate(); err != nil {
+			_ = s.Close()
+			return nil, err
+		}
 	}
 	return s, nil
 }
@@ -90,107 +124,9 @@ func (s *Store) Close() error {
 	if s == nil || s.db == nil {
 		return nil
 	}
-	return s.db.Close()
-}
-
-func (s *Store) initSchema() error {
-	stmts := []string{
-		`CREATE TABLE IF NOT EXISTS messages (
-		  message_id TEXT PRIMARY KEY,
-		  channel_id TEXT NOT NULL,
-		  author_id TEXT NOT NULL,
-		  attachment_id TEXT NOT NULL,
-		  attachment_url TEXT NOT NULL,
-		  attachment_filename TEXT,
-		  content_type TEXT,
-		  message_content TEXT,
-		  audio_path TEXT,
-		  transcript_path TEXT,
-		  status TEXT NOT NULL,
-		  attempts INTEGER NOT NULL DEFAULT 0,
-		  next_retry_at TEXT,
-		  last_error TEXT,
-		  journal_path TEXT,
-		  discord_jump_url TEXT,
-		  created_at TEXT NOT NULL,
-		  updated_at TEXT NOT NULL
-		);`,
-		`CREATE TABLE IF NOT EXISTS runs (
-		  run_id TEXT PRIMARY KEY,
-		  command TEXT NOT NULL,
-		  started_at TEXT NOT NULL,
-		  finished_at TEXT,
-		  processed_count INTEGER NOT NULL DEFAULT 0,
-		  success_count INTEGER NOT NULL DEFAULT 0,
-		  failed_count INTEGER NOT NULL DEFAULT 0
-		);`,
-		`CREATE TABLE IF NOT EXISTS kv (
-		  key TEXT PRIMARY KEY,
-		  value TEXT NOT NULL,
-		  updated_at TEXT NOT NULL
-		);`,
-		`CREATE TABLE IF NOT EXISTS captures (
-		  capture_id TEXT PRIMARY KEY,
-		  source TEXT NOT NULL,
-		  source_dedupe_key TEXT,
-		  device_id TEXT,
-		  captured_at TEXT,
-		  received_at TEXT NOT NULL,
-		  raw_audio_path TEXT NOT NULL,
-		  content_type TEXT,
-		  transcript_text TEXT,
-		  status TEXT NOT NULL,
-		  attempts INTEGER NOT NULL DEFAULT 0,
-		  next_retry_at TEXT,
-		  journal_path TEXT,
-		  transcript_path TEXT,
-		  last_error TEXT,
-		  created_at TEXT NOT NULL,
-		  updated_at TEXT NOT NULL
-		);`,
-	}
-	for _, stmt := range stmts {
-		if _, err := s.db.Exec(stmt); err != nil {
-			return err
-		}
-	}
-	if _, err := s.db.Exec(`ALTER TABLE messages ADD COLUMN message_content TEXT`); err != nil {
-		lower := strings.ToLower(err.Error())
-		if !strings.Contains(lower, "duplicate column name") {
-			return err
-		}
-	}
-	version, ok, err := s.GetKV("schema_version")
-	if err != nil {
-		return err
-	}
-	switch strings.TrimSpace(version) {
-	case "3":
-	case "2":
-		if _, err := s.db.Exec(`ALTER TABLE captures ADD COLUMN transcript_text TEXT`); err != nil {
-			lower := strings.ToLower(err.Error())
-			if !strings.Contains(lower, "duplicate column name") {
-				return err
-			}
-		}
-		if err := s.SetKV("schema_version", "3"); err != nil {
-			return err
-		}
-	default:
-		if !ok {
-			if err := s.SetKV("schema_version", "3"); err != nil {
-				return err
-			}
-			break
-		}
-		if err := s.SetKV("schema_version", "3"); err != nil {
-			return err
-		}
-	}
-	if _, err := s.db.Exec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_captures_source_dedupe_key ON captures(source_dedupe_key)`); err != nil {
-		return err
-	}
-	return nil
+	err := s.db.Close()
+	_ = s.inUse.Release()
+	return err
 }
 
 func (s *Store) BeginRun(command string, startedAt time.Time) (string, error) {
@@ -205,12 +141,16 @@ func (s *Store) BeginRun(command string, startedAt time.Time) (string, error) {
 	return runID, nil
 }
 
-func (s *Store) FinishRun(runID string, finishedAt time.Time, processed, success, failed int) error {
-	_, err := s.db.Exec(`
+func (s *Store) FinishRun(runID string, finishedAt time.Time, processed, success, failed int, errs []string) error {
+	errorsJSON, err := encodeRunErrors(errs)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`
 		UPDATE runs
-		SET finished_at = ?, processed_count = ?, success_count = ?, failed_count = ?
+		SET finished_at = ?, processed_count = ?, success_count = ?, failed_count = ?, errors_json = ?
 		WHERE run_id = ?`,
-		finishedAt.UTC().Format(time.RFC3339), processed, success, failed, runID)
+		finishedAt.UTC().Format(time.RFC3339), processed, success, failed, errorsJSON, runID)
 	return err
 }
 
@@ -237,34 +177,39 @@ func (s *Store) GetKV(key string) (string, bool, error) {
 
 func (s *Store) UpsertPending(rec MessageRecord) error {
 	now := time.Now().UTC().Format(time.RFC3339)
-	_, err := s.db.Exec(`
-		INSERT INTO messages (
-			message_id, channel_id, author_id, attachment_id, attachment_url,
-			attachment_filename, content_type, message_content, status, attempts, created_at, updated_at,
-			discord_jump_url
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, 'pending', 0, ?, ?, ?)
-		ON CONFLICT(message_id) DO UPDATE SET
-			channel_id = excluded.channel_id,
-			author_id = excluded.author_id,
-			attachment_id = excluded.attachment_id,
-			attachment_url = excluded.attachment_url,
-			attachment_filename = excluded.attachment_filename,
-			content_type = excluded.content_type,
-			message_content = excluded.message_content,
-			discord_jump_url = excluded.discord_jump_url,
-			updated_at = excluded.updated_at
-	`,
-		rec.MessageID, rec.ChannelID, rec.AuthorID, rec.AttachmentID, rec.AttachmentURL,
-		rec.AttachmentFilename, rec.ContentType, rec.MessageContent, now, now, rec.DiscordJumpURL,
-	)
-	return err
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`
+			INSERT INTO messages (
+				message_id, channel_id, author_id, attachment_id, attachment_url,
+				attachment_filename, content_type, message_content, status, attempts, created_at, updated_at,
+				discord_jump_url
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, 'pending', 0, ?, ?, ?)
+			ON CONFLICT(message_id) DO UPDATE SET
+				channel_id = excluded.channel_id,
+				author_id = excluded.author_id,
+				attachment_id = excluded.attachment_id,
+				attachment_url = excluded.attachment_url,
+				attachment_filename = excluded.attachment_filename,
+				content_type = excluded.content_type,
+				message_content = excluded.message_content,
+				discord_jump_url = excluded.discord_jump_url,
+				updated_at = excluded.updated_at
+		`,
+			rec.MessageID, rec.ChannelID, rec.AuthorID, rec.AttachmentID, rec.AttachmentURL,
+			rec.AttachmentFilename, rec.ContentType, rec.MessageContent, now, now, rec.DiscordJumpURL,
+		); err != nil {
+			return err
+		}
+		return s.recordEvent(tx, ItemEvent{Source: SourceDiscord, ItemID: rec.MessageID, Event: "queued", Status: "pending"})
+	})
 }
 
 func (s *Store) GetMessage(messageID string) (MessageRecord, bool, error) {
 	row := s.db.QueryRow(`
 		SELECT message_id, channel_id, author_id, attachment_id, attachment_url, attachment_filename,
 			content_type, message_content, audio_path, transcript_path, status, attempts, next_retry_at,
-			last_error, journal_path, discord_jump_url, created_at, updated_at
+			last_error, journal_path, discord_jump_url, created_at, updated_at,
+			duration_ms, audio_codec, audio_channels, audio_sample_rate, audio_bitrate, stage, transcript_text
 		FROM messages WHERE message_id = ?
 	`, messageID)
 
@@ -296,39 +241,44 @@ func (s *Store) CreateCapture(rec CaptureRecord) error {
 	if status == "" {
 		status = "pending"
 	}
-	_, err := s.db.Exec(`
-		INSERT INTO captures (
-			capture_id, source, source_dedupe_key, device_id, captured_at, received_at,
-			raw_audio_path, content_type, transcript_text, status, attempts, next_retry_at,
-			journal_path, transcript_path, last_error, created_at, updated_at
-		) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-	`,
-		rec.CaptureID,
-		rec.Source,
-		nullable(rec.SourceDedupeKey),
-		nullable(rec.DeviceID),
-		formatTime(rec.CapturedAt),
-		receivedAt.UTC().Format(time.RFC3339),
-		rec.RawAudioPath,
-		nullable(rec.ContentType),
-		nullable(rec.TranscriptText),
-		status,
-		rec.Attempts,
-		formatTime(rec.NextRetryAt),
-		nullable(rec.JournalPath),
-		nullable(rec.TranscriptPath),
-		nullable(trimError(rec.LastError)),
-		createdAt.UTC().Format(time.RFC3339),
-		updatedAt.UTC().Format(time.RFC3339),
-	)
-	return err
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`
+			INSERT INTO captures (
+				capture_id, source, source_dedupe_key, device_id, captured_at, received_at,
+				raw_audio_path, content_type, transcript_text, status, attempts, next_retry_at,
+				journal_path, transcript_path, last_error, created_at, updated_at
+			) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		`,
+			rec.CaptureID,
+			rec.Source,
+			nullable(rec.SourceDedupeKey),
+			nullable(rec.DeviceID),
+			formatTime(rec.CapturedAt),
+			receivedAt.UTC().Format(time.RFC3339),
+			rec.RawAudioPath,
+			nullable(rec.ContentType),
+			nullable(rec.TranscriptText),
+			status,
+			rec.Attempts,
+			formatTime(rec.NextRetryAt),
+			nullable(rec.JournalPath),
+			nullable(rec.TranscriptPath),
+			nullable(trimError(rec.LastError)),
+			createdAt.UTC().Format(time.RFC3339),
+			updatedAt.UTC().Format(time.RFC3339),
+		); err != nil {
+			return err
+		}
+		return s.recordEvent(tx, ItemEvent{Source: SourceCapture, ItemID: rec.CaptureID, Event: "received", Status: status, Attempt: rec.Attempts})
+	})
 }
 
 func (s *Store) GetCapture(captureID string) (CaptureRecord, bool, error) {
 	row := s.db.QueryRow(`
 		SELECT capture_id, source, source_dedupe_key, device_id, captured_at, received_at,
 			raw_audio_path, content_type, transcript_text, status, attempts, next_retry_at,
-			journal_path, transcript_path, last_error, created_at, updated_at
+			journal_path, transcript_path, last_error, created_at, updated_at,
+			duration_ms, audio_codec, audio_channels, audio_sample_rate, audio_bitrate, stage
 		FROM captures WHERE capture_id = ?
 	`, captureID)
 	rec, found, err := scanCaptureRow(row)
@@ -345,7 +295,8 @@ func (s *Store) GetCaptureBySourceDedupeKey(sourceDedupeKey string) (CaptureReco
 	row := s.db.QueryRow(`
 		SELECT capture_id, source, source_dedupe_key, device_id, captured_at, received_at,
 			raw_audio_path, content_type, transcript_text, status, attempts, next_retry_at,
-			journal_path, transcript_path, last_error, created_at, updated_at
+			journal_path, transcript_path, last_error, created_at, updated_at,
+			duration_ms, audio_codec, audio_channels, audio_sample_rate, audio_bitrate, stage
 		FROM captures
 		WHERE source_dedupe_key = ?
 	`, sourceDedupeKey)
@@ -419,6 +370,17 @@ func (s *Store) RecoverStuckCaptures(now time.Time, staleAfter time.Duration, re
 		); err != nil {
 			return 0, err
 		}
+		if err := s.recordEvent(tx, ItemEvent{
+			Source:    SourceCapture,
+			ItemID:    rec.captureID,
+			Event:     "recovered",
+			Status:    "failed",
+			Attempt:   attempts,
+			Error:     "recovered from stuck processing",
+			CreatedAt: now,
+		}); err != nil {
+			return 0, err
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -434,7 +396,8 @@ func (s *Store) ListCapturesForProcessing(now time.Time, limit int) ([]CaptureRe
 	rows, err := s.db.Query(`
 		SELECT capture_id, source, source_dedupe_key, device_id, captured_at, received_at,
 			raw_audio_path, content_type, transcript_text, status, attempts, next_retry_at,
-			journal_path, transcript_path, last_error, created_at, updated_at
+			journal_path, transcript_path, last_error, created_at, updated_at,
+			duration_ms, audio_codec, audio_channels, audio_sample_rate, audio_bitrate, stage
 		FROM captures
 		WHERE status = 'pending'
 		   OR (status = 'failed' AND next_retry_at IS NOT NULL AND next_retry_at <= ?)
@@ -485,56 +448,92 @@ func recoverCaptureNextRetryAt(now time.Time, attempts, baseSeconds, maxSeconds
 }
 
 func (s *Store) MarkCaptureProcessing(captureID string) error {
-	_, err := s.db.Exec(`
-		UPDATE captures
-		SET status = 'processing', next_retry_at = NULL, updated_at = ?
-		WHERE capture_id = ?
-	`, time.Now().UTC().Format(time.RFC3339), captureID)
-	return err
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`
+			UPDATE captures
+			SET status = 'processing', next_retry_at = NULL, updated_at = ?
+			WHERE capture_id = ?
+		`, time.Now().UTC().Format(time.RFC3339), captureID); err != nil {
+			return err
+		}
+		return s.recordEvent(tx, ItemEvent{Source: SourceCapture, ItemID: captureID, Event: "processing", Status: "processing"})
+	})
 }
 
 func (s *Store) MarkCaptureDone(captureID, journalPath, transcriptPath string) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`
+			UPDATE captures
+			SET status = 'done',
+				stage = 'acknowledged',
+				journal_path = ?,
+				transcript_path = ?,
+				last_error = NULL,
+				next_retry_at = NULL,
+				updated_at = ?
+			WHERE capture_id = ?
+		`, nullable(journalPath), nullable(transcriptPath), time.Now().UTC().Format(time.RFC3339), captureID); err != nil {
+			return err
+		}
+		return s.recordEvent(tx, ItemEvent{Source: SourceCapture, ItemID: captureID, Event: "done", Status: "done", Stage: StageAcknowledged})
+	})
+}
+
+func (s *Store) MarkCaptureFailed(captureID, errText string, attempts int, nextRetryAt *time.Time) error {
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`
+			UPDATE captures
+			SET status = 'failed',
+				attempts = ?,
+				last_error = ?,
+				next_retry_at = ?,
+				updated_at = ?
+			WHERE capture_id = ?
+		`, attempts, trimError(errText), formatTime(nextRetryAt), time.Now().UTC().Format(time.RFC3339), captureID); err != nil {
+			return err
+		}
+		return s.recordEvent(tx, ItemEvent{Source: SourceCapture, ItemID: captureID, Event: "failed", Status: "failed", Attempt: attempts, Error: errText})
+	})
+}
+
+func (s *Store) SetMessageAudioMetadata(messageID string, meta AudioMetadata) error {
 	_, err := s.db.Exec(`
-		UPDATE captures
-		SET status = 'done',
-			journal_path = ?,
-			transcript_path = ?,
-			last_error = NULL,
-			next_retry_at = NULL,
-			updated_at = ?
-		WHERE capture_id = ?
-	`, nullable(journalPath), nullable(transcriptPath), time.Now().UTC().Format(time.RFC3339), captureID)
+		UPDATE messages
+		SET duration_ms = ?, audio_codec = ?, audio_channels = ?, audio_sample_rate = ?, audio_bitrate = ?, updated_at = ?
+		WHERE message_id = ?
+	`, meta.DurationMS, nullable(meta.Codec), meta.Channels, meta.SampleRate, meta.Bitrate, time.Now().UTC().Format(time.RFC3339), messageID)
 	return err
 }
 
-func (s *Store) MarkCaptureFailed(captureID, errText string, attempts int, nextRetryAt *time.Time) error {
+func (s *Store) SetCaptureAudioMetadata(captureID string, meta AudioMetadata) error {
 	_, err := s.db.Exec(`
 		UPDATE captures
-		SET status = 'failed',
-			attempts = ?,
-			last_error = ?,
-			next_retry_at = ?,
-			updated_at = ?
+		SET duration_ms = ?, audio_codec = ?, audio_channels = ?, audio_sample_rate = ?, audio_bitrate = ?, updated_at = ?
 		WHERE capture_id = ?
-	`, attempts, trimError(errText), formatTime(nextRetryAt), time.Now().UTC().Format(time.RFC3339), captureID)
+	`, meta.DurationMS, nullable(meta.Codec), meta.Channels, meta.SampleRate, meta.Bitrate, time.Now().UTC().Format(time.RFC3339), captureID)
 	return err
 }
 
 func (s *Store) MarkDone(messageID, journalPath, audioPath, transcriptPath, jumpURL string) error {
 	now := time.Now().UTC().Format(time.RFC3339)
-	_, err := s.db.Exec(`
-		UPDATE messages SET
-			status = 'done',
-			journal_path = ?,
-			audio_path = ?,
-			transcript_path = ?,
-			discord_jump_url = ?,
-			last_error = NULL,
-			next_retry_at = NULL,
-			updated_at = ?
-		WHERE message_id = ?
-	`, journalPath, nullable(audioPath), nullable(transcriptPath), nullable(jumpURL), now, messageID)
-	return err
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`
+			UPDATE messages SET
+				status = 'done',
+				stage = 'acknowledged',
+				journal_path = ?,
+				audio_path = ?,
+				transcript_path = ?,
+				discord_jump_url = ?,
+				last_error = NULL,
+				next_retry_at = NULL,
+				updated_at = ?
+			WHERE message_id = ?
+		`, journalPath, nullable(audioPath), nullable(transcriptPath), nullable(jumpURL), now, messageID); err != nil {
+			return err
+		}
+		return s.recordEvent(tx, ItemEvent{Source: SourceDiscord, ItemID: messageID, Event: "done", Status: "done", Stage: StageAcknowledged})
+	})
 }
 
 func (s *Store) MarkReactionPending(
@@ -548,30 +547,43 @@ func (s *Store) MarkReactionPending(
 	jumpURL string,
 ) error {
 	now := time.Now().UTC().Format(time.RFC3339)
-	_, err := s.db.Exec(`
-		UPDATE messages SET
-			status = 'reaction_pending',
-			attempts = ?,
-			journal_path = ?,
-			audio_path = ?,
-			transcript_path = ?,
-			discord_jump_url = ?,
-			last_error = ?,
-			next_retry_at = ?,
-			updated_at = ?
-		WHERE message_id = ?
-	`,
-		attempts,
-		nullable(journalPath),
-		nullable(audioPath),
-		nullable(transcriptPath),
-		nullable(jumpURL),
-		trimError(errText),
-		nextRetryAt.UTC().Format(time.RFC3339),
-		now,
-		messageID,
-	)
-	return err
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`
+			UPDATE messages SET
+				status = 'reaction_pending',
+				stage = 'journaled',
+				attempts = ?,
+				journal_path = ?,
+				audio_path = ?,
+				transcript_path = ?,
+				discord_jump_url = ?,
+				last_error = ?,
+				next_retry_at = ?,
+				updated_at = ?
+			WHERE message_id = ?
+		`,
+			attempts,
+			nullable(journalPath),
+			nullable(audioPath),
+			nullable(transcriptPath),
+			nullable(jumpURL),
+			trimError(errText),
+			nextRetryAt.UTC().Format(time.RFC3339),
+			now,
+			messageID,
+		); err != nil {
+			return err
+		}
+		return s.recordEvent(tx, ItemEvent{
+			Source:  SourceDiscord,
+			ItemID:  messageID,
+			Event:   "reaction_pending",
+			Status:  "reaction_pending",
+			Stage:   StageJournaled,
+			Attempt: attempts,
+			Error:   errText,
+		})
+	})
 }
 
 func (s *Store) MarkFailed(messageID, errText string, attempts int, nextRetryAt *time.Time) error {
@@ -580,16 +592,20 @@ func (s *Store) MarkFailed(messageID, errText string, attempts int, nextRetryAt
 	if nextRetryAt != nil {
 		next = nextRetryAt.UTC().Format(time.RFC3339)
 	}
-	_, err := s.db.Exec(`
-		UPDATE messages SET
-			status = 'failed',
-			attempts = ?,
-			last_error = ?,
-			next_retry_at = ?,
-			updated_at = ?
-		WHERE message_id = ?
-	`, attempts, trimError(errText), next, now, messageID)
-	return err
+	return s.inTx(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`
+			UPDATE messages SET
+				status = 'failed',
+				attempts = ?,
+				last_error = ?,
+				next_retry_at = ?,
+				updated_at = ?
+			WHERE message_id = ?
+		`, attempts, trimError(errText), next, now, messageID); err != nil {
+			return err
+		}
+		return s.recordEvent(tx, ItemEvent{Source: SourceDiscord, ItemID: messageID, Event: "failed", Status: "failed", Attempt: attempts, Error: errText})
+	})
 }
 
 func (s *Store) ListRetryCandidates(now time.Time, limit int) ([]MessageRecord, error) {
@@ -599,7 +615,8 @@ func (s *Store) ListRetryCandidates(now time.Time, limit int) ([]MessageRecord,
 	rows, err := s.db.Query(`
 		SELECT message_id, channel_id, author_id, attachment_id, attachment_url, attachment_filename,
 			content_type, message_content, audio_path, transcript_path, status, attempts, next_retry_at,
-			last_error, journal_path, discord_jump_url, created_at, updated_at
+			last_error, journal_path, discord_jump_url, created_at, updated_at,
+			duration_ms, audio_codec, audio_channels, audio_sample_rate, audio_bitrate, stage, transcript_text
 		FROM messages
 		WHERE status IN ('failed', 'reaction_pending')
 		  AND (
@@ -638,7 +655,8 @@ func (s *Store) ListDoneWithAudioBefore(cutoff time.Time, limit int) ([]MessageR
 	rows, err := s.db.Query(`
 		SELECT message_id, channel_id, author_id, attachment_id, attachment_url, attachment_filename,
 			content_type, message_content, audio_path, transcript_path, status, attempts, next_retry_at,
-			last_error, journal_path, discord_jump_url, created_at, updated_at
+			last_error, journal_path, discord_jump_url, created_at, updated_at,
+			duration_ms, audio_codec, audio_channels, audio_sample_rate, audio_bitrate, stage, transcript_text
 		FROM messages
 		WHERE status = 'done'
 		  AND audio_path IS NOT NULL
@@ -671,7 +689,8 @@ func (s *Store) ListDoneWithTranscriptBefore(cutoff time.Time, limit int) ([]Mes
 	rows, err := s.db.Query(`
 		SELECT message_id, channel_id, author_id, attachment_id, attachment_url, attachment_filename,
 			content_type, message_content, audio_path, transcript_path, status, attempts, next_retry_at,
-			last_error, journal_path, discord_jump_url, created_at, updated_at
+			last_error, journal_path, discord_jump_url, created_at, updated_at,
+			duration_ms, audio_codec, audio_channels, audio_sample_rate, audio_bitrate, stage, transcript_text
 		FROM messages
 		WHERE status = 'done'
 		  AND transcript_path IS NOT NULL
@@ -714,7 +733,8 @@ func (s *Store) ListDoneCapturesWithAudioBefore(cutoff time.Time, limit int) ([]
 	rows, err := s.db.Query(`
 		SELECT capture_id, source, source_dedupe_key, device_id, captured_at, received_at,
 			raw_audio_path, content_type, transcript_text, status, attempts, next_retry_at,
-			journal_path, transcript_path, last_error, created_at, updated_at
+			journal_path, transcript_path, last_error, created_at, updated_at,
+			duration_ms, audio_codec, audio_channels, audio_sample_rate, audio_bitrate, stage
 		FROM captures
 		WHERE status = 'done'
 		  AND raw_audio_path IS NOT NULL
@@ -748,7 +768,8 @@ func (s *Store) ListDoneCapturesWithTranscriptBefore(cutoff time.Time, limit int
 	rows, err := s.db.Query(`
 		SELECT capture_id, source, source_dedupe_key, device_id, captured_at, received_at,
 			raw_audio_path, content_type, transcript_text, status, attempts, next_retry_at,
-			journal_path, transcript_path, last_error, created_at, updated_at
+			journal_path, transcript_path, last_error, created_at, updated_at,
+			duration_ms, audio_codec, audio_channels, audio_sample_rate, audio_bitrate, stage
 		FROM captures
 		WHERE status = 'done'
 		  AND transcript_path IS NOT NULL
@@ -784,6 +805,28 @@ func (s *Store) ClearCaptureTranscriptPath(captureID string) error {
 	return err
 }
 
+func (s *Store) OldestPending() (map[string]time.Time, error) {
+	out := map[string]time.Time{}
+	for source, query := range map[string]string{
+		SourceDiscord: `SELECT MIN(created_at) FROM messages WHERE status = 'pending'`,
+		SourceCapture: `SELECT MIN(received_at) FROM captures WHERE status = 'pending'`,
+	} {
+		var raw sql.NullString
+		if err := s.db.QueryRow(query).Scan(&raw); err != nil {
+			return out, err
+		}
+		if !raw.Valid {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw.String)
+		if err != nil {
+			return out, err
+		}
+		out[source] = t
+	}
+	return out, nil
+}
+
 func (s *Store) Summary(now time.Time, maxRetryAttempts int) (StatusSummary, error) {
 	summary := StatusSummary{ByStatus: map[string]int{}, CaptureByStatus: map[string]int{}}
 
@@ -846,6 +889,19 @@ func (s *Store) Summary(now time.Time, maxRetryAttempts int) (StatusSummary, err
 		   OR (status = 'failed' AND next_retry_at IS NOT NULL AND next_retry_at <= ?)
 	`, now.UTC().Format(time.RFC3339)).Scan(&summary.CaptureRetryDue)
 
+	if summary.ByStage, err = s.countOpenByStage("messages"); err != nil {
+		return summary, err
+	}
+	if summary.CaptureByStage, err = s.countOpenByStage("captures"); err != nil {
+		return summary, err
+	}
+
+	progress, err := s.ListTranscriptProgress()
+	if err != nil {
+		return summary, err
+	}
+	summary.TranscriptionsInProgress = progress
+
 	if v, ok, err := s.GetKV("last_seen_message_id"); err == nil && ok {
 		summary.LastSeenMessageID = v
 	}
@@ -853,159 +909,11 @@ func (s *Store) Summary(now time.Time, maxRetryAttempts int) (StatusSummary, err
 }
 
 func scanMessageRows(rows *sql.Rows) (MessageRecord, bool, error) {
-	var rec MessageRecord
-	var nextRetry sql.NullString
-	var audioPath sql.NullString
-	var transcriptPath sql.NullString
-	var lastError sql.NullString
-	var journalPath sql.NullString
-	var jumpURL sql.NullString
-	var contentType sql.NullString
-	var messageContent sql.NullString
-	var attachmentFilename sql.NullString
-	var createdAtRaw string
-	var updatedAtRaw string
-
-	err := rows.Scan(
-		&rec.MessageID,
-		&rec.ChannelID,
-		&rec.AuthorID,
-		&rec.AttachmentID,
-		&rec.AttachmentURL,
-		&attachmentFilename,
-		&contentType,
-		&messageContent,
-		&audioPath,
-		&transcriptPath,
-		&rec.Status,
-		&rec.Attempts,
-		&nextRetry,
-		&lastError,
-		&journalPath,
-		&jumpURL,
-		&createdAtRaw,
-		&updatedAtRaw,
-	)
-	if err != nil {
-		return MessageRecord{}, false, err
-	}
-	if attachmentFilename.Valid {
-		rec.AttachmentFilename = attachmentFilename.String
-	}
-	if contentType.Valid {
-		rec.ContentType = contentType.String
-	}
-	if messageContent.Valid {
-		rec.MessageContent = messageContent.String
-	}
-	if audioPath.Valid {
-		rec.AudioPath = audioPath.String
-	}
-	if transcriptPath.Valid {
-		rec.TranscriptPath = transcriptPath.String
-	}
-	if lastError.Valid {
-		rec.LastError = lastError.String
-	}
-	if journalPath.Valid {
-		rec.JournalPath = journalPath.String
-	}
-	if jumpURL.Valid {
-		rec.DiscordJumpURL = jumpURL.String
-	}
-	if nextRetry.Valid {
-		t, err := time.Parse(time.RFC3339, nextRetry.String)
-		if err == nil {
-			rec.NextRetryAt = &t
-		}
-	}
-	if t, err := time.Parse(time.RFC3339, createdAtRaw); err == nil {
-		rec.CreatedAt = t
-	}
-	if t, err := time.Parse(time.RFC3339, updatedAtRaw); err == nil {
-		rec.UpdatedAt = t
-	}
-	return rec, true, nil
+	return scanMessageRow(rows)
 }
 
 func scanCaptureRows(rows *sql.Rows) (CaptureRecord, bool, error) {
-	var rec CaptureRecord
-	var sourceDedupe sql.NullString
-	var deviceID sql.NullString
-	var capturedAt sql.NullString
-	var nextRetry sql.NullString
-	var journalPath sql.NullString
-	var transcriptPath sql.NullString
-	var transcriptText sql.NullString
-	var lastError sql.NullString
-	var contentType sql.NullString
-	var receivedAtRaw string
-	var createdAtRaw string
-	var updatedAtRaw string
-
-	err := rows.Scan(
-		&rec.CaptureID,
-		&rec.Source,
-		&sourceDedupe,
-		&deviceID,
-		&capturedAt,
-		&receivedAtRaw,
-		&rec.RawAudioPath,
-		&contentType,
-		&transcriptText,
-		&rec.Status,
-		&rec.Attempts,
-		&nextRetry,
-		&journalPath,
-		&transcriptPath,
-		&lastError,
-		&createdAtRaw,
-		&updatedAtRaw,
-	)
-	if err != nil {
-		return CaptureRecord{}, false, err
-	}
-	if sourceDedupe.Valid {
-		rec.SourceDedupeKey = sourceDedupe.String
-	}
-	if deviceID.Valid {
-		rec.DeviceID = deviceID.String
-	}
-	if contentType.Valid {
-		rec.ContentType = contentType.String
-	}
-	if transcriptText.Valid {
-		rec.TranscriptText = transcriptText.String
-	}
-	if journalPath.Valid {
-		rec.JournalPath = journalPath.String
-	}
-	if transcriptPath.Valid {
-		rec.TranscriptPath = transcriptPath.String
-	}
-	if lastError.Valid {
-		rec.LastError = lastError.String
-	}
-	if capturedAt.Valid {
-		if t, err := time.Parse(time.RFC3339, capturedAt.String); err == nil {
-			rec.CapturedAt = &t
-		}
-	}
-	if nextRetry.Valid {
-		if t, err := time.Parse(time.RFC3339, nextRetry.String); err == nil {
-			rec.NextRetryAt = &t
-		}
-	}
-	if t, err := time.Parse(time.RFC3339, receivedAtRaw); err == nil {
-		rec.ReceivedAt = t
-	}
-	if t, err := time.Parse(time.RFC3339, createdAtRaw); err == nil {
-		rec.CreatedAt = t
-	}
-	if t, err := time.Parse(time.RFC3339, updatedAtRaw); err == nil {
-		rec.UpdatedAt = t
-	}
-	return rec, true, nil
+	return scanCaptureRow(rows)
 }
 
 type rowScanner interface {
@@ -1014,6 +922,9 @@ type rowScanner interface {
 
 func scanMessageRow(row rowScanner) (MessageRecord, bool, error) {
 	var rec MessageRecord
+	var audio nullableAudio
+	var stage sql.NullString
+	var transcriptText sql.NullString
 	var nextRetry sql.NullString
 	var audioPath sql.NullString
 	var transcriptPath sql.NullString
@@ -1045,6 +956,13 @@ func scanMessageRow(row rowScanner) (MessageRecord, bool, error) {
 		&jumpURL,
 		&createdAtRaw,
 		&updatedAtRaw,
+		&audio.durationMS,
+		&audio.codec,
+		&audio.channels,
+		&audio.sampleRate,
+		&audio.bitrate,
+		&stage,
+		&transcriptText,
 	)
 	if err != nil {
 		return MessageRecord{}, false, err
@@ -1073,6 +991,9 @@ func scanMessageRow(row rowScanner) (MessageRecord, bool, error) {
 	if jumpURL.Valid {
 		rec.DiscordJumpURL = jumpURL.String
 	}
+	if transcriptText.Valid {
+		rec.TranscriptText = transcriptText.String
+	}
 	if nextRetry.Valid {
 		t, err := time.Parse(time.RFC3339, nextRetry.String)
 		if err == nil {
@@ -1085,11 +1006,15 @@ func scanMessageRow(row rowScanner) (MessageRecord, bool, error) {
 	if t, err := time.Parse(time.RFC3339, updatedAtRaw); err == nil {
 		rec.UpdatedAt = t
 	}
+	rec.Audio = audio.metadata()
+	rec.Stage = stage.String
 	return rec, true, nil
 }
 
 func scanCaptureRow(row rowScanner) (CaptureRecord, bool, error) {
 	var rec CaptureRecord
+	var audio nullableAudio
+	var stage sql.NullString
 	var sourceDedupe sql.NullString
 	var deviceID sql.NullString
 	var capturedAt sql.NullString
@@ -1121,6 +1046,12 @@ func scanCaptureRow(row rowScanner) (CaptureRecord, bool, error) {
 		&lastError,
 		&createdAtRaw,
 		&updatedAtRaw,
+		&audio.durationMS,
+		&audio.codec,
+		&audio.channels,
+		&audio.sampleRate,
+		&audio.bitrate,
+		&stage,
 	)
 	if err != nil {
 		return CaptureRecord{}, false, err
@@ -1165,9 +1096,29 @@ func scanCaptureRow(row rowScanner) (CaptureRecord, bool, error) {
 	if t, err := time.Parse(time.RFC3339, updatedAtRaw); err == nil {
 		rec.UpdatedAt = t
 	}
+	rec.Audio = audio.metadata()
+	rec.Stage = stage.String
 	return rec, true, nil
 }
 
+type nullableAudio struct {
+	durationMS sql.NullInt64
+	codec      sql.NullString
+	channels   sql.NullInt64
+	sampleRate sql.NullInt64
+	bitrate    sql.NullInt64
+}
+
+func (a nullableAudio) metadata() AudioMetadata {
+	return AudioMetadata{
+		DurationMS: a.durationMS.Int64,
+		Codec:      a.codec.String,
+		Channels:   int(a.channels.Int64),
+		SampleRate: int(a.sampleRate.Int64),
+		Bitrate:    a.bitrate.Int64,
+	}
+}
+
 func randomID() (string, error) {
 	var b [12]byte
 	if _, err := rand.Read(b[:]); err != nil {
@@ -2,11 +2,15 @@ package state
 
 import (
 	"database/sql"
+	"errors"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
-func TestOpenMigratesCaptureSchemaV2ToV3Idempotently(t *testing.T) {
+func TestOpenMigratesLegacySchemaV2Idempotently(t *testing.T) {
 	dbPath := filepath.Join(t.TempDir(), "state.db")
 	db, err := sql.Open("sqlite", dbPath)
 	if err != nil {
@@ -85,8 +89,17 @@ func TestOpenMigratesCaptureSchemaV2ToV3Idempotently(t *testing.T) {
 	if err != nil {
 		t.Fatalf("get schema version: %v", err)
 	}
-	if !ok || version != "3" {
-		t.Fatalf("expected schema_version=3, got ok=%v value=%q", ok, version)
+	if want := strconv.Itoa(LatestSchemaVersion()); !ok || version != want {
+		t.Fatalf("expected schema_version=%s, got ok=%v value=%q", want, ok, version)
+	}
+	statuses, err := st.MigrationStatus()
+	if err != nil {
+		t.Fatalf("migration status: %v", err)
+	}
+	for _, m := range statuses {
+		if m.AppliedAt == nil {
+			t.Fatalf("expected migration %d to be recorded as applied", m.Version)
+		}
 	}
 	assertCaptureColumnExists(t, st.db, "transcript_text")
 	if err := st.Close(); err != nil {
@@ -103,12 +116,70 @@ func TestOpenMigratesCaptureSchemaV2ToV3Idempotently(t *testing.T) {
 	if err != nil {
 		t.Fatalf("get schema version after reopen: %v", err)
 	}
-	if !ok || version != "3" {
-		t.Fatalf("expected schema_version=3 after reopen, got ok=%v value=%q", ok, version)
+	if want := strconv.Itoa(LatestSchemaVersion()); !ok || version != want {
+		t.Fatalf("expected schema_version=%s after reopen, got ok=%v value=%q", want, ok, version)
+	}
+	pending, err := st.PendingMigrations()
+	if err != nil {
+		t.Fatalf("pending migrations: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("expected no pending migrations after reopen, got %+v", pending)
 	}
 	assertCaptureColumnExists(t, st.db, "transcript_text")
 }
 
+func TestOpenRefusesNewerSchema(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "state.db")
+	st, err := Open(dbPath)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	future := LatestSchemaVersion() + 1
+	if _, err := st.db.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, 'from the future', '2030-01-01T00:00:00Z')`, future); err != nil {
+		t.Fatalf("stamp future migration: %v", err)
+	}
+	if err := st.Close(); err != nil {
+		t.Fatalf("close store: %v", err)
+	}
+
+	if _, err := Open(dbPath); !errors.Is(err, ErrSchemaTooNew) {
+		t.Fatalf("expected ErrSchemaTooNew, got %v", err)
+	}
+}
+
+func TestOpenUnmigratedReportsPendingWithoutApplying(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "state.db")
+	st, err := OpenUnmigrated(dbPath)
+	if err != nil {
+		t.Fatalf("open unmigrated: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+
+	pending, err := st.PendingMigrations()
+	if err != nil {
+		t.Fatalf("pending migrations: %v", err)
+	}
+	if len(pending) != LatestSchemaVersion() {
+		t.Fatalf("expected all %d migrations pending, got %d", LatestSchemaVersion(), len(pending))
+	}
+	if len(pending[0].Stmts) == 0 {
+		t.Fatalf("expected pending migration to list its statements")
+	}
+	version, err := st.SchemaVersion()
+	if err != nil || version != 0 {
+		t.Fatalf("expected untouched db at version 0, got %d err=%v", version, err)
+	}
+
+	applied, err := st.Migrate()
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if len(applied) != LatestSchemaVersion() {
+		t.Fatalf("expected %d applied migrations, got %d", LatestSchemaVersion(), len(applied))
+	}
+}
+
 func assertCaptureColumnExists(t *testing.T, db *sql.DB, column string) {
 	t.Helper()
 
@@ -137,3 +208,37 @@ func assertCaptureColumnExists(t *testing.T, db *sql.DB, column string) {
 	}
 	t.Fatalf("expected captures.%s column to exist", column)
 }
+
+func TestListAttachmentsBeforePagesPastEarlierRows(t *testing.T) {
+	st, err := Open(filepath.Join(t.TempDir(), "state.db"))
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	defer func() { _ = st.Close() }()
+
+	old := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	for _, name := range []string{"a.ogg", "b.ogg", "c.ogg"} {
+		if err := st.RecordAttachment(VaultAttachment{VaultPath: "Attachments/" + name, NotePath: "Journal/x.md", CreatedAt: old}); err != nil {
+			t.Fatalf("record attachment: %v", err)
+		}
+	}
+
+	var seen []string
+	var after VaultAttachment
+	for {
+		page, err := st.ListAttachmentsBefore(old.Add(time.Hour), after, 2)
+		if err != nil {
+			t.Fatalf("list attachments: %v", err)
+		}
+		for _, rec := range page {
+			seen = append(seen, rec.VaultPath)
+		}
+		if len(page) < 2 {
+			break
+		}
+		after = page[len(page)-1]
+	}
+	if strings.Join(seen, ",") != "Attachments/a.ogg,Attachments/b.ogg,Attachments/c.ogg" {
+		t.Fatalf("expected every attachment exactly once, got %v", seen)
+	}
+}
@@ -0,0 +1,64 @@
+package state
+
+import (
+	"database/sql"
+	"time"
+)
+
+type CachedTranscript struct {
+	AudioSHA256    string
+	Model          string
+	Language       string
+	Text           string
+	TranscriptPath string
+	CreatedAt      time.Time
+}
+
+func (s *Store) GetCachedTranscript(audioSHA256, model, language string) (CachedTranscript, bool, error) {
+	var rec CachedTranscript
+	var transcriptPath sql.NullString
+	var createdAtRaw string
+	err := s.db.QueryRow(`
+		SELECT audio_sha256, model, language, text, transcript_path, created_at
+		FROM transcript_cache
+		WHERE audio_sha256 = ? AND model = ? AND language = ?
+	`, audioSHA256, model, language).Scan(&rec.AudioSHA256, &rec.Model, &rec.Language, &rec.Text, &transcriptPath, &createdAtRaw)
+	if err == sql.ErrNoRows {
+		return CachedTranscript{}, false, nil
+	}
+	if err != nil {
+		return CachedTranscript{}, false, err
+	}
+	if transcriptPath.Valid {
+		rec.TranscriptPath = transcriptPath.String
+	}
+	if t, err := time.Parse(time.RFC3339, createdAtRaw); err == nil {
+		rec.CreatedAt = t
+	}
+	return rec, true, nil
+}
+
+func (s *Store) PutCachedTranscript(rec CachedTranscript) error {
+	createdAt := rec.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+	_, err := s.db.Exec(`
+		INSERT INTO transcript_cache (audio_sha256, model, language, text, transcript_path, created_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(audio_sha256, model, language) DO UPDATE SET
+			text = excluded.text,
+			transcript_path = excluded.transcript_path,
+			created_at = excluded.created_at
+	`, rec.AudioSHA256, rec.Model, rec.Language, rec.Text, nullable(rec.TranscriptPath), createdAt.UTC().Format(time.RFC3339))
+	return err
+}
+
+func (s *Store) PruneTranscriptCache(cutoff time.Time) (int, error) {
+	res, err := s.db.Exec(`DELETE FROM transcript_cache WHERE created_at < ?`, cutoff.UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
@@ -0,0 +1,205 @@
+package systemd
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+const (
+	ModeDaemon = "daemon"
+	ModeTimers = "timers"
+)
+
+type Options struct {
+	Mode                string
+	User                bool
+	RunAs               string
+	Binary              string
+	WorkingDir          string
+	ReadWritePaths      []string
+	PollIntervalSeconds int
+	CleanupHour         int
+	CleanupMinute       int
+}
+
+type Unit struct {
+	Name    string
+	Content string
+}
+
+func (u Unit) Enable() bool {
+	return strings.HasSuffix(u.Name, ".timer") || !strings.Contains(u.Content, "Type=oneshot")
+}
+
+func Render(opts Options) ([]Unit, error) {
+	if !filepath.IsAbs(opts.Binary) {
+		return nil, fmt.Errorf("binary path must be absolute: %q", opts.Binary)
+	}
+	if !filepath.IsAbs(opts.WorkingDir) {
+		return nil, fmt.Errorf("working directory must be absolute: %q", opts.WorkingDir)
+	}
+	data := unitData{
+		Options:  opts,
+		Exec:     quote(opts.Binary),
+		WorkDir:  quote(opts.WorkingDir),
+		Writable: quotePaths(opts.ReadWritePaths),
+		WantedBy: "multi-user.target",
+	}
+	if opts.User {
+		data.WantedBy = "default.target"
+		data.RunAs = ""
+	}
+
+	var specs []unitSpec
+	switch opts.Mode {
+	case "", ModeDaemon:
+		specs = []unitSpec{{"voice-inbox.service", serviceTemplate, "Voice inbox daemon (ingest, poll, retry, cleanup)", "daemon"}}
+	case ModeTimers:
+		specs = []unitSpec{
+			{"voice-inbox-serve.service", serviceTemplate, "Voice inbox ingest server", "serve"},
+			{"voice-inbox-poll.service", oneshotTemplate, "Voice inbox Discord poll", "poll --once --json"},
+			{"voice-inbox-poll.timer", pollTimerTemplate, "Run voice inbox poll periodically", ""},
+			{"voice-inbox-cleanup.service", oneshotTemplate, "Voice inbox cleanup", "cleanup --json"},
+			{"voice-inbox-cleanup.timer", cleanupTimerTemplate, "Run voice inbox cleanup daily", ""},
+		}
+	default:
+		return nil, fmt.Errorf("unknown mode %q (want %s or %s)", opts.Mode, ModeDaemon, ModeTimers)
+	}
+
+	units := make([]Unit, 0, len(specs))
+	for _, spec := range specs {
+		data.Description = spec.description
+		data.Args = spec.args
+		var b strings.Builder
+		if err := spec.tmpl.Execute(&b, data); err != nil {
+			return nil, fmt.Errorf("render %s: %w", spec.name, err)
+		}
+		units = append(units, Unit{Name: spec.name, Content: b.String()})
+	}
+	return units, nil
+}
+
+type unitSpec struct {
+	name        string
+	tmpl        *template.Template
+	description string
+	args        string
+}
+
+type unitData struct {
+	Options
+	Exec        string
+	WorkDir     string
+	Writable    string
+	WantedBy    string
+	Description string
+	Args        string
+}
+
+func quote(s string) string {
+	if !strings.ContainsAny(s, " \t\"'\\") {
+		return s
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func quotePaths(paths []string) string {
+	var out []string
+	for _, p := range paths {
+		if p = strings.TrimSpace(p); p == "" {
+			continue
+		}
+		q := "-" + quote(filepath.Clean(p))
+		if !slices.Contains(out, q) {
+			out = append(out, q)
+		}
+	}
+	return strings.Join(out, " ")
+}
+
+const sandbox = `NoNewPrivileges=yes
+PrivateTmp=yes
+PrivateDevices=yes
+ProtectSystem=strict
+ProtectHome=read-only
+{{- if .Writable}}
+ReadWritePaths={{.Writable}}
+{{- end}}
+ProtectKernelTunables=yes
+ProtectKernelModules=yes
+ProtectKernelLogs=yes
+ProtectControlGroups=yes
+ProtectClock=yes
+ProtectHostname=yes
+RestrictSUIDSGID=yes
+RestrictRealtime=yes
+RestrictNamespaces=yes
+RestrictAddressFamilies=AF_UNIX AF_INET AF_INET6
+LockPersonality=yes
+SystemCallArchitectures=native
+UMask=0077`
+
+var serviceTemplate = template.Must(template.New("service").Parse(`[Unit]
+Description={{.Description}}
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=simple
+{{- if .RunAs}}
+User={{.RunAs}}
+{{- end}}
+WorkingDirectory={{.WorkDir}}
+ExecStart={{.Exec}} {{.Args}}
+ExecReload=/bin/kill -HUP $MAINPID
+Restart=on-failure
+RestartSec=10
+TimeoutStopSec=60
+` + sandbox + `
+
+[Install]
+WantedBy={{.WantedBy}}
+`))
+
+var oneshotTemplate = template.Must(template.New("oneshot").Parse(`[Unit]
+Description={{.Description}}
+Wants=network-online.target
+After=network-online.target
+
+[Service]
+Type=oneshot
+{{- if .RunAs}}
+User={{.RunAs}}
+{{- end}}
+WorkingDirectory={{.WorkDir}}
+ExecStart={{.Exec}} {{.Args}}
+SuccessExitStatus=2
+` + sandbox + `
+`))
+
+var pollTimerTemplate = template.Must(template.New("poll-timer").Parse(`[Unit]
+Description={{.Description}}
+
+[Timer]
+OnBootSec=1min
+OnUnitActiveSec={{.PollIntervalSeconds}}s
+Unit=voice-inbox-poll.service
+
+[Install]
+WantedBy=timers.target
+`))
+
+var cleanupTimerTemplate = template.Must(template.New("cleanup-timer").Parse(`[Unit]
+Description={{.Description}}
+
+[Timer]
+OnCalendar=*-*-* {{printf "%02d:%02d" .CleanupHour .CleanupMinute}}:00
+Persistent=true
+Unit=voice-inbox-cleanup.service
+
+[Install]
+WantedBy=timers.target
+`))
@@ -0,0 +1,85 @@
+package systemd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderDaemonUnitIncludesSandboxAndQuotesPaths(t *testing.T) {
+	units, err := Render(Options{
+		RunAs:          "kai",
+		Binary:         "/opt/voice inbox/voice-inbox",
+		WorkingDir:     "/srv/voice-inbox",
+		ReadWritePaths: []string{"/var/lib/voice-inbox", "", "/var/lib/voice-inbox/", "/var/log/voice-inbox"},
+	})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if len(units) != 1 || units[0].Name != "voice-inbox.service" || !units[0].Enable() {
+		t.Fatalf("unexpected units: %+v", units)
+	}
+	content := units[0].Content
+	for _, want := range []string{
+		`ExecStart="/opt/voice inbox/voice-inbox" daemon`,
+		"ExecReload=/bin/kill -HUP $MAINPID",
+		"User=kai",
+		"WorkingDirectory=/srv/voice-inbox",
+		"ProtectSystem=strict",
+		"NoNewPrivileges=yes",
+		"ReadWritePaths=-/var/lib/voice-inbox -/var/log/voice-inbox\n",
+		"WantedBy=multi-user.target",
+	} {
+		if !strings.Contains(content, want) {
+			t.Fatalf("expected %q in:\n%s", want, content)
+		}
+	}
+}
+
+func TestRenderTimersForUserManager(t *testing.T) {
+	units, err := Render(Options{
+		Mode:                ModeTimers,
+		User:                true,
+		RunAs:               "ignored",
+		Binary:              "/usr/local/bin/voice-inbox",
+		WorkingDir:          "/home/kai/voice-inbox",
+		PollIntervalSeconds: 120,
+		CleanupHour:         3,
+		CleanupMinute:       5,
+	})
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	byName := map[string]Unit{}
+	var enabled []string
+	for _, u := range units {
+		byName[u.Name] = u
+		if u.Enable() {
+			enabled = append(enabled, u.Name)
+		}
+	}
+	if got := strings.Join(enabled, " "); got != "voice-inbox-serve.service voice-inbox-poll.timer voice-inbox-cleanup.timer" {
+		t.Fatalf("unexpected enabled units: %s", got)
+	}
+	if c := byName["voice-inbox-poll.timer"].Content; !strings.Contains(c, "OnUnitActiveSec=120s") {
+		t.Fatalf("unexpected poll timer:\n%s", c)
+	}
+	if c := byName["voice-inbox-cleanup.timer"].Content; !strings.Contains(c, "OnCalendar=*-*-* 03:05:00") {
+		t.Fatalf("unexpected cleanup timer:\n%s", c)
+	}
+	serve := byName["voice-inbox-serve.service"].Content
+	if strings.Contains(serve, "User=") || !strings.Contains(serve, "WantedBy=default.target") || strings.Contains(serve, "ReadWritePaths") {
+		t.Fatalf("unexpected user service:\n%s", serve)
+	}
+	if c := byName["voice-inbox-poll.service"].Content; !strings.Contains(c, "ExecStart=/usr/local/bin/voice-inbox poll --once --json") {
+		t.Fatalf("unexpected poll service:\n%s", c)
+	}
+}
+
+func TestRenderRejectsRelativePathsAndUnknownMode(t *testing.T) {
+	if _, err := Render(Options{Binary: "voice-inbox", WorkingDir: "/srv"}); err == nil {
+		t.Fatalf("expected error for relative binary")
+	}
+	if _, err := Render(Options{Mode: "cron", Binary: "/bin/voice-inbox", WorkingDir: "/srv"}); err == nil {
+		t.Fatalf("expected error for unknown mode")
+	}
+}
@@ -0,0 +1,111 @@
+package tagging
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"voice-inbox-daemon/internal/journal"
+)
+
+const maxSpokenWords = 3
+
+var (
+	typedHashtag    = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&<#/])#([\p{L}\p{N}_/-]+)`)
+	spokenPhrase    = regexp.MustCompile(`(?i)(^|[.!?。！？\n][ \t]*)hash[ -]?tag[\s,:]+([\p{L}\p{N}_-]+(?:[ \t]+[\p{L}\p{N}_-]+){0,` + fmt.Sprint(maxSpokenWords-1) + `})([ \t]*(?:[.,!?;:\n]|$))`)
+	spokenHashtag   = regexp.MustCompile(`(?i)\bhash[ -]?tag[\s,:]+([\p{L}\p{N}_-]+)`)
+	spokenHashtagJA = regexp.MustCompile(`ハッシュタグ[\s、:：]*([^\s、。,.!?！？「」]+)`)
+	extraSpaces     = regexp.MustCompile(`[ \t　]{2,}`)
+	spaceBeforePunc = regexp.MustCompile(`[ \t　]+([,.!?、。！？])`)
+	emptySentence   = regexp.MustCompile(`(^|\n)[ \t]*[,.、。]+[ \t]*`)
+	repeatedPunc    = regexp.MustCompile(`([.!?。！？])[,.、。]+`)
+)
+
+type Tagger struct {
+	hashtags bool
+	keywords []keyword
+}
+
+type keyword struct {
+	word string
+	re   *regexp.Regexp
+	tag  string
+}
+
+func New(hashtags bool, keywords []string) (*Tagger, error) {
+	t := &Tagger{hashtags: hashtags}
+	for _, raw := range keywords {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		word, tag, ok := strings.Cut(raw, "=")
+		word = strings.TrimSpace(word)
+		if !ok || word == "" {
+			return nil, fmt.Errorf("tag keyword %q: want keyword=tag", raw)
+		}
+		normalized := journal.NormalizeTag(tag)
+		if normalized == "" {
+			return nil, fmt.Errorf("tag keyword %q: %q is not a valid tag", raw, strings.TrimSpace(tag))
+		}
+		k := keyword{word: strings.ToLower(word), tag: normalized}
+		if isASCII(word) {
+			k.re = regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(word) + `\b`)
+		}
+		t.keywords = append(t.keywords, k)
+	}
+	return t, nil
+}
+
+func (t *Tagger) Apply(text string) (string, []string) {
+	if t == nil {
+		return text, nil
+	}
+	var tags []string
+	if t.hashtags {
+		for _, m := range typedHashtag.FindAllStringSubmatch(text, -1) {
+			tags = append(tags, m[1])
+		}
+		stripped := text
+		for _, re := range []*regexp.Regexp{spokenPhrase, spokenHashtag, spokenHashtagJA} {
+			group, replacement := 1, ""
+			if re == spokenPhrase {
+				group, replacement = 2, "$1$3"
+			}
+			for _, m := range re.FindAllStringSubmatch(stripped, -1) {
+				tags = append(tags, m[group])
+			}
+			stripped = re.ReplaceAllString(stripped, replacement)
+		}
+		if stripped != text {
+			text = tidy(stripped)
+		}
+	}
+	lower := strings.ToLower(text)
+	for _, k := range t.keywords {
+		if k.re != nil && k.re.MatchString(text) || k.re == nil && strings.Contains(lower, k.word) {
+			tags = append(tags, k.tag)
+		}
+	}
+	return text, journal.MergeTags(nil, tags)
+}
+
+func tidy(text string) string {
+	text = extraSpaces.ReplaceAllString(text, " ")
+	text = spaceBeforePunc.ReplaceAllString(text, "$1")
+	text = repeatedPunc.ReplaceAllString(text, "$1")
+	text = emptySentence.ReplaceAllString(text, "$1")
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,73 @@
+package tagging
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyCollectsTypedHashtags(t *testing.T) {
+	tagger, err := New(true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, tags := tagger.Apply("Shipped the build #Alpha #work/Meetings, see <#123> and https://example.com/page#top. Issue #42")
+	if body != "Shipped the build #Alpha #work/Meetings, see <#123> and https://example.com/page#top. Issue #42" {
+		t.Fatalf("typed hashtags should stay in the body, got %q", body)
+	}
+	if want := []string{"alpha", "work/meetings"}; !reflect.DeepEqual(tags, want) {
+		t.Fatalf("tags = %v, want %v", tags, want)
+	}
+}
+
+func TestApplyStripsSpokenHashtags(t *testing.T) {
+	tagger, _ := New(true, nil)
+	cases := []struct {
+		in, body string
+		tags     []string
+	}{
+		{"Call the vendor tomorrow. Hashtag project alpha.", "Call the vendor tomorrow.", []string{"project-alpha"}},
+		{"hashtag ideas, a better onboarding flow", "a better onboarding flow", []string{"ideas"}},
+		{"企画書を直す。ハッシュタグ 仕事。", "企画書を直す。", []string{"仕事"}},
+		{"hashtag alpha I need to buy milk", "I need to buy milk", []string{"alpha"}},
+		{"Buy milk hashtag errands before Friday. Hashtag home", "Buy milk before Friday.", []string{"home", "errands"}},
+	}
+	for _, tc := range cases {
+		body, tags := tagger.Apply(tc.in)
+		if body != tc.body || !reflect.DeepEqual(tags, tc.tags) {
+			t.Fatalf("Apply(%q) = %q %v, want %q %v", tc.in, body, tags, tc.body, tc.tags)
+		}
+	}
+}
+
+func TestApplyKeywordMappings(t *testing.T) {
+	tagger, err := New(false, []string{"budget=Finance", "予算=finance", "standup=work/meetings"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, tags := tagger.Apply("来期の予算を確認。Standup moved. #ignored")
+	if body != "来期の予算を確認。Standup moved. #ignored" {
+		t.Fatalf("keywords must not change the body, got %q", body)
+	}
+	if want := []string{"finance", "work/meetings"}; !reflect.DeepEqual(tags, want) {
+		t.Fatalf("tags = %v, want %v", tags, want)
+	}
+	if _, tags := tagger.Apply("budgetary notes"); len(tags) != 0 {
+		t.Fatalf("ASCII keywords should match whole words only, got %v", tags)
+	}
+}
+
+func TestNewRejectsBadKeywords(t *testing.T) {
+	for _, raw := range []string{"budget", "=finance", "budget=#", "budget=123"} {
+		if _, err := New(true, []string{raw}); err == nil {
+			t.Fatalf("expected error for %q", raw)
+		}
+	}
+}
+
+func TestNilTaggerIsNoop(t *testing.T) {
+	var tagger *Tagger
+	body, tags := tagger.Apply("hashtag alpha")
+	if body != "hashtag alpha" || tags != nil {
+		t.Fatalf("nil tagger changed input: %q %v", body, tags)
+	}
+}
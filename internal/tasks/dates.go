@@ -0,0 +1,134 @@
+package tasks
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	isoDatePattern     = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`)
+	jaMonthDayPattern  = regexp.MustCompile(`(\d{1,2})月(\d{1,2})日`)
+	jaDaysLaterPattern = regexp.MustCompile(`(\d+)日後`)
+	jaWeekdayPattern   = regexp.MustCompile(`(来週|今週)?の?([月火水木金土日])曜`)
+	enInDaysPattern    = regexp.MustCompile(`\bin (\d+) days?\b`)
+	enWeekdayPattern   = regexp.MustCompile(`\b(next |this |on )?(monday|tuesday|wednesday|thursday|friday|saturday|sunday)\b`)
+	enMonthDayPattern  = regexp.MustCompile(`\b(january|february|march|april|may|june|july|august|september|october|november|december) (\d{1,2})(st|nd|rd|th)?\b`)
+)
+
+var jaWeekdays = map[string]time.Weekday{
+	"日": time.Sunday, "月": time.Monday, "火": time.Tuesday, "水": time.Wednesday,
+	"木": time.Thursday, "金": time.Friday, "土": time.Saturday,
+}
+
+var enWeekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+var enMonths = map[string]time.Month{
+	"january": time.January, "february": time.February, "march": time.March, "april": time.April,
+	"may": time.May, "june": time.June, "july": time.July, "august": time.August,
+	"september": time.September, "october": time.October, "november": time.November, "december": time.December,
+}
+
+func ResolveDue(sentence string, at time.Time) (time.Time, bool) {
+	today := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, at.Location())
+	lower := strings.ToLower(sentence)
+
+	if m := isoDatePattern.FindStringSubmatch(lower); m != nil {
+		y, _ := strconv.Atoi(m[1])
+		mo, _ := strconv.Atoi(m[2])
+		d, _ := strconv.Atoi(m[3])
+		if valid(y, mo, d) {
+			return time.Date(y, time.Month(mo), d, 0, 0, 0, 0, at.Location()), true
+		}
+	}
+	if m := jaMonthDayPattern.FindStringSubmatch(lower); m != nil {
+		mo, _ := strconv.Atoi(m[1])
+		d, _ := strconv.Atoi(m[2])
+		if due, ok := upcomingDate(today, time.Month(mo), d); ok {
+			return due, true
+		}
+	}
+	if m := enMonthDayPattern.FindStringSubmatch(lower); m != nil {
+		d, _ := strconv.Atoi(m[2])
+		if due, ok := upcomingDate(today, enMonths[m[1]], d); ok {
+			return due, true
+		}
+	}
+	switch {
+	case strings.Contains(lower, "明後日"), strings.Contains(lower, "あさって"), strings.Contains(lower, "day after tomorrow"):
+		return today.AddDate(0, 0, 2), true
+	case strings.Contains(lower, "明日"), strings.Contains(lower, "あした"), strings.Contains(lower, "tomorrow"):
+		return today.AddDate(0, 0, 1), true
+	case strings.Contains(lower, "今日"), strings.Contains(lower, "今夜"), wordIn(lower, "today"), wordIn(lower, "tonight"):
+		return today, true
+	}
+	if m := jaDaysLaterPattern.FindStringSubmatch(lower); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return today.AddDate(0, 0, n), true
+	}
+	if m := enInDaysPattern.FindStringSubmatch(lower); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return today.AddDate(0, 0, n), true
+	}
+	if m := jaWeekdayPattern.FindStringSubmatch(lower); m != nil {
+		return weekday(today, jaWeekdays[m[2]], map[string]string{"来週": "next", "今週": "this"}[m[1]]), true
+	}
+	if m := enWeekdayPattern.FindStringSubmatch(lower); m != nil {
+		return weekday(today, enWeekdays[m[2]], strings.TrimSpace(m[1])), true
+	}
+	switch {
+	case strings.Contains(lower, "来週"), strings.Contains(lower, "next week"):
+		return weekday(today, time.Monday, "next"), true
+	case strings.Contains(lower, "in a week"):
+		return today.AddDate(0, 0, 7), true
+	}
+	return time.Time{}, false
+}
+
+func weekday(today time.Time, day time.Weekday, qualifier string) time.Time {
+	switch qualifier {
+	case "next", "this":
+		monday := today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+		if qualifier == "next" {
+			monday = monday.AddDate(0, 0, 7)
+		}
+		due := monday.AddDate(0, 0, (int(day)+6)%7)
+		if due.Before(today) {
+			due = due.AddDate(0, 0, 7)
+		}
+		return due
+	default:
+		diff := (int(day) - int(today.Weekday()) + 7) % 7
+		if diff == 0 {
+			diff = 7
+		}
+		return today.AddDate(0, 0, diff)
+	}
+}
+
+func upcomingDate(today time.Time, month time.Month, day int) (time.Time, bool) {
+	if !valid(today.Year(), int(month), day) {
+		return time.Time{}, false
+	}
+	due := time.Date(today.Year(), month, day, 0, 0, 0, 0, today.Location())
+	if due.Before(today) {
+		due = due.AddDate(1, 0, 0)
+	}
+	return due, true
+}
+
+func valid(y, m, d int) bool {
+	if m < 1 || m > 12 || d < 1 {
+		return false
+	}
+	t := time.Date(y, time.Month(m), d, 0, 0, 0, 0, time.UTC)
+	return t.Day() == d
+}
+
+func wordIn(s, word string) bool {
+	return indexWord(s, word) >= 0
+}
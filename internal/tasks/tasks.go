@@ -0,0 +1,162 @@
+package tasks
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"voice-inbox-daemon/internal/journal"
+)
+
+var DefaultTriggers = []string{
+	"TODO",
+	"remind me to",
+	"don't forget to",
+	"やること",
+	"リマインド",
+	"忘れずに",
+}
+
+const leadingSeparators = ":：、,，　 \t-"
+
+type Extractor struct {
+	triggers []string
+	patterns []*regexp.Regexp
+}
+
+func New(triggers, patterns []string) (*Extractor, error) {
+	e := &Extractor{}
+	for _, t := range triggers {
+		if t = strings.TrimSpace(t); t != "" {
+			e.triggers = append(e.triggers, t)
+		}
+	}
+	for _, p := range patterns {
+		if strings.TrimSpace(p) == "" {
+			continue
+		}
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("task pattern %q: %w", p, err)
+		}
+		e.patterns = append(e.patterns, re)
+	}
+	return e, nil
+}
+
+func (e *Extractor) Extract(text string, at time.Time) []journal.Task {
+	if e == nil {
+		return nil
+	}
+	var out []journal.Task
+	for _, sentence := range Sentences(text) {
+		task, ok := e.match(sentence)
+		if !ok {
+			continue
+		}
+		task = strings.TrimSpace(strings.TrimRight(task, "。.!！?？"))
+		if task == "" {
+			continue
+		}
+		t := journal.Task{Text: task}
+		if due, ok := ResolveDue(sentence, at); ok {
+			t.Due = &due
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+func (e *Extractor) match(sentence string) (string, bool) {
+	for _, re := range e.patterns {
+		m := re.FindStringSubmatch(sentence)
+		if m == nil {
+			continue
+		}
+		if i := re.SubexpIndex("task"); i > 0 && strings.TrimSpace(m[i]) != "" {
+			return m[i], true
+		}
+		return sentence, true
+	}
+	for _, trigger := range e.triggers {
+		idx := indexWord(sentence, trigger)
+		if idx < 0 {
+			continue
+		}
+		if idx == 0 {
+			return strings.TrimLeft(sentence[len(trigger):], leadingSeparators), true
+		}
+		if asciiWord(trigger) {
+			continue
+		}
+		return sentence, true
+	}
+	return "", false
+}
+
+func Sentences(text string) []string {
+	var out []string
+	var b strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(b.String()); s != "" {
+			out = append(out, s)
+		}
+		b.Reset()
+	}
+	runes := []rune(text)
+	for i, r := range runes {
+		switch {
+		case r == '\n' || r == '。' || r == '！' || r == '？':
+			b.WriteRune(r)
+			flush()
+		case (r == '.' || r == '!' || r == '?') && (i+1 == len(runes) || unicode.IsSpace(runes[i+1])):
+			b.WriteRune(r)
+			flush()
+		default:
+			b.WriteRune(r)
+		}
+	}
+	flush()
+	return out
+}
+
+func indexWord(s, word string) int {
+	lower, w := strings.ToLower(s), strings.ToLower(word)
+	for from := 0; from <= len(lower)-len(w); {
+		i := strings.Index(lower[from:], w)
+		if i < 0 {
+			return -1
+		}
+		i += from
+		if boundary(lower, i-1, true) && boundary(lower, i+len(w), false) || !asciiWord(w) {
+			return i
+		}
+		from = i + 1
+	}
+	return -1
+}
+
+func asciiWord(w string) bool {
+	for _, r := range w {
+		if r >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+func boundary(s string, i int, before bool) bool {
+	if i < 0 || i >= len(s) {
+		return true
+	}
+	var r rune
+	if before {
+		r, _ = utf8.DecodeLastRuneInString(s[:i+1])
+	} else {
+		r, _ = utf8.DecodeRuneInString(s[i:])
+	}
+	return !(r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)))
+}
@@ -0,0 +1,82 @@
+package tasks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExtractUsesTriggersAndPatterns(t *testing.T) {
+	e, err := New(DefaultTriggers, []string{`^(?P<task>.+)を買う`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2026, 3, 19, 22, 30, 0, 0, time.UTC)
+	got := e.Extract("TODO: 請求書を送る。今日は疲れた。牛乳を買う\nRemind me to call Bob on Friday. The todos list is long. There is nothing todo today.", at)
+
+	want := []struct {
+		text string
+		due  string
+	}{
+		{"請求書を送る", ""},
+		{"牛乳", ""},
+		{"call Bob on Friday", "2026-03-20"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d tasks, got %+v", len(want), got)
+	}
+	for i, w := range want {
+		due := ""
+		if got[i].Due != nil {
+			due = got[i].Due.Format("2006-01-02")
+		}
+		if got[i].Text != w.text || due != w.due {
+			t.Fatalf("task %d: got %q due=%q, want %q due=%q", i, got[i].Text, due, w.text, w.due)
+		}
+	}
+}
+
+func TestNewRejectsInvalidPattern(t *testing.T) {
+	if _, err := New(nil, []string{"("}); err == nil {
+		t.Fatal("expected invalid pattern error")
+	}
+}
+
+func TestResolveDue(t *testing.T) {
+	at := time.Date(2026, 3, 19, 9, 0, 0, 0, time.UTC)
+	cases := map[string]string{
+		"明日までに送る":                "2026-03-20",
+		"あさって電話":                 "2026-03-21",
+		"今日中に":                   "2026-03-19",
+		"来週の火曜に確認":               "2026-03-24",
+		"今週の土曜":                  "2026-03-21",
+		"木曜に出す":                  "2026-03-26",
+		"3日後にリマインド":              "2026-03-22",
+		"1月5日に更新":                "2027-01-05",
+		"tomorrow morning":       "2026-03-20",
+		"the day after tomorrow": "2026-03-21",
+		"next monday":            "2026-03-23",
+		"this sunday":            "2026-03-22",
+		"in 10 days":             "2026-03-29",
+		"by april 2nd":           "2026-04-02",
+		"deadline 2026-05-01":    "2026-05-01",
+		"next week":              "2026-03-23",
+	}
+	for in, want := range cases {
+		due, ok := ResolveDue(in, at)
+		if !ok || due.Format("2006-01-02") != want {
+			t.Fatalf("%q: got %v ok=%v, want %s", in, due, ok, want)
+		}
+	}
+	saturday := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	for in, want := range map[string]string{"this friday": "2026-10-23", "今週の金曜": "2026-10-23", "this saturday": "2026-10-17"} {
+		due, ok := ResolveDue(in, saturday)
+		if !ok || due.Format("2006-01-02") != want {
+			t.Fatalf("%q on a saturday: got %v ok=%v, want %s", in, due, ok, want)
+		}
+	}
+	for _, in := range []string{"特に期限なし", "todays plan", "2026-02-30"} {
+		if due, ok := ResolveDue(in, at); ok {
+			t.Fatalf("%q: expected no due date, got %v", in, due)
+		}
+	}
+}
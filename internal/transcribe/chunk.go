@@ -0,0 +1,356 @@
+package transcribe
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	minTranscriptionTimeout = 10 * time.Minute
+	energyWindow            = 100 * time.Millisecond
+	maxStitchOverlapRunes   = 400
+	minStitchOverlapRunes   = 4
+)
+
+var ErrNotPCMWav = errors.New("not a 16-bit PCM wav file")
+
+type WavInfo struct {
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+	DataOffset    int64
+	DataSize      int64
+	Duration      time.Duration
+}
+
+type ChunkOptions struct {
+	Length       time.Duration
+	Overlap      time.Duration
+	SearchWindow time.Duration
+}
+
+type Chunk struct {
+	Index int
+	Start time.Duration
+	End   time.Duration
+}
+
+func (c Chunk) Duration() time.Duration {
+	return c.End - c.Start
+}
+
+func ProbeWav(path string) (WavInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return WavInfo{}, err
+	}
+	defer f.Close()
+
+	var riff [12]byte
+	if _, err := io.ReadFull(f, riff[:]); err != nil {
+		return WavInfo{}, ErrNotPCMWav
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return WavInfo{}, ErrNotPCMWav
+	}
+
+	info := WavInfo{}
+	offset := int64(12)
+	haveFormat := false
+	for {
+		var header [8]byte
+		if _, err := io.ReadFull(f, header[:]); err != nil {
+			return WavInfo{}, ErrNotPCMWav
+		}
+		offset += 8
+		id := string(header[0:4])
+		size := int64(binary.LittleEndian.Uint32(header[4:8]))
+
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return WavInfo{}, ErrNotPCMWav
+			}
+			buf := make([]byte, size)
+			if _, err := io.ReadFull(f, buf); err != nil {
+				return WavInfo{}, ErrNotPCMWav
+			}
+			format := binary.LittleEndian.Uint16(buf[0:2])
+			info.Channels = int(binary.LittleEndian.Uint16(buf[2:4]))
+			info.SampleRate = int(binary.LittleEndian.Uint32(buf[4:8]))
+			info.BitsPerSample = int(binary.LittleEndian.Uint16(buf[14:16]))
+			if format != 1 || info.BitsPerSample != 16 || info.Channels <= 0 || info.SampleRate <= 0 {
+				return WavInfo{}, ErrNotPCMWav
+			}
+			haveFormat = true
+			if size%2 == 1 {
+				if _, err := f.Seek(1, io.SeekCurrent); err != nil {
+					return WavInfo{}, ErrNotPCMWav
+				}
+			}
+			offset += size + size%2
+			continue
+		case "data":
+			if !haveFormat {
+				return WavInfo{}, ErrNotPCMWav
+			}
+			stat, err := f.Stat()
+			if err != nil {
+				return WavInfo{}, err
+			}
+			// ffmpeg writes a placeholder size when streaming, so trust the file length.
+			if remaining := stat.Size() - offset; size == 0 || size > remaining {
+				size = remaining
+			}
+			info.DataOffset = offset
+			info.DataSize = size - size%int64(info.frameSize())
+			info.Duration = info.durationOf(info.DataSize)
+			return info, nil
+		}
+
+		skip := size + size%2
+		if _, err := f.Seek(skip, io.SeekCurrent); err != nil {
+			return WavInfo{}, ErrNotPCMWav
+		}
+		offset += skip
+	}
+}
+
+func (w WavInfo) frameSize() int {
+	return w.Channels * w.BitsPerSample / 8
+}
+
+func (w WavInfo) durationOf(bytes int64) time.Duration {
+	frames := bytes / int64(w.frameSize())
+	return time.Duration(frames) * time.Second / time.Duration(w.SampleRate)
+}
+
+func (w WavInfo) byteOffset(at time.Duration) int64 {
+	frames := int64(at) * int64(w.SampleRate) / int64(time.Second)
+	off := frames * int64(w.frameSize())
+	if off > w.DataSize {
+		off = w.DataSize
+	}
+	return off
+}
+
+func PlanChunks(path string, info WavInfo, opts ChunkOptions) ([]Chunk, error) {
+	if opts.Length <= 0 {
+		return nil, fmt.Errorf("chunk length must be > 0")
+	}
+	if opts.Overlap < 0 || opts.Overlap*2 >= opts.Length {
+		return nil, fmt.Errorf("chunk overlap must be >= 0 and less than half the chunk length")
+	}
+	if opts.SearchWindow <= 0 || opts.SearchWindow > opts.Length/2 {
+		opts.SearchWindow = opts.Length / 4
+	}
+	if info.Duration <= opts.Length {
+		return []Chunk{{Index: 0, Start: 0, End: info.Duration}}, nil
+	}
+
+	energies, err := windowEnergies(path, info)
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks []Chunk
+	start := time.Duration(0)
+	for {
+		if info.Duration-start <= opts.Length {
+			chunks = append(chunks, Chunk{Index: len(chunks), Start: start, End: info.Duration})
+			return chunks, nil
+		}
+		target := start + opts.Length
+		cut := quietestPoint(energies, target-opts.SearchWindow, target)
+		if cut-opts.Overlap <= start {
+			cut = target
+		}
+		chunks = append(chunks, Chunk{Index: len(chunks), Start: start, End: cut})
+		start = cut - opts.Overlap
+	}
+}
+
+func windowEnergies(path string, info WavInfo) ([]float64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(info.DataOffset, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	framesPerWindow := int(int64(info.SampleRate) * int64(energyWindow) / int64(time.Second))
+	if framesPerWindow <= 0 {
+		framesPerWindow = 1
+	}
+	buf := make([]byte, framesPerWindow*info.frameSize())
+	reader := bufio.NewReader(io.LimitReader(f, info.DataSize))
+
+	var energies []float64
+	for {
+		n, err := io.ReadFull(reader, buf)
+		if n > 0 {
+			samples := n / 2
+			var sum float64
+			for i := 0; i+1 < n; i += 2 {
+				v := float64(int16(binary.LittleEndian.Uint16(buf[i : i+2])))
+				sum += v * v
+			}
+			energies = append(energies, math.Sqrt(sum/float64(samples)))
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return energies, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func quietestPoint(energies []float64, from, to time.Duration) time.Duration {
+	first := int(from / energyWindow)
+	last := int(to / energyWindow)
+	if first < 0 {
+		first = 0
+	}
+	if last > len(energies) {
+		last = len(energies)
+	}
+	if first >= last {
+		return to
+	}
+	best := first
+	for i := first + 1; i < last; i++ {
+		if energies[i] < energies[best] {
+			best = i
+		}
+	}
+	return time.Duration(best)*energyWindow + energyWindow/2
+}
+
+func WriteChunk(path string, info WavInfo, chunk Chunk, outPath string) error {
+	if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
+		return err
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	begin := info.byteOffset(chunk.Start)
+	end := info.byteOffset(chunk.End)
+	if _, err := src.Seek(info.DataOffset+begin, io.SeekStart); err != nil {
+		return err
+	}
+
+	dst, err := os.Create(outPath)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	size := end - begin
+	if err := writeWavHeader(dst, info, size); err != nil {
+		return err
+	}
+	if _, err := io.CopyN(dst, src, size); err != nil {
+		return fmt.Errorf("copy chunk %d: %w", chunk.Index, err)
+	}
+	return dst.Close()
+}
+
+func writeWavHeader(w io.Writer, info WavInfo, dataSize int64) error {
+	byteRate := info.SampleRate * info.frameSize()
+	header := make([]byte, 44)
+	copy(header[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(header[4:8], uint32(36+dataSize))
+	copy(header[8:12], "WAVE")
+	copy(header[12:16], "fmt ")
+	binary.LittleEndian.PutUint32(header[16:20], 16)
+	binary.LittleEndian.PutUint16(header[20:22], 1)
+	binary.LittleEndian.PutUint16(header[22:24], uint16(info.Channels))
+	binary.LittleEndian.PutUint32(header[24:28], uint32(info.SampleRate))
+	binary.LittleEndian.PutUint32(header[28:32], uint32(byteRate))
+	binary.LittleEndian.PutUint16(header[32:34], uint16(info.frameSize()))
+	binary.LittleEndian.PutUint16(header[34:36], uint16(info.BitsPerSample))
+	copy(header[36:40], "data")
+	binary.LittleEndian.PutUint32(header[40:44], uint32(dataSize))
+	_, err := w.Write(header)
+	return err
+}
+
+func StitchTranscripts(parts []string) string {
+	var out string
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if out == "" {
+			out = part
+			continue
+		}
+		n := overlapLength(out, part)
+		rest := strings.TrimSpace(part[n:])
+		if rest == "" {
+			continue
+		}
+		if needsSpace(out, rest) {
+			out += " "
+		}
+		out += rest
+	}
+	return out
+}
+
+func overlapLength(prev, next string) int {
+	maxRunes := utf8.RuneCountInString(next)
+	if maxRunes > maxStitchOverlapRunes {
+		maxRunes = maxStitchOverlapRunes
+	}
+	best := 0
+	runes := 0
+	for i := range next {
+		if runes > maxRunes {
+			break
+		}
+		if runes >= minStitchOverlapRunes && strings.HasSuffix(prev, next[:i]) {
+			best = i
+		}
+		runes++
+	}
+	if runes >= minStitchOverlapRunes && runes <= maxRunes && strings.HasSuffix(prev, next) {
+		best = len(next)
+	}
+	return best
+}
+
+func needsSpace(prev, next string) bool {
+	last, _ := utf8.DecodeLastRuneInString(prev)
+	first, _ := utf8.DecodeRuneInString(next)
+	return last < utf8.RuneSelf && first < utf8.RuneSelf && last != ' ' && first != ' '
+}
+
+func TranscriptionTimeout(audio time.Duration, factor float64) time.Duration {
+	scaled := time.Duration(float64(audio) * factor)
+	if scaled < minTranscriptionTimeout {
+		return minTranscriptionTimeout
+	}
+	return scaled
+}
+
+func ContextWithTranscriptionTimeoutFor(parent context.Context, audio time.Duration, factor float64) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(parent, TranscriptionTimeout(audio, factor))
+}
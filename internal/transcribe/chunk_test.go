@@ -0,0 +1,127 @@
+package transcribe
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeTestWav(t *testing.T, path string, sampleRate int, segments []struct {
+	seconds float64
+	loud    bool
+}) {
+	t.Helper()
+	var samples []int16
+	for _, seg := range segments {
+		n := int(seg.seconds * float64(sampleRate))
+		for i := 0; i < n; i++ {
+			v := int16(0)
+			if seg.loud {
+				v = 8000
+				if i%2 == 1 {
+					v = -8000
+				}
+			}
+			samples = append(samples, v)
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("create wav: %v", err)
+	}
+	defer f.Close()
+	info := WavInfo{SampleRate: sampleRate, Channels: 1, BitsPerSample: 16}
+	if err := writeWavHeader(f, info, int64(len(samples)*2)); err != nil {
+		t.Fatalf("write header: %v", err)
+	}
+	if err := binary.Write(f, binary.LittleEndian, samples); err != nil {
+		t.Fatalf("write samples: %v", err)
+	}
+}
+
+func TestProbeWavRejectsNonWav(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fake.wav")
+	if err := os.WriteFile(path, []byte("FAKE_AUDIO"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ProbeWav(path); err != ErrNotPCMWav {
+		t.Fatalf("expected ErrNotPCMWav, got %v", err)
+	}
+}
+
+func TestPlanChunksCutsAtSilence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "long.wav")
+	writeTestWav(t, path, 8000, []struct {
+		seconds float64
+		loud    bool
+	}{
+		{seconds: 8.5, loud: true},
+		{seconds: 0.5, loud: false},
+		{seconds: 11, loud: true},
+	})
+
+	info, err := ProbeWav(path)
+	if err != nil {
+		t.Fatalf("probe wav: %v", err)
+	}
+	if info.Duration != 20*time.Second {
+		t.Fatalf("expected 20s duration, got %s", info.Duration)
+	}
+
+	chunks, err := PlanChunks(path, info, ChunkOptions{Length: 10 * time.Second, Overlap: time.Second, SearchWindow: 3 * time.Second})
+	if err != nil {
+		t.Fatalf("plan chunks: %v", err)
+	}
+	if len(chunks) != 3 {
+		t.Fatalf("expected 3 chunks, got %+v", chunks)
+	}
+	if chunks[0].End < 8500*time.Millisecond || chunks[0].End > 9*time.Second {
+		t.Fatalf("expected first cut inside the silent gap, got %s", chunks[0].End)
+	}
+	if chunks[1].Start != chunks[0].End-time.Second {
+		t.Fatalf("expected 1s overlap, got %+v", chunks[:2])
+	}
+	if last := chunks[len(chunks)-1]; last.End != info.Duration {
+		t.Fatalf("expected last chunk to end at %s, got %s", info.Duration, last.End)
+	}
+
+	out := filepath.Join(t.TempDir(), "chunk.wav")
+	if err := WriteChunk(path, info, chunks[1], out); err != nil {
+		t.Fatalf("write chunk: %v", err)
+	}
+	chunkInfo, err := ProbeWav(out)
+	if err != nil {
+		t.Fatalf("probe chunk: %v", err)
+	}
+	if chunkInfo.Duration != chunks[1].Duration() {
+		t.Fatalf("expected chunk duration %s, got %s", chunks[1].Duration(), chunkInfo.Duration)
+	}
+}
+
+func TestStitchTranscriptsRemovesOverlap(t *testing.T) {
+	cases := []struct {
+		parts []string
+		want  string
+	}{
+		{parts: []string{"今日は天気が良いので散歩に", "良いので散歩に行きました"}, want: "今日は天気が良いので散歩に行きました"},
+		{parts: []string{"deploy the api server", "api server on friday"}, want: "deploy the api server on friday"},
+		{parts: []string{"first part", "", "second part"}, want: "first part second part"},
+		{parts: []string{"abc", "abd"}, want: "abc abd"},
+	}
+	for _, tc := range cases {
+		if got := StitchTranscripts(tc.parts); got != tc.want {
+			t.Fatalf("StitchTranscripts(%q) = %q, want %q", tc.parts, got, tc.want)
+		}
+	}
+}
+
+func TestTranscriptionTimeoutScalesWithDuration(t *testing.T) {
+	if got := TranscriptionTimeout(time.Minute, 3); got != 10*time.Minute {
+		t.Fatalf("expected floor of 10m, got %s", got)
+	}
+	if got := TranscriptionTimeout(time.Hour, 3); got != 3*time.Hour {
+		t.Fatalf("expected 3h, got %s", got)
+	}
+}
@@ -0,0 +1,133 @@
+package transcribe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"voice-inbox-daemon/internal/metrics"
+)
+
+var (
+	ErrNoAudioStream  = errors.New("no audio stream found")
+	ErrUnreadableFile = errors.New("file is not readable media")
+)
+
+type AudioInfo struct {
+	DurationMS int64
+	Codec      string
+	Channels   int
+	SampleRate int
+	Bitrate    int64
+}
+
+func ProbeAudio(ctx context.Context, ffprobeBin, inputPath string) (AudioInfo, error) {
+	cmd := exec.CommandContext(
+		ctx,
+		ffprobeBin,
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		inputPath,
+	)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	started := time.Now()
+	out, err := cmd.Output()
+	metrics.ExternalCallDuration.ObserveSince(started, "ffmpeg", "probe", metrics.Result(err))
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return AudioInfo{}, fmt.Errorf("ffprobe interrupted: %w", ctxErr)
+		}
+		msg := strings.TrimSpace(stderr.String())
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.Exited() && invalidMedia(msg) {
+			return AudioInfo{}, fmt.Errorf("%w: ffprobe: %s", ErrUnreadableFile, msg)
+		}
+		if msg != "" {
+			return AudioInfo{}, fmt.Errorf("ffprobe failed: %w: %s", err, msg)
+		}
+		return AudioInfo{}, fmt.Errorf("ffprobe failed: %w", err)
+	}
+	return parseProbeOutput(out)
+}
+
+var invalidMediaMarkers = []string{
+	"invalid data found when processing input",
+	"moov atom not found",
+	"could not find codec parameters",
+	"does not contain any stream",
+}
+
+func invalidMedia(stderr string) bool {
+	lower := strings.ToLower(stderr)
+	for _, marker := range invalidMediaMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func parseProbeOutput(out []byte) (AudioInfo, error) {
+	var payload struct {
+		Streams []struct {
+			CodecType  string `json:"codec_type"`
+			CodecName  string `json:"codec_name"`
+			Channels   int    `json:"channels"`
+			SampleRate string `json:"sample_rate"`
+			BitRate    string `json:"bit_rate"`
+			Duration   string `json:"duration"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+			BitRate  string `json:"bit_rate"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(out, &payload); err != nil {
+		return AudioInfo{}, fmt.Errorf("%w: parse ffprobe json: %v", ErrUnreadableFile, err)
+	}
+
+	for _, st := range payload.Streams {
+		if st.CodecType != "audio" {
+			continue
+		}
+		info := AudioInfo{
+			Codec:      st.CodecName,
+			Channels:   st.Channels,
+			SampleRate: parseInt(st.SampleRate),
+			Bitrate:    int64(parseInt(st.BitRate)),
+			DurationMS: parseSecondsMS(payload.Format.Duration),
+		}
+		if info.DurationMS == 0 {
+			info.DurationMS = parseSecondsMS(st.Duration)
+		}
+		if info.Bitrate == 0 {
+			info.Bitrate = int64(parseInt(payload.Format.BitRate))
+		}
+		return info, nil
+	}
+	return AudioInfo{}, ErrNoAudioStream
+}
+
+func parseInt(raw string) int {
+	v, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+func parseSecondsMS(raw string) int64 {
+	v, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil || v < 0 {
+		return 0
+	}
+	return int64(v * 1000)
+}
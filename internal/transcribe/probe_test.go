@@ -0,0 +1,89 @@
+package transcribe
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseProbeOutputReadsAudioStream(t *testing.T) {
+	out := []byte(`{
+		"streams": [
+			{"codec_type": "video", "codec_name": "mjpeg"},
+			{"codec_type": "audio", "codec_name": "opus", "channels": 1, "sample_rate": "48000"}
+		],
+		"format": {"duration": "134.250000", "bit_rate": "32000"}
+	}`)
+	info, err := parseProbeOutput(out)
+	if err != nil {
+		t.Fatalf("parse probe output: %v", err)
+	}
+	want := AudioInfo{DurationMS: 134250, Codec: "opus", Channels: 1, SampleRate: 48000, Bitrate: 32000}
+	if info != want {
+		t.Fatalf("got %+v, want %+v", info, want)
+	}
+}
+
+func TestParseProbeOutputRejectsFilesWithoutAudio(t *testing.T) {
+	_, err := parseProbeOutput([]byte(`{"streams":[{"codec_type":"video","codec_name":"h264"}],"format":{"duration":"3.0"}}`))
+	if !errors.Is(err, ErrNoAudioStream) {
+		t.Fatalf("expected ErrNoAudioStream, got %v", err)
+	}
+	_, err = parseProbeOutput([]byte(`not json`))
+	if !errors.Is(err, ErrUnreadableFile) {
+		t.Fatalf("expected ErrUnreadableFile, got %v", err)
+	}
+}
+
+func TestProbeAudioCancelIsNotUnreadable(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "ffprobe")
+	if err := os.WriteFile(bin, []byte("#!/bin/sh\nexec sleep 5\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := ProbeAudio(ctx, bin, filepath.Join(dir, "memo.ogg"))
+	if err == nil {
+		t.Fatal("expected an error from a cancelled probe")
+	}
+	if errors.Is(err, ErrUnreadableFile) || errors.Is(err, ErrNoAudioStream) {
+		t.Fatalf("cancelled probe must stay retryable, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the context error, got %v", err)
+	}
+}
+
+func TestProbeAudioReportsUnreadableWhenFFprobeExits(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "ffprobe")
+	if err := os.WriteFile(bin, []byte("#!/bin/sh\necho 'Invalid data found when processing input' >&2\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ProbeAudio(context.Background(), bin, filepath.Join(dir, "memo.ogg")); !errors.Is(err, ErrUnreadableFile) {
+		t.Fatalf("expected ErrUnreadableFile, got %v", err)
+	}
+}
+
+func TestProbeAudioKeepsFileAndIOErrorsRetryable(t *testing.T) {
+	dir := t.TempDir()
+	for _, msg := range []string{
+		"memo.ogg: No such file or directory",
+		"memo.ogg: Permission denied",
+		"memo.ogg: Input/output error",
+	} {
+		bin := filepath.Join(dir, "ffprobe")
+		if err := os.WriteFile(bin, []byte("#!/bin/sh\necho '"+msg+"' >&2\nexit 1\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		_, err := ProbeAudio(context.Background(), bin, filepath.Join(dir, "memo.ogg"))
+		if err == nil || errors.Is(err, ErrUnreadableFile) || errors.Is(err, ErrNoAudioStream) {
+			t.Fatalf("%q must stay retryable, got %v", msg, err)
+		}
+	}
+}
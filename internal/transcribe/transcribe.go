@@ -9,6 +9,8 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"voice-inbox-daemon/internal/metrics"
 )
 
 type WhisperConfig struct {
@@ -37,13 +39,40 @@ func NormalizeToWav(ctx context.Context, ffmpegBin, inputPath, outputWavPath str
 		"-c:a", "pcm_s16le",
 		outputWavPath,
 	)
+	started := time.Now()
 	out, err := cmd.CombinedOutput()
+	metrics.ExternalCallDuration.ObserveSince(started, "ffmpeg", "normalize", metrics.Result(err))
 	if err != nil {
 		return fmt.Errorf("ffmpeg failed: %w: %s", err, strings.TrimSpace(string(out)))
 	}
 	return nil
 }
 
+func EncodeOpus(ctx context.Context, ffmpegBin, inputPath, outputPath string) error {
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(
+		ctx,
+		ffmpegBin,
+		"-y",
+		"-i", inputPath,
+		"-vn",
+		"-ac", "1",
+		"-c:a", "libopus",
+		"-b:a", "32k",
+		"-application", "voip",
+		outputPath,
+	)
+	started := time.Now()
+	out, err := cmd.CombinedOutput()
+	metrics.ExternalCallDuration.ObserveSince(started, "ffmpeg", "encode_opus", metrics.Result(err))
+	if err != nil {
+		return fmt.Errorf("ffmpeg opus encode failed: %w: %s", err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
 func RunWhisper(ctx context.Context, cfg WhisperConfig, wavPath, outputDir string) (Result, error) {
 	if err := os.MkdirAll(outputDir, 0o755); err != nil {
 		return Result{}, err
@@ -59,7 +88,9 @@ func RunWhisper(ctx context.Context, cfg WhisperConfig, wavPath, outputDir strin
 		"--output_dir", outputDir,
 		"--verbose", "False",
 	)
+	started := time.Now()
 	out, err := cmd.CombinedOutput()
+	metrics.ExternalCallDuration.ObserveSince(started, "whisper", "transcribe", metrics.Result(err))
 	if err != nil {
 		return Result{}, fmt.Errorf("whisper failed: %w: %s", err, strings.TrimSpace(string(out)))
 	}
@@ -116,5 +147,5 @@ func extractWhisperText(path string) (string, error) {
 }
 
 func ContextWithTranscriptionTimeout(parent context.Context) (context.Context, context.CancelFunc) {
-	return context.WithTimeout(parent, 10*time.Minute)
+	return context.WithTimeout(parent, minTranscriptionTimeout)
 }
@@ -3,26 +3,18 @@ set -euo pipefail
 
 SCRIPT_DIR="$(cd "$(dirname "${BASH_SOURCE[0]}")" && pwd)"
 PROJECT_DIR="$(cd "$SCRIPT_DIR/.." && pwd)"
-ENV_FILE="$PROJECT_DIR/.env"
+BIN="$PROJECT_DIR/dist/voice-inbox"
 
-if [[ ! -f "$ENV_FILE" ]]; then
-  echo "[warn] $ENV_FILE がありません。先に .env.example から .env を作成してください。"
+if [[ ! -x "$BIN" ]]; then
+  echo "[warn] $BIN がありません。先に go build -o ./dist/voice-inbox ./cmd/voice-inbox を実行してください。"
   exit 1
 fi
 
-token="${1:-}"
-if [[ -z "$token" ]]; then
-  read -r -s -p "Discord Bot Token: " token
-  echo
-fi
+echo "[info] このスクリプトは非推奨です。voice-inbox secret set DISCORD_BOT_TOKEN を使ってください。"
 
-if [[ -z "$token" ]]; then
-  echo "[warn] token が空です。"
-  exit 1
+cd "$PROJECT_DIR"
+if [[ -n "${1:-}" ]]; then
+  printf '%s' "$1" | "$BIN" secret set DISCORD_BOT_TOKEN --store dotenv
+else
+  "$BIN" secret set DISCORD_BOT_TOKEN --store dotenv
 fi
-
-escaped_token=$(printf '%s' "$token" | sed 's/[&/\\]/\\&/g')
-perl -0777 -i -pe "s|^DISCORD_BOT_TOKEN=.*$|DISCORD_BOT_TOKEN=${escaped_token}|m" "$ENV_FILE"
-chmod 600 "$ENV_FILE"
-
-echo "updated: DISCORD_BOT_TOKEN=[SET]"